@@ -73,7 +73,7 @@ func main() {
 		})
 	})
 
-	api.RegisterRoutes(router, db)
+	api.RegisterRoutes(router, db, cfg)
 
 	// Start cleanup service
 	cleanupService := cleanup.NewService(db, 10*time.Minute)
@@ -95,12 +95,25 @@ func main() {
 	fmt.Println("  POST /auth/login    - Login user")
 	fmt.Println("  GET  /auth/me       - Get current user info")
 	fmt.Println("  POST /auth/logout   - Logout user")
-	fmt.Println("  POST /analyze       - Analyze binary (with optional ?dynamic=true)")
-	fmt.Println("  GET  /analyze       - List all analysis results")
-	fmt.Println("  GET  /analyze/{id}  - Get specific analysis result")
+	fmt.Println("  POST /analyze       - Analyze binary or ar/tar(.gz)/zip archive (with optional ?dynamic=true, ?strings=true&min_len=N, ?yara=true)")
+	fmt.Println("  POST /analyze       - ELF core dump; an optional 'executable' form file symbolizes its stack frames")
+	fmt.Println("  GET  /analyze       - List all analysis results (?import_hash=&text_hash=&section_hash=)")
+	fmt.Println("  GET  /analyze/diff  - Compare two analysis results (?a={id}&b={id})")
+	fmt.Println("  GET  /analyze/{id}  - Get specific analysis result (?demangle=true)")
+	fmt.Println("  GET  /analyze/{id}/strings - Get extracted strings (?offset=&limit=&category=&section=)")
+	fmt.Println("  GET  /analyze/{id}/disasm  - Disassemble entry point or symbol (?symbol=main&count=200)")
+	fmt.Println("  GET  /analyze/{id}/callgraph - Recovered functions and call graph (?format=json|dot)")
+	fmt.Println("  GET  /analyze/{id}/deps      - Resolve shared library dependencies (?sysroot=name&origin=/usr/bin)")
+	fmt.Println("  GET  /analyze/{id}/similar   - Previous results ranked by TLSH/ssdeep similarity (?min_score=&offset=&limit=)")
+	fmt.Println("  GET  /analyze/{id}/syscalls/summary - Per-syscall counts, errors and time of a dynamic trace")
+	fmt.Println("  GET  /rules         - List loaded detection rules")
+	fmt.Println("  POST /admin/rules/reload - Reload detection rules from disk (admin only)")
+	fmt.Println("  GET  /yara          - List loaded YARA rules and skipped unsupported ones")
+	fmt.Println("  POST /admin/yara/reload  - Reload YARA rules from disk (admin only)")
 	fmt.Println("  POST /bench         - Benchmark binary (with optional ?trace=true)")
 	fmt.Println("  GET  /bench         - List all benchmark results")
 	fmt.Println("  GET  /bench/{id}    - Get specific benchmark result")
+	fmt.Println("  GET  /bench/{id}/syscalls/summary - Per-syscall counts, errors and time of a traced benchmark")
 	fmt.Println("  GET  /proc/{pid}    - Inspect process")
 	fmt.Println("  GET  /proc/{pid}/files - Get process open files")
 	fmt.Println("  GET  /proc/{pid}/net   - Get process network connections")
@@ -0,0 +1,536 @@
+package analyzer
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/arch/arm64/arm64asm"
+	"golang.org/x/arch/x86/x86asm"
+)
+
+const (
+	// Bounds so hostile or huge inputs cannot stall the request
+	maxDiscoveredFunctions = 20000
+	maxFunctionInsts       = 20000
+)
+
+// Function discovery sources
+const (
+	FuncSourceEntry     = "entry"
+	FuncSourceSymbol    = "symbol"
+	FuncSourceEHFrame   = "eh_frame"
+	FuncSourceInitArray = "init_array"
+	FuncSourceFiniArray = "fini_array"
+	FuncSourcePrologue  = "prologue"
+	FuncSourceCall      = "call"
+)
+
+type FunctionInfo struct {
+	Address       uint64   `json:"address"`
+	Name          string   `json:"name"`
+	Size          uint64   `json:"size"`
+	BasicBlocks   int      `json:"basic_blocks"`
+	Instructions  int      `json:"instructions"`
+	Calls         []uint64 `json:"calls,omitempty"`
+	ImportedCalls []string `json:"imported_calls,omitempty"`
+	IndirectCalls int      `json:"indirect_calls,omitempty"`
+	Sources       []string `json:"sources"`
+}
+
+type CallEdge struct {
+	From uint64 `json:"from"`
+	To   uint64 `json:"to"`
+}
+
+type CallGraph struct {
+	Arch      string         `json:"arch"`
+	Functions []FunctionInfo `json:"functions"`
+	Edges     []CallEdge     `json:"edges"`
+}
+
+type flowKind int
+
+const (
+	flowNormal flowKind = iota
+	flowCall
+	flowIndirectCall
+	flowJump
+	flowCondJump
+	flowIndirectJump
+	flowReturn
+	flowHalt
+)
+
+type flowInst struct {
+	length int
+	kind   flowKind
+	target uint64
+}
+
+type flowDecoder func(code []byte, pc uint64) (flowInst, error)
+
+// codeRegion is an executable chunk of the image with its backing bytes
+type codeRegion struct {
+	name string
+	addr uint64
+	data []byte
+}
+
+type callGraphBuilder struct {
+	elfFile *elf.File
+	decode  flowDecoder
+	regions []codeRegion
+	symbols *symbolTable
+	plt     map[uint64]string
+
+	funcs   map[uint64]*FunctionInfo
+	queue   []uint64
+	edgeSet map[CallEdge]struct{}
+}
+
+// BuildCallGraph recovers function boundaries by recursive descent from the
+// entry point, symbols, .eh_frame FDEs, init/fini arrays and prologue scanning
+func BuildCallGraph(fileBytes []byte) (*CallGraph, error) {
+	elfFile, err := elf.NewFile(bytes.NewReader(fileBytes))
+	if err != nil {
+		return nil, fmt.Errorf("error elf parsing: %s", err.Error())
+	}
+
+	b := &callGraphBuilder{
+		elfFile: elfFile,
+		symbols: newSymbolTable(elfFile),
+		plt:     pltSymbols(elfFile),
+		funcs:   make(map[uint64]*FunctionInfo),
+		edgeSet: make(map[CallEdge]struct{}),
+	}
+
+	graph := &CallGraph{}
+	switch elfFile.Machine {
+	case elf.EM_X86_64:
+		graph.Arch = "x86-64"
+		b.decode = x86FlowDecoder(64)
+	case elf.EM_386:
+		graph.Arch = "i386"
+		b.decode = x86FlowDecoder(32)
+	case elf.EM_AARCH64:
+		graph.Arch = "arm64"
+		b.decode = arm64FlowDecoder
+	default:
+		return nil, fmt.Errorf("unsupported machine for call graph: %s", elfFile.Machine)
+	}
+
+	b.regions = executableRegions(elfFile, fileBytes)
+	b.seed(fileBytes)
+
+	for len(b.queue) > 0 && len(b.funcs) <= maxDiscoveredFunctions {
+		addr := b.queue[0]
+		b.queue = b.queue[1:]
+		b.explore(b.funcs[addr])
+	}
+
+	for _, fn := range b.funcs {
+		graph.Functions = append(graph.Functions, *fn)
+	}
+	sort.Slice(graph.Functions, func(i, j int) bool {
+		return graph.Functions[i].Address < graph.Functions[j].Address
+	})
+	for edge := range b.edgeSet {
+		graph.Edges = append(graph.Edges, edge)
+	}
+	sort.Slice(graph.Edges, func(i, j int) bool {
+		if graph.Edges[i].From != graph.Edges[j].From {
+			return graph.Edges[i].From < graph.Edges[j].From
+		}
+		return graph.Edges[i].To < graph.Edges[j].To
+	})
+
+	return graph, nil
+}
+
+func executableRegions(elfFile *elf.File, fileBytes []byte) []codeRegion {
+	var regions []codeRegion
+	for _, sec := range elfFile.Sections {
+		if sec.Flags&elf.SHF_EXECINSTR == 0 || sec.Type == elf.SHT_NOBITS || sec.Size == 0 {
+			continue
+		}
+		regions = append(regions, codeRegion{
+			name: sec.Name,
+			addr: sec.Addr,
+			data: fileRegion(fileBytes, sec.Offset, sec.Size),
+		})
+	}
+	if len(regions) > 0 {
+		return regions
+	}
+	// No section headers: fall back to executable segments
+	for i, ph := range elfFile.Progs {
+		if ph.Type == elf.PT_LOAD && ph.Flags&elf.PF_X != 0 {
+			regions = append(regions, codeRegion{
+				name: fmt.Sprintf("segment%d", i),
+				addr: ph.Vaddr,
+				data: fileRegion(fileBytes, ph.Off, ph.Filesz),
+			})
+		}
+	}
+	return regions
+}
+
+func (b *callGraphBuilder) regionAt(addr uint64) *codeRegion {
+	for i := range b.regions {
+		r := &b.regions[i]
+		if addr >= r.addr && addr < r.addr+uint64(len(r.data)) {
+			return r
+		}
+	}
+	return nil
+}
+
+func isPLTRegion(name string) bool {
+	return strings.HasPrefix(name, ".plt") || name == ".iplt"
+}
+
+// addFunction registers a function start, queueing it for exploration the
+// first time it is seen
+func (b *callGraphBuilder) addFunction(addr uint64, source string) {
+	region := b.regionAt(addr)
+	if region == nil || isPLTRegion(region.name) {
+		return
+	}
+	if fn, ok := b.funcs[addr]; ok {
+		for _, s := range fn.Sources {
+			if s == source {
+				return
+			}
+		}
+		fn.Sources = append(fn.Sources, source)
+		return
+	}
+	if len(b.funcs) >= maxDiscoveredFunctions {
+		return
+	}
+
+	name := b.symbols.describe(addr)
+	if name == "" || strings.Contains(name, "+0x") {
+		name = fmt.Sprintf("sub_%x", addr)
+	}
+	b.funcs[addr] = &FunctionInfo{
+		Address: addr,
+		Name:    name,
+		Sources: []string{source},
+	}
+	b.queue = append(b.queue, addr)
+}
+
+func (b *callGraphBuilder) seed(fileBytes []byte) {
+	if b.elfFile.Entry != 0 {
+		b.addFunction(b.elfFile.Entry, FuncSourceEntry)
+	}
+
+	for _, sym := range b.symbols.syms {
+		if elf.ST_TYPE(sym.Info) == elf.STT_FUNC {
+			b.addFunction(sym.Value, FuncSourceSymbol)
+		}
+	}
+
+	for _, fde := range parseEHFrame(b.elfFile) {
+		b.addFunction(fde.Start, FuncSourceEHFrame)
+	}
+
+	for _, addr := range pointerArray(b.elfFile, fileBytes, ".init_array") {
+		b.addFunction(addr, FuncSourceInitArray)
+	}
+	for _, addr := range pointerArray(b.elfFile, fileBytes, ".fini_array") {
+		b.addFunction(addr, FuncSourceFiniArray)
+	}
+
+	for _, addr := range b.scanPrologues() {
+		b.addFunction(addr, FuncSourcePrologue)
+	}
+}
+
+// pointerArray reads the function pointers in .init_array/.fini_array. PIE
+// images linked with RELA may leave the slots zeroed and keep the value in
+// R_*_RELATIVE addends instead.
+func pointerArray(elfFile *elf.File, fileBytes []byte, name string) []uint64 {
+	sec := elfFile.Section(name)
+	if sec == nil || sec.Type == elf.SHT_NOBITS {
+		return nil
+	}
+	data := fileRegion(fileBytes, sec.Offset, sec.Size)
+
+	ptrSize := 4
+	if elfFile.Class == elf.ELFCLASS64 {
+		ptrSize = 8
+	}
+
+	relative := relativeAddends(elfFile)
+	var ptrs []uint64
+	for off := 0; off+ptrSize <= len(data); off += ptrSize {
+		var val uint64
+		if ptrSize == 8 {
+			val = elfFile.ByteOrder.Uint64(data[off:])
+		} else {
+			val = uint64(elfFile.ByteOrder.Uint32(data[off:]))
+		}
+		if val == 0 {
+			val = relative[sec.Addr+uint64(off)]
+		}
+		// -1 and 0 are used as list terminators by some toolchains
+		if val != 0 && val != ^uint64(0) && val != 0xffffffff {
+			ptrs = append(ptrs, val)
+		}
+	}
+	return ptrs
+}
+
+// scanPrologues looks for common function entry sequences at aligned addresses
+func (b *callGraphBuilder) scanPrologues() []uint64 {
+	var found []uint64
+	for _, r := range b.regions {
+		if isPLTRegion(r.name) {
+			continue
+		}
+		switch b.elfFile.Machine {
+		case elf.EM_X86_64, elf.EM_386:
+			for off := 0; off+4 <= len(r.data); off += 16 {
+				code := r.data[off:]
+				if _, ok := x86Endbr(code); ok {
+					found = append(found, r.addr+uint64(off))
+					continue
+				}
+				// push %rbp; mov %rsp,%rbp
+				if bytes.HasPrefix(code, []byte{0x55, 0x48, 0x89, 0xe5}) || bytes.HasPrefix(code, []byte{0x55, 0x89, 0xe5}) {
+					found = append(found, r.addr+uint64(off))
+				}
+			}
+		case elf.EM_AARCH64:
+			for off := 0; off+4 <= len(r.data); off += 4 {
+				word := binary.LittleEndian.Uint32(r.data[off:])
+				// paciasp, or stp x29, x30, [sp, #-N]!
+				if word == 0xd503233f || word&0xffc07fff == 0xa9807bfd {
+					found = append(found, r.addr+uint64(off))
+				}
+			}
+		}
+	}
+	return found
+}
+
+// explore walks the basic blocks reachable from fn's entry without crossing
+// into other known functions
+func (b *callGraphBuilder) explore(fn *FunctionInfo) {
+	blocks := map[uint64]bool{fn.Address: true}
+	work := []uint64{fn.Address}
+	visited := make(map[uint64]bool)
+	calls := make(map[uint64]bool)
+	imported := make(map[string]bool)
+	end := fn.Address
+
+	for len(work) > 0 && fn.Instructions < maxFunctionInsts {
+		pc := work[len(work)-1]
+		work = work[:len(work)-1]
+
+		for fn.Instructions < maxFunctionInsts {
+			if visited[pc] {
+				break
+			}
+			if pc != fn.Address {
+				if _, other := b.funcs[pc]; other {
+					break
+				}
+			}
+			region := b.regionAt(pc)
+			if region == nil {
+				break
+			}
+			inst, err := b.decode(region.data[pc-region.addr:], pc)
+			if err != nil || inst.length == 0 {
+				break
+			}
+			visited[pc] = true
+			fn.Instructions++
+			next := pc + uint64(inst.length)
+			if next > end {
+				end = next
+			}
+
+			switch inst.kind {
+			case flowCall:
+				if name, ok := b.plt[inst.target]; ok {
+					imported[name] = true
+				} else if target := b.regionAt(inst.target); target != nil && isPLTRegion(target.name) {
+					imported[fmt.Sprintf("plt_%x", inst.target)] = true
+				} else {
+					calls[inst.target] = true
+					b.addFunction(inst.target, FuncSourceCall)
+				}
+			case flowIndirectCall:
+				fn.IndirectCalls++
+			case flowCondJump:
+				if !blocks[inst.target] {
+					blocks[inst.target] = true
+					work = append(work, inst.target)
+				}
+				if !blocks[next] {
+					blocks[next] = true
+				}
+			case flowJump:
+				if name, ok := b.plt[inst.target]; ok {
+					// Tail call through the PLT
+					imported[name] = true
+				} else if _, other := b.funcs[inst.target]; other && inst.target != fn.Address {
+					calls[inst.target] = true
+				} else if !blocks[inst.target] {
+					blocks[inst.target] = true
+					work = append(work, inst.target)
+				}
+			}
+
+			if inst.kind == flowJump || inst.kind == flowIndirectJump || inst.kind == flowReturn || inst.kind == flowHalt {
+				break
+			}
+			pc = next
+		}
+	}
+
+	fn.Size = end - fn.Address
+	fn.BasicBlocks = len(blocks)
+	for target := range calls {
+		fn.Calls = append(fn.Calls, target)
+		b.edgeSet[CallEdge{From: fn.Address, To: target}] = struct{}{}
+	}
+	sort.Slice(fn.Calls, func(i, j int) bool { return fn.Calls[i] < fn.Calls[j] })
+	for name := range imported {
+		fn.ImportedCalls = append(fn.ImportedCalls, name)
+	}
+	sort.Strings(fn.ImportedCalls)
+}
+
+func x86FlowDecoder(mode int) flowDecoder {
+	return func(code []byte, pc uint64) (flowInst, error) {
+		if _, ok := x86Endbr(code); ok {
+			return flowInst{length: 4}, nil
+		}
+		inst, err := x86asm.Decode(code, mode)
+		if err != nil {
+			return flowInst{}, err
+		}
+		fi := flowInst{length: inst.Len}
+
+		var rel x86asm.Rel
+		direct := false
+		if r, ok := inst.Args[0].(x86asm.Rel); ok {
+			rel = r
+			direct = true
+		}
+		target := uint64(int64(pc) + int64(inst.Len) + int64(rel))
+
+		switch inst.Op {
+		case x86asm.CALL:
+			if direct {
+				fi.kind, fi.target = flowCall, target
+			} else {
+				fi.kind = flowIndirectCall
+			}
+		case x86asm.JMP:
+			if direct {
+				fi.kind, fi.target = flowJump, target
+			} else {
+				fi.kind = flowIndirectJump
+			}
+		case x86asm.RET, x86asm.LRET, x86asm.IRET, x86asm.IRETD, x86asm.IRETQ:
+			fi.kind = flowReturn
+		case x86asm.HLT, x86asm.UD2, x86asm.UD1, x86asm.UD0:
+			fi.kind = flowHalt
+		case x86asm.JA, x86asm.JAE, x86asm.JB, x86asm.JBE, x86asm.JCXZ, x86asm.JE, x86asm.JECXZ,
+			x86asm.JG, x86asm.JGE, x86asm.JL, x86asm.JLE, x86asm.JNE, x86asm.JNO, x86asm.JNP,
+			x86asm.JNS, x86asm.JO, x86asm.JP, x86asm.JRCXZ, x86asm.JS,
+			x86asm.LOOP, x86asm.LOOPE, x86asm.LOOPNE:
+			if direct {
+				fi.kind, fi.target = flowCondJump, target
+			}
+		}
+		return fi, nil
+	}
+}
+
+func arm64FlowDecoder(code []byte, pc uint64) (flowInst, error) {
+	if len(code) < 4 {
+		return flowInst{}, fmt.Errorf("truncated")
+	}
+	inst, err := arm64asm.Decode(code[:4])
+	if err != nil {
+		return flowInst{}, err
+	}
+	fi := flowInst{length: 4}
+
+	var target uint64
+	direct := false
+	conditional := false
+	for _, arg := range inst.Args {
+		switch a := arg.(type) {
+		case arm64asm.PCRel:
+			target = uint64(int64(pc) + int64(a))
+			direct = true
+		case arm64asm.Cond:
+			conditional = true
+		}
+	}
+
+	switch inst.Op {
+	case arm64asm.BL:
+		fi.kind, fi.target = flowCall, target
+	case arm64asm.BLR:
+		fi.kind = flowIndirectCall
+	case arm64asm.B:
+		if !direct {
+			break
+		}
+		if conditional {
+			fi.kind, fi.target = flowCondJump, target
+		} else {
+			fi.kind, fi.target = flowJump, target
+		}
+	case arm64asm.CBZ, arm64asm.CBNZ, arm64asm.TBZ, arm64asm.TBNZ:
+		fi.kind, fi.target = flowCondJump, target
+	case arm64asm.BR:
+		fi.kind = flowIndirectJump
+	case arm64asm.RET, arm64asm.ERET:
+		fi.kind = flowReturn
+	case arm64asm.BRK, arm64asm.HLT:
+		fi.kind = flowHalt
+	}
+	return fi, nil
+}
+
+// DOT renders the call graph in Graphviz format
+func (g *CallGraph) DOT() string {
+	var sb strings.Builder
+	sb.WriteString("digraph callgraph {\n")
+	sb.WriteString("\tnode [shape=box, fontname=\"monospace\"];\n")
+
+	for _, fn := range g.Functions {
+		label := fmt.Sprintf("%s\\n0x%x size=%d bbs=%d", dotEscape(fn.Name), fn.Address, fn.Size, fn.BasicBlocks)
+		if len(fn.ImportedCalls) > 0 {
+			label += "\\nimports: " + dotEscape(strings.Join(fn.ImportedCalls, ", "))
+		}
+		fmt.Fprintf(&sb, "\t\"0x%x\" [label=\"%s\"];\n", fn.Address, label)
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&sb, "\t\"0x%x\" -> \"0x%x\";\n", e.From, e.To)
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// dotEscaper quotes names taken from the binary for a DOT string, so that a
+// trailing backslash or a quote cannot end the label early
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", "")
+
+func dotEscape(s string) string {
+	return dotEscaper.Replace(s)
+}
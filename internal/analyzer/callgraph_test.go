@@ -0,0 +1,72 @@
+package analyzer
+
+import (
+	"bytes"
+	"debug/elf"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestBuildCallGraph(t *testing.T) {
+	data, err := os.ReadFile("/bin/ls")
+	if err != nil {
+		t.Fatalf("failed to read test binary: %v", err)
+	}
+
+	graph, err := BuildCallGraph(data)
+	if err != nil {
+		t.Fatalf("BuildCallGraph failed: %v", err)
+	}
+
+	info, err := AnalyzeBinary(data)
+	if err != nil {
+		t.Fatalf("AnalyzeBinary failed: %v", err)
+	}
+
+	var entry *FunctionInfo
+	imported := 0
+	for i, fn := range graph.Functions {
+		if fn.Address == info.EntryPoint {
+			entry = &graph.Functions[i]
+		}
+		imported += len(fn.ImportedCalls)
+	}
+	if entry == nil {
+		t.Fatal("entry point was not discovered as a function")
+	}
+	if imported == 0 {
+		t.Error("expected calls through the PLT to resolve to imports")
+	}
+	if len(parseEHFrame(mustELF(t, data))) == 0 {
+		t.Error("expected FDEs in .eh_frame")
+	}
+
+	dot := graph.DOT()
+	if !strings.HasPrefix(dot, "digraph callgraph {") {
+		t.Errorf("unexpected DOT output: %.40s", dot)
+	}
+	t.Logf("functions=%d edges=%d", len(graph.Functions), len(graph.Edges))
+}
+
+func TestCallGraphDOT_EscapesNames(t *testing.T) {
+	graph := &CallGraph{Functions: []FunctionInfo{{
+		Name:          "evil\\",
+		Address:       0x1000,
+		ImportedCalls: []string{`a\"];x[y="`, "b\nc"},
+	}}}
+
+	want := `	"0x1000" [label="evil\\\n0x1000 size=0 bbs=0\nimports: a\\\"];x[y=\", b\nc"];` + "\n"
+	if dot := graph.DOT(); !strings.Contains(dot, want) {
+		t.Errorf("label not escaped:\n%s", dot)
+	}
+}
+
+func mustELF(t *testing.T, data []byte) *elf.File {
+	t.Helper()
+	f, err := elf.NewFile(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to parse ELF: %v", err)
+	}
+	return f
+}
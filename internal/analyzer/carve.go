@@ -0,0 +1,591 @@
+package analyzer
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/x509"
+	"debug/elf"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sort"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+const (
+	ArtifactELF         = "elf"
+	ArtifactGzip        = "gzip"
+	ArtifactZlib        = "zlib"
+	ArtifactXZ          = "xz"
+	ArtifactZstd        = "zstd"
+	ArtifactZip         = "zip"
+	ArtifactTar         = "tar"
+	ArtifactFile        = "file" // member of a zip or tar archive
+	ArtifactPEM         = "pem"
+	ArtifactCertificate = "certificate" // DER-encoded X.509
+)
+
+const (
+	// maxCarveDepth bounds how many containers deep carving recurses
+	maxCarveDepth = 3
+	// maxCarvedArtifacts caps the findings reported for one container
+	maxCarvedArtifacts = 64
+	// maxInflatedBytes caps the bytes decompressed or extracted per analysis,
+	// so that compression bombs cannot exhaust memory
+	maxInflatedBytes = 64 << 20
+	// maxZipDirectoryProbes bounds the end-of-directory records tried per zip
+	maxZipDirectoryProbes = 16
+)
+
+// EmbeddedArtifact is a payload found inside another file. Offsets are
+// relative to the containing data: the analyzed file, a decompressed stream
+// or an archive member. Children hold what was carved out of the artifact's
+// decompressed or extracted content, and Binary the analysis of a nested ELF.
+type EmbeddedArtifact struct {
+	Type             string             `json:"type"`
+	Offset           uint64             `json:"offset"`
+	Size             uint64             `json:"size"`
+	SHA256           string             `json:"sha256"` // of the raw bytes; of the extracted content for archive members
+	Name             string             `json:"name,omitempty"`
+	Description      string             `json:"description,omitempty"`
+	DecompressedSize uint64             `json:"decompressed_size,omitempty"`
+	Truncated        bool               `json:"truncated,omitempty"` // content cut off by the file end or the extraction limit
+	Binary           *BinaryInfo        `json:"binary,omitempty"`
+	Error            string             `json:"error,omitempty"`
+	Children         []EmbeddedArtifact `json:"children,omitempty"`
+}
+
+type carveSignature struct {
+	magic []byte
+	delta int // offset of the magic from the artifact start
+	typ   string
+}
+
+var carveSignatures = []carveSignature{
+	{[]byte("\x7fELF"), 0, ArtifactELF},
+	{[]byte{0x1f, 0x8b, 0x08}, 0, ArtifactGzip},
+	{[]byte{0xfd, '7', 'z', 'X', 'Z', 0x00}, 0, ArtifactXZ},
+	{[]byte{0x28, 0xb5, 0x2f, 0xfd}, 0, ArtifactZstd},
+	{[]byte("PK\x03\x04"), 0, ArtifactZip},
+	{[]byte("ustar"), 257, ArtifactTar},
+	{[]byte("-----BEGIN "), 0, ArtifactPEM},
+	{[]byte{0x30, 0x82}, 0, ArtifactCertificate},
+	// zlib has no real magic; 0x78 plus a valid FLG byte is checked in carveZlib
+	{[]byte{0x78}, 0, ArtifactZlib},
+}
+
+// carver carries the extraction budget shared by one analysis and its
+// nested payloads
+type carver struct {
+	inflated int64
+}
+
+// analyze parses a binary and carves its embedded payloads. The file itself
+// starts at offset 0, so artifacts there are not reported again.
+func (c *carver) analyze(fileBytes []byte, depth int) (*BinaryInfo, error) {
+	p, err := parserFor(fileBytes)
+	if err != nil {
+		return nil, err
+	}
+	info, err := p.Parse(fileBytes)
+	if err != nil {
+		return nil, err
+	}
+	// A core's memory image holds every mapped library; those are not payloads
+	if depth < maxCarveDepth && info.Core == nil {
+		info.Embedded = c.carve(fileBytes, depth, true)
+	}
+	return info, nil
+}
+
+type carveCandidate struct {
+	off int
+	sig *carveSignature
+}
+
+// carve scans data for every known signature and keeps the artifacts that
+// validate. Matches inside an accepted artifact are skipped: its content is
+// carved on its own, one level deeper.
+func (c *carver) carve(data []byte, depth int, skipSelf bool) []EmbeddedArtifact {
+	var candidates []carveCandidate
+	for i := range carveSignatures {
+		sig := &carveSignatures[i]
+		for pos := 0; pos < len(data); {
+			j := bytes.Index(data[pos:], sig.magic)
+			if j < 0 {
+				break
+			}
+			if off := pos + j - sig.delta; off >= 0 && !(skipSelf && off == 0) {
+				candidates = append(candidates, carveCandidate{off: off, sig: sig})
+			}
+			pos += j + 1
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].off < candidates[j].off })
+
+	var found []EmbeddedArtifact
+	covered := 0
+	for _, cand := range candidates {
+		if cand.off < covered {
+			continue
+		}
+		a := c.carveAt(cand.sig.typ, data, cand.off, depth)
+		if a == nil {
+			continue
+		}
+		found = append(found, *a)
+		covered = cand.off + int(a.Size)
+		if len(found) == maxCarvedArtifacts {
+			break
+		}
+	}
+	return found
+}
+
+// carveAt validates a signature match and measures the artifact, or returns
+// nil for a false positive
+func (c *carver) carveAt(typ string, data []byte, off, depth int) *EmbeddedArtifact {
+	switch typ {
+	case ArtifactELF:
+		return c.carveELF(data, off, depth)
+	case ArtifactGzip:
+		return c.carveGzip(data, off, depth)
+	case ArtifactZlib:
+		return c.carveZlib(data, off, depth)
+	case ArtifactXZ:
+		return c.carveXZ(data, off, depth)
+	case ArtifactZstd:
+		return c.carveZstd(data, off, depth)
+	case ArtifactZip:
+		return c.carveZip(data, off, depth)
+	case ArtifactTar:
+		return c.carveTar(data, off, depth)
+	case ArtifactPEM:
+		return carvePEM(data, off)
+	case ArtifactCertificate:
+		return carveDERCertificate(data, off)
+	}
+	return nil
+}
+
+func newArtifact(typ string, data []byte, off, size int) *EmbeddedArtifact {
+	return &EmbeddedArtifact{
+		Type:   typ,
+		Offset: uint64(off),
+		Size:   uint64(size),
+		SHA256: sha256Hex(data[off : off+size]),
+	}
+}
+
+// inflate reads r within the remaining extraction budget
+func (c *carver) inflate(r io.Reader) (content []byte, truncated bool, err error) {
+	limit := max(maxInflatedBytes-c.inflated, 0)
+	content, err = io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if int64(len(content)) > limit {
+		content, truncated = content[:limit], true
+	}
+	c.inflated += int64(len(content))
+	return content, truncated, nil
+}
+
+// expand records decompressed or extracted content and carves it
+func (c *carver) expand(a *EmbeddedArtifact, content []byte, truncated bool, depth int) {
+	a.DecompressedSize = uint64(len(content))
+	a.Truncated = a.Truncated || truncated
+	if depth+1 < maxCarveDepth {
+		a.Children = c.carve(content, depth+1, false)
+	}
+}
+
+func (c *carver) carveELF(data []byte, off, depth int) *EmbeddedArtifact {
+	size, truncated, ok := elfExtent(data[off:])
+	if !ok {
+		return nil
+	}
+	a := newArtifact(ArtifactELF, data, off, size)
+	a.Truncated = truncated
+	info, err := c.analyze(data[off:off+size], depth+1)
+	if err != nil {
+		a.Error = err.Error()
+	} else {
+		a.Binary = info
+	}
+	return a
+}
+
+// elfExtent validates an ELF header and returns the file size it implies: the
+// furthest of the header tables, segments and sections
+func elfExtent(b []byte) (size int, truncated, ok bool) {
+	f, err := elf.NewFile(bytes.NewReader(b))
+	if err != nil || f.Machine == elf.EM_NONE {
+		return 0, false, false
+	}
+	switch f.Type {
+	case elf.ET_REL, elf.ET_EXEC, elf.ET_DYN, elf.ET_CORE:
+	default:
+		return 0, false, false
+	}
+
+	var end uint64
+	order := f.ByteOrder
+	if f.Class == elf.ELFCLASS64 {
+		if len(b) < 64 {
+			return 0, false, false
+		}
+		end = max(uint64(order.Uint16(b[0x34:])),
+			order.Uint64(b[0x20:])+uint64(order.Uint16(b[0x36:]))*uint64(order.Uint16(b[0x38:])),
+			order.Uint64(b[0x28:])+uint64(order.Uint16(b[0x3a:]))*uint64(order.Uint16(b[0x3c:])))
+	} else {
+		end = max(uint64(order.Uint16(b[0x28:])),
+			uint64(order.Uint32(b[0x1c:]))+uint64(order.Uint16(b[0x2a:]))*uint64(order.Uint16(b[0x2c:])),
+			uint64(order.Uint32(b[0x20:]))+uint64(order.Uint16(b[0x2e:]))*uint64(order.Uint16(b[0x30:])))
+	}
+	for _, prog := range f.Progs {
+		end = max(end, prog.Off+prog.Filesz)
+	}
+	for _, sec := range f.Sections {
+		if sec.Type != elf.SHT_NOBITS && sec.Type != elf.SHT_NULL {
+			end = max(end, sec.Offset+sec.Size)
+		}
+	}
+	if end > uint64(len(b)) {
+		return len(b), true, true
+	}
+	return int(end), false, true
+}
+
+func (c *carver) carveGzip(data []byte, off, depth int) *EmbeddedArtifact {
+	br := bytes.NewReader(data[off:])
+	zr, err := gzip.NewReader(br)
+	if err != nil {
+		return nil
+	}
+	zr.Multistream(false)
+	content, truncated, err := c.inflate(zr)
+	if err != nil {
+		return nil
+	}
+	// bytes.Reader is an io.ByteReader, so gzip consumed exactly the stream
+	a := newArtifact(ArtifactGzip, data, off, len(data)-off-br.Len())
+	a.Name = zr.Name
+	c.expand(a, content, truncated, depth)
+	return a
+}
+
+func (c *carver) carveZlib(data []byte, off, depth int) *EmbeddedArtifact {
+	if off+2 > len(data) {
+		return nil
+	}
+	cmf, flg := data[off], data[off+1]
+	if (uint16(cmf)<<8|uint16(flg))%31 != 0 || flg&0x20 != 0 {
+		return nil
+	}
+	br := bytes.NewReader(data[off:])
+	zr, err := zlib.NewReader(br)
+	if err != nil {
+		return nil
+	}
+	content, truncated, err := c.inflate(zr)
+	// Without a real magic, only streams that decode to something count
+	if err != nil || len(content) == 0 {
+		return nil
+	}
+	a := newArtifact(ArtifactZlib, data, off, len(data)-off-br.Len())
+	c.expand(a, content, truncated, depth)
+	return a
+}
+
+func (c *carver) carveXZ(data []byte, off, depth int) *EmbeddedArtifact {
+	end, ok := xzStreamEnd(data[off:])
+	if !ok {
+		return nil
+	}
+	xr, err := xz.ReaderConfig{SingleStream: true}.NewReader(bytes.NewReader(data[off : off+end]))
+	if err != nil {
+		return nil
+	}
+	content, truncated, err := c.inflate(xr)
+	if err != nil {
+		return nil
+	}
+	a := newArtifact(ArtifactXZ, data, off, end)
+	c.expand(a, content, truncated, depth)
+	return a
+}
+
+// xzStreamEnd finds the stream footer matching the header's flags. A stream
+// is a multiple of four bytes long and its footer is CRC-protected.
+func xzStreamEnd(b []byte) (int, bool) {
+	if len(b) < 24 {
+		return 0, false
+	}
+	flags := b[6:8]
+	if crc32.ChecksumIEEE(flags) != binary.LittleEndian.Uint32(b[8:12]) {
+		return 0, false
+	}
+	for pos := 12; pos < len(b); {
+		i := bytes.Index(b[pos:], []byte("YZ"))
+		if i < 0 {
+			break
+		}
+		end := pos + i + 2
+		footer := end - 12
+		if footer >= 12 && end%4 == 0 && bytes.Equal(b[footer+8:footer+10], flags) &&
+			crc32.ChecksumIEEE(b[footer+4:footer+10]) == binary.LittleEndian.Uint32(b[footer:]) {
+			return end, true
+		}
+		pos += i + 1
+	}
+	return 0, false
+}
+
+func (c *carver) carveZstd(data []byte, off, depth int) *EmbeddedArtifact {
+	end, ok := zstdFrameEnd(data[off:])
+	if !ok {
+		return nil
+	}
+	dec, err := zstd.NewReader(bytes.NewReader(data[off:off+end]), zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil
+	}
+	defer dec.Close()
+	content, truncated, err := c.inflate(dec)
+	if err != nil {
+		return nil
+	}
+	a := newArtifact(ArtifactZstd, data, off, end)
+	c.expand(a, content, truncated, depth)
+	return a
+}
+
+// zstdFrameEnd walks a zstd frame header and its block headers to find where
+// the frame ends, since the decoder reads ahead
+func zstdFrameEnd(b []byte) (int, bool) {
+	if len(b) < 6 {
+		return 0, false
+	}
+	fhd := b[4]
+	if fhd&0x08 != 0 { // reserved bit
+		return 0, false
+	}
+	singleSegment := fhd&0x20 != 0
+	pos := 5
+	if !singleSegment {
+		pos++ // window descriptor
+	}
+	pos += []int{0, 1, 2, 4}[fhd&0x03] // dictionary ID
+	switch fhd >> 6 {
+	case 0:
+		if singleSegment {
+			pos++
+		}
+	case 1:
+		pos += 2
+	case 2:
+		pos += 4
+	case 3:
+		pos += 8
+	}
+
+	for {
+		if pos+3 > len(b) {
+			return 0, false
+		}
+		header := uint32(b[pos]) | uint32(b[pos+1])<<8 | uint32(b[pos+2])<<16
+		pos += 3
+		last := header&1 != 0
+		size := int(header >> 3)
+		switch (header >> 1) & 3 {
+		case 0, 2: // raw, compressed
+			if size > 128<<10 {
+				return 0, false
+			}
+			pos += size
+		case 1: // RLE stores a single byte
+			pos++
+		default:
+			return 0, false
+		}
+		if last {
+			break
+		}
+	}
+	if fhd&0x04 != 0 { // content checksum
+		pos += 4
+	}
+	if pos > len(b) {
+		return 0, false
+	}
+	return pos, true
+}
+
+func (c *carver) carveZip(data []byte, off, depth int) *EmbeddedArtifact {
+	// The archive ends with the first end-of-central-directory record that
+	// yields a readable directory
+	pos := off
+	for probe := 0; probe < maxZipDirectoryProbes; probe++ {
+		i := bytes.Index(data[pos:], []byte("PK\x05\x06"))
+		if i < 0 || pos+i+22 > len(data) {
+			return nil
+		}
+		eocd := pos + i
+		pos = eocd + 1
+		end := eocd + 22 + int(binary.LittleEndian.Uint16(data[eocd+20:]))
+		if end > len(data) {
+			continue
+		}
+		zr, err := zip.NewReader(bytes.NewReader(data[off:end]), int64(end-off))
+		if err != nil {
+			continue
+		}
+
+		a := newArtifact(ArtifactZip, data, off, end-off)
+		a.Description = fmt.Sprintf("%d entries", len(zr.File))
+		for _, f := range zr.File {
+			if f.FileInfo().IsDir() {
+				continue
+			}
+			if len(a.Children) == maxCarvedArtifacts {
+				break
+			}
+			a.Children = append(a.Children, c.zipMember(f, depth))
+		}
+		return a
+	}
+	return nil
+}
+
+func (c *carver) zipMember(f *zip.File, depth int) EmbeddedArtifact {
+	member := EmbeddedArtifact{
+		Type:             ArtifactFile,
+		Name:             f.Name,
+		Size:             f.CompressedSize64,
+		DecompressedSize: f.UncompressedSize64,
+	}
+	if dataOff, err := f.DataOffset(); err == nil {
+		member.Offset = uint64(dataOff)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		member.Error = err.Error()
+		return member
+	}
+	defer rc.Close()
+	content, truncated, err := c.inflate(rc)
+	if err != nil {
+		member.Error = err.Error()
+		return member
+	}
+	member.SHA256 = sha256Hex(content)
+	c.expand(&member, content, truncated, depth)
+	return member
+}
+
+func (c *carver) carveTar(data []byte, off, depth int) *EmbeddedArtifact {
+	br := bytes.NewReader(data[off:])
+	tr := tar.NewReader(br)
+	consumed := func() int { return len(data) - off - br.Len() }
+
+	var members []EmbeddedArtifact
+	truncated := false
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			// A broken first header means this was no tar at all
+			if len(members) == 0 {
+				return nil
+			}
+			truncated = !errors.Is(err, io.EOF)
+			break
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		member := EmbeddedArtifact{
+			Type:   ArtifactFile,
+			Name:   hdr.Name,
+			Offset: uint64(consumed()),
+			Size:   uint64(hdr.Size),
+		}
+		content, cut, err := c.inflate(tr)
+		if err != nil {
+			member.Error = err.Error()
+		} else {
+			member.SHA256 = sha256Hex(content)
+			c.expand(&member, content, cut, depth)
+		}
+		if len(members) < maxCarvedArtifacts {
+			members = append(members, member)
+		}
+	}
+
+	a := newArtifact(ArtifactTar, data, off, consumed())
+	a.Truncated = truncated
+	a.Children = members
+	return a
+}
+
+func carvePEM(data []byte, off int) *EmbeddedArtifact {
+	rest := data[off+len("-----BEGIN "):]
+	labelEnd := bytes.Index(rest, []byte("-----"))
+	if labelEnd <= 0 || labelEnd > 64 || bytes.ContainsAny(rest[:labelEnd], "\r\n") {
+		return nil
+	}
+	endMarker := []byte("-----END " + string(rest[:labelEnd]) + "-----")
+	e := bytes.Index(data[off:], endMarker)
+	if e < 0 {
+		return nil
+	}
+	end := off + e + len(endMarker)
+	block, _ := pem.Decode(data[off:end])
+	if block == nil {
+		return nil
+	}
+
+	a := newArtifact(ArtifactPEM, data, off, end-off)
+	a.Name = block.Type
+	switch {
+	case block.Type == "CERTIFICATE":
+		if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+			a.Description = describeCertificate(cert)
+		}
+	case bytes.Contains([]byte(block.Type), []byte("PRIVATE KEY")):
+		a.Description = "private key"
+	}
+	return a
+}
+
+func carveDERCertificate(data []byte, off int) *EmbeddedArtifact {
+	// SEQUENCE (certificate) directly wrapping SEQUENCE (tbsCertificate)
+	if off+8 > len(data) || data[off+4] != 0x30 || data[off+5] != 0x82 {
+		return nil
+	}
+	size := 4 + int(binary.BigEndian.Uint16(data[off+2:]))
+	if off+size > len(data) {
+		return nil
+	}
+	cert, err := x509.ParseCertificate(data[off : off+size])
+	if err != nil {
+		return nil
+	}
+	a := newArtifact(ArtifactCertificate, data, off, size)
+	a.Description = describeCertificate(cert)
+	return a
+}
+
+func describeCertificate(cert *x509.Certificate) string {
+	return fmt.Sprintf("subject %q, issuer %q, valid %s to %s",
+		cert.Subject.String(), cert.Issuer.String(),
+		cert.NotBefore.Format("2006-01-02"), cert.NotAfter.Format("2006-01-02"))
+}
@@ -0,0 +1,181 @@
+package analyzer
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+func testCertificate(t *testing.T) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "dropper.example"},
+		// Long enough for the two-byte DER length form real certificates use
+		DNSNames:  []string{strings.Repeat("a", 63) + ".example", strings.Repeat("b", 63) + ".example"},
+		NotBefore: time.Now(),
+		NotAfter:  time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func TestCarveEmbedded(t *testing.T) {
+	host, err := os.ReadFile("/bin/ls")
+	if err != nil {
+		t.Fatalf("failed to read test binary: %v", err)
+	}
+	stage2, err := os.ReadFile("/bin/true")
+	if err != nil {
+		t.Fatalf("failed to read test binary: %v", err)
+	}
+	cert := testCertificate(t)
+
+	var blob bytes.Buffer
+	blob.Write(host)
+	offsets := map[string]int{}
+	mark := func(typ string) {
+		blob.WriteString("padding!")
+		offsets[typ] = blob.Len()
+	}
+
+	mark(ArtifactGzip)
+	gz := gzip.NewWriter(&blob)
+	gz.Name = "stage2"
+	gz.Write(stage2)
+	gz.Close()
+
+	mark(ArtifactXZ)
+	xw, err := xz.NewWriter(&blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	xw.Write([]byte(strings.Repeat("xz payload ", 100)))
+	xw.Close()
+
+	mark(ArtifactZstd)
+	zw, err := zstd.NewWriter(&blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw.Write([]byte(strings.Repeat("zstd payload ", 100)))
+	zw.Close()
+
+	mark(ArtifactZlib)
+	zl := zlib.NewWriter(&blob)
+	zl.Write([]byte(strings.Repeat("zlib payload ", 100)))
+	zl.Close()
+
+	mark(ArtifactZip)
+	var zipped bytes.Buffer
+	zipw := zip.NewWriter(&zipped)
+	f, _ := zipw.Create("bin/true")
+	f.Write(stage2)
+	zipw.Close()
+	blob.Write(zipped.Bytes())
+
+	mark(ArtifactTar)
+	tw := tar.NewWriter(&blob)
+	tw.WriteHeader(&tar.Header{Name: "readme.txt", Mode: 0644, Size: 5, Typeflag: tar.TypeReg})
+	tw.Write([]byte("hello"))
+	tw.Close()
+
+	mark(ArtifactPEM)
+	pem.Encode(&blob, &pem.Block{Type: "CERTIFICATE", Bytes: cert})
+
+	mark(ArtifactCertificate)
+	blob.Write(cert)
+
+	mark(ArtifactELF)
+	blob.Write(stage2)
+
+	info, err := AnalyzeBinary(blob.Bytes())
+	if err != nil {
+		t.Fatalf("AnalyzeBinary failed: %v", err)
+	}
+
+	byType := map[string]EmbeddedArtifact{}
+	for _, a := range info.Embedded {
+		byType[a.Type] = a
+	}
+	for typ, off := range offsets {
+		a, ok := byType[typ]
+		if !ok {
+			t.Errorf("%s artifact not found", typ)
+			continue
+		}
+		if a.Offset != uint64(off) {
+			t.Errorf("%s: offset %d, want %d", typ, a.Offset, off)
+		}
+	}
+	if len(info.Embedded) != len(offsets) {
+		for _, a := range info.Embedded {
+			t.Logf("found %s at %d (%d bytes)", a.Type, a.Offset, a.Size)
+		}
+		t.Errorf("expected %d artifacts, got %d", len(offsets), len(info.Embedded))
+	}
+
+	gzArt := byType[ArtifactGzip]
+	if gzArt.Name != "stage2" || gzArt.DecompressedSize != uint64(len(stage2)) {
+		t.Errorf("unexpected gzip artifact: %+v", gzArt)
+	}
+	if len(gzArt.Children) != 1 || gzArt.Children[0].Type != ArtifactELF || gzArt.Children[0].Binary == nil {
+		t.Fatalf("expected the gzip stream to hold an analyzed ELF: %+v", gzArt.Children)
+	}
+	if gzArt.Children[0].SHA256 != sha256Hex(stage2) {
+		t.Errorf("nested ELF hash mismatch")
+	}
+
+	zipArt := byType[ArtifactZip]
+	if zipArt.Size != uint64(zipped.Len()) || len(zipArt.Children) != 1 || zipArt.Children[0].Name != "bin/true" {
+		t.Errorf("unexpected zip artifact: %+v", zipArt)
+	} else if kids := zipArt.Children[0].Children; len(kids) != 1 || kids[0].Binary == nil {
+		t.Errorf("expected the zip member to be analyzed as ELF: %+v", kids)
+	}
+
+	elfArt := byType[ArtifactELF]
+	if elfArt.Size != uint64(len(stage2)) || elfArt.Binary == nil || elfArt.Truncated {
+		t.Errorf("unexpected appended ELF: size %d, truncated %v", elfArt.Size, elfArt.Truncated)
+	}
+	if !strings.Contains(byType[ArtifactPEM].Description, "dropper.example") ||
+		!strings.Contains(byType[ArtifactCertificate].Description, "dropper.example") {
+		t.Errorf("certificate subjects not described: %q / %q", byType[ArtifactPEM].Description, byType[ArtifactCertificate].Description)
+	}
+}
+
+func TestCarveEmbedded_PlainBinary(t *testing.T) {
+	data, err := os.ReadFile("/bin/ls")
+	if err != nil {
+		t.Fatalf("failed to read test binary: %v", err)
+	}
+	info, err := AnalyzeBinary(data)
+	if err != nil {
+		t.Fatalf("AnalyzeBinary failed: %v", err)
+	}
+	for _, a := range info.Embedded {
+		t.Errorf("unexpected artifact in /bin/ls: %s at %d", a.Type, a.Offset)
+	}
+}
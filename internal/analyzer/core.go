@@ -0,0 +1,448 @@
+package analyzer
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/ashborn3/BinTraceBench/internal/syscalls"
+)
+
+const (
+	maxCoreThreads     = 256
+	maxStackFrames     = 64
+	maxCoreMappings    = 4096
+	maxCoreStringBytes = 4096
+)
+
+// Note types of Linux core files that debug/elf does not name
+const (
+	ntPrstatus = 1
+	ntPrpsinfo = 3
+	ntAuxv     = 6
+	ntSiginfo  = 0x53494749
+	ntFile     = 0x46494c45
+)
+
+type CoreInfo struct {
+	Command         string        `json:"command,omitempty"`
+	Args            string        `json:"args,omitempty"`
+	PID             int32         `json:"pid,omitempty"`
+	Signal          int           `json:"signal,omitempty"`
+	SignalName      string        `json:"signal_name,omitempty"`
+	FaultAddress    uint64        `json:"fault_address,omitempty"`
+	Executable      string        `json:"executable,omitempty"` // AT_EXECFN
+	Threads         []CoreThread  `json:"threads"`
+	Mappings        []CoreMapping `json:"mappings,omitempty"`
+	Auxv            []AuxvEntry   `json:"auxv,omitempty"`
+	CompanionSHA256 string        `json:"companion_sha256,omitempty"` // executable the frames were symbolized against
+	SymbolizeError  string        `json:"symbolize_error,omitempty"`
+}
+
+type CoreThread struct {
+	TID        int32             `json:"tid"`
+	Signal     int               `json:"signal,omitempty"`
+	SignalName string            `json:"signal_name,omitempty"`
+	Registers  map[string]uint64 `json:"registers,omitempty"`
+	Frames     []StackFrame      `json:"frames,omitempty"`
+}
+
+// StackFrame is one return address recovered by walking frame pointers; the
+// first frame is the thread's program counter
+type StackFrame struct {
+	PC           uint64 `json:"pc"`
+	Module       string `json:"module,omitempty"`
+	ModuleOffset uint64 `json:"module_offset,omitempty"` // file offset within Module
+	Symbol       string `json:"symbol,omitempty"`
+}
+
+type CoreMapping struct {
+	Start      uint64 `json:"start"`
+	End        uint64 `json:"end"`
+	FileOffset uint64 `json:"file_offset"`
+	Path       string `json:"path"`
+}
+
+type AuxvEntry struct {
+	Type   string `json:"type"`
+	Value  uint64 `json:"value"`
+	String string `json:"string,omitempty"` // for entries pointing at a string
+}
+
+var auxvNames = map[uint64]string{
+	3: "AT_PHDR", 4: "AT_PHENT", 5: "AT_PHNUM", 6: "AT_PAGESZ", 7: "AT_BASE",
+	8: "AT_FLAGS", 9: "AT_ENTRY", 11: "AT_UID", 12: "AT_EUID", 13: "AT_GID",
+	14: "AT_EGID", 15: "AT_PLATFORM", 16: "AT_HWCAP", 17: "AT_CLKTCK",
+	23: "AT_SECURE", 24: "AT_BASE_PLATFORM", 25: "AT_RANDOM", 26: "AT_HWCAP2",
+	31: "AT_EXECFN", 32: "AT_SYSINFO", 33: "AT_SYSINFO_EHDR", 51: "AT_MINSIGSTKSZ",
+}
+
+const (
+	atPlatform = 15
+	atExecFn   = 31
+)
+
+// coreRegs describes the elf_gregset_t of one architecture: register names in
+// order and which of them hold the program counter and frame pointer
+type coreRegs struct {
+	names []string
+	pc    string
+	fp    string
+}
+
+var coreRegSets = map[elf.Machine]coreRegs{
+	elf.EM_X86_64: {
+		names: []string{"r15", "r14", "r13", "r12", "rbp", "rbx", "r11", "r10", "r9", "r8",
+			"rax", "rcx", "rdx", "rsi", "rdi", "orig_rax", "rip", "cs", "eflags", "rsp", "ss",
+			"fs_base", "gs_base", "ds", "es", "fs", "gs"},
+		pc: "rip",
+		fp: "rbp",
+	},
+	elf.EM_386: {
+		names: []string{"ebx", "ecx", "edx", "esi", "edi", "ebp", "eax", "ds", "es", "fs", "gs",
+			"orig_eax", "eip", "cs", "eflags", "esp", "ss"},
+		pc: "eip",
+		fp: "ebp",
+	},
+	elf.EM_AARCH64: {
+		names: []string{"x0", "x1", "x2", "x3", "x4", "x5", "x6", "x7", "x8", "x9", "x10",
+			"x11", "x12", "x13", "x14", "x15", "x16", "x17", "x18", "x19", "x20", "x21",
+			"x22", "x23", "x24", "x25", "x26", "x27", "x28", "x29", "x30", "sp", "pc", "pstate"},
+		pc: "pc",
+		fp: "x29",
+	},
+}
+
+// coreFile gives word-sized access to the notes and memory image of a core
+type coreFile struct {
+	order   binary.ByteOrder
+	word    int
+	loads   []*elf.Prog
+	machine elf.Machine
+}
+
+func (c *coreFile) readWord(b []byte) uint64 {
+	if c.word == 8 {
+		return c.order.Uint64(b)
+	}
+	return uint64(c.order.Uint32(b))
+}
+
+// readMemory copies n bytes of process memory at addr, if the core holds them
+func (c *coreFile) readMemory(addr uint64, n int) ([]byte, bool) {
+	for _, p := range c.loads {
+		if addr < p.Vaddr || addr-p.Vaddr+uint64(n) > p.Filesz {
+			continue
+		}
+		buf := make([]byte, n)
+		if _, err := p.ReadAt(buf, int64(addr-p.Vaddr)); err != nil {
+			return nil, false
+		}
+		return buf, true
+	}
+	return nil, false
+}
+
+func (c *coreFile) readCString(addr uint64) string {
+	var out []byte
+	for len(out) < maxCoreStringBytes {
+		b, ok := c.readMemory(addr+uint64(len(out)), 1)
+		if !ok || b[0] == 0 {
+			break
+		}
+		out = append(out, b[0])
+	}
+	return string(out)
+}
+
+// analyzeCore decodes the process state recorded in the notes of an ET_CORE
+// file: threads with their registers, the command line, mapped files and the
+// auxiliary vector. Stacks are walked by frame pointer, which only recovers
+// callers built with frame pointers.
+func analyzeCore(elfFile *elf.File) *CoreInfo {
+	if elfFile.Type != elf.ET_CORE {
+		return nil
+	}
+	c := &coreFile{order: elfFile.ByteOrder, word: 8, machine: elfFile.Machine}
+	if elfFile.Class == elf.ELFCLASS32 {
+		c.word = 4
+	}
+	for _, p := range elfFile.Progs {
+		if p.Type == elf.PT_LOAD && p.Filesz > 0 {
+			c.loads = append(c.loads, p)
+		}
+	}
+
+	core := &CoreInfo{}
+	for _, p := range elfFile.Progs {
+		if p.Type != elf.PT_NOTE {
+			continue
+		}
+		notes := make([]byte, p.Filesz)
+		if _, err := p.ReadAt(notes, 0); err != nil {
+			continue
+		}
+		c.parseNotes(core, notes)
+	}
+
+	for _, a := range core.Auxv {
+		if a.Type == "AT_EXECFN" {
+			core.Executable = a.String
+		}
+	}
+	if len(core.Threads) > 0 {
+		core.PID = core.Threads[0].TID
+		if core.Signal == 0 {
+			core.Signal = core.Threads[0].Signal
+			core.SignalName = core.Threads[0].SignalName
+		}
+	}
+	for i := range core.Threads {
+		core.Threads[i].Frames = c.walkStack(core, core.Threads[i].Registers)
+	}
+	return core
+}
+
+func (c *coreFile) parseNotes(core *CoreInfo, notes []byte) {
+	align := func(n uint32) int { return int((n + 3) &^ 3) }
+	for len(notes) >= 12 {
+		namesz := c.order.Uint32(notes[0:])
+		descsz := c.order.Uint32(notes[4:])
+		typ := c.order.Uint32(notes[8:])
+		nameEnd := 12 + align(namesz)
+		descEnd := nameEnd + align(descsz)
+		if uint64(namesz) > uint64(len(notes)) || uint64(descsz) > uint64(len(notes)) || descEnd > len(notes) {
+			return
+		}
+		name := strings.TrimRight(string(notes[12:12+namesz]), "\x00")
+		desc := notes[nameEnd : nameEnd+int(descsz)]
+		notes = notes[descEnd:]
+
+		if name != "CORE" && !(name == "LINUX" && typ == ntSiginfo) {
+			continue
+		}
+		switch typ {
+		case ntPrstatus:
+			if len(core.Threads) < maxCoreThreads {
+				if t, ok := c.parsePrstatus(desc); ok {
+					core.Threads = append(core.Threads, t)
+				}
+			}
+		case ntPrpsinfo:
+			c.parsePrpsinfo(core, desc)
+		case ntSiginfo:
+			// The signal that killed the process, with the faulting address
+			addrOff := 12 + c.word - 4 // si_addr follows three ints, word aligned
+			if len(desc) >= addrOff+c.word && core.Signal == 0 {
+				core.Signal = int(int32(c.order.Uint32(desc)))
+				core.SignalName = syscalls.SignalNames[core.Signal]
+				if sig := core.Signal; sig == 4 || sig == 7 || sig == 8 || sig == 11 {
+					core.FaultAddress = c.readWord(desc[addrOff:])
+				}
+			}
+		case ntFile:
+			core.Mappings = c.parseFileNote(desc)
+		case ntAuxv:
+			core.Auxv = c.parseAuxv(desc)
+		}
+	}
+}
+
+// parsePrstatus reads struct elf_prstatus: the signal, the thread id and the
+// general purpose registers
+func (c *coreFile) parsePrstatus(desc []byte) (CoreThread, bool) {
+	// Fixed fields before pr_reg: elf_siginfo, pr_cursig and two signal sets,
+	// four pids and four timevals, all sized by the word width
+	pidOff := 16 + 2*c.word
+	regOff := pidOff + 16 + 8*c.word
+	if len(desc) < regOff {
+		return CoreThread{}, false
+	}
+	t := CoreThread{
+		TID:    int32(c.order.Uint32(desc[pidOff:])),
+		Signal: int(c.order.Uint16(desc[12:])),
+	}
+	t.SignalName = syscalls.SignalNames[t.Signal]
+	if set, ok := coreRegSets[c.machine]; ok && len(desc) >= regOff+len(set.names)*c.word {
+		t.Registers = make(map[string]uint64, len(set.names))
+		for i, name := range set.names {
+			t.Registers[name] = c.readWord(desc[regOff+i*c.word:])
+		}
+	}
+	return t, true
+}
+
+// parsePrpsinfo reads struct elf_prpsinfo, which ends with the 16-byte
+// pr_fname and the 80-byte pr_psargs on every architecture
+func (c *coreFile) parsePrpsinfo(core *CoreInfo, desc []byte) {
+	if len(desc) < 96 {
+		return
+	}
+	cstr := func(b []byte) string {
+		if i := bytes.IndexByte(b, 0); i >= 0 {
+			b = b[:i]
+		}
+		return strings.TrimSpace(string(b))
+	}
+	core.Command = cstr(desc[len(desc)-96 : len(desc)-80])
+	core.Args = cstr(desc[len(desc)-80:])
+}
+
+// parseFileNote reads NT_FILE: a count and page size, then start, end and
+// page offset per mapping, followed by the NUL separated paths
+func (c *coreFile) parseFileNote(desc []byte) []CoreMapping {
+	w := c.word
+	if len(desc) < 2*w {
+		return nil
+	}
+	count := c.readWord(desc)
+	pageSize := c.readWord(desc[w:])
+	if count > maxCoreMappings || uint64(len(desc)) < uint64(2*w)+count*uint64(3*w) {
+		return nil
+	}
+	names := bytes.Split(desc[2*w+int(count)*3*w:], []byte{0})
+	var mappings []CoreMapping
+	for i := 0; i < int(count) && i < len(names); i++ {
+		entry := desc[2*w+i*3*w:]
+		mappings = append(mappings, CoreMapping{
+			Start:      c.readWord(entry),
+			End:        c.readWord(entry[w:]),
+			FileOffset: c.readWord(entry[2*w:]) * pageSize,
+			Path:       string(names[i]),
+		})
+	}
+	return mappings
+}
+
+func (c *coreFile) parseAuxv(desc []byte) []AuxvEntry {
+	var entries []AuxvEntry
+	for off := 0; off+2*c.word <= len(desc); off += 2 * c.word {
+		typ := c.readWord(desc[off:])
+		if typ == 0 {
+			break
+		}
+		val := c.readWord(desc[off+c.word:])
+		name, ok := auxvNames[typ]
+		if !ok {
+			name = fmt.Sprintf("AT_%d", typ)
+		}
+		e := AuxvEntry{Type: name, Value: val}
+		if typ == atExecFn || typ == atPlatform {
+			e.String = c.readCString(val)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// walkStack follows the saved frame pointer chain from the thread's frame
+// pointer register. Each frame record holds the caller's frame pointer and,
+// one word above it, the return address.
+func (c *coreFile) walkStack(core *CoreInfo, regs map[string]uint64) []StackFrame {
+	set, ok := coreRegSets[c.machine]
+	if !ok || regs == nil {
+		return nil
+	}
+	frames := []StackFrame{core.frameAt(regs[set.pc])}
+	fp := regs[set.fp]
+	for len(frames) < maxStackFrames && fp != 0 && fp%uint64(c.word) == 0 {
+		record, ok := c.readMemory(fp, 2*c.word)
+		if !ok {
+			break
+		}
+		next, ret := c.readWord(record), c.readWord(record[c.word:])
+		if ret == 0 {
+			break
+		}
+		frames = append(frames, core.frameAt(ret))
+		// Stacks grow down, so callers' frames sit at higher addresses
+		if next <= fp {
+			break
+		}
+		fp = next
+	}
+	return frames
+}
+
+func (core *CoreInfo) frameAt(pc uint64) StackFrame {
+	f := StackFrame{PC: pc}
+	for _, m := range core.Mappings {
+		if pc >= m.Start && pc < m.End {
+			f.Module = m.Path
+			f.ModuleOffset = pc - m.Start + m.FileOffset
+			break
+		}
+	}
+	return f
+}
+
+// SymbolizeCore names the stack frames that fall inside the crashed program's
+// executable, using the symbols of its companion upload. The load bias comes
+// from AT_ENTRY, so position independent executables resolve as well.
+func SymbolizeCore(info *BinaryInfo, exe []byte) error {
+	core := info.Core
+	if core == nil {
+		return fmt.Errorf("not a core file")
+	}
+	elfFile, err := elf.NewFile(bytes.NewReader(exe))
+	if err != nil {
+		return fmt.Errorf("error elf parsing companion executable: %s", err.Error())
+	}
+	if machine := elfFile.Machine.String(); machine != info.Machine {
+		return fmt.Errorf("companion executable is %s, core is %s", machine, info.Machine)
+	}
+
+	var entry uint64
+	var haveEntry bool
+	for _, a := range core.Auxv {
+		if a.Type == "AT_ENTRY" {
+			entry, haveEntry = a.Value, true
+		}
+	}
+	if !haveEntry {
+		return fmt.Errorf("core has no AT_ENTRY to locate the executable")
+	}
+	bias := entry - elfFile.Entry
+
+	// Only addresses inside the executable's own segments are symbolized
+	var lo, hi uint64
+	for _, p := range elfFile.Progs {
+		if p.Type != elf.PT_LOAD {
+			continue
+		}
+		if lo == 0 && hi == 0 || p.Vaddr < lo {
+			lo = p.Vaddr
+		}
+		if end := p.Vaddr + p.Memsz; end > hi {
+			hi = end
+		}
+	}
+
+	symbols := newSymbolTable(elfFile)
+	for i := range core.Threads {
+		for j := range core.Threads[i].Frames {
+			f := &core.Threads[i].Frames[j]
+			f.Symbol = ""
+			addr := f.PC - bias
+			if addr < lo || addr >= hi {
+				continue
+			}
+			// Return addresses point after the call; look up the call itself
+			lookup := addr
+			if j > 0 {
+				lookup--
+			}
+			if name, base := symbols.lookup(lookup); name == "" {
+				continue
+			} else if addr == base {
+				f.Symbol = name
+			} else {
+				f.Symbol = fmt.Sprintf("%s+0x%x", name, addr-base)
+			}
+		}
+	}
+	core.CompanionSHA256 = sha256Hex(exe)
+	return nil
+}
@@ -0,0 +1,157 @@
+package analyzer
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"os"
+	"testing"
+)
+
+// buildCore assembles a minimal x86-64 core file: one thread whose rbp chain
+// holds a single caller frame, a process name, one NT_FILE mapping and an
+// auxiliary vector
+func buildCore(rip, rbp uint64, stackBase uint64, stack []byte, auxv [][2]uint64) []byte {
+	le := binary.LittleEndian
+	var notes bytes.Buffer
+	note := func(typ uint32, desc []byte) {
+		binary.Write(&notes, le, []uint32{5, uint32(len(desc)), typ})
+		notes.WriteString("CORE\x00\x00\x00\x00")
+		notes.Write(desc)
+		for notes.Len()%4 != 0 {
+			notes.WriteByte(0)
+		}
+	}
+
+	prstatus := make([]byte, 336)
+	le.PutUint16(prstatus[12:], 11)
+	le.PutUint32(prstatus[32:], 4242)
+	le.PutUint64(prstatus[112+4*8:], rbp)
+	le.PutUint64(prstatus[112+16*8:], rip)
+	note(ntPrstatus, prstatus)
+
+	prpsinfo := make([]byte, 136)
+	copy(prpsinfo[40:], "victim")
+	copy(prpsinfo[56:], "victim --flag ")
+	note(ntPrpsinfo, prpsinfo)
+
+	var file bytes.Buffer
+	binary.Write(&file, le, []uint64{1, 4096, rip &^ 0xffff, rip&^0xffff + 0x10000, 2})
+	file.WriteString("/opt/victim\x00")
+	note(ntFile, file.Bytes())
+
+	var aux bytes.Buffer
+	for _, kv := range auxv {
+		binary.Write(&aux, le, kv[:])
+	}
+	binary.Write(&aux, le, []uint64{0, 0})
+	note(ntAuxv, aux.Bytes())
+
+	const headers = 64 + 2*56
+	var out bytes.Buffer
+	ident := [16]byte{0x7f, 'E', 'L', 'F', byte(elf.ELFCLASS64), byte(elf.ELFDATA2LSB), 1}
+	binary.Write(&out, le, elf.Header64{
+		Ident: ident, Type: uint16(elf.ET_CORE), Machine: uint16(elf.EM_X86_64), Version: 1,
+		Phoff: 64, Ehsize: 64, Phentsize: 56, Phnum: 2,
+	})
+	binary.Write(&out, le, elf.Prog64{
+		Type: uint32(elf.PT_NOTE), Off: headers, Filesz: uint64(notes.Len()),
+	})
+	binary.Write(&out, le, elf.Prog64{
+		Type: uint32(elf.PT_LOAD), Flags: uint32(elf.PF_R | elf.PF_W), Off: headers + uint64(notes.Len()),
+		Vaddr: stackBase, Filesz: uint64(len(stack)), Memsz: uint64(len(stack)), Align: 1,
+	})
+	out.Write(notes.Bytes())
+	out.Write(stack)
+	return out.Bytes()
+}
+
+func TestAnalyzeCore(t *testing.T) {
+	// Any ELF with function symbols serves as the companion; libc exports plenty
+	exe, err := os.ReadFile("/lib/x86_64-linux-gnu/libc.so.6")
+	if err != nil {
+		t.Skipf("no x86-64 libc to symbolize against: %v", err)
+	}
+	elfFile, err := elf.NewFile(bytes.NewReader(exe))
+	if err != nil {
+		t.Fatalf("failed to parse libc: %v", err)
+	}
+	symbols := newSymbolTable(elfFile)
+	callee, ok1 := symbols.byName["abort"]
+	caller, ok2 := symbols.byName["exit"]
+	if !ok1 || !ok2 {
+		t.Skip("libc lacks the expected symbols")
+	}
+
+	// Pretend the executable was loaded 0x20000000 above its link address
+	const bias = 0x20000000
+	const stackBase = 0x7ff000
+	le := binary.LittleEndian
+	stack := make([]byte, 0x400)
+	le.PutUint64(stack[0x100:], stackBase+0x200)
+	le.PutUint64(stack[0x108:], bias+caller.Value+0x10)
+	copy(stack[0x300:], "/opt/victim\x00")
+
+	data := buildCore(bias+callee.Value+4, stackBase+0x100, stackBase, stack, [][2]uint64{
+		{9, bias + elfFile.Entry},
+		{31, stackBase + 0x300},
+	})
+
+	info, err := AnalyzeBinary(data)
+	if err != nil {
+		t.Fatalf("AnalyzeBinary failed: %v", err)
+	}
+	core := info.Core
+	if core == nil {
+		t.Fatal("expected core info")
+	}
+	if core.Command != "victim" || core.Args != "victim --flag" || core.PID != 4242 || core.SignalName != "SIGSEGV" {
+		t.Errorf("unexpected process info: %+v", core)
+	}
+	if core.Executable != "/opt/victim" {
+		t.Errorf("executable = %q", core.Executable)
+	}
+	if len(core.Mappings) != 1 || core.Mappings[0].Path != "/opt/victim" || core.Mappings[0].FileOffset != 2*4096 {
+		t.Errorf("unexpected mappings: %+v", core.Mappings)
+	}
+	if len(info.Embedded) != 0 {
+		t.Errorf("core memory should not be carved, got %d artifacts", len(info.Embedded))
+	}
+	if len(core.Threads) != 1 {
+		t.Fatalf("expected 1 thread, got %d", len(core.Threads))
+	}
+	frames := core.Threads[0].Frames
+	if len(frames) != 2 {
+		t.Fatalf("expected 2 frames, got %+v", frames)
+	}
+	if frames[0].Module != "/opt/victim" {
+		t.Errorf("frame 0 module = %q", frames[0].Module)
+	}
+
+	if err := SymbolizeCore(info, exe); err != nil {
+		t.Fatalf("SymbolizeCore failed: %v", err)
+	}
+	if frames[0].Symbol != "abort+0x4" || frames[1].Symbol != "exit+0x10" {
+		t.Errorf("unexpected symbols: %q, %q", frames[0].Symbol, frames[1].Symbol)
+	}
+	if core.CompanionSHA256 != sha256Hex(exe) {
+		t.Error("companion hash not recorded")
+	}
+}
+
+func TestAnalyzeCore_NotCore(t *testing.T) {
+	data, err := os.ReadFile("/bin/ls")
+	if err != nil {
+		t.Fatalf("failed to read test binary: %v", err)
+	}
+	info, err := AnalyzeBinary(data)
+	if err != nil {
+		t.Fatalf("AnalyzeBinary failed: %v", err)
+	}
+	if info.Core != nil {
+		t.Error("executable reported as a core file")
+	}
+	if err := SymbolizeCore(info, data); err == nil {
+		t.Error("expected SymbolizeCore to reject a non-core result")
+	}
+}
@@ -0,0 +1,285 @@
+package analyzer
+
+import (
+	"debug/dwarf"
+	"debug/elf"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+const (
+	// Caps keep the stored analysis row bounded for large debug builds
+	maxDebugFunctions = 20000
+	maxDebugLines     = 10000
+	maxDebugTypes     = 5000
+)
+
+type DebugInfo struct {
+	CompileUnits     []CompileUnitInfo `json:"compile_units"`
+	SourceFiles      []string          `json:"source_files,omitempty"`
+	Functions        []DebugFunction   `json:"functions,omitempty"`
+	Types            []DebugType       `json:"types,omitempty"`
+	BuildPaths       []string          `json:"build_paths,omitempty"`
+	LeaksBuildPaths  bool              `json:"leaks_build_paths"`
+	UnoptimizedUnits int               `json:"unoptimized_units"`
+	Truncated        bool              `json:"truncated,omitempty"`
+}
+
+type CompileUnitInfo struct {
+	Name         string      `json:"name"`
+	Producer     string      `json:"producer,omitempty"`
+	Compiler     string      `json:"compiler,omitempty"`
+	Flags        []string    `json:"flags,omitempty"`
+	Optimization string      `json:"optimization,omitempty"`
+	Unoptimized  bool        `json:"unoptimized"`
+	CompDir      string      `json:"comp_dir,omitempty"`
+	Language     string      `json:"language,omitempty"`
+	LowPC        uint64      `json:"low_pc,omitempty"`
+	HighPC       uint64      `json:"high_pc,omitempty"`
+	Lines        []LineEntry `json:"lines,omitempty"`
+}
+
+type LineEntry struct {
+	Address uint64 `json:"address"`
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+}
+
+type DebugFunction struct {
+	Name        string `json:"name"`
+	CompileUnit string `json:"compile_unit"`
+	LowPC       uint64 `json:"low_pc"`
+	HighPC      uint64 `json:"high_pc"`
+	File        string `json:"file,omitempty"`
+	Line        int    `json:"line,omitempty"`
+	External    bool   `json:"external"`
+}
+
+type DebugType struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+	Size int64  `json:"size,omitempty"`
+}
+
+var dwarfLanguages = map[int64]string{
+	0x01:   "C89",
+	0x02:   "C",
+	0x04:   "C++",
+	0x0c:   "C99",
+	0x16:   "Go",
+	0x1a:   "C++11",
+	0x1c:   "Rust",
+	0x1d:   "C11",
+	0x21:   "C++14",
+	0x2a:   "C++17",
+	0x2b:   "C++20",
+	0x2c:   "C17",
+	0x8001: "Mips Assembler",
+}
+
+var debugTypeKinds = map[dwarf.Tag]string{
+	dwarf.TagStructType:      "struct",
+	dwarf.TagUnionType:       "union",
+	dwarf.TagEnumerationType: "enum",
+	dwarf.TagTypedef:         "typedef",
+	dwarf.TagClassType:       "class",
+	dwarf.TagBaseType:        "base",
+}
+
+// analyzeDebugInfo reads DWARF from .debug_info; it returns nil for binaries
+// built without debug information
+func analyzeDebugInfo(elfFile *elf.File) *DebugInfo {
+	if elfFile.Section(".debug_info") == nil && elfFile.Section(".zdebug_info") == nil {
+		return nil
+	}
+	d, err := elfFile.DWARF()
+	if err != nil {
+		return nil
+	}
+
+	info := &DebugInfo{}
+	files := make(map[string]struct{})
+	buildPaths := make(map[string]struct{})
+	typesSeen := make(map[string]struct{})
+	totalLines := 0
+
+	var cu *CompileUnitInfo
+	var cuFiles []*dwarf.LineFile
+
+	r := d.Reader()
+	for {
+		entry, err := r.Next()
+		if err != nil || entry == nil {
+			break
+		}
+
+		switch entry.Tag {
+		case dwarf.TagCompileUnit, dwarf.TagPartialUnit:
+			info.CompileUnits = append(info.CompileUnits, newCompileUnitInfo(d, entry))
+			cu = &info.CompileUnits[len(info.CompileUnits)-1]
+			if cu.CompDir != "" {
+				buildPaths[cu.CompDir] = struct{}{}
+			}
+			if cu.Unoptimized {
+				info.UnoptimizedUnits++
+			}
+
+			cuFiles = nil
+			lr, err := d.LineReader(entry)
+			if err != nil || lr == nil {
+				break
+			}
+			cuFiles = lr.Files()
+			for _, f := range cuFiles {
+				if f != nil && f.Name != "" {
+					files[f.Name] = struct{}{}
+				}
+			}
+			var le dwarf.LineEntry
+			for {
+				if err := lr.Next(&le); err != nil {
+					if err != io.EOF {
+						info.Truncated = true
+					}
+					break
+				}
+				if le.EndSequence || le.File == nil {
+					continue
+				}
+				if totalLines >= maxDebugLines {
+					info.Truncated = true
+					break
+				}
+				cu.Lines = append(cu.Lines, LineEntry{Address: le.Address, File: le.File.Name, Line: le.Line})
+				totalLines++
+			}
+
+		case dwarf.TagSubprogram:
+			if decl, _ := entry.Val(dwarf.AttrDeclaration).(bool); decl {
+				break
+			}
+			ranges, err := d.Ranges(entry)
+			if err != nil || len(ranges) == 0 {
+				break
+			}
+			if len(info.Functions) >= maxDebugFunctions {
+				info.Truncated = true
+				break
+			}
+			fn := DebugFunction{
+				LowPC:  ranges[0][0],
+				HighPC: ranges[0][1],
+			}
+			fn.Name, _ = entry.Val(dwarf.AttrName).(string)
+			if fn.Name == "" {
+				fn.Name, _ = entry.Val(dwarf.AttrLinkageName).(string)
+			}
+			fn.External, _ = entry.Val(dwarf.AttrExternal).(bool)
+			if cu != nil {
+				fn.CompileUnit = cu.Name
+			}
+			if idx, ok := entry.Val(dwarf.AttrDeclFile).(int64); ok && idx >= 0 && int(idx) < len(cuFiles) && cuFiles[idx] != nil {
+				fn.File = cuFiles[idx].Name
+			}
+			if line, ok := entry.Val(dwarf.AttrDeclLine).(int64); ok {
+				fn.Line = int(line)
+			}
+			info.Functions = append(info.Functions, fn)
+
+		default:
+			kind, ok := debugTypeKinds[entry.Tag]
+			if !ok {
+				break
+			}
+			name, _ := entry.Val(dwarf.AttrName).(string)
+			if name == "" {
+				break
+			}
+			key := kind + " " + name
+			if _, seen := typesSeen[key]; seen {
+				break
+			}
+			if len(info.Types) >= maxDebugTypes {
+				info.Truncated = true
+				break
+			}
+			typesSeen[key] = struct{}{}
+			size, _ := entry.Val(dwarf.AttrByteSize).(int64)
+			info.Types = append(info.Types, DebugType{Name: name, Kind: kind, Size: size})
+		}
+	}
+
+	for f := range files {
+		info.SourceFiles = append(info.SourceFiles, f)
+		if path.IsAbs(f) {
+			buildPaths[path.Dir(f)] = struct{}{}
+		}
+	}
+	sort.Strings(info.SourceFiles)
+	for p := range buildPaths {
+		info.BuildPaths = append(info.BuildPaths, p)
+		if path.IsAbs(p) {
+			info.LeaksBuildPaths = true
+		}
+	}
+	sort.Strings(info.BuildPaths)
+
+	return info
+}
+
+func newCompileUnitInfo(d *dwarf.Data, entry *dwarf.Entry) CompileUnitInfo {
+	cu := CompileUnitInfo{}
+	cu.Name, _ = entry.Val(dwarf.AttrName).(string)
+	cu.Producer, _ = entry.Val(dwarf.AttrProducer).(string)
+	cu.CompDir, _ = entry.Val(dwarf.AttrCompDir).(string)
+	if lang, ok := entry.Val(dwarf.AttrLanguage).(int64); ok {
+		if name, known := dwarfLanguages[lang]; known {
+			cu.Language = name
+		} else {
+			cu.Language = fmt.Sprintf("DW_LANG_0x%x", lang)
+		}
+	}
+	if ranges, err := d.Ranges(entry); err == nil && len(ranges) > 0 {
+		cu.LowPC = ranges[0][0]
+		cu.HighPC = ranges[len(ranges)-1][1]
+	}
+
+	cu.Compiler, cu.Flags = splitProducer(cu.Producer)
+	for _, flag := range cu.Flags {
+		if strings.HasPrefix(flag, "-O") {
+			cu.Optimization = flag
+		}
+	}
+
+	switch {
+	case cu.Optimization == "-O0":
+		cu.Unoptimized = true
+	case strings.HasPrefix(cu.Producer, "Go cmd/compile"):
+		// Go records -N (disable optimizations) in the producer string
+		for _, flag := range cu.Flags {
+			if flag == "-N" {
+				cu.Unoptimized = true
+			}
+		}
+	case strings.HasPrefix(cu.Producer, "GNU ") && len(cu.Flags) > 0 && cu.Optimization == "":
+		// GCC records its switches, and defaults to -O0 when none is given
+		cu.Unoptimized = true
+	}
+	return cu
+}
+
+// splitProducer separates "GNU C17 13.2.0 -mtune=generic -O2 -g" into the
+// compiler identification and its recorded command-line flags
+func splitProducer(producer string) (string, []string) {
+	producer = strings.ReplaceAll(producer, ";", " ")
+	fields := strings.Fields(producer)
+	for i, f := range fields {
+		if strings.HasPrefix(f, "-") {
+			return strings.Join(fields[:i], " "), fields[i:]
+		}
+	}
+	return producer, nil
+}
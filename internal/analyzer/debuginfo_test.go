@@ -0,0 +1,89 @@
+package analyzer
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestAnalyzeBinary_DebugInfo(t *testing.T) {
+	gcc, err := exec.LookPath("gcc")
+	if err != nil {
+		t.Skip("gcc not available")
+	}
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "dbg.c")
+	if err := os.WriteFile(src, []byte("int helper(int x) { return x * 2; }\nint main(void) { return helper(3); }\n"), 0644); err != nil {
+		t.Fatalf("failed to write source: %v", err)
+	}
+	bin := filepath.Join(dir, "dbg")
+	if out, err := exec.Command(gcc, "-g", "-O0", "-o", bin, src).CombinedOutput(); err != nil {
+		t.Skipf("gcc failed: %v: %s", err, out)
+	}
+
+	data, err := os.ReadFile(bin)
+	if err != nil {
+		t.Fatalf("failed to read test binary: %v", err)
+	}
+	info, err := AnalyzeBinary(data)
+	if err != nil {
+		t.Fatalf("AnalyzeBinary failed: %v", err)
+	}
+
+	if info.Debug == nil {
+		t.Fatal("expected debug info for a -g build")
+	}
+	if len(info.Debug.CompileUnits) == 0 {
+		t.Fatal("expected at least one compile unit")
+	}
+	if info.Debug.UnoptimizedUnits == 0 {
+		t.Error("expected the -O0 compile unit to be flagged")
+	}
+	if !info.Debug.LeaksBuildPaths {
+		t.Error("expected absolute build paths to be flagged")
+	}
+
+	found := false
+	for _, fn := range info.Debug.Functions {
+		if fn.Name == "helper" {
+			found = true
+			if fn.File != src || fn.Line != 1 || fn.HighPC <= fn.LowPC {
+				t.Errorf("unexpected helper entry: %+v", fn)
+			}
+		}
+	}
+	if !found {
+		t.Error("expected helper in debug functions")
+	}
+}
+
+func TestAnalyzeBinary_NoDebugInfo(t *testing.T) {
+	data, err := os.ReadFile("/bin/ls")
+	if err != nil {
+		t.Fatalf("failed to read test binary: %v", err)
+	}
+	info, err := AnalyzeBinary(data)
+	if err != nil {
+		t.Fatalf("AnalyzeBinary failed: %v", err)
+	}
+	if info.Debug != nil {
+		t.Error("expected no debug info for a stripped binary")
+	}
+}
+
+func TestSplitProducer(t *testing.T) {
+	compiler, flags := splitProducer("GNU C17 12.2.0 -mtune=generic -march=x86-64 -g -O2")
+	if compiler != "GNU C17 12.2.0" {
+		t.Errorf("unexpected compiler %q", compiler)
+	}
+	if len(flags) != 4 || flags[3] != "-O2" {
+		t.Errorf("unexpected flags %v", flags)
+	}
+
+	compiler, flags = splitProducer("Go cmd/compile go1.24.4; -N -l")
+	if compiler != "Go cmd/compile go1.24.4" || len(flags) != 2 || flags[0] != "-N" {
+		t.Errorf("unexpected Go producer split: %q %v", compiler, flags)
+	}
+}
@@ -0,0 +1,58 @@
+package analyzer
+
+import (
+	"strings"
+
+	"github.com/ianlancetaylor/demangle"
+)
+
+// Demangle returns the readable form of an Itanium C++ or Rust (v0 and
+// legacy) symbol name, or "" when name is not mangled
+func Demangle(name string) string {
+	// Some toolchains keep the symbol version attached, e.g. _ZNSt...@GLIBCXX_3.4
+	base, suffix := name, ""
+	if i := strings.IndexByte(name, '@'); i > 0 {
+		base, suffix = name[:i], name[i:]
+	}
+	if !strings.HasPrefix(base, "_Z") && !strings.HasPrefix(base, "__Z") && !strings.HasPrefix(base, "_R") {
+		return ""
+	}
+	// Mach-O symbols carry an extra leading underscore
+	if strings.HasPrefix(base, "__Z") {
+		base = base[1:]
+	}
+	out, err := demangle.ToString(base)
+	if err != nil {
+		return ""
+	}
+	return out + suffix
+}
+
+// DemangleSymbols fills the Demangled field of every symbol, import and
+// export that carries a C++ or Rust mangled name, including those of the
+// other fat Mach-O slices and of embedded binaries
+func (info *BinaryInfo) DemangleSymbols() {
+	if info == nil {
+		return
+	}
+	for i := range info.Symbols {
+		info.Symbols[i].Demangled = Demangle(info.Symbols[i].Name)
+	}
+	for i := range info.Imports {
+		info.Imports[i].Demangled = Demangle(info.Imports[i].Name)
+	}
+	for i := range info.Exports {
+		info.Exports[i].Demangled = Demangle(info.Exports[i].Name)
+	}
+	for _, slice := range info.Slices {
+		slice.DemangleSymbols()
+	}
+	demangleEmbedded(info.Embedded)
+}
+
+func demangleEmbedded(artifacts []EmbeddedArtifact) {
+	for i := range artifacts {
+		artifacts[i].Binary.DemangleSymbols()
+		demangleEmbedded(artifacts[i].Children)
+	}
+}
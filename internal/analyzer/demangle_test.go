@@ -0,0 +1,41 @@
+package analyzer
+
+import "testing"
+
+func TestDemangle(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"_ZNSt6vectorIiSaIiEE9push_backERKi", "std::vector<int, std::allocator<int> >::push_back(int const&)"},
+		{"_ZdlPv@GLIBCXX_3.4", "operator delete(void*)@GLIBCXX_3.4"},
+		{"__ZN3foo3barEv", "foo::bar()"},
+		{"_RNvCs15kBYyAo9fc_7mycrate7example", "mycrate::example"},
+		{"_ZN4core3fmt5write17h0123456789abcdefE", "core::fmt::write"},
+		{"printf", ""},
+		{"_Zinvalid", ""},
+	}
+
+	for _, tt := range tests {
+		if got := Demangle(tt.name); got != tt.want {
+			t.Errorf("Demangle(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDemangleSymbols_SlicesAndEmbedded(t *testing.T) {
+	slice := &BinaryInfo{Symbols: []SymbolInfo{{Name: "_ZN3foo3barEv"}}}
+	embedded := &BinaryInfo{Exports: []SymbolInfo{{Name: "_ZN3foo3barEv"}}}
+	info := &BinaryInfo{
+		Slices:   []*BinaryInfo{slice},
+		Embedded: []EmbeddedArtifact{{Children: []EmbeddedArtifact{{Binary: embedded}}}},
+	}
+	info.DemangleSymbols()
+
+	if got := slice.Symbols[0].Demangled; got != "foo::bar()" {
+		t.Errorf("slice symbol demangled to %q", got)
+	}
+	if got := embedded.Exports[0].Demangled; got != "foo::bar()" {
+		t.Errorf("embedded export demangled to %q", got)
+	}
+}
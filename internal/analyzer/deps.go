@@ -0,0 +1,483 @@
+package analyzer
+
+import (
+	"bufio"
+	"bytes"
+	"debug/elf"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	maxDependencies = 512
+	maxSymlinkHops  = 40
+	maxLdConfDepth  = 8
+
+	ntGNUBuildID = 3
+)
+
+// Where a library was found, in ld.so search order
+const (
+	DepFoundPath       = "path" // DT_NEEDED contained a slash
+	DepFoundRPath      = "rpath"
+	DepFoundRunPath    = "runpath"
+	DepFoundConfigured = "library_dirs"
+	DepFoundLdSoConf   = "ld.so.conf"
+	DepFoundDefault    = "default"
+)
+
+// ResolverOptions control where ResolveDependencies looks for libraries.
+// Every search directory is interpreted inside Sysroot.
+type ResolverOptions struct {
+	Sysroot     string   // required; "/" resolves against the host
+	LibraryDirs []string // searched before DT_RUNPATH, like LD_LIBRARY_PATH
+	OriginDir   string   // directory $ORIGIN expands to for the analyzed binary
+}
+
+type DependencyNode struct {
+	Name      string            `json:"name"`
+	Path      string            `json:"path,omitempty"`
+	FoundVia  string            `json:"found_via,omitempty"`
+	BuildID   string            `json:"build_id,omitempty"`
+	Missing   bool              `json:"missing,omitempty"`
+	Duplicate bool              `json:"duplicate,omitempty"` // already loaded earlier in the tree
+	Children  []*DependencyNode `json:"children,omitempty"`
+}
+
+type MissingLibrary struct {
+	Name     string   `json:"name"`
+	NeededBy []string `json:"needed_by"`
+}
+
+type UnresolvedSymbol struct {
+	Name     string `json:"name"`
+	Version  string `json:"version,omitempty"`
+	Library  string `json:"library,omitempty"` // library the version requirement names
+	NeededBy string `json:"needed_by"`
+}
+
+type DependencyTree struct {
+	Sysroot    string             `json:"sysroot,omitempty"`
+	Root       *DependencyNode    `json:"root"`
+	LoadOrder  []string           `json:"load_order"`
+	Missing    []MissingLibrary   `json:"missing,omitempty"`
+	Unresolved []UnresolvedSymbol `json:"unresolved_symbols,omitempty"`
+	Truncated  bool               `json:"truncated,omitempty"`
+}
+
+// loadedObject is one ELF image in the simulated link map
+type loadedObject struct {
+	name    string
+	path    string // inside the sysroot
+	file    *elf.File
+	rpath   []string
+	runpath []string
+	parent  *loadedObject
+}
+
+type depResolver struct {
+	opts     ResolverOptions
+	class    elf.Class
+	machine  elf.Machine
+	ldConf   []string
+	loaded   map[string]*loadedObject // by DT_NEEDED name and by path
+	order    []*loadedObject
+	missing  map[string][]string
+	files    []*os.File
+	truncate bool
+}
+
+// ResolveDependencies walks DT_NEEDED transitively the way ld.so would:
+// DT_RPATH (unless DT_RUNPATH is present), configured library dirs,
+// DT_RUNPATH, the sysroot's ld.so.conf and finally the default directories.
+// It then reports undefined symbols no loaded object provides.
+func ResolveDependencies(fileBytes []byte, opts ResolverOptions) (*DependencyTree, error) {
+	elfFile, err := elf.NewFile(bytes.NewReader(fileBytes))
+	if err != nil {
+		return nil, fmt.Errorf("error elf parsing: %s", err.Error())
+	}
+	if opts.Sysroot == "" {
+		return nil, fmt.Errorf("no sysroot to resolve dependencies in")
+	}
+	if opts.OriginDir == "" {
+		opts.OriginDir = "/"
+	}
+
+	r := &depResolver{
+		opts:    opts,
+		class:   elfFile.Class,
+		machine: elfFile.Machine,
+		loaded:  make(map[string]*loadedObject),
+		missing: make(map[string][]string),
+	}
+	defer func() {
+		for _, f := range r.files {
+			f.Close()
+		}
+	}()
+	r.ldConf = r.readLdSoConf("/etc/ld.so.conf", 0)
+
+	exe := &loadedObject{
+		name: "(binary)",
+		path: path.Join(opts.OriginDir, "(binary)"),
+		file: elfFile,
+	}
+	exe.rpath, exe.runpath = dynPaths(elfFile)
+
+	tree := &DependencyTree{
+		Root: &DependencyNode{Name: exe.name, BuildID: buildID(elfFile)},
+	}
+	if opts.Sysroot != "/" {
+		tree.Sysroot = opts.Sysroot
+	}
+
+	// ld.so loads breadth-first; the tree records who asked for what
+	type pending struct {
+		obj  *loadedObject
+		node *DependencyNode
+	}
+	queue := []pending{{exe, tree.Root}}
+	r.order = append(r.order, exe)
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+
+		needed, _ := cur.obj.file.DynString(elf.DT_NEEDED)
+		for _, name := range needed {
+			node := &DependencyNode{Name: name}
+			cur.node.Children = append(cur.node.Children, node)
+
+			if obj, ok := r.loaded[name]; ok {
+				node.Path, node.Duplicate = obj.path, true
+				continue
+			}
+			if len(r.order) > maxDependencies {
+				r.truncate = true
+				continue
+			}
+
+			obj, via := r.find(name, cur.obj)
+			if obj == nil {
+				node.Missing = true
+				r.missing[name] = append(r.missing[name], cur.obj.name)
+				continue
+			}
+			if prev, ok := r.loaded[obj.path]; ok {
+				// Same file reached through a different DT_NEEDED spelling
+				r.loaded[name] = prev
+				node.Path, node.Duplicate = prev.path, true
+				continue
+			}
+
+			obj.parent = cur.obj
+			r.loaded[name] = obj
+			r.loaded[obj.path] = obj
+			r.order = append(r.order, obj)
+
+			node.Path = obj.path
+			node.FoundVia = via
+			node.BuildID = buildID(obj.file)
+			queue = append(queue, pending{obj, node})
+		}
+	}
+
+	for _, obj := range r.order[1:] {
+		tree.LoadOrder = append(tree.LoadOrder, obj.path)
+	}
+	for name, by := range r.missing {
+		tree.Missing = append(tree.Missing, MissingLibrary{Name: name, NeededBy: by})
+	}
+	sort.Slice(tree.Missing, func(i, j int) bool { return tree.Missing[i].Name < tree.Missing[j].Name })
+	tree.Unresolved = r.unresolvedSymbols()
+	tree.Truncated = r.truncate
+
+	return tree, nil
+}
+
+// find locates name for the object that needs it, returning the opened
+// library and which search step matched
+func (r *depResolver) find(name string, needer *loadedObject) (*loadedObject, string) {
+	if strings.Contains(name, "/") {
+		p := r.expand(name, needer)
+		if obj := r.open(name, p); obj != nil {
+			return obj, DepFoundPath
+		}
+		return nil, ""
+	}
+
+	type step struct {
+		dirs []string
+		via  string
+	}
+	var steps []step
+
+	// DT_RPATH of the needing object and its loaders applies only when the
+	// needing object has no DT_RUNPATH
+	if len(needer.runpath) == 0 {
+		for o := needer; o != nil; o = o.parent {
+			steps = append(steps, step{o.rpath, DepFoundRPath})
+		}
+	}
+	steps = append(steps,
+		step{r.opts.LibraryDirs, DepFoundConfigured},
+		step{needer.runpath, DepFoundRunPath},
+		step{r.ldConf, DepFoundLdSoConf},
+		step{r.defaultDirs(), DepFoundDefault},
+	)
+
+	for _, s := range steps {
+		for _, dir := range s.dirs {
+			if dir == "" {
+				continue
+			}
+			dir = r.expand(dir, needer)
+			if obj := r.open(name, path.Join(dir, name)); obj != nil {
+				return obj, s.via
+			}
+		}
+	}
+	return nil, ""
+}
+
+func (r *depResolver) defaultDirs() []string {
+	if r.class == elf.ELFCLASS64 {
+		return []string{"/lib64", "/usr/lib64", "/lib", "/usr/lib"}
+	}
+	return []string{"/lib32", "/usr/lib32", "/lib", "/usr/lib"}
+}
+
+// expand substitutes the dynamic string tokens ld.so understands
+func (r *depResolver) expand(p string, obj *loadedObject) string {
+	origin := path.Dir(obj.path)
+	lib := "lib"
+	if r.class == elf.ELFCLASS64 {
+		lib = "lib64"
+	}
+	p = strings.NewReplacer(
+		"${ORIGIN}", origin, "$ORIGIN", origin,
+		"${LIB}", lib, "$LIB", lib,
+	).Replace(p)
+	if !path.IsAbs(p) {
+		p = path.Join(origin, p)
+	}
+	return path.Clean(p)
+}
+
+// open returns the library at p if it is an ELF image ld.so would accept
+// for this binary, i.e. same class and machine
+func (r *depResolver) open(name, p string) *loadedObject {
+	hostPath, resolved, ok := r.resolveInRoot(p)
+	if !ok {
+		return nil
+	}
+	if obj, seen := r.loaded[resolved]; seen {
+		return obj
+	}
+	f, err := os.Open(hostPath)
+	if err != nil {
+		return nil
+	}
+	elfFile, err := elf.NewFile(f)
+	if err != nil || elfFile.Class != r.class || elfFile.Machine != r.machine {
+		f.Close()
+		return nil
+	}
+	r.files = append(r.files, f)
+
+	obj := &loadedObject{name: name, path: resolved, file: elfFile}
+	obj.rpath, obj.runpath = dynPaths(elfFile)
+	return obj
+}
+
+// resolveInRoot follows symlinks in every component of p while keeping
+// absolute targets and ".." inside the sysroot, so that no link leads to the
+// host. It returns the host path and the canonical in-root path of a regular
+// file.
+func (r *depResolver) resolveInRoot(p string) (string, string, bool) {
+	cur := "/"
+	rest := strings.Split(p, "/")
+	hops := 0
+	for len(rest) > 0 {
+		part := rest[0]
+		rest = rest[1:]
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			cur = path.Dir(cur)
+			continue
+		}
+
+		next := path.Join(cur, part)
+		fi, err := os.Lstat(filepath.Join(r.opts.Sysroot, filepath.FromSlash(next)))
+		if err != nil {
+			return "", "", false
+		}
+		switch {
+		case fi.Mode()&os.ModeSymlink != 0:
+			if hops++; hops > maxSymlinkHops {
+				return "", "", false
+			}
+			target, err := os.Readlink(filepath.Join(r.opts.Sysroot, filepath.FromSlash(next)))
+			if err != nil {
+				return "", "", false
+			}
+			if path.IsAbs(target) {
+				cur = "/"
+			}
+			rest = append(strings.Split(target, "/"), rest...)
+		case len(rest) == 0 || fi.IsDir():
+			cur = next
+		default:
+			return "", "", false
+		}
+	}
+	fi, err := os.Lstat(filepath.Join(r.opts.Sysroot, filepath.FromSlash(cur)))
+	if err != nil || !fi.Mode().IsRegular() {
+		return "", "", false
+	}
+	return filepath.Join(r.opts.Sysroot, filepath.FromSlash(cur)), cur, true
+}
+
+// readLdSoConf collects directories from the sysroot's ld.so.conf, following
+// include directives
+func (r *depResolver) readLdSoConf(conf string, depth int) []string {
+	if depth > maxLdConfDepth {
+		return nil
+	}
+	host, _, ok := r.resolveInRoot(conf)
+	if !ok {
+		return nil
+	}
+	f, err := os.Open(host)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var dirs []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "include "):
+			pattern := strings.TrimSpace(strings.TrimPrefix(line, "include "))
+			if !path.IsAbs(pattern) {
+				pattern = path.Join(path.Dir(conf), pattern)
+			}
+			matches, _ := filepath.Glob(filepath.Join(r.opts.Sysroot, filepath.FromSlash(pattern)))
+			sort.Strings(matches)
+			for _, m := range matches {
+				rel, err := filepath.Rel(r.opts.Sysroot, m)
+				if err != nil {
+					continue
+				}
+				dirs = append(dirs, r.readLdSoConf("/"+filepath.ToSlash(rel), depth+1)...)
+			}
+		case strings.HasPrefix(line, "hwcap "):
+		default:
+			dirs = append(dirs, line)
+		}
+	}
+	return dirs
+}
+
+// unresolvedSymbols lists non-weak undefined dynamic symbols that no object
+// in the link map defines
+func (r *depResolver) unresolvedSymbols() []UnresolvedSymbol {
+	defined := make(map[string]bool)
+	for _, obj := range r.order {
+		syms, err := obj.file.DynamicSymbols()
+		if err != nil {
+			continue
+		}
+		for _, sym := range syms {
+			if sym.Section == elf.SHN_UNDEF || elf.ST_VISIBILITY(sym.Other) == elf.STV_HIDDEN {
+				continue
+			}
+			if elf.ST_BIND(sym.Info) == elf.STB_LOCAL {
+				continue
+			}
+			defined[sym.Name] = true
+		}
+	}
+
+	var out []UnresolvedSymbol
+	for _, obj := range r.order {
+		syms, err := obj.file.DynamicSymbols()
+		if err != nil {
+			continue
+		}
+		for _, sym := range syms {
+			if sym.Section != elf.SHN_UNDEF || sym.Name == "" || elf.ST_BIND(sym.Info) == elf.STB_WEAK {
+				continue
+			}
+			if defined[sym.Name] {
+				continue
+			}
+			out = append(out, UnresolvedSymbol{
+				Name:     sym.Name,
+				Version:  sym.Version,
+				Library:  sym.Library,
+				NeededBy: obj.path,
+			})
+		}
+	}
+	return out
+}
+
+// dynPaths returns DT_RPATH and DT_RUNPATH split into directories
+func dynPaths(elfFile *elf.File) (rpath, runpath []string) {
+	if vals, err := elfFile.DynString(elf.DT_RPATH); err == nil {
+		for _, v := range vals {
+			rpath = append(rpath, strings.Split(v, ":")...)
+		}
+	}
+	if vals, err := elfFile.DynString(elf.DT_RUNPATH); err == nil {
+		for _, v := range vals {
+			runpath = append(runpath, strings.Split(v, ":")...)
+		}
+	}
+	return rpath, runpath
+}
+
+// buildID returns the hex GNU build-id note, or "" if there is none
+func buildID(elfFile *elf.File) string {
+	for _, sec := range elfFile.Sections {
+		if sec.Type != elf.SHT_NOTE {
+			continue
+		}
+		data, err := sec.Data()
+		if err != nil {
+			continue
+		}
+		for len(data) >= 12 {
+			namesz := elfFile.ByteOrder.Uint32(data[0:])
+			descsz := elfFile.ByteOrder.Uint32(data[4:])
+			typ := elfFile.ByteOrder.Uint32(data[8:])
+			if namesz > uint32(len(data)) || descsz > uint32(len(data)) {
+				break
+			}
+			nameEnd := 12 + alignUp(int(namesz), 4)
+			descEnd := nameEnd + alignUp(int(descsz), 4)
+			if descEnd > len(data) {
+				break
+			}
+			if typ == ntGNUBuildID && namesz == 4 && string(data[12:15]) == "GNU" {
+				return hex.EncodeToString(data[nameEnd : nameEnd+int(descsz)])
+			}
+			data = data[descEnd:]
+		}
+	}
+	return ""
+}
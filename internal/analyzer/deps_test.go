@@ -0,0 +1,151 @@
+package analyzer
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveDependencies_Host(t *testing.T) {
+	data, err := os.ReadFile("/bin/ls")
+	if err != nil {
+		t.Fatalf("failed to read test binary: %v", err)
+	}
+
+	tree, err := ResolveDependencies(data, ResolverOptions{Sysroot: "/", OriginDir: "/usr/bin"})
+	if err != nil {
+		t.Fatalf("ResolveDependencies failed: %v", err)
+	}
+	if len(tree.Missing) != 0 {
+		t.Errorf("expected every library of /bin/ls to resolve, missing %+v", tree.Missing)
+	}
+
+	found := false
+	for _, child := range tree.Root.Children {
+		if child.Name == "libc.so.6" {
+			found = child.Path != "" && child.BuildID != ""
+		}
+	}
+	if !found {
+		t.Error("expected libc.so.6 resolved with a build-id")
+	}
+}
+
+func TestResolveDependencies_OriginAndMissing(t *testing.T) {
+	gcc, err := exec.LookPath("gcc")
+	if err != nil {
+		t.Skip("gcc not available")
+	}
+
+	dir := t.TempDir()
+	libDir := filepath.Join(dir, "lib")
+	if err := os.Mkdir(libDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	libSrc := filepath.Join(dir, "foo.c")
+	mainSrc := filepath.Join(dir, "main.c")
+	os.WriteFile(libSrc, []byte("int foo(void) { return 42; }\n"), 0644)
+	os.WriteFile(mainSrc, []byte("int foo(void);\nint main(void) { return foo(); }\n"), 0644)
+
+	lib := filepath.Join(libDir, "libfoo.so")
+	bin := filepath.Join(dir, "app")
+	build := [][]string{
+		{"-shared", "-fPIC", "-o", lib, libSrc},
+		{"-o", bin, mainSrc, "-L" + libDir, "-lfoo", "-Wl,-rpath,$ORIGIN/lib"},
+	}
+	for _, args := range build {
+		if out, err := exec.Command(gcc, args...).CombinedOutput(); err != nil {
+			t.Skipf("gcc failed: %v: %s", err, out)
+		}
+	}
+
+	data, err := os.ReadFile(bin)
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+
+	// $ORIGIN is interpreted inside the sysroot, so serve the fixture as one
+	opts := ResolverOptions{Sysroot: dir, OriginDir: "/"}
+	tree, err := ResolveDependencies(data, opts)
+	if err != nil {
+		t.Fatalf("ResolveDependencies failed: %v", err)
+	}
+	var foo *DependencyNode
+	for _, child := range tree.Root.Children {
+		if child.Name == "libfoo.so" {
+			foo = child
+		}
+	}
+	if foo == nil || foo.Path != "/lib/libfoo.so" || foo.BuildID == "" {
+		t.Fatalf("expected libfoo.so via $ORIGIN, got %+v", foo)
+	}
+	if foo.FoundVia != DepFoundRunPath && foo.FoundVia != DepFoundRPath {
+		t.Errorf("expected runpath/rpath lookup, got %q", foo.FoundVia)
+	}
+
+	if err := os.Remove(lib); err != nil {
+		t.Fatal(err)
+	}
+	tree, err = ResolveDependencies(data, opts)
+	if err != nil {
+		t.Fatalf("ResolveDependencies failed: %v", err)
+	}
+	missing := false
+	for _, lib := range tree.Missing {
+		if lib.Name == "libfoo.so" {
+			missing = true
+		}
+	}
+	if !missing {
+		t.Fatalf("expected libfoo.so reported missing, got %+v", tree.Missing)
+	}
+
+	unresolved := false
+	for _, sym := range tree.Unresolved {
+		if sym.Name == "foo" && strings.HasSuffix(sym.NeededBy, "(binary)") {
+			unresolved = true
+		}
+	}
+	if !unresolved {
+		t.Errorf("expected foo to be unresolved, got %+v", tree.Unresolved)
+	}
+}
+
+func TestResolveDependencies_NoSysroot(t *testing.T) {
+	data, err := os.ReadFile("/bin/ls")
+	if err != nil {
+		t.Fatalf("failed to read test binary: %v", err)
+	}
+	if _, err := ResolveDependencies(data, ResolverOptions{}); err == nil {
+		t.Error("expected resolution without a sysroot to fail")
+	}
+}
+
+func TestResolveInRoot_StaysInside(t *testing.T) {
+	host := t.TempDir()
+	os.WriteFile(filepath.Join(host, "secret.so"), []byte("x"), 0644)
+
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "usr/lib"), 0755)
+	os.WriteFile(filepath.Join(root, "usr/lib/libc.so.6"), []byte("x"), 0644)
+	os.Symlink("usr/lib", filepath.Join(root, "lib"))
+	os.Symlink("/usr/lib/libc.so.6", filepath.Join(root, "lib/libc.so"))
+	os.Symlink(host, filepath.Join(root, "escape"))
+	os.Symlink("../../..", filepath.Join(root, "usr/lib/up"))
+
+	r := &depResolver{opts: ResolverOptions{Sysroot: root}}
+	for p, want := range map[string]string{
+		"/lib/libc.so.6":                "/usr/lib/libc.so.6",
+		"/lib/libc.so":                  "/usr/lib/libc.so.6",
+		"/usr/lib/up/lib/libc.so.6":     "/usr/lib/libc.so.6",
+		"/escape/secret.so":             "",
+		"/../../" + host + "/secret.so": "",
+	} {
+		_, got, ok := r.resolveInRoot(p)
+		if got != want || ok != (want != "") {
+			t.Errorf("resolveInRoot(%q) = %q, %v, want %q", p, got, ok, want)
+		}
+	}
+}
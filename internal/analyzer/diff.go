@@ -0,0 +1,289 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+)
+
+// BinaryDiff summarises what changed from one analysis result (A) to another (B)
+type BinaryDiff struct {
+	FormatChanged        bool               `json:"format_changed,omitempty"`
+	MachineChanged       bool               `json:"machine_changed,omitempty"`
+	EntryPoint           *EntryPointChange  `json:"entry_point,omitempty"`
+	SectionsAdded        []string           `json:"sections_added,omitempty"`
+	SectionsRemoved      []string           `json:"sections_removed,omitempty"`
+	SectionSizes         []SizeChange       `json:"section_sizes,omitempty"`
+	SegmentsAdded        []string           `json:"segments_added,omitempty"`
+	SegmentsRemoved      []string           `json:"segments_removed,omitempty"`
+	SegmentSizes         []SizeChange       `json:"segment_sizes,omitempty"`
+	SymbolsAdded         []string           `json:"symbols_added,omitempty"`
+	SymbolsRemoved       []string           `json:"symbols_removed,omitempty"`
+	ImportsAdded         []string           `json:"imports_added,omitempty"`
+	ImportsRemoved       []string           `json:"imports_removed,omitempty"`
+	LibrariesAdded       []string           `json:"libraries_added,omitempty"`
+	LibrariesRemoved     []string           `json:"libraries_removed,omitempty"`
+	HardeningRegressions []HardeningChange  `json:"hardening_regressions,omitempty"`
+	HardeningImproved    []HardeningChange  `json:"hardening_improved,omitempty"`
+	Syscalls             *SyscallSetChanges `json:"syscalls,omitempty"`
+}
+
+type EntryPointChange struct {
+	Old        uint64 `json:"old"`
+	New        uint64 `json:"new"`
+	Delta      int64  `json:"delta"`
+	OldSection string `json:"old_section,omitempty"`
+	NewSection string `json:"new_section,omitempty"`
+}
+
+// SizeChange is the size delta of a section or segment present in both results.
+// Segments are keyed by name where the format has one (Mach-O) and by type and
+// ordinal otherwise, e.g. "PT_LOAD#1"
+type SizeChange struct {
+	Name    string `json:"name"`
+	OldSize uint64 `json:"old_size"`
+	NewSize uint64 `json:"new_size"`
+	Delta   int64  `json:"delta"`
+}
+
+type HardeningChange struct {
+	Feature string `json:"feature"`
+	Old     string `json:"old"`
+	New     string `json:"new"`
+}
+
+// SyscallSetChanges lists syscalls seen in only one of the two traces
+type SyscallSetChanges struct {
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// DiffBinaries compares two static analysis results
+func DiffBinaries(a, b *BinaryInfo) *BinaryDiff {
+	if a == nil {
+		a = &BinaryInfo{}
+	}
+	if b == nil {
+		b = &BinaryInfo{}
+	}
+
+	d := &BinaryDiff{
+		FormatChanged:  a.Format != b.Format,
+		MachineChanged: a.Machine != b.Machine,
+	}
+
+	if a.EntryPoint != b.EntryPoint {
+		d.EntryPoint = &EntryPointChange{
+			Old:        a.EntryPoint,
+			New:        b.EntryPoint,
+			Delta:      int64(b.EntryPoint - a.EntryPoint),
+			OldSection: sectionContaining(a.Sections, a.EntryPoint),
+			NewSection: sectionContaining(b.Sections, b.EntryPoint),
+		}
+	}
+
+	oldSecs, newSecs := sectionSizes(a.Sections), sectionSizes(b.Sections)
+	d.SectionsAdded, d.SectionsRemoved, d.SectionSizes = diffSizes(oldSecs, newSecs)
+	oldSegs, newSegs := segmentSizes(a.Segments), segmentSizes(b.Segments)
+	d.SegmentsAdded, d.SegmentsRemoved, d.SegmentSizes = diffSizes(oldSegs, newSegs)
+
+	d.SymbolsAdded, d.SymbolsRemoved = diffSets(symbolNames(a.Symbols), symbolNames(b.Symbols))
+	d.ImportsAdded, d.ImportsRemoved = diffSets(importNames(a.Imports), importNames(b.Imports))
+	d.LibrariesAdded, d.LibrariesRemoved = diffSets(a.DynamicNeeded, b.DynamicNeeded)
+	d.HardeningRegressions, d.HardeningImproved = diffHardening(a.Hardening, b.Hardening)
+	return d
+}
+
+// DiffSyscalls compares the sets of syscall names observed in two traces
+func DiffSyscalls(a, b []VerboseSyscallEntry) *SyscallSetChanges {
+	names := func(entries []VerboseSyscallEntry) []string {
+		out := make([]string, 0, len(entries))
+		for _, e := range entries {
+			out = append(out, e.Name)
+		}
+		return out
+	}
+	added, removed := diffSets(names(a), names(b))
+	return &SyscallSetChanges{Added: added, Removed: removed}
+}
+
+func sectionContaining(sections []SectionInfo, addr uint64) string {
+	for _, s := range sections {
+		if s.Addr != 0 && addr >= s.Addr && addr < s.Addr+s.Size {
+			return s.Name
+		}
+	}
+	return ""
+}
+
+type sizedRegion struct {
+	key  string
+	size uint64
+}
+
+func sectionSizes(sections []SectionInfo) []sizedRegion {
+	out := make([]sizedRegion, 0, len(sections))
+	seen := map[string]int{}
+	for _, s := range sections {
+		if s.Name == "" {
+			continue
+		}
+		key := s.Name
+		if n := seen[s.Name]; n > 0 {
+			key = fmt.Sprintf("%s#%d", s.Name, n)
+		}
+		seen[s.Name]++
+		out = append(out, sizedRegion{key, s.Size})
+	}
+	return out
+}
+
+func segmentSizes(segments []SegmentInfo) []sizedRegion {
+	out := make([]sizedRegion, 0, len(segments))
+	seen := map[string]int{}
+	for _, s := range segments {
+		key := s.Name
+		if key == "" {
+			key = fmt.Sprintf("%s#%d", s.Type, seen[s.Type])
+			seen[s.Type]++
+		}
+		out = append(out, sizedRegion{key, s.Memsz})
+	}
+	return out
+}
+
+func diffSizes(a, b []sizedRegion) (added, removed []string, changed []SizeChange) {
+	old := make(map[string]uint64, len(a))
+	for _, r := range a {
+		old[r.key] = r.size
+	}
+	inB := make(map[string]bool, len(b))
+	for _, r := range b {
+		inB[r.key] = true
+		oldSize, ok := old[r.key]
+		if !ok {
+			added = append(added, r.key)
+			continue
+		}
+		if oldSize != r.size {
+			changed = append(changed, SizeChange{
+				Name:    r.key,
+				OldSize: oldSize,
+				NewSize: r.size,
+				Delta:   int64(r.size - oldSize),
+			})
+		}
+	}
+	for _, r := range a {
+		if !inB[r.key] {
+			removed = append(removed, r.key)
+		}
+	}
+	return added, removed, changed
+}
+
+func symbolNames(symbols []SymbolInfo) []string {
+	out := make([]string, 0, len(symbols))
+	for _, s := range symbols {
+		out = append(out, s.Name)
+	}
+	return out
+}
+
+func importNames(imports []ImportInfo) []string {
+	out := make([]string, 0, len(imports))
+	for _, imp := range imports {
+		out = append(out, imp.Name)
+	}
+	return out
+}
+
+// diffSets returns the sorted, de-duplicated names present only in b (added)
+// and only in a (removed)
+func diffSets(a, b []string) (added, removed []string) {
+	inA := make(map[string]bool, len(a))
+	for _, s := range a {
+		inA[s] = true
+	}
+	inB := make(map[string]bool, len(b))
+	for _, s := range b {
+		inB[s] = true
+	}
+	for s := range inB {
+		if s != "" && !inA[s] {
+			added = append(added, s)
+		}
+	}
+	for s := range inA {
+		if s != "" && !inB[s] {
+			removed = append(removed, s)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
+
+var relroRank = map[string]int{RELRONone: 0, RELROPartial: 1, RELROFull: 2}
+
+var pieRank = map[string]int{PIENone: 0, PIEDSO: 1, PIEEnabled: 1}
+
+// diffHardening classifies each changed mitigation as a regression or an
+// improvement; a missing report on either side is treated as "nothing known"
+func diffHardening(a, b *HardeningInfo) (regressions, improved []HardeningChange) {
+	if a == nil || b == nil {
+		return nil, nil
+	}
+
+	record := func(feature, oldVal, newVal string, better bool) {
+		c := HardeningChange{Feature: feature, Old: oldVal, New: newVal}
+		if better {
+			improved = append(improved, c)
+		} else {
+			regressions = append(regressions, c)
+		}
+	}
+	flag := func(feature string, oldOn, newOn bool) {
+		if oldOn != newOn {
+			record(feature, onOff(oldOn), onOff(newOn), newOn)
+		}
+	}
+
+	if a.RELRO != b.RELRO && a.RELRO != "" && b.RELRO != "" {
+		record("relro", a.RELRO, b.RELRO, relroRank[b.RELRO] > relroRank[a.RELRO])
+	}
+	if a.PIE != "" && b.PIE != "" && pieRank[a.PIE] != pieRank[b.PIE] {
+		record("pie", a.PIE, b.PIE, pieRank[b.PIE] > pieRank[a.PIE])
+	}
+	flag("nx", a.NX, b.NX)
+	flag("canary", a.Canary, b.Canary)
+	flag("fortify", a.Fortify, b.Fortify)
+	flag("ibt", a.IBT, b.IBT)
+	flag("shstk", a.SHSTK, b.SHSTK)
+
+	// Newly introduced search paths let libraries be loaded from unexpected places
+	if len(a.RPath) != len(b.RPath) {
+		record("rpath", fmt.Sprint(a.RPath), fmt.Sprint(b.RPath), len(b.RPath) < len(a.RPath))
+	}
+	if len(a.RunPath) != len(b.RunPath) {
+		record("runpath", fmt.Sprint(a.RunPath), fmt.Sprint(b.RunPath), len(b.RunPath) < len(a.RunPath))
+	}
+
+	if a.PE != nil && b.PE != nil {
+		flag("pe.high_entropy_va", a.PE.HighEntropyVA, b.PE.HighEntropyVA)
+		flag("pe.cfg", a.PE.CFG, b.PE.CFG)
+		flag("pe.force_integrity", a.PE.ForceIntegrity, b.PE.ForceIntegrity)
+		flag("pe.signed", a.PE.Signed, b.PE.Signed)
+	}
+	if a.MachO != nil && b.MachO != nil {
+		flag("macho.code_signature", a.MachO.CodeSignature, b.MachO.CodeSignature)
+		flag("macho.restrict", a.MachO.Restrict, b.MachO.Restrict)
+	}
+	return regressions, improved
+}
+
+func onOff(v bool) string {
+	if v {
+		return "enabled"
+	}
+	return "disabled"
+}
@@ -0,0 +1,78 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffBinaries(t *testing.T) {
+	a := &BinaryInfo{
+		Format:     FormatELF,
+		EntryPoint: 0x1040,
+		Sections: []SectionInfo{
+			{Name: ".text", Addr: 0x1000, Size: 0x200},
+			{Name: ".data", Addr: 0x3000, Size: 0x10},
+			{Name: ".comment", Size: 0x20},
+		},
+		Segments: []SegmentInfo{
+			{Type: "PT_LOAD", Memsz: 0x1000},
+			{Type: "PT_LOAD", Memsz: 0x2000},
+		},
+		Symbols:       []SymbolInfo{{Name: "main"}, {Name: "helper"}},
+		Imports:       []ImportInfo{{Name: "puts"}},
+		DynamicNeeded: []string{"libc.so.6"},
+		Hardening:     &HardeningInfo{RELRO: RELROFull, NX: true, PIE: PIEEnabled, Canary: true},
+	}
+	b := &BinaryInfo{
+		Format:     FormatELF,
+		EntryPoint: 0x1080,
+		Sections: []SectionInfo{
+			{Name: ".text", Addr: 0x1000, Size: 0x280},
+			{Name: ".data", Addr: 0x3000, Size: 0x10},
+			{Name: ".init_array", Addr: 0x2000, Size: 0x8},
+		},
+		Segments: []SegmentInfo{
+			{Type: "PT_LOAD", Memsz: 0x1000},
+			{Type: "PT_LOAD", Memsz: 0x2100},
+		},
+		Symbols:       []SymbolInfo{{Name: "main"}, {Name: "decrypt"}},
+		Imports:       []ImportInfo{{Name: "puts"}, {Name: "ptrace"}},
+		DynamicNeeded: []string{"libc.so.6"},
+		Hardening:     &HardeningInfo{RELRO: RELROPartial, NX: true, PIE: PIEEnabled, Canary: true, SHSTK: true},
+	}
+
+	d := DiffBinaries(a, b)
+	if d.EntryPoint == nil || d.EntryPoint.Delta != 0x40 || d.EntryPoint.NewSection != ".text" {
+		t.Errorf("unexpected entry point change: %+v", d.EntryPoint)
+	}
+	if !reflect.DeepEqual(d.SectionsAdded, []string{".init_array"}) || !reflect.DeepEqual(d.SectionsRemoved, []string{".comment"}) {
+		t.Errorf("unexpected section changes: +%v -%v", d.SectionsAdded, d.SectionsRemoved)
+	}
+	if len(d.SectionSizes) != 1 || d.SectionSizes[0].Name != ".text" || d.SectionSizes[0].Delta != 0x80 {
+		t.Errorf("unexpected section sizes: %+v", d.SectionSizes)
+	}
+	if len(d.SegmentSizes) != 1 || d.SegmentSizes[0].Name != "PT_LOAD#1" || d.SegmentSizes[0].Delta != 0x100 {
+		t.Errorf("unexpected segment sizes: %+v", d.SegmentSizes)
+	}
+	if !reflect.DeepEqual(d.SymbolsAdded, []string{"decrypt"}) || !reflect.DeepEqual(d.SymbolsRemoved, []string{"helper"}) {
+		t.Errorf("unexpected symbol changes: +%v -%v", d.SymbolsAdded, d.SymbolsRemoved)
+	}
+	if !reflect.DeepEqual(d.ImportsAdded, []string{"ptrace"}) || d.ImportsRemoved != nil {
+		t.Errorf("unexpected import changes: +%v -%v", d.ImportsAdded, d.ImportsRemoved)
+	}
+	if len(d.HardeningRegressions) != 1 || d.HardeningRegressions[0].Feature != "relro" {
+		t.Errorf("expected a RELRO regression: %+v", d.HardeningRegressions)
+	}
+	if len(d.HardeningImproved) != 1 || d.HardeningImproved[0].Feature != "shstk" {
+		t.Errorf("expected an SHSTK improvement: %+v", d.HardeningImproved)
+	}
+}
+
+func TestDiffSyscalls(t *testing.T) {
+	a := []VerboseSyscallEntry{{Name: "read"}, {Name: "write"}, {Name: "write"}}
+	b := []VerboseSyscallEntry{{Name: "read"}, {Name: "connect"}}
+	d := DiffSyscalls(a, b)
+	if !reflect.DeepEqual(d.Added, []string{"connect"}) || !reflect.DeepEqual(d.Removed, []string{"write"}) {
+		t.Errorf("unexpected syscall changes: %+v", d)
+	}
+}
@@ -0,0 +1,288 @@
+package analyzer
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/arch/arm64/arm64asm"
+	"golang.org/x/arch/x86/x86asm"
+)
+
+const (
+	DefaultDisasmCount = 100
+	MaxDisasmCount     = 2000
+)
+
+type Instruction struct {
+	Address      uint64 `json:"address"`
+	Bytes        string `json:"bytes"`
+	Text         string `json:"text"`
+	Target       uint64 `json:"target,omitempty"`
+	TargetSymbol string `json:"target_symbol,omitempty"`
+}
+
+type Disassembly struct {
+	Arch         string        `json:"arch"`
+	Symbol       string        `json:"symbol,omitempty"`
+	Start        uint64        `json:"start"`
+	Instructions []Instruction `json:"instructions"`
+}
+
+// symbolTable resolves addresses to the nearest preceding function or object
+type symbolTable struct {
+	syms   []elf.Symbol
+	byName map[string]elf.Symbol
+}
+
+func newSymbolTable(elfFile *elf.File) *symbolTable {
+	st := &symbolTable{byName: make(map[string]elf.Symbol)}
+	var all []elf.Symbol
+	if syms, err := elfFile.Symbols(); err == nil {
+		all = append(all, syms...)
+	}
+	if syms, err := elfFile.DynamicSymbols(); err == nil {
+		all = append(all, syms...)
+	}
+	for _, sym := range all {
+		typ := elf.ST_TYPE(sym.Info)
+		if sym.Name == "" || sym.Value == 0 || (typ != elf.STT_FUNC && typ != elf.STT_OBJECT && typ != elf.STT_NOTYPE) {
+			continue
+		}
+		if _, ok := st.byName[sym.Name]; !ok {
+			st.byName[sym.Name] = sym
+			st.syms = append(st.syms, sym)
+		}
+	}
+
+	// Name PLT stubs after the import they jump to, e.g. printf@plt
+	named := make(map[uint64]bool, len(st.syms))
+	for _, sym := range st.syms {
+		named[sym.Value] = true
+	}
+	for addr, name := range pltSymbols(elfFile) {
+		stub := elf.Symbol{
+			Name:  name + "@plt",
+			Info:  elf.ST_INFO(elf.STB_LOCAL, elf.STT_NOTYPE),
+			Value: addr,
+		}
+		if named[addr] {
+			continue
+		}
+		if _, ok := st.byName[stub.Name]; !ok {
+			st.byName[stub.Name] = stub
+			st.syms = append(st.syms, stub)
+		}
+	}
+
+	sort.Slice(st.syms, func(i, j int) bool {
+		return st.syms[i].Value < st.syms[j].Value
+	})
+	return st
+}
+
+// lookup returns the symbol covering addr and the offset into it
+func (st *symbolTable) lookup(addr uint64) (string, uint64) {
+	i := sort.Search(len(st.syms), func(i int) bool {
+		return st.syms[i].Value > addr
+	}) - 1
+	if i < 0 {
+		return "", 0
+	}
+	// Zero-sized labels such as _init only name their own address
+	sym := st.syms[i]
+	if addr != sym.Value && (sym.Size == 0 || addr >= sym.Value+sym.Size) {
+		return "", 0
+	}
+	return sym.Name, sym.Value
+}
+
+func (st *symbolTable) describe(addr uint64) string {
+	name, base := st.lookup(addr)
+	if name == "" {
+		return ""
+	}
+	if addr == base {
+		return name
+	}
+	return fmt.Sprintf("%s+0x%x", name, addr-base)
+}
+
+// Disassemble decodes up to count instructions starting at the named symbol,
+// a hex address (e.g. "0x401000"), or the entry point when target is empty
+func Disassemble(fileBytes []byte, target string, count int) (*Disassembly, error) {
+	elfFile, err := elf.NewFile(bytes.NewReader(fileBytes))
+	if err != nil {
+		return nil, fmt.Errorf("error elf parsing: %s", err.Error())
+	}
+
+	if count <= 0 {
+		count = DefaultDisasmCount
+	}
+	if count > MaxDisasmCount {
+		count = MaxDisasmCount
+	}
+
+	symbols := newSymbolTable(elfFile)
+	start := elfFile.Entry
+	if target != "" {
+		if sym, ok := symbols.byName[target]; ok {
+			start = sym.Value
+		} else if addr, err := strconv.ParseUint(strings.TrimPrefix(target, "0x"), 16, 64); err == nil && strings.HasPrefix(target, "0x") {
+			start = addr
+		} else {
+			return nil, fmt.Errorf("symbol not found: %s", target)
+		}
+	}
+
+	code, err := codeAt(elfFile, fileBytes, start)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Disassembly{
+		Symbol: symbols.describe(start),
+		Start:  start,
+	}
+
+	switch elfFile.Machine {
+	case elf.EM_X86_64:
+		result.Arch = "x86-64"
+		result.Instructions = disassembleX86(code, start, count, 64, symbols)
+	case elf.EM_386:
+		result.Arch = "i386"
+		result.Instructions = disassembleX86(code, start, count, 32, symbols)
+	case elf.EM_AARCH64:
+		result.Arch = "arm64"
+		result.Instructions = disassembleARM64(code, start, count, symbols)
+	default:
+		return nil, fmt.Errorf("unsupported machine for disassembly: %s", elfFile.Machine)
+	}
+
+	return result, nil
+}
+
+// codeAt returns the file bytes backing the executable mapping at addr
+func codeAt(elfFile *elf.File, fileBytes []byte, addr uint64) ([]byte, error) {
+	for _, ph := range elfFile.Progs {
+		if ph.Type != elf.PT_LOAD || ph.Flags&elf.PF_X == 0 {
+			continue
+		}
+		if addr >= ph.Vaddr && addr < ph.Vaddr+ph.Filesz {
+			return fileRegion(fileBytes, ph.Off+(addr-ph.Vaddr), ph.Filesz-(addr-ph.Vaddr)), nil
+		}
+	}
+	// Relocatable objects have no program headers, fall back to sections
+	for _, sec := range elfFile.Sections {
+		if sec.Flags&elf.SHF_EXECINSTR == 0 || sec.Type == elf.SHT_NOBITS {
+			continue
+		}
+		if addr >= sec.Addr && addr < sec.Addr+sec.Size {
+			return fileRegion(fileBytes, sec.Offset+(addr-sec.Addr), sec.Size-(addr-sec.Addr)), nil
+		}
+	}
+	return nil, fmt.Errorf("address 0x%x is not in an executable region", addr)
+}
+
+func disassembleX86(code []byte, pc uint64, count int, mode int, symbols *symbolTable) []Instruction {
+	symname := func(addr uint64) (string, uint64) {
+		return symbols.lookup(addr)
+	}
+
+	var out []Instruction
+	for len(out) < count && len(code) > 0 {
+		if text, ok := x86Endbr(code); ok {
+			out = append(out, Instruction{
+				Address: pc,
+				Bytes:   hex.EncodeToString(code[:4]),
+				Text:    text,
+			})
+			code = code[4:]
+			pc += 4
+			continue
+		}
+
+		inst, err := x86asm.Decode(code, mode)
+		if err != nil || inst.Len == 0 {
+			out = append(out, Instruction{
+				Address: pc,
+				Bytes:   hex.EncodeToString(code[:1]),
+				Text:    "(bad)",
+			})
+			code = code[1:]
+			pc++
+			continue
+		}
+
+		ins := Instruction{
+			Address: pc,
+			Bytes:   hex.EncodeToString(code[:inst.Len]),
+			Text:    x86asm.GNUSyntax(inst, pc, symname),
+		}
+		for _, arg := range inst.Args {
+			if rel, ok := arg.(x86asm.Rel); ok {
+				ins.Target = uint64(int64(pc) + int64(inst.Len) + int64(rel))
+				ins.TargetSymbol = symbols.describe(ins.Target)
+			}
+		}
+		out = append(out, ins)
+		code = code[inst.Len:]
+		pc += uint64(inst.Len)
+	}
+	return out
+}
+
+// x86Endbr recognises endbr64/endbr32, which x86asm predates and decodes as a
+// bare REP prefix
+func x86Endbr(code []byte) (string, bool) {
+	if len(code) < 4 || code[0] != 0xf3 || code[1] != 0x0f || code[2] != 0x1e {
+		return "", false
+	}
+	switch code[3] {
+	case 0xfa:
+		return "endbr64", true
+	case 0xfb:
+		return "endbr32", true
+	}
+	return "", false
+}
+
+func disassembleARM64(code []byte, pc uint64, count int, symbols *symbolTable) []Instruction {
+	var out []Instruction
+	for len(out) < count && len(code) >= 4 {
+		ins := Instruction{
+			Address: pc,
+			Bytes:   hex.EncodeToString(code[:4]),
+		}
+		inst, err := arm64asm.Decode(code[:4])
+		if err != nil {
+			ins.Text = "(bad)"
+		} else {
+			ins.Text = arm64asm.GNUSyntax(inst)
+			if isARM64Branch(inst.Op) {
+				for _, arg := range inst.Args {
+					if rel, ok := arg.(arm64asm.PCRel); ok {
+						ins.Target = uint64(int64(pc) + int64(rel))
+						ins.TargetSymbol = symbols.describe(ins.Target)
+					}
+				}
+			}
+		}
+		out = append(out, ins)
+		code = code[4:]
+		pc += 4
+	}
+	return out
+}
+
+func isARM64Branch(op arm64asm.Op) bool {
+	switch op {
+	case arm64asm.B, arm64asm.BL, arm64asm.CBZ, arm64asm.CBNZ, arm64asm.TBZ, arm64asm.TBNZ:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package analyzer
+
+import (
+	"debug/elf"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDisassemble_EntryPoint(t *testing.T) {
+	data, err := os.ReadFile("/bin/ls")
+	if err != nil {
+		t.Fatalf("failed to read test binary: %v", err)
+	}
+
+	dis, err := Disassemble(data, "", 20)
+	if err != nil {
+		t.Fatalf("Disassemble failed: %v", err)
+	}
+	if len(dis.Instructions) != 20 {
+		t.Fatalf("expected 20 instructions, got %d", len(dis.Instructions))
+	}
+	if dis.Instructions[0].Address != dis.Start {
+		t.Errorf("first instruction at 0x%x, expected 0x%x", dis.Instructions[0].Address, dis.Start)
+	}
+	for _, ins := range dis.Instructions {
+		t.Logf("%#x %-20s %s %s", ins.Address, ins.Bytes, ins.Text, ins.TargetSymbol)
+	}
+
+	if _, err := Disassemble(data, "no_such_symbol", 10); err == nil {
+		t.Error("expected error for unknown symbol")
+	}
+}
+
+func TestDisassemble_SymbolsAndPLT(t *testing.T) {
+	gcc, err := exec.LookPath("gcc")
+	if err != nil {
+		t.Skip("gcc not available")
+	}
+	dir := t.TempDir()
+	src := filepath.Join(dir, "main.c")
+	bin := filepath.Join(dir, "main")
+	os.WriteFile(src, []byte(`#include <stdio.h>
+int helper(int x) { return x * 3; }
+int main(void) { puts("hi"); return helper(2); }
+`), 0644)
+	if out, err := exec.Command(gcc, "-O0", "-fplt", "-o", bin, src).CombinedOutput(); err != nil {
+		t.Skipf("gcc failed: %v: %s", err, out)
+	}
+	data, err := os.ReadFile(bin)
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+
+	dis, err := Disassemble(data, "main", 20)
+	if err != nil {
+		t.Fatalf("Disassemble failed: %v", err)
+	}
+	if dis.Symbol != "main" || dis.Arch != "x86-64" {
+		t.Errorf("unexpected start: %s %s", dis.Symbol, dis.Arch)
+	}
+	targets := map[string]bool{}
+	for _, ins := range dis.Instructions {
+		targets[ins.TargetSymbol] = true
+	}
+	if !targets["puts@plt"] || !targets["helper"] {
+		t.Errorf("expected calls to puts@plt and helper, got targets %v", targets)
+	}
+
+	// The same code by address
+	byAddr, err := Disassemble(data, fmt.Sprintf("0x%x", dis.Start), 20)
+	if err != nil {
+		t.Fatalf("Disassemble by address failed: %v", err)
+	}
+	if byAddr.Symbol != "main" || len(byAddr.Instructions) != len(dis.Instructions) ||
+		byAddr.Instructions[5].Text != dis.Instructions[5].Text {
+		t.Errorf("address 0x%x did not disassemble main: %+v", dis.Start, byAddr)
+	}
+	if inside, err := Disassemble(data, fmt.Sprintf("0x%x", dis.Instructions[1].Address), 1); err != nil || inside.Symbol == "main" {
+		t.Errorf("expected an offset into main, got %+v, %v", inside, err)
+	}
+}
+
+func TestDisassembleARM64(t *testing.T) {
+	code := []byte{
+		0x02, 0x00, 0x00, 0x94, // bl 0x1008
+		0xc0, 0x03, 0x5f, 0xd6, // ret
+		0x1f, 0x20, 0x03, 0xd5, // nop
+	}
+	callee := elf.Symbol{Name: "callee", Value: 0x1008, Size: 4, Info: elf.ST_INFO(elf.STB_GLOBAL, elf.STT_FUNC)}
+	symbols := &symbolTable{syms: []elf.Symbol{callee}, byName: map[string]elf.Symbol{"callee": callee}}
+
+	ins := disassembleARM64(code, 0x1000, 10, symbols)
+	if len(ins) != 3 {
+		t.Fatalf("expected 3 instructions, got %+v", ins)
+	}
+	if ins[0].Target != 0x1008 || ins[0].TargetSymbol != "callee" {
+		t.Errorf("unexpected branch: %+v", ins[0])
+	}
+	if ins[1].Address != 0x1004 || strings.TrimSpace(ins[1].Text) != "ret" || strings.TrimSpace(ins[2].Text) != "nop" {
+		t.Errorf("unexpected decoding: %+v", ins[1:])
+	}
+}
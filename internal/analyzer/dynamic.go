@@ -1,6 +1,7 @@
 package analyzer
 
 import (
+	"debug/elf"
 	"fmt"
 	"os"
 	"os/exec"
@@ -10,7 +11,19 @@ import (
 	"github.com/ashborn3/BinTraceBench/internal/syscalls"
 )
 
-func TraceBinary(filebytes []byte) ([]VerboseSyscallEntry, error) {
+// maxTraceDuration bounds a dynamic analysis run, including any daemons the
+// sample leaves behind; it matches the sandbox execution limit
+const maxTraceDuration = 30 * time.Second
+
+// TraceResult is a dynamic analysis run: the syscalls of every traced task,
+// their per-syscall totals and the process tree they formed
+type TraceResult struct {
+	Syscalls  []VerboseSyscallEntry
+	Summary   *syscalls.Summary
+	Processes []TracedProcess
+}
+
+func TraceBinary(filebytes []byte) (*TraceResult, error) {
 	tmpfile, err := os.CreateTemp("", "bintracebench-*")
 	if err != nil {
 		return nil, fmt.Errorf("error creating temp file: %s", err.Error())
@@ -25,143 +38,150 @@ func TraceBinary(filebytes []byte) ([]VerboseSyscallEntry, error) {
 	tmpfile.Chmod(0755)
 	tmpfile.Close()
 
-	logs, err := ptraceBinaryPath(tmpfile.Name())
-	if err != nil {
+	if err := CheckTraceable(tmpfile.Name()); err != nil {
 		return nil, err
 	}
-	return logs, nil
+	return ptraceBinaryPath(tmpfile.Name())
 }
 
-type VerboseSyscallEntry struct {
-	PID       int      `json:"pid"`
-	Name      string   `json:"name"`
-	Number    uint64   `json:"number"`
-	Args      []string `json:"args"`
-	Return    string   `json:"return,omitempty"`
-	Timestamp string   `json:"timestamp"`
-	Event     string   `json:"event"` // "entry" or "exit"
-}
-
-func ptraceBinaryPath(path string) ([]VerboseSyscallEntry, error) {
-	cmd := exec.Command(path)
-	cmd.SysProcAttr = &syscall.SysProcAttr{
-		Ptrace: true,
+// CheckTraceable rejects ELF binaries this host cannot run, whose syscalls
+// would otherwise be looked up in the wrong table. Other files, such as
+// scripts, are left to the kernel.
+func CheckTraceable(path string) error {
+	f, err := elf.Open(path)
+	if err != nil {
+		return nil
 	}
+	defer f.Close()
 
-	if err := cmd.Start(); err != nil {
-		return nil, fmt.Errorf("error starting ptraced binary: %s", err.Error())
+	abi := syscalls.ABIForMachine(f.Machine)
+	for _, host := range hostABIs {
+		if abi == host {
+			return nil
+		}
 	}
-	pid := cmd.Process.Pid
+	return fmt.Errorf("cannot trace %s binaries on this host (%s)", f.Machine, hostABIs[0].Name)
+}
 
-	if _, err := syscall.Wait4(pid, nil, 0, nil); err != nil {
-		return nil, fmt.Errorf("initial wait failed: %w", err)
-	}
+// VerboseSyscallEntry is one traced syscall, from entry to exit
+type VerboseSyscallEntry struct {
+	PID  int    `json:"pid"`
+	Name string `json:"name"`
+	// Arch is the syscall ABI the call was made with, which Number belongs to
+	Arch   string `json:"arch,omitempty"`
+	Number uint64 `json:"number"`
+	// Args renders Decoded strace-style as name=value; output arguments are
+	// decoded once the call returns
+	Args    []string       `json:"args"`
+	Decoded []syscalls.Arg `json:"decoded,omitempty"`
+	// Return is absent for calls that never returned, such as exit_group
+	Return *int64 `json:"retval,omitempty"`
+	Errno  string `json:"errno,omitempty"` // set when Return is a negated errno
+	// StartUS is the entry time in microseconds since tracing began
+	StartUS    int64 `json:"start_us"`
+	DurationUS int64 `json:"duration_us"`
+	// Event is only set by traces stored before calls were paired, which
+	// hold separate "entry" and "exit" records without return or duration
+	Event string `json:"event,omitempty"`
+}
 
-	var logs []VerboseSyscallEntry
-	inSyscall := false
-	for {
-		if err := syscall.PtraceSyscall(pid, 0); err != nil {
-			break
-		}
-		if _, err := syscall.Wait4(pid, nil, 0, nil); err != nil {
-			break
-		}
+// SyscallRecorder turns the syscall stops of a trace into one record per
+// call, pairing each thread's entry stop with its exit stop
+type SyscallRecorder struct {
+	start   time.Time
+	records []VerboseSyscallEntry
+	pending map[int]pendingSyscall
+}
 
-		var regs syscall.PtraceRegs
-		if err := syscall.PtraceGetRegs(pid, &regs); err != nil {
-			break
-		}
+type pendingSyscall struct {
+	index   int
+	abi     *syscalls.ABI
+	args    [6]uint64
+	entered time.Time
+}
 
-		now := time.Now().Format("2006-01-02 15:04:05.000000")
-		if !inSyscall {
-			// Syscall entry
-			args := []string{
-				fmt.Sprintf("RDI=0x%x", regs.Rdi),
-				fmt.Sprintf("RSI=0x%x", regs.Rsi),
-				fmt.Sprintf("RDX=0x%x", regs.Rdx),
-				fmt.Sprintf("R10=0x%x", regs.R10),
-				fmt.Sprintf("R8=0x%x", regs.R8),
-				fmt.Sprintf("R9=0x%x", regs.R9),
-			}
-			// Try to decode pointer arguments for open/execve
-			name := humanSyscallName(regs.Orig_rax)
-			if name == "open" || name == "openat" {
-				// First arg is filename pointer
-				filename := readStringFromChild(pid, uintptr(regs.Rdi))
-				args[0] = fmt.Sprintf("filename=\"%s\" (0x%x)", filename, regs.Rdi)
-			} else if name == "execve" {
-				filename := readStringFromChild(pid, uintptr(regs.Rdi))
-				argv := readStringArrayFromChild(pid, uintptr(regs.Rsi))
-				args[0] = fmt.Sprintf("filename=\"%s\" (0x%x)", filename, regs.Rdi)
-				args[1] = fmt.Sprintf("argv=%v (0x%x)", argv, regs.Rsi)
-			}
-			entry := VerboseSyscallEntry{
-				PID:       pid,
-				Name:      name,
-				Number:    regs.Orig_rax,
-				Args:      args,
-				Timestamp: now,
-				Event:     "entry",
-			}
-			logs = append(logs, entry)
-		} else {
-			// Syscall exit
-			entry := VerboseSyscallEntry{
-				PID:       pid,
-				Name:      humanSyscallName(regs.Orig_rax),
-				Number:    regs.Orig_rax,
-				Return:    fmt.Sprintf("0x%x", regs.Rax),
-				Timestamp: now,
-				Event:     "exit",
-			}
-			logs = append(logs, entry)
-		}
-		inSyscall = !inSyscall
+func NewSyscallRecorder() *SyscallRecorder {
+	return &SyscallRecorder{start: time.Now(), pending: make(map[int]pendingSyscall)}
+}
+
+// Stop records a syscall stop; pass it as the callback of TraceCommand. The
+// syscall table is picked at each entry from the ABI the thread used, since
+// a tracee may exec a binary of another architecture or make 32-bit calls.
+func (r *SyscallRecorder) Stop(stop SyscallStop) {
+	now := time.Now()
+	tid, regs := stop.TID, &stop.Regs
+	if stop.Entry {
+		abi := syscallABI(tid, regs)
+		nr := syscallNumber(regs)
+		name := abi.SyscallName(nr)
+		args := syscallArgs(abi, regs)
+		decoded := abi.DecodeEntry(name, args, TraceeMemory(tid))
+		r.pending[tid] = pendingSyscall{index: len(r.records), abi: abi, args: args, entered: now}
+		r.records = append(r.records, VerboseSyscallEntry{
+			PID:     tid,
+			Name:    name,
+			Arch:    abi.Name,
+			Number:  nr,
+			Args:    syscalls.Strings(decoded),
+			Decoded: decoded,
+			StartUS: now.Sub(r.start).Microseconds(),
+		})
+		return
 	}
 
-	return logs, nil
+	p, ok := r.pending[tid]
+	if !ok {
+		return
+	}
+	delete(r.pending, tid)
+	rec := &r.records[p.index]
+	ret := p.abi.Return(syscallReturn(regs))
+	rec.Return = &ret
+	rec.Errno = syscalls.ErrnoName(ret)
+	rec.DurationUS = now.Sub(p.entered).Microseconds()
+	p.abi.DecodeExit(rec.Name, p.args, ret, TraceeMemory(tid), rec.Decoded)
+	rec.Args = syscalls.Strings(rec.Decoded)
 }
 
-// Read a null-terminated string from the traced process's memory
-func readStringFromChild(pid int, addr uintptr) string {
-	var data []byte
-	for i := 0; i < 256; i++ { // limit max string length
-		var tmp [1]byte
-		_, err := syscall.PtracePeekData(pid, addr+uintptr(i), tmp[:])
-		if err != nil || tmp[0] == 0 {
-			break
-		}
-		data = append(data, tmp[0])
+// Records returns every call seen so far in order of entry
+func (r *SyscallRecorder) Records() []VerboseSyscallEntry {
+	return r.records
+}
+
+func ptraceBinaryPath(path string) (*TraceResult, error) {
+	recorder := NewSyscallRecorder()
+	procs, err := TraceCommand(exec.Command(path), maxTraceDuration, recorder.Stop)
+	if err != nil {
+		return nil, err
 	}
-	return string(data)
+	calls := recorder.Records()
+	return &TraceResult{Syscalls: calls, Summary: SummarizeSyscalls(calls), Processes: procs}, nil
 }
 
-// Read a null-terminated array of string pointers from the traced process's memory
-func readStringArrayFromChild(pid int, addr uintptr) []string {
-	var result []string
-	for j := 0; j < 16; j++ { // limit max argv
-		var ptrBuf [8]byte
-		_, err := syscall.PtracePeekData(pid, addr+uintptr(j*8), ptrBuf[:])
-		if err != nil {
-			break
-		}
-		ptr := uintptr(0)
-		for k := 0; k < 8; k++ {
-			ptr |= uintptr(ptrBuf[k]) << (8 * k)
-		}
-		if ptr == 0 {
-			break
+// SummarizeSyscalls aggregates a dynamic trace per syscall. Legacy traces are
+// counted by their entry records and have no timings or errors.
+func SummarizeSyscalls(calls []VerboseSyscallEntry) *syscalls.Summary {
+	s := syscalls.NewSummarizer()
+	legacy := false
+	for _, c := range calls {
+		if c.Event != "" {
+			legacy = true
+			if c.Event != "entry" {
+				continue
+			}
 		}
-		s := readStringFromChild(pid, ptr)
-		result = append(result, s)
+		s.Add(c.Name, c.DurationUS, c.Errno != "")
 	}
-	return result
+	sum := s.Summary()
+	sum.TimingsUnavailable = legacy
+	return sum
 }
 
-// Helper to get a human-readable syscall name
-func humanSyscallName(num uint64) string {
-	if int(num) < len(syscalls.SyscallNames) {
-		return syscalls.SyscallNames[num]
+// TraceeMemory reads the memory of a stopped tracee
+func TraceeMemory(pid int) syscalls.Memory {
+	return func(addr uint64, n int) []byte {
+		buf := make([]byte, n)
+		count, _ := syscall.PtracePeekData(pid, uintptr(addr), buf)
+		return buf[:count]
 	}
-	return fmt.Sprintf("syscall_%d", num)
 }
@@ -0,0 +1,270 @@
+package analyzer
+
+import (
+	"debug/elf"
+	"encoding/binary"
+	"fmt"
+)
+
+// DWARF exception header pointer encodings (DW_EH_PE_*)
+const (
+	dwEHPEAbsptr  = 0x00
+	dwEHPEUleb128 = 0x01
+	dwEHPEUdata2  = 0x02
+	dwEHPEUdata4  = 0x03
+	dwEHPEUdata8  = 0x04
+	dwEHPESleb128 = 0x09
+	dwEHPESdata2  = 0x0a
+	dwEHPESdata4  = 0x0b
+	dwEHPESdata8  = 0x0c
+	dwEHPEPcrel   = 0x10
+	dwEHPEOmit    = 0xff
+)
+
+// FDERange is the code range covered by one .eh_frame FDE
+type FDERange struct {
+	Start uint64
+	Size  uint64
+}
+
+type ehReader struct {
+	data     []byte
+	pos      int
+	order    binary.ByteOrder
+	ptrSize  int
+	baseAddr uint64 // virtual address of data[0]
+}
+
+func (r *ehReader) remaining() int { return len(r.data) - r.pos }
+
+func (r *ehReader) u8() (byte, error) {
+	if r.remaining() < 1 {
+		return 0, fmt.Errorf("truncated")
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *ehReader) fixed(n int) (uint64, error) {
+	if r.remaining() < n {
+		return 0, fmt.Errorf("truncated")
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	switch n {
+	case 2:
+		return uint64(r.order.Uint16(b)), nil
+	case 4:
+		return uint64(r.order.Uint32(b)), nil
+	case 8:
+		return r.order.Uint64(b), nil
+	}
+	return 0, fmt.Errorf("bad width %d", n)
+}
+
+func (r *ehReader) uleb() (uint64, error) {
+	var result uint64
+	var shift uint
+	for {
+		b, err := r.u8()
+		if err != nil {
+			return 0, err
+		}
+		result |= uint64(b&0x7f) << shift
+		shift += 7
+		if b&0x80 == 0 {
+			return result, nil
+		}
+	}
+}
+
+func (r *ehReader) sleb() (int64, error) {
+	var result int64
+	var shift uint
+	for {
+		b, err := r.u8()
+		if err != nil {
+			return 0, err
+		}
+		result |= int64(b&0x7f) << shift
+		shift += 7
+		if b&0x80 == 0 {
+			if shift < 64 && b&0x40 != 0 {
+				result |= -1 << shift
+			}
+			return result, nil
+		}
+	}
+}
+
+func (r *ehReader) cstring() (string, error) {
+	start := r.pos
+	for r.pos < len(r.data) {
+		if r.data[r.pos] == 0 {
+			s := string(r.data[start:r.pos])
+			r.pos++
+			return s, nil
+		}
+		r.pos++
+	}
+	return "", fmt.Errorf("unterminated string")
+}
+
+// encoded reads a pointer in the given DW_EH_PE encoding
+func (r *ehReader) encoded(enc byte) (uint64, error) {
+	if enc == dwEHPEOmit {
+		return 0, nil
+	}
+	fieldAddr := r.baseAddr + uint64(r.pos)
+
+	var val uint64
+	var err error
+	switch enc & 0x0f {
+	case dwEHPEAbsptr:
+		val, err = r.fixed(r.ptrSize)
+	case dwEHPEUleb128:
+		val, err = r.uleb()
+	case dwEHPEUdata2:
+		val, err = r.fixed(2)
+	case dwEHPEUdata4:
+		val, err = r.fixed(4)
+	case dwEHPEUdata8:
+		val, err = r.fixed(8)
+	case dwEHPESleb128:
+		var s int64
+		s, err = r.sleb()
+		val = uint64(s)
+	case dwEHPESdata2:
+		val, err = r.fixed(2)
+		val = uint64(int64(int16(val)))
+	case dwEHPESdata4:
+		val, err = r.fixed(4)
+		val = uint64(int64(int32(val)))
+	case dwEHPESdata8:
+		val, err = r.fixed(8)
+	default:
+		return 0, fmt.Errorf("unsupported pointer encoding 0x%x", enc)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if enc&0x70 == dwEHPEPcrel {
+		val += fieldAddr
+	}
+	return val, nil
+}
+
+// parseEHFrame walks .eh_frame and returns the code range of every FDE
+func parseEHFrame(elfFile *elf.File) []FDERange {
+	sec := elfFile.Section(".eh_frame")
+	if sec == nil || sec.Type == elf.SHT_NOBITS {
+		return nil
+	}
+	data, err := sec.Data()
+	if err != nil {
+		return nil
+	}
+
+	ptrSize := 4
+	if elfFile.Class == elf.ELFCLASS64 {
+		ptrSize = 8
+	}
+
+	fdeEncodings := make(map[int]byte) // CIE offset -> FDE pointer encoding
+	var ranges []FDERange
+
+	pos := 0
+	for pos+4 <= len(data) {
+		r := &ehReader{data: data, pos: pos, order: elfFile.ByteOrder, ptrSize: ptrSize, baseAddr: sec.Addr}
+		length, _ := r.fixed(4)
+		if length == 0 {
+			break
+		}
+		if length == 0xffffffff {
+			if length, err = r.fixed(8); err != nil {
+				break
+			}
+		}
+		end := r.pos + int(length)
+		if end > len(data) || end < r.pos {
+			break
+		}
+
+		idPos := r.pos
+		id, err := r.fixed(4)
+		if err != nil {
+			break
+		}
+		if id == 0 {
+			fdeEncodings[pos] = parseCIEEncoding(r)
+		} else {
+			ciePos := idPos - int(id)
+			enc, ok := fdeEncodings[ciePos]
+			if !ok {
+				enc = dwEHPEAbsptr
+			}
+			start, err1 := r.encoded(enc)
+			size, err2 := r.encoded(enc & 0x0f)
+			if err1 == nil && err2 == nil && start != 0 {
+				ranges = append(ranges, FDERange{Start: start, Size: size})
+			}
+		}
+		pos = end
+	}
+	return ranges
+}
+
+// parseCIEEncoding returns the FDE pointer encoding from a CIE's "zR" augmentation
+func parseCIEEncoding(r *ehReader) byte {
+	version, err := r.u8()
+	if err != nil {
+		return dwEHPEAbsptr
+	}
+	aug, err := r.cstring()
+	if err != nil {
+		return dwEHPEAbsptr
+	}
+	if version >= 4 {
+		// address_size and segment_selector_size
+		r.pos += 2
+	}
+	r.uleb() // code alignment
+	r.sleb() // data alignment
+	if version == 1 {
+		r.u8()
+	} else {
+		r.uleb()
+	}
+
+	if len(aug) == 0 || aug[0] != 'z' {
+		return dwEHPEAbsptr
+	}
+	if _, err := r.uleb(); err != nil {
+		return dwEHPEAbsptr
+	}
+	for _, c := range aug[1:] {
+		switch c {
+		case 'R':
+			enc, err := r.u8()
+			if err != nil {
+				return dwEHPEAbsptr
+			}
+			return enc
+		case 'P':
+			enc, err := r.u8()
+			if err != nil {
+				return dwEHPEAbsptr
+			}
+			if _, err := r.encoded(enc); err != nil {
+				return dwEHPEAbsptr
+			}
+		case 'L':
+			if _, err := r.u8(); err != nil {
+				return dwEHPEAbsptr
+			}
+		}
+	}
+	return dwEHPEAbsptr
+}
@@ -0,0 +1,46 @@
+package analyzer
+
+import (
+	"math"
+)
+
+// shannonEntropy returns the entropy of data in bits per byte (0.0 - 8.0)
+func shannonEntropy(data []byte) float64 {
+	if len(data) == 0 {
+		return 0
+	}
+
+	var counts [256]int
+	for _, b := range data {
+		counts[b]++
+	}
+
+	entropy := 0.0
+	total := float64(len(data))
+	for _, c := range counts {
+		if c == 0 {
+			continue
+		}
+		p := float64(c) / total
+		entropy -= p * math.Log2(p)
+	}
+	return math.Round(entropy*1000) / 1000
+}
+
+// regionEntropy computes the entropy of the file bytes in [offset, offset+size),
+// clamped to the file length
+func regionEntropy(fileBytes []byte, offset, size uint64) float64 {
+	return shannonEntropy(fileRegion(fileBytes, offset, size))
+}
+
+func fileRegion(fileBytes []byte, offset, size uint64) []byte {
+	fileLen := uint64(len(fileBytes))
+	if offset >= fileLen {
+		return nil
+	}
+	end := offset + size
+	if end > fileLen || end < offset {
+		end = fileLen
+	}
+	return fileBytes[offset:end]
+}
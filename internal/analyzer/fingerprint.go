@@ -0,0 +1,102 @@
+package analyzer
+
+import (
+	"crypto/sha256"
+	"debug/elf"
+	"encoding/hex"
+	"sort"
+	"strings"
+)
+
+// Fingerprints are structural hashes that stay stable when only embedded data,
+// timestamps or build paths change between builds
+type Fingerprints struct {
+	ImportHash string `json:"import_hash,omitempty"` // sorted imported symbols and needed libraries
+	TextHash   string `json:"text_hash,omitempty"`   // SHA-256 of .text
+}
+
+// computeFingerprints hashes the import surface and .text of an ELF file, and
+// fills in the per-section SHA-256 of every section with file contents
+func computeFingerprints(elfFile *elf.File, fileBytes []byte, sections []SectionInfo, imports []ImportInfo, needed []string) *Fingerprints {
+	fp := &Fingerprints{ImportHash: importHash(imports, needed)}
+
+	for i, sec := range elfFile.Sections {
+		if sec.Type == elf.SHT_NOBITS || sec.Type == elf.SHT_NULL || sec.Size == 0 {
+			continue
+		}
+		region := fileRegion(fileBytes, sec.Offset, sec.Size)
+		if uint64(len(region)) != sec.Size {
+			continue // truncated file, a partial hash would be misleading
+		}
+		sections[i].SHA256 = sha256Hex(region)
+		if sec.Name == ".text" {
+			fp.TextHash = sections[i].SHA256
+		}
+	}
+
+	if fp.ImportHash == "" && fp.TextHash == "" {
+		return nil
+	}
+	return fp
+}
+
+// importHash is an ELF analogue of the PE imphash: symbol versions are left out
+// so that rebuilding against a newer libc does not change the fingerprint
+func importHash(imports []ImportInfo, needed []string) string {
+	if len(imports) == 0 && len(needed) == 0 {
+		return ""
+	}
+	libs := uniqueSorted(needed)
+	names := make([]string, 0, len(imports))
+	for _, imp := range imports {
+		names = append(names, imp.Name)
+	}
+	names = uniqueSorted(names)
+	return sha256Hex([]byte(strings.Join(libs, ",") + ";" + strings.Join(names, ",")))
+}
+
+func uniqueSorted(in []string) []string {
+	seen := make(map[string]bool, len(in))
+	out := make([]string, 0, len(in))
+	for _, s := range in {
+		s = strings.ToLower(s)
+		if s == "" || seen[s] {
+			continue
+		}
+		seen[s] = true
+		out = append(out, s)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// MatchesFingerprint reports whether info carries the given import hash, .text
+// hash or section hash; empty criteria match everything
+func (info *BinaryInfo) MatchesFingerprint(importHash, textHash, sectionHash string) bool {
+	if importHash == "" && textHash == "" && sectionHash == "" {
+		return true
+	}
+	if info == nil || info.Fingerprints == nil {
+		return false
+	}
+	if importHash != "" && !strings.EqualFold(info.Fingerprints.ImportHash, importHash) {
+		return false
+	}
+	if textHash != "" && !strings.EqualFold(info.Fingerprints.TextHash, textHash) {
+		return false
+	}
+	if sectionHash != "" {
+		for _, sec := range info.Sections {
+			if strings.EqualFold(sec.SHA256, sectionHash) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
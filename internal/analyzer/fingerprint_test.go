@@ -0,0 +1,69 @@
+package analyzer
+
+import (
+	"os"
+	"testing"
+)
+
+func TestImportHashIsOrderAndVersionInsensitive(t *testing.T) {
+	a := importHash([]ImportInfo{
+		{Name: "printf", Version: "GLIBC_2.2.5"},
+		{Name: "malloc", Version: "GLIBC_2.2.5"},
+	}, []string{"libc.so.6", "libm.so.6"})
+	b := importHash([]ImportInfo{
+		{Name: "malloc", Version: "GLIBC_2.34"},
+		{Name: "printf"},
+		{Name: "printf"},
+	}, []string{"libm.so.6", "libc.so.6"})
+	if a == "" || a != b {
+		t.Errorf("expected equal import hashes, got %q and %q", a, b)
+	}
+
+	if c := importHash([]ImportInfo{{Name: "printf"}}, []string{"libc.so.6"}); c == a {
+		t.Error("expected a different import set to change the hash")
+	}
+	if importHash(nil, nil) != "" {
+		t.Error("expected no import hash for a binary without imports")
+	}
+}
+
+func TestFingerprints_DataChangeKeepsTextHash(t *testing.T) {
+	data, err := os.ReadFile("/bin/ls")
+	if err != nil {
+		t.Fatalf("failed to read test binary: %v", err)
+	}
+	info, err := AnalyzeBinary(data)
+	if err != nil {
+		t.Fatalf("AnalyzeBinary failed: %v", err)
+	}
+	if info.Fingerprints == nil || info.Fingerprints.ImportHash == "" || info.Fingerprints.TextHash == "" {
+		t.Fatalf("expected import and .text fingerprints: %+v", info.Fingerprints)
+	}
+
+	// Patch a byte of .rodata; .text and the imports are untouched
+	var rodata *SectionInfo
+	for i := range info.Sections {
+		if info.Sections[i].Name == ".rodata" {
+			rodata = &info.Sections[i]
+		}
+	}
+	if rodata == nil {
+		t.Skip("test binary has no .rodata")
+	}
+	patched := append([]byte(nil), data...)
+	patched[rodata.Offset] ^= 0xff
+
+	other, err := AnalyzeBinary(patched)
+	if err != nil {
+		t.Fatalf("AnalyzeBinary failed: %v", err)
+	}
+	if *other.Fingerprints != *info.Fingerprints {
+		t.Errorf("fingerprints changed: %+v vs %+v", other.Fingerprints, info.Fingerprints)
+	}
+	if !other.MatchesFingerprint(info.Fingerprints.ImportHash, info.Fingerprints.TextHash, "") {
+		t.Error("expected patched binary to match the original fingerprints")
+	}
+	if other.MatchesFingerprint("", "", rodata.SHA256) {
+		t.Error("expected the patched .rodata hash to differ")
+	}
+}
@@ -0,0 +1,62 @@
+package analyzer
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	FormatELF   = "ELF"
+	FormatPE    = "PE"
+	FormatMachO = "Mach-O"
+)
+
+// BinaryParser turns one executable format into the common BinaryInfo
+type BinaryParser interface {
+	Format() string
+	Match(fileBytes []byte) bool
+	Parse(fileBytes []byte) (*BinaryInfo, error)
+}
+
+var parsers = []BinaryParser{
+	elfParser{},
+	peParser{},
+	machoParser{},
+}
+
+// DetectFormat returns the executable format of fileBytes by its magic
+func DetectFormat(fileBytes []byte) (string, error) {
+	p, err := parserFor(fileBytes)
+	if err != nil {
+		return "", err
+	}
+	return p.Format(), nil
+}
+
+func parserFor(fileBytes []byte) (BinaryParser, error) {
+	for _, p := range parsers {
+		if p.Match(fileBytes) {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported binary format (expected ELF, PE or Mach-O)")
+}
+
+// AnalyzeBinary detects the format of fileBytes, runs its static parser and
+// carves embedded payloads, analyzing any nested ELF files in turn
+func AnalyzeBinary(fileBytes []byte) (*BinaryInfo, error) {
+	return (&carver{}).analyze(fileBytes, 0)
+}
+
+// peOffset returns the offset of the "PE\0\0" signature named by the DOS header
+func peOffset(fileBytes []byte) (uint32, bool) {
+	if len(fileBytes) < 0x40 || fileBytes[0] != 'M' || fileBytes[1] != 'Z' {
+		return 0, false
+	}
+	off := binary.LittleEndian.Uint32(fileBytes[0x3c:])
+	if uint64(off)+4 > uint64(len(fileBytes)) {
+		return 0, false
+	}
+	sig := fileBytes[off : off+4]
+	return off, sig[0] == 'P' && sig[1] == 'E' && sig[2] == 0 && sig[3] == 0
+}
@@ -0,0 +1,150 @@
+package analyzer
+
+import (
+	"bytes"
+	"debug/pe"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// Go ships PE and Mach-O samples as debug/pe and debug/macho test data
+func readGoTestdata(t *testing.T, pkg, name string) []byte {
+	t.Helper()
+	path := filepath.Join(runtime.GOROOT(), "src", "debug", pkg, "testdata", name)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Skipf("sample not available: %v", err)
+	}
+	if filepath.Ext(name) == ".base64" {
+		if data, err = base64.StdEncoding.DecodeString(string(data)); err != nil {
+			t.Fatalf("failed to decode %s: %v", name, err)
+		}
+	}
+	return data
+}
+
+func TestDetectFormat(t *testing.T) {
+	elfData, err := os.ReadFile("/bin/ls")
+	if err != nil {
+		t.Fatalf("failed to read test binary: %v", err)
+	}
+	if format, err := DetectFormat(elfData); err != nil || format != FormatELF {
+		t.Errorf("expected ELF, got %q (%v)", format, err)
+	}
+
+	// Java class files share the fat Mach-O magic
+	class := []byte{0xca, 0xfe, 0xba, 0xbe, 0x00, 0x00, 0x00, 0x34}
+	if _, err := DetectFormat(class); err == nil {
+		t.Error("expected Java class file to be rejected")
+	}
+	if _, err := DetectFormat([]byte("#!/bin/sh\n")); err == nil {
+		t.Error("expected shell script to be rejected")
+	}
+}
+
+func TestAnalyzeBinary_PE(t *testing.T) {
+	data := readGoTestdata(t, "pe", "gcc-amd64-mingw-exec")
+
+	info, err := AnalyzeBinary(data)
+	if err != nil {
+		t.Fatalf("AnalyzeBinary failed: %v", err)
+	}
+	if info.Format != FormatPE || info.Class != "PE32+" || info.Type != "EXE" {
+		t.Errorf("unexpected header: %s %s %s", info.Format, info.Class, info.Type)
+	}
+	if info.EntryPoint == 0 || len(info.Sections) == 0 {
+		t.Error("expected entry point and sections")
+	}
+
+	foundLib := false
+	for _, lib := range info.DynamicNeeded {
+		if lib == "KERNEL32.dll" {
+			foundLib = true
+		}
+	}
+	if !foundLib {
+		t.Errorf("expected KERNEL32.dll in %v", info.DynamicNeeded)
+	}
+
+	if info.Hardening == nil || info.Hardening.PE == nil {
+		t.Fatal("expected PE hardening information")
+	}
+	// Old mingw images are linked without ASLR
+	if !info.Hardening.PE.RelocsStripped || info.Hardening.PIE != PIENone {
+		t.Errorf("unexpected ASLR state: %+v", info.Hardening)
+	}
+}
+
+func TestPEImage_ReadsSectionsOnce(t *testing.T) {
+	data := readGoTestdata(t, "pe", "gcc-amd64-mingw-exec")
+	peFile, err := pe.NewFile(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("pe.NewFile failed: %v", err)
+	}
+
+	img := newPEImage(peFile)
+	sec := peFile.Sections[0]
+	first, got := img.rva(sec.VirtualAddress + 1)
+	if got != sec || len(first) == 0 {
+		t.Fatalf("rva did not resolve into %s", sec.Name)
+	}
+	again, _ := img.rva(sec.VirtualAddress)
+	if &again[1] != &first[0] {
+		t.Error("section data was read again instead of reused")
+	}
+}
+
+func TestAnalyzeBinary_MachO(t *testing.T) {
+	data := readGoTestdata(t, "macho", "clang-amd64-darwin-exec-with-rpath.base64")
+
+	info, err := AnalyzeBinary(data)
+	if err != nil {
+		t.Fatalf("AnalyzeBinary failed: %v", err)
+	}
+	if info.Format != FormatMachO || info.Class != "MachO64" {
+		t.Errorf("unexpected header: %s %s", info.Format, info.Class)
+	}
+	if info.EntryPoint == 0 {
+		t.Error("expected entry point from LC_MAIN")
+	}
+	if info.Hardening == nil || info.Hardening.PIE != PIEEnabled {
+		t.Errorf("expected PIE, got %+v", info.Hardening)
+	}
+	if len(info.Hardening.RPath) != 1 || info.Hardening.RPath[0] != "/my/rpath" {
+		t.Errorf("unexpected rpath %v", info.Hardening.RPath)
+	}
+
+	found := false
+	for _, imp := range info.Imports {
+		if imp.Name == "_printf" && imp.Library == "/usr/lib/libSystem.B.dylib" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected _printf imported from libSystem, got %+v", info.Imports)
+	}
+}
+
+func TestAnalyzeBinary_FatMachO(t *testing.T) {
+	data := readGoTestdata(t, "macho", "fat-gcc-386-amd64-darwin-exec.base64")
+
+	info, err := AnalyzeBinary(data)
+	if err != nil {
+		t.Fatalf("AnalyzeBinary failed: %v", err)
+	}
+	if info.Class != "Fat" || len(info.Architectures) != 2 || len(info.Slices) != 1 {
+		t.Fatalf("unexpected fat layout: %s %v %d slices", info.Class, info.Architectures, len(info.Slices))
+	}
+
+	// Slice sections must index the fat file, not the slice
+	slice := info.Slices[0]
+	if slice.Machine != "CpuAmd64" || slice.EntryPoint == 0 {
+		t.Errorf("unexpected second slice: %s 0x%x", slice.Machine, slice.EntryPoint)
+	}
+	if len(slice.Sections) == 0 || slice.Sections[0].Offset <= info.Sections[0].Offset {
+		t.Error("expected second slice sections to be rebased past the first slice")
+	}
+}
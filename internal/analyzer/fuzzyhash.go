@@ -0,0 +1,45 @@
+package analyzer
+
+// FuzzyHashes holds the locality-sensitive digests used to find related samples
+type FuzzyHashes struct {
+	TLSH   string `json:"tlsh,omitempty"`
+	SSDeep string `json:"ssdeep,omitempty"`
+}
+
+// Similarity describes how closely two samples' fuzzy hashes match
+type Similarity struct {
+	Score        int  `json:"score"`
+	SSDeepScore  int  `json:"ssdeep_score"`
+	TLSHDistance *int `json:"tlsh_distance,omitempty"`
+}
+
+// ComputeFuzzyHashes digests data with both TLSH and ssdeep
+func ComputeFuzzyHashes(data []byte) FuzzyHashes {
+	return FuzzyHashes{
+		TLSH:   ComputeTLSH(data),
+		SSDeep: ComputeSSDeep(data),
+	}
+}
+
+// CompareFuzzyHashes scores a against b from 0 to 100. TLSH distances are
+// mapped onto the same scale (distance 0 -> 100, 100 and beyond -> 0) and the
+// better of the two scores wins, since each digest misses cases the other catches
+func CompareFuzzyHashes(a, b FuzzyHashes) Similarity {
+	var sim Similarity
+	if a.SSDeep != "" && b.SSDeep != "" {
+		if score, err := SSDeepCompare(a.SSDeep, b.SSDeep); err == nil {
+			sim.SSDeepScore = score
+		}
+	}
+	sim.Score = sim.SSDeepScore
+
+	if a.TLSH != "" && b.TLSH != "" {
+		if dist, err := TLSHDistance(a.TLSH, b.TLSH); err == nil {
+			sim.TLSHDistance = &dist
+			if score := 100 - dist; score > sim.Score {
+				sim.Score = score
+			}
+		}
+	}
+	return sim
+}
@@ -0,0 +1,75 @@
+package analyzer
+
+import (
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestTLSHPearsonIsPermutation(t *testing.T) {
+	var seen [256]bool
+	for _, v := range tlshPearson {
+		if seen[v] {
+			t.Fatalf("value %d appears twice in the Pearson table", v)
+		}
+		seen[v] = true
+	}
+}
+
+func TestComputeTLSH(t *testing.T) {
+	if h := ComputeTLSH([]byte("too short")); h != "" {
+		t.Errorf("expected no digest for short input, got %q", h)
+	}
+	if h := ComputeTLSH(make([]byte, 4096)); h != "" {
+		t.Errorf("expected no digest for constant input, got %q", h)
+	}
+
+	data := make([]byte, 8192)
+	rand.New(rand.NewSource(1)).Read(data)
+	h := ComputeTLSH(data)
+	if !strings.HasPrefix(h, "T1") || len(h) != 2+2*tlshDigestBytes {
+		t.Fatalf("unexpected digest format: %q", h)
+	}
+	if d, err := TLSHDistance(h, h); err != nil || d != 0 {
+		t.Errorf("expected distance 0 to itself, got %d (%v)", d, err)
+	}
+}
+
+func TestComputeSSDeep(t *testing.T) {
+	if h := ComputeSSDeep(nil); h != "3::" {
+		t.Errorf("unexpected digest for empty input: %q", h)
+	}
+	if _, err := SSDeepCompare("garbage", "3::"); err == nil {
+		t.Error("expected malformed digest to be rejected")
+	}
+}
+
+func TestCompareFuzzyHashes(t *testing.T) {
+	data, err := os.ReadFile("/bin/ls")
+	if err != nil {
+		t.Fatalf("failed to read test binary: %v", err)
+	}
+
+	// A rebuild with a patched timestamp and tweaked data should still match closely
+	patched := append([]byte(nil), data...)
+	for i := len(patched) / 2; i < len(patched)/2+64; i++ {
+		patched[i] ^= 0xff
+	}
+
+	original := ComputeFuzzyHashes(data)
+	sim := CompareFuzzyHashes(original, ComputeFuzzyHashes(patched))
+	if sim.Score < 80 || sim.TLSHDistance == nil {
+		t.Errorf("expected patched binary to be similar: %+v", sim)
+	}
+
+	noise := make([]byte, len(data))
+	rand.New(rand.NewSource(2)).Read(noise)
+	if sim := CompareFuzzyHashes(original, ComputeFuzzyHashes(noise)); sim.Score > 20 {
+		t.Errorf("expected random data to be dissimilar: %+v", sim)
+	}
+
+	if sim := CompareFuzzyHashes(original, original); sim.Score != 100 || sim.SSDeepScore != 100 {
+		t.Errorf("expected identical hashes to score 100: %+v", sim)
+	}
+}
@@ -0,0 +1,220 @@
+package analyzer
+
+import (
+	"bytes"
+	"debug/buildinfo"
+	"debug/elf"
+	"debug/gosym"
+	"encoding/binary"
+	"fmt"
+	"runtime/debug"
+	"sort"
+)
+
+const maxGoFunctions = 10000
+
+type GoInfo struct {
+	GoVersion     string            `json:"go_version"`
+	Path          string            `json:"path,omitempty"`
+	Main          *GoModule         `json:"main,omitempty"`
+	Deps          []GoModule        `json:"deps,omitempty"`
+	Settings      map[string]string `json:"settings,omitempty"`
+	TrimPath      bool              `json:"trimpath"`
+	CGOEnabled    bool              `json:"cgo_enabled"`
+	GOOS          string            `json:"goos,omitempty"`
+	GOARCH        string            `json:"goarch,omitempty"`
+	VCS           string            `json:"vcs,omitempty"`
+	VCSRevision   string            `json:"vcs_revision,omitempty"`
+	VCSTime       string            `json:"vcs_time,omitempty"`
+	VCSModified   bool              `json:"vcs_modified,omitempty"`
+	FunctionCount int               `json:"function_count,omitempty"`
+	Functions     []GoFunction      `json:"functions,omitempty"`
+	Truncated     bool              `json:"truncated,omitempty"`
+	// PclntabError tells why no functions could be recovered from .gopclntab
+	PclntabError string `json:"pclntab_error,omitempty"`
+}
+
+type GoModule struct {
+	Path    string    `json:"path"`
+	Version string    `json:"version,omitempty"`
+	Sum     string    `json:"sum,omitempty"`
+	Replace *GoModule `json:"replace,omitempty"`
+}
+
+type GoFunction struct {
+	Name  string `json:"name"`
+	Entry uint64 `json:"entry"`
+	End   uint64 `json:"end"`
+	File  string `json:"file,omitempty"`
+	Line  int    `json:"line,omitempty"`
+}
+
+// analyzeGoBinary reads the embedded build information of Go binaries and,
+// for stripped ones, recovers function names from .gopclntab. It returns nil
+// for binaries not built by the Go toolchain.
+func analyzeGoBinary(elfFile *elf.File, fileBytes []byte) *GoInfo {
+	bi, err := buildinfo.Read(bytes.NewReader(fileBytes))
+	if err != nil {
+		if elfFile.Section(".go.buildinfo") == nil && elfFile.Section(".gopclntab") == nil {
+			return nil
+		}
+		// Pre-module or damaged buildinfo, still worth reporting the pclntab
+		bi = &debug.BuildInfo{}
+	}
+
+	info := &GoInfo{
+		GoVersion: bi.GoVersion,
+		Path:      bi.Path,
+	}
+	if bi.Main.Path != "" {
+		info.Main = newGoModule(&bi.Main)
+	}
+	for _, dep := range bi.Deps {
+		info.Deps = append(info.Deps, *newGoModule(dep))
+	}
+
+	if len(bi.Settings) > 0 {
+		info.Settings = make(map[string]string, len(bi.Settings))
+	}
+	for _, s := range bi.Settings {
+		info.Settings[s.Key] = s.Value
+		switch s.Key {
+		case "-trimpath":
+			info.TrimPath = s.Value == "true"
+		case "CGO_ENABLED":
+			info.CGOEnabled = s.Value == "1"
+		case "GOOS":
+			info.GOOS = s.Value
+		case "GOARCH":
+			info.GOARCH = s.Value
+		case "vcs":
+			info.VCS = s.Value
+		case "vcs.revision":
+			info.VCSRevision = s.Value
+		case "vcs.time":
+			info.VCSTime = s.Value
+		case "vcs.modified":
+			info.VCSModified = s.Value == "true"
+		}
+	}
+
+	// Unstripped binaries already expose these names through the symbol table
+	if elfFile.Section(".symtab") == nil {
+		var err error
+		info.Functions, info.FunctionCount, err = recoverGoFunctions(elfFile)
+		if err != nil {
+			info.PclntabError = err.Error()
+		}
+		info.Truncated = info.FunctionCount > len(info.Functions)
+	}
+
+	return info
+}
+
+func newGoModule(m *debug.Module) *GoModule {
+	mod := &GoModule{Path: m.Path, Version: m.Version, Sum: m.Sum}
+	if m.Replace != nil {
+		mod.Replace = newGoModule(m.Replace)
+	}
+	return mod
+}
+
+// recoverGoFunctions decodes the function table in .gopclntab, returning up
+// to maxGoFunctions entries and the total number of functions. The table
+// comes from the upload and debug/gosym may panic on malformed ones, so
+// panics are turned into errors.
+func recoverGoFunctions(elfFile *elf.File) (out []GoFunction, total int, err error) {
+	sec := elfFile.Section(".gopclntab")
+	if sec == nil || sec.Type == elf.SHT_NOBITS {
+		return nil, 0, nil
+	}
+	data, err := sec.Data()
+	if err != nil {
+		return nil, 0, fmt.Errorf("error reading .gopclntab: %w", err)
+	}
+
+	// gosym allocates a Func per declared function before checking anything,
+	// and a runaway allocation is fatal rather than a panic
+	if n := pclntabFuncCount(elfFile.ByteOrder, data); n*8 > uint64(len(data)) {
+		return nil, 0, fmt.Errorf("malformed .gopclntab: %d functions do not fit in %d bytes", n, len(data))
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			out, total, err = nil, 0, fmt.Errorf("malformed .gopclntab: %v", r)
+		}
+	}()
+
+	table, err := gosym.NewTable(nil, gosym.NewLineTable(data, goTextStart(elfFile, data)))
+	if err != nil {
+		return nil, 0, fmt.Errorf("error parsing .gopclntab: %w", err)
+	}
+
+	funcs := table.Funcs
+	sort.Slice(funcs, func(i, j int) bool {
+		return funcs[i].Entry < funcs[j].Entry
+	})
+
+	for i := range funcs {
+		if len(out) >= maxGoFunctions {
+			break
+		}
+		fn := &funcs[i]
+		gf := GoFunction{Name: fn.Name, Entry: fn.Entry, End: fn.End}
+		gf.File, gf.Line, _ = table.PCToLine(fn.Entry)
+		out = append(out, gf)
+	}
+	return out, len(funcs), nil
+}
+
+// pclntabFuncCount reads the number of functions a pclntab header declares,
+// truncated to 32 bits as gosym does. Every layout keeps it right after the
+// 8-byte header, and every function takes at least 8 bytes of the table.
+func pclntabFuncCount(order binary.ByteOrder, pclntab []byte) uint64 {
+	if len(pclntab) < 16 {
+		return 0
+	}
+	if pclntab[7] == 4 {
+		return uint64(order.Uint32(pclntab[8:]))
+	}
+	return uint64(uint32(order.Uint64(pclntab[8:])))
+}
+
+// goTextStart returns the value of runtime.text, which pclntab offsets are
+// relative to. Stripped binaries lose the symbol, but Go 1.18+ pclntab
+// headers record it directly.
+func goTextStart(elfFile *elf.File, pclntab []byte) uint64 {
+	if syms, err := elfFile.Symbols(); err == nil {
+		for _, sym := range syms {
+			if sym.Name == "runtime.text" {
+				return sym.Value
+			}
+		}
+	}
+
+	if len(pclntab) >= 8 {
+		magic := binary.LittleEndian.Uint32(pclntab)
+		if elfFile.ByteOrder == binary.BigEndian {
+			magic = binary.BigEndian.Uint32(pclntab)
+		}
+		ptrSize := int(pclntab[7])
+		off := 8 + 2*ptrSize
+		// go1.18 and go1.20 magics
+		if (magic == 0xfffffff0 || magic == 0xfffffff1) && (ptrSize == 4 || ptrSize == 8) && len(pclntab) >= off+ptrSize {
+			var text uint64
+			if ptrSize == 8 {
+				text = elfFile.ByteOrder.Uint64(pclntab[off:])
+			} else {
+				text = uint64(elfFile.ByteOrder.Uint32(pclntab[off:]))
+			}
+			if text != 0 {
+				return text
+			}
+		}
+	}
+
+	if sec := elfFile.Section(".text"); sec != nil {
+		return sec.Addr
+	}
+	return 0
+}
@@ -0,0 +1,99 @@
+package analyzer
+
+import (
+	"bytes"
+	"debug/elf"
+	"os"
+	"runtime"
+	"testing"
+)
+
+func TestAnalyzeBinary_GoInfo(t *testing.T) {
+	// The test binary itself is a Go executable
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatalf("failed to locate test binary: %v", err)
+	}
+	data, err := os.ReadFile(exe)
+	if err != nil {
+		t.Fatalf("failed to read test binary: %v", err)
+	}
+
+	info, err := AnalyzeBinary(data)
+	if err != nil {
+		t.Fatalf("AnalyzeBinary failed: %v", err)
+	}
+	if info.Go == nil {
+		t.Fatal("expected Go build information")
+	}
+	if info.Go.GoVersion != runtime.Version() {
+		t.Errorf("expected Go version %s, got %s", runtime.Version(), info.Go.GoVersion)
+	}
+	if info.Go.GOARCH != runtime.GOARCH {
+		t.Errorf("expected GOARCH %s, got %s", runtime.GOARCH, info.Go.GOARCH)
+	}
+
+	elfFile, err := elf.NewFile(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to parse test binary: %v", err)
+	}
+	funcs, total, err := recoverGoFunctions(elfFile)
+	if err != nil || total == 0 || len(funcs) == 0 {
+		t.Fatal("expected functions recovered from .gopclntab")
+	}
+	found := false
+	for _, fn := range funcs {
+		if fn.Name == "runtime.main" {
+			found = fn.End > fn.Entry && fn.File != ""
+		}
+	}
+	if !found {
+		t.Error("expected runtime.main with a valid range and source file")
+	}
+}
+
+func TestRecoverGoFunctions_Malformed(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatalf("failed to locate test binary: %v", err)
+	}
+	data, err := os.ReadFile(exe)
+	if err != nil {
+		t.Fatalf("failed to read test binary: %v", err)
+	}
+	elfFile, err := elf.NewFile(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to parse test binary: %v", err)
+	}
+	sec := elfFile.Section(".gopclntab")
+	if sec == nil {
+		t.Skip("test binary has no .gopclntab")
+	}
+
+	// A function count far beyond the table would make gosym allocate until
+	// the process dies
+	bad := append([]byte(nil), data...)
+	elfFile.ByteOrder.PutUint32(bad[sec.Offset+8:], 0xffffffff)
+	badFile, err := elf.NewFile(bytes.NewReader(bad))
+	if err != nil {
+		t.Fatalf("failed to parse corrupted binary: %v", err)
+	}
+	funcs, total, err := recoverGoFunctions(badFile)
+	if err == nil || len(funcs) != 0 || total != 0 {
+		t.Errorf("expected a pclntab error, got %d functions (%d total), err %v", len(funcs), total, err)
+	}
+}
+
+func TestAnalyzeBinary_NotGo(t *testing.T) {
+	data, err := os.ReadFile("/bin/ls")
+	if err != nil {
+		t.Fatalf("failed to read test binary: %v", err)
+	}
+	info, err := AnalyzeBinary(data)
+	if err != nil {
+		t.Fatalf("AnalyzeBinary failed: %v", err)
+	}
+	if info.Go != nil {
+		t.Error("expected no Go information for a C binary")
+	}
+}
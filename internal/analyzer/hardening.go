@@ -0,0 +1,224 @@
+package analyzer
+
+import (
+	"debug/elf"
+	"encoding/binary"
+	"sort"
+	"strings"
+)
+
+const (
+	RELROFull    = "full"
+	RELROPartial = "partial"
+	RELRONone    = "none"
+
+	PIEEnabled = "pie"
+	PIEDSO     = "dso"
+	PIENone    = "none"
+)
+
+// GNU property note constants (see the x86-64 and AArch64 psABI supplements)
+const (
+	ntGNUPropertyType0 = 5
+
+	gnuPropertyX86Feature1And = 0xc0000002
+	gnuPropertyX86IBT         = 1 << 0
+	gnuPropertyX86SHSTK       = 1 << 1
+)
+
+// HardeningInfo is shared by all formats: NX doubles as DEP and PIE as ASLR
+// for PE and Mach-O, whose format-specific flags live in PE and MachO
+type HardeningInfo struct {
+	RELRO              string          `json:"relro,omitempty"`
+	NX                 bool            `json:"nx"`
+	PIE                string          `json:"pie"`
+	Canary             bool            `json:"canary"`
+	Fortify            bool            `json:"fortify"`
+	FortifiedFunctions []string        `json:"fortified_functions,omitempty"`
+	RPath              []string        `json:"rpath,omitempty"`
+	RunPath            []string        `json:"runpath,omitempty"`
+	IBT                bool            `json:"ibt"`
+	SHSTK              bool            `json:"shstk"`
+	Stripped           bool            `json:"stripped"`
+	PE                 *PEHardening    `json:"pe,omitempty"`
+	MachO              *MachOHardening `json:"macho,omitempty"`
+}
+
+type PEHardening struct {
+	HighEntropyVA  bool `json:"high_entropy_va"`
+	CFG            bool `json:"cfg"`
+	NoSEH          bool `json:"no_seh"`
+	ForceIntegrity bool `json:"force_integrity"`
+	AppContainer   bool `json:"app_container"`
+	RelocsStripped bool `json:"relocs_stripped"`
+	Signed         bool `json:"signed"` // Authenticode certificate table present
+}
+
+type MachOHardening struct {
+	CodeSignature bool `json:"code_signature"`
+	Encrypted     bool `json:"encrypted"`
+	Restrict      bool `json:"restrict"`
+	HeapNX        bool `json:"heap_nx"`
+}
+
+func analyzeHardening(elfFile *elf.File) *HardeningInfo {
+	info := &HardeningInfo{
+		RELRO: RELRONone,
+		PIE:   PIENone,
+	}
+
+	// RELRO is partial with PT_GNU_RELRO alone, full once lazy binding is disabled
+	hasRelro := false
+	hasStack := false
+	hasInterp := false
+	for _, ph := range elfFile.Progs {
+		switch ph.Type {
+		case elf.PT_GNU_RELRO:
+			hasRelro = true
+		case elf.PT_GNU_STACK:
+			hasStack = true
+			info.NX = ph.Flags&elf.PF_X == 0
+		case elf.PT_INTERP:
+			hasInterp = true
+		}
+	}
+
+	flags := dynValue(elfFile, elf.DT_FLAGS)
+	flags1 := dynValue(elfFile, elf.DT_FLAGS_1)
+	bindNow := flags&uint64(elf.DF_BIND_NOW) != 0 || flags1&uint64(elf.DF_1_NOW) != 0
+	if vals, err := elfFile.DynValue(elf.DT_BIND_NOW); err == nil && len(vals) > 0 {
+		bindNow = true
+	}
+	if hasRelro {
+		info.RELRO = RELROPartial
+		if bindNow {
+			info.RELRO = RELROFull
+		}
+	}
+
+	// Without PT_GNU_STACK the kernel maps an executable stack on most targets
+	if !hasStack {
+		info.NX = false
+	}
+
+	if elfFile.Type == elf.ET_DYN {
+		if flags1&uint64(elf.DF_1_PIE) != 0 || hasInterp {
+			info.PIE = PIEEnabled
+		} else {
+			info.PIE = PIEDSO
+		}
+	}
+
+	names := importedNames(elfFile)
+	for name := range names {
+		if name == "__stack_chk_fail" || name == "__stack_chk_guard" || name == "__intel_security_cookie" {
+			info.Canary = true
+		}
+		if strings.HasPrefix(name, "__") && strings.HasSuffix(name, "_chk") && name != "__stack_chk_fail" {
+			info.FortifiedFunctions = append(info.FortifiedFunctions, name)
+		}
+	}
+	sort.Strings(info.FortifiedFunctions)
+	info.Fortify = len(info.FortifiedFunctions) > 0
+
+	info.RPath, _ = elfFile.DynString(elf.DT_RPATH)
+	info.RunPath, _ = elfFile.DynString(elf.DT_RUNPATH)
+
+	info.IBT, info.SHSTK = parseX86Features(elfFile)
+
+	info.Stripped = elfFile.Section(".symtab") == nil
+
+	return info
+}
+
+func dynValue(elfFile *elf.File, tag elf.DynTag) uint64 {
+	vals, err := elfFile.DynValue(tag)
+	if err != nil || len(vals) == 0 {
+		return 0
+	}
+	return vals[0]
+}
+
+// importedNames collects dynamic imports plus undefined .symtab names. Names
+// the binary defines do not count: static binaries and libc itself define
+// __stack_chk_fail and the _chk functions whether or not they use them.
+func importedNames(elfFile *elf.File) map[string]struct{} {
+	names := make(map[string]struct{})
+	if imports, err := elfFile.ImportedSymbols(); err == nil {
+		for _, imp := range imports {
+			names[imp.Name] = struct{}{}
+		}
+	}
+	if syms, err := elfFile.Symbols(); err == nil {
+		for _, sym := range syms {
+			if sym.Name != "" && sym.Section == elf.SHN_UNDEF {
+				names[sym.Name] = struct{}{}
+			}
+		}
+	}
+	return names
+}
+
+// parseX86Features reads GNU_PROPERTY_X86_FEATURE_1_AND from .note.gnu.property
+func parseX86Features(elfFile *elf.File) (ibt bool, shstk bool) {
+	sec := elfFile.Section(".note.gnu.property")
+	if sec == nil {
+		return false, false
+	}
+	data, err := sec.Data()
+	if err != nil {
+		return false, false
+	}
+
+	order := elfFile.ByteOrder
+	align := 4
+	if elfFile.Class == elf.ELFCLASS64 {
+		align = 8
+	}
+
+	for len(data) >= 12 {
+		namesz := int(order.Uint32(data[0:4]))
+		descsz := int(order.Uint32(data[4:8]))
+		noteType := order.Uint32(data[8:12])
+		nameEnd := 12 + alignUp(namesz, 4)
+		descEnd := nameEnd + alignUp(descsz, align)
+		if nameEnd > len(data) || nameEnd+descsz > len(data) {
+			break
+		}
+		name := strings.TrimRight(string(data[12:12+namesz]), "\x00")
+		if noteType == ntGNUPropertyType0 && name == "GNU" {
+			if feat, ok := findGNUProperty(data[nameEnd:nameEnd+descsz], order, align, gnuPropertyX86Feature1And); ok {
+				ibt = feat&gnuPropertyX86IBT != 0
+				shstk = feat&gnuPropertyX86SHSTK != 0
+			}
+		}
+		if descEnd > len(data) {
+			break
+		}
+		data = data[descEnd:]
+	}
+	return ibt, shstk
+}
+
+func findGNUProperty(desc []byte, order binary.ByteOrder, align int, want uint32) (uint32, bool) {
+	for len(desc) >= 8 {
+		prType := order.Uint32(desc[0:4])
+		prSize := int(order.Uint32(desc[4:8]))
+		if 8+prSize > len(desc) {
+			break
+		}
+		if prType == want && prSize >= 4 {
+			return order.Uint32(desc[8:12]), true
+		}
+		next := 8 + alignUp(prSize, align)
+		if next > len(desc) {
+			break
+		}
+		desc = desc[next:]
+	}
+	return 0, false
+}
+
+func alignUp(n, align int) int {
+	return (n + align - 1) &^ (align - 1)
+}
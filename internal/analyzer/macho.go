@@ -0,0 +1,400 @@
+package analyzer
+
+import (
+	"bytes"
+	"debug/macho"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Load commands debug/macho does not name
+const (
+	machoLoadCmdCodeSignature  = 0x1d
+	machoLoadCmdEncryptionInfo = 0x21
+	machoLoadCmdLoadWeakDylib  = 0x80000018
+	machoLoadCmdReexportDylib  = 0x8000001f
+	machoLoadCmdLazyLoadDylib  = 0x20
+	machoLoadCmdUpwardDylib    = 0x80000023
+	machoLoadCmdEncryption64   = 0x2c
+	machoLoadCmdMain           = 0x80000028
+)
+
+// nlist n_type and n_desc bits
+const (
+	machoNStab    = 0xe0
+	machoNPExt    = 0x10
+	machoNType    = 0x0e
+	machoNExt     = 0x01
+	machoNUndf    = 0x00
+	machoNAbs     = 0x02
+	machoNIndr    = 0x0a
+	machoNSect    = 0x0e
+	machoNWeakRef = 0x40
+	machoNWeakDef = 0x80
+)
+
+var machoSectionTypes = map[uint32]string{
+	0x0:  "S_REGULAR",
+	0x1:  "S_ZEROFILL",
+	0x2:  "S_CSTRING_LITERALS",
+	0x6:  "S_NON_LAZY_SYMBOL_POINTERS",
+	0x7:  "S_LAZY_SYMBOL_POINTERS",
+	0x8:  "S_SYMBOL_STUBS",
+	0x9:  "S_MOD_INIT_FUNC_POINTERS",
+	0xa:  "S_MOD_TERM_FUNC_POINTERS",
+	0xc:  "S_GB_ZEROFILL",
+	0x11: "S_THREAD_LOCAL_REGULAR",
+	0x12: "S_THREAD_LOCAL_ZEROFILL",
+	0x13: "S_THREAD_LOCAL_VARIABLES",
+}
+
+type machoParser struct{}
+
+func (machoParser) Format() string { return FormatMachO }
+
+func (machoParser) Match(fileBytes []byte) bool {
+	if len(fileBytes) < 8 {
+		return false
+	}
+	if isFatMachO(fileBytes) {
+		return true
+	}
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		magic := order.Uint32(fileBytes)
+		if magic == macho.Magic32 || magic == macho.Magic64 {
+			return true
+		}
+	}
+	return false
+}
+
+// isFatMachO tells universal binaries apart from Java class files, which
+// share the 0xcafebabe magic but carry a class version where nfat_arch is
+func isFatMachO(fileBytes []byte) bool {
+	if len(fileBytes) < 8 || binary.BigEndian.Uint32(fileBytes) != macho.MagicFat {
+		return false
+	}
+	n := binary.BigEndian.Uint32(fileBytes[4:])
+	return n > 0 && n < 20
+}
+
+func (machoParser) Parse(fileBytes []byte) (*BinaryInfo, error) {
+	if !isFatMachO(fileBytes) {
+		machoFile, err := macho.NewFile(bytes.NewReader(fileBytes))
+		if err != nil {
+			return nil, fmt.Errorf("error macho parsing: %s", err.Error())
+		}
+		return analyzeMachO(machoFile, fileBytes, 0, uint64(len(fileBytes))), nil
+	}
+
+	fat, err := macho.NewFatFile(bytes.NewReader(fileBytes))
+	if err != nil {
+		return nil, fmt.Errorf("error macho parsing: %s", err.Error())
+	}
+
+	// The first slice is reported at the top level and the rest in Slices
+	var info *BinaryInfo
+	var archs []string
+	for _, arch := range fat.Arches {
+		archs = append(archs, arch.Cpu.String())
+		slice := analyzeMachO(arch.File, fileBytes, uint64(arch.Offset), uint64(arch.Size))
+		if info == nil {
+			info = slice
+			continue
+		}
+		info.Slices = append(info.Slices, slice)
+	}
+	info.Class = "Fat"
+	info.Architectures = archs
+	info.Entropy = shannonEntropy(fileBytes)
+	return info, nil
+}
+
+// analyzeMachO reports one thin Mach-O image located at base in fileBytes;
+// section and segment offsets are rebased so they index fileBytes directly
+func analyzeMachO(machoFile *macho.File, fileBytes []byte, base, size uint64) *BinaryInfo {
+	order := machoFile.ByteOrder
+
+	class := "MachO32"
+	if machoFile.Magic == macho.Magic64 {
+		class = "MachO64"
+	}
+	data := "LittleEndian"
+	if order == binary.BigEndian {
+		data = "BigEndian"
+	}
+
+	var sections []SectionInfo
+	for _, sec := range machoFile.Sections {
+		typ := sec.Flags & 0xff
+		info := SectionInfo{
+			Name:      sec.Seg + "," + sec.Name,
+			Type:      machoSectionTypes[typ],
+			Flags:     machoSectionFlags(sec.Flags),
+			Addr:      sec.Addr,
+			Size:      sec.Size,
+			Addralign: 1 << sec.Align,
+		}
+		if info.Type == "" {
+			info.Type = fmt.Sprintf("0x%x", typ)
+		}
+		if sec.Offset != 0 && typ != 0x1 && typ != 0xc && typ != 0x12 {
+			info.Offset = base + uint64(sec.Offset)
+			info.Entropy = regionEntropy(fileBytes, info.Offset, sec.Size)
+		}
+		sections = append(sections, info)
+	}
+
+	var segments []SegmentInfo
+	var textAddr uint64
+	restrict := false
+	for _, l := range machoFile.Loads {
+		seg, ok := l.(*macho.Segment)
+		if !ok {
+			continue
+		}
+		typ := "LC_SEGMENT"
+		if seg.Cmd == macho.LoadCmdSegment64 {
+			typ = "LC_SEGMENT_64"
+		}
+		switch seg.Name {
+		case "__TEXT":
+			textAddr = seg.Addr
+		case "__RESTRICT":
+			restrict = true
+		}
+		segments = append(segments, SegmentInfo{
+			Name:    seg.Name,
+			Type:    typ,
+			Flags:   machoProtFlags(seg.Prot),
+			Vaddr:   seg.Addr,
+			Filesz:  seg.Filesz,
+			Memsz:   seg.Memsz,
+			Entropy: regionEntropy(fileBytes, base+seg.Offset, seg.Filesz),
+		})
+	}
+
+	// Walk the raw load commands for what debug/macho leaves undecoded
+	var dylibs, rpaths []string
+	var entry uint64
+	codeSigned, encrypted := false, false
+	for _, l := range machoFile.Loads {
+		raw := l.Raw()
+		if len(raw) < 8 {
+			continue
+		}
+		switch cmd := order.Uint32(raw); cmd {
+		case uint32(macho.LoadCmdDylib), machoLoadCmdLoadWeakDylib, machoLoadCmdReexportDylib,
+			machoLoadCmdLazyLoadDylib, machoLoadCmdUpwardDylib:
+			dylibs = append(dylibs, machoLoadString(raw, order))
+		case uint32(macho.LoadCmdRpath):
+			rpaths = append(rpaths, machoLoadString(raw, order))
+		case machoLoadCmdMain:
+			if len(raw) >= 16 {
+				entry = textAddr + order.Uint64(raw[8:])
+			}
+		case uint32(macho.LoadCmdUnixThread):
+			if entry == 0 {
+				entry = machoThreadPC(machoFile.Cpu, raw, order)
+			}
+		case machoLoadCmdCodeSignature:
+			codeSigned = true
+		case machoLoadCmdEncryptionInfo, machoLoadCmdEncryption64:
+			if len(raw) >= 20 && order.Uint32(raw[16:]) != 0 {
+				encrypted = true
+			}
+		}
+	}
+
+	var symbols, exports []SymbolInfo
+	var imports []ImportInfo
+	hasLocal := false
+	if machoFile.Symtab != nil {
+		for _, sym := range machoFile.Symtab.Syms {
+			if sym.Type&machoNStab != 0 {
+				continue
+			}
+			si := SymbolInfo{
+				Name:         sym.Name,
+				Value:        sym.Value,
+				Type:         machoSymbolType(sym.Type),
+				Binding:      "LOCAL",
+				Visibility:   "DEFAULT",
+				SectionIndex: uint16(sym.Sect),
+			}
+			if sym.Sect > 0 && int(sym.Sect) <= len(machoFile.Sections) {
+				si.Section = sections[sym.Sect-1].Name
+			}
+			if sym.Type&machoNPExt != 0 {
+				si.Visibility = "HIDDEN"
+			}
+			if sym.Type&machoNExt != 0 {
+				si.Binding = "GLOBAL"
+				if sym.Desc&(machoNWeakRef|machoNWeakDef) != 0 {
+					si.Binding = "WEAK"
+				}
+			} else {
+				hasLocal = true
+			}
+
+			switch {
+			case sym.Type&machoNType == machoNUndf && sym.Type&machoNExt != 0:
+				// Two-level namespace: the high byte of n_desc is the dylib ordinal
+				ord := int(sym.Desc >> 8)
+				if ord > 0 && ord <= len(dylibs) {
+					si.Library = dylibs[ord-1]
+				}
+				imports = append(imports, ImportInfo{Name: sym.Name, Library: si.Library})
+			case sym.Type&machoNType == machoNSect && sym.Type&machoNExt != 0 && sym.Type&machoNPExt == 0:
+				exports = append(exports, si)
+			}
+			symbols = append(symbols, si)
+		}
+	}
+
+	var securityNotes []string
+	if codeSigned {
+		securityNotes = append(securityNotes, "Code signature present")
+	}
+	if encrypted {
+		securityNotes = append(securityNotes, "Encrypted segment present")
+	}
+	if restrict {
+		securityNotes = append(securityNotes, "__RESTRICT segment present")
+	}
+
+	hardening := &HardeningInfo{
+		PIE:      PIENone,
+		NX:       machoFile.Flags&macho.FlagAllowStackExecution == 0,
+		RPath:    rpaths,
+		Stripped: !hasLocal,
+		MachO: &MachOHardening{
+			CodeSignature: codeSigned,
+			Encrypted:     encrypted,
+			Restrict:      restrict,
+			HeapNX:        machoFile.Flags&macho.FlagNoHeapExecution != 0,
+		},
+	}
+	switch {
+	case machoFile.Type == macho.TypeDylib || machoFile.Type == macho.TypeBundle:
+		hardening.PIE = PIEDSO
+	case machoFile.Flags&macho.FlagPIE != 0:
+		hardening.PIE = PIEEnabled
+	}
+	for _, imp := range imports {
+		switch {
+		case imp.Name == "___stack_chk_fail" || imp.Name == "___stack_chk_guard":
+			hardening.Canary = true
+		case strings.HasPrefix(imp.Name, "___") && strings.HasSuffix(imp.Name, "_chk"):
+			hardening.FortifiedFunctions = append(hardening.FortifiedFunctions, imp.Name)
+		}
+	}
+	sort.Strings(hardening.FortifiedFunctions)
+	hardening.Fortify = len(hardening.FortifiedFunctions) > 0
+
+	return &BinaryInfo{
+		Format:        FormatMachO,
+		Class:         class,
+		Data:          data,
+		OSABI:         "Darwin",
+		Type:          machoFile.Type.String(),
+		Machine:       machoFile.Cpu.String(),
+		EntryPoint:    entry,
+		Entropy:       regionEntropy(fileBytes, base, size),
+		Sections:      sections,
+		Segments:      segments,
+		Symbols:       symbols,
+		Imports:       imports,
+		Exports:       exports,
+		DynamicNeeded: dylibs,
+		SecurityNotes: securityNotes,
+		Hardening:     hardening,
+	}
+}
+
+// machoLoadString reads the lc_str at offset 8 of dylib and rpath commands
+func machoLoadString(raw []byte, order binary.ByteOrder) string {
+	if len(raw) < 12 {
+		return ""
+	}
+	off := order.Uint32(raw[8:])
+	if off >= uint32(len(raw)) {
+		return ""
+	}
+	s := raw[off:]
+	if i := bytes.IndexByte(s, 0); i >= 0 {
+		s = s[:i]
+	}
+	return string(s)
+}
+
+// machoThreadPC pulls the initial program counter out of LC_UNIXTHREAD,
+// which pre-LC_MAIN executables use to name their entry point
+func machoThreadPC(cpu macho.Cpu, raw []byte, order binary.ByteOrder) uint64 {
+	const stateOff = 16 // cmd, cmdsize, flavor, count
+	switch cpu {
+	case macho.CpuAmd64:
+		if len(raw) >= stateOff+17*8 {
+			return order.Uint64(raw[stateOff+16*8:]) // rip
+		}
+	case macho.Cpu386:
+		if len(raw) >= stateOff+11*4 {
+			return uint64(order.Uint32(raw[stateOff+10*4:])) // eip
+		}
+	case macho.CpuArm64:
+		if len(raw) >= stateOff+33*8 {
+			return order.Uint64(raw[stateOff+32*8:]) // pc
+		}
+	}
+	return 0
+}
+
+func machoSymbolType(typ uint8) string {
+	switch typ & machoNType {
+	case machoNUndf:
+		return "N_UNDF"
+	case machoNAbs:
+		return "N_ABS"
+	case machoNSect:
+		return "N_SECT"
+	case machoNIndr:
+		return "N_INDR"
+	}
+	return fmt.Sprintf("0x%x", typ&machoNType)
+}
+
+func machoSectionFlags(flags uint32) string {
+	var out []string
+	if flags&0x80000000 != 0 {
+		out = append(out, "S_ATTR_PURE_INSTRUCTIONS")
+	}
+	if flags&0x400 != 0 {
+		out = append(out, "S_ATTR_SOME_INSTRUCTIONS")
+	}
+	if flags&0x02000000 != 0 {
+		out = append(out, "S_ATTR_DEBUG")
+	}
+	if len(out) == 0 {
+		return "0x0"
+	}
+	return strings.Join(out, "+")
+}
+
+func machoProtFlags(prot uint32) string {
+	var out []string
+	if prot&1 != 0 {
+		out = append(out, "VM_PROT_READ")
+	}
+	if prot&2 != 0 {
+		out = append(out, "VM_PROT_WRITE")
+	}
+	if prot&4 != 0 {
+		out = append(out, "VM_PROT_EXECUTE")
+	}
+	if len(out) == 0 {
+		return "VM_PROT_NONE"
+	}
+	return strings.Join(out, "+")
+}
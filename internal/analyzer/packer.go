@@ -0,0 +1,120 @@
+package analyzer
+
+import (
+	"bytes"
+	"debug/elf"
+	"fmt"
+)
+
+const (
+	// Compiled code rarely exceeds ~6.5 bits/byte; compressed or encrypted data sits near 8
+	highEntropyThreshold = 7.2
+	// Tiny regions give noisy entropy values, so ignore them
+	minEntropyRegionSize = 512
+)
+
+var upxMagic = []byte("UPX!")
+
+// upxTrailerWindow is how far from the end of the file the packheader UPX
+// appends after the compressed data may start
+const upxTrailerWindow = 64
+
+type PackingInfo struct {
+	LikelyPacked       bool     `json:"likely_packed"`
+	Packer             string   `json:"packer,omitempty"`
+	WritableExecutable bool     `json:"writable_executable"`
+	EntryOutsideText   bool     `json:"entry_outside_text"`
+	EntrySection       string   `json:"entry_section,omitempty"`
+	NoSectionHeaders   bool     `json:"no_section_headers"`
+	HighEntropyCode    bool     `json:"high_entropy_code"`
+	Indicators         []string `json:"indicators,omitempty"`
+}
+
+func detectPacking(elfFile *elf.File, fileBytes []byte, sections []SectionInfo, segments []SegmentInfo) *PackingInfo {
+	info := &PackingInfo{}
+
+	// UPX puts its packheader at fixed places and names sections UPX0/UPX1.
+	// The bare magic elsewhere proves nothing: any binary may contain it.
+	if hasUPXPackHeader(elfFile, fileBytes) {
+		info.Packer = "UPX"
+	}
+	for _, sec := range elfFile.Sections {
+		if sec.Name == "UPX0" || sec.Name == "UPX1" {
+			info.Packer = "UPX"
+		}
+	}
+	if info.Packer != "" {
+		info.Indicators = append(info.Indicators, info.Packer+" signature found")
+	}
+
+	for i, ph := range elfFile.Progs {
+		if ph.Type == elf.PT_LOAD && ph.Flags&elf.PF_W != 0 && ph.Flags&elf.PF_X != 0 {
+			info.WritableExecutable = true
+			info.Indicators = append(info.Indicators, fmt.Sprintf("segment %d is writable and executable", i))
+		}
+		if ph.Type == elf.PT_LOAD && ph.Flags&elf.PF_X != 0 && ph.Filesz >= minEntropyRegionSize &&
+			segments[i].Entropy >= highEntropyThreshold {
+			info.HighEntropyCode = true
+			info.Indicators = append(info.Indicators, fmt.Sprintf("executable segment %d has entropy %.3f", i, segments[i].Entropy))
+		}
+	}
+
+	if len(elfFile.Sections) == 0 {
+		info.NoSectionHeaders = true
+		info.Indicators = append(info.Indicators, "no section headers")
+	}
+
+	for i, sec := range elfFile.Sections {
+		if sec.Flags&elf.SHF_EXECINSTR == 0 || sec.Type == elf.SHT_NOBITS || sec.Size < minEntropyRegionSize {
+			continue
+		}
+		if sections[i].Entropy >= highEntropyThreshold {
+			info.HighEntropyCode = true
+			info.Indicators = append(info.Indicators, fmt.Sprintf("executable section %s has entropy %.3f", sec.Name, sections[i].Entropy))
+		}
+	}
+
+	if elfFile.Entry != 0 && len(elfFile.Sections) > 0 {
+		for _, sec := range elfFile.Sections {
+			if sec.Flags&elf.SHF_ALLOC != 0 && elfFile.Entry >= sec.Addr && elfFile.Entry < sec.Addr+sec.Size {
+				info.EntrySection = sec.Name
+				break
+			}
+		}
+		if info.EntrySection != ".text" {
+			info.EntryOutsideText = true
+			if info.EntrySection == "" {
+				info.Indicators = append(info.Indicators, "entry point is not inside any section")
+			} else {
+				info.Indicators = append(info.Indicators, "entry point is in "+info.EntrySection)
+			}
+		}
+	}
+
+	// A known packer or compressed code is conclusive; otherwise require two
+	// independent structural anomalies
+	info.LikelyPacked = info.Packer != "" || info.HighEntropyCode || len(info.Indicators) >= 2
+
+	return info
+}
+
+// hasUPXPackHeader looks for the UPX magic where the packer writes it: in the
+// l_info block right after the program headers, whose first word is a
+// checksum, and in the packheader at the end of the file
+func hasUPXPackHeader(elfFile *elf.File, fileBytes []byte) bool {
+	var phoff, phsize uint64
+	if elfFile.Class == elf.ELFCLASS64 && len(fileBytes) >= 0x40 {
+		phoff = elfFile.ByteOrder.Uint64(fileBytes[0x20:])
+		phsize = uint64(elfFile.ByteOrder.Uint16(fileBytes[0x36:]))
+	} else if elfFile.Class == elf.ELFCLASS32 && len(fileBytes) >= 0x34 {
+		phoff = uint64(elfFile.ByteOrder.Uint32(fileBytes[0x1c:]))
+		phsize = uint64(elfFile.ByteOrder.Uint16(fileBytes[0x2a:]))
+	}
+	lInfo := phoff + phsize*uint64(len(elfFile.Progs)) + 4
+	if phoff != 0 && lInfo+4 <= uint64(len(fileBytes)) && bytes.Equal(fileBytes[lInfo:lInfo+4], upxMagic) {
+		return true
+	}
+
+	tail := fileBytes[max(0, len(fileBytes)-upxTrailerWindow):]
+	return bytes.Contains(tail, upxMagic)
+}
@@ -0,0 +1,88 @@
+package analyzer
+
+import (
+	"encoding/binary"
+	"os"
+	"testing"
+)
+
+func TestShannonEntropy(t *testing.T) {
+	if e := shannonEntropy(make([]byte, 4096)); e != 0 {
+		t.Errorf("expected zero entropy for constant data, got %f", e)
+	}
+
+	uniform := make([]byte, 256*16)
+	for i := range uniform {
+		uniform[i] = byte(i)
+	}
+	if e := shannonEntropy(uniform); e != 8 {
+		t.Errorf("expected 8 bits/byte for uniform data, got %f", e)
+	}
+}
+
+func TestDetectPacking_NoSectionHeaders(t *testing.T) {
+	data, err := os.ReadFile("/bin/ls")
+	if err != nil {
+		t.Fatalf("failed to read test binary: %v", err)
+	}
+
+	info, err := AnalyzeBinary(data)
+	if err != nil {
+		t.Fatalf("AnalyzeBinary failed: %v", err)
+	}
+	if info.Packing == nil || info.Packing.LikelyPacked {
+		t.Fatalf("/bin/ls should not look packed: %+v", info.Packing)
+	}
+
+	// Drop the section header table the way sstrip-style tools do
+	stripped := append([]byte(nil), data...)
+	binary.LittleEndian.PutUint64(stripped[0x28:], 0)
+	binary.LittleEndian.PutUint16(stripped[0x3c:], 0)
+	binary.LittleEndian.PutUint16(stripped[0x3e:], 0)
+
+	info, err = AnalyzeBinary(stripped)
+	if err != nil {
+		t.Fatalf("AnalyzeBinary failed: %v", err)
+	}
+	if !info.Packing.NoSectionHeaders {
+		t.Errorf("expected missing section headers to be flagged: %+v", info.Packing)
+	}
+}
+
+func TestDetectPacking_UPXMagic(t *testing.T) {
+	data, err := os.ReadFile("/bin/ls")
+	if err != nil {
+		t.Fatalf("failed to read test binary: %v", err)
+	}
+
+	// The magic in the middle of the file, e.g. in a string table, is not a
+	// packheader
+	inside := append([]byte(nil), data...)
+	copy(inside[len(inside)/2:], "UPX!")
+	info, err := AnalyzeBinary(inside)
+	if err != nil {
+		t.Fatalf("AnalyzeBinary failed: %v", err)
+	}
+	if info.Packing.Packer != "" || info.Packing.LikelyPacked {
+		t.Errorf("stray UPX! flagged as packed: %+v", info.Packing)
+	}
+
+	// l_info right after the program headers: checksum, then the magic
+	phoff := binary.LittleEndian.Uint64(data[0x20:])
+	phdrs := uint64(binary.LittleEndian.Uint16(data[0x36:])) * uint64(binary.LittleEndian.Uint16(data[0x38:]))
+	lInfo := append([]byte(nil), data...)
+	copy(lInfo[phoff+phdrs+4:], "UPX!")
+
+	trailer := append(append([]byte(nil), data...), "UPX!\x0d\x16\x08\x08"...)
+	trailer = append(trailer, make([]byte, 28)...)
+
+	for name, packed := range map[string][]byte{"l_info": lInfo, "trailer": trailer} {
+		info, err := AnalyzeBinary(packed)
+		if err != nil {
+			t.Fatalf("%s: AnalyzeBinary failed: %v", name, err)
+		}
+		if info.Packing.Packer != "UPX" || !info.Packing.LikelyPacked {
+			t.Errorf("%s: UPX packheader not detected: %+v", name, info.Packing)
+		}
+	}
+}
@@ -0,0 +1,354 @@
+package analyzer
+
+import (
+	"bytes"
+	"debug/pe"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Export tables beyond this many entries are almost certainly corrupt
+const maxPEExports = 65536
+
+var peMachines = map[uint16]string{
+	pe.IMAGE_FILE_MACHINE_I386:  "IMAGE_FILE_MACHINE_I386",
+	pe.IMAGE_FILE_MACHINE_AMD64: "IMAGE_FILE_MACHINE_AMD64",
+	pe.IMAGE_FILE_MACHINE_ARM:   "IMAGE_FILE_MACHINE_ARM",
+	pe.IMAGE_FILE_MACHINE_ARMNT: "IMAGE_FILE_MACHINE_ARMNT",
+	pe.IMAGE_FILE_MACHINE_ARM64: "IMAGE_FILE_MACHINE_ARM64",
+	pe.IMAGE_FILE_MACHINE_IA64:  "IMAGE_FILE_MACHINE_IA64",
+}
+
+type peParser struct{}
+
+func (peParser) Format() string { return FormatPE }
+
+func (peParser) Match(fileBytes []byte) bool {
+	_, ok := peOffset(fileBytes)
+	return ok
+}
+
+func (peParser) Parse(fileBytes []byte) (*BinaryInfo, error) {
+	peFile, err := pe.NewFile(bytes.NewReader(fileBytes))
+	if err != nil {
+		return nil, fmt.Errorf("error pe parsing: %s", err.Error())
+	}
+
+	class := "Unknown"
+	var imageBase, entry uint64
+	var dllChars uint16
+	var dirs []pe.DataDirectory
+	switch oh := peFile.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		class = "PE32"
+		imageBase = uint64(oh.ImageBase)
+		entry = uint64(oh.AddressOfEntryPoint)
+		dllChars = oh.DllCharacteristics
+		dirs = oh.DataDirectory[:min(int(oh.NumberOfRvaAndSizes), len(oh.DataDirectory))]
+	case *pe.OptionalHeader64:
+		class = "PE32+"
+		imageBase = oh.ImageBase
+		entry = uint64(oh.AddressOfEntryPoint)
+		dllChars = oh.DllCharacteristics
+		dirs = oh.DataDirectory[:min(int(oh.NumberOfRvaAndSizes), len(oh.DataDirectory))]
+	}
+	if entry != 0 {
+		entry += imageBase
+	}
+
+	typ := "EXE"
+	switch {
+	case peFile.OptionalHeader == nil:
+		typ = "OBJ"
+	case peFile.Characteristics&pe.IMAGE_FILE_DLL != 0:
+		typ = "DLL"
+	}
+
+	machine, ok := peMachines[peFile.Machine]
+	if !ok {
+		machine = fmt.Sprintf("IMAGE_FILE_MACHINE_0x%x", peFile.Machine)
+	}
+
+	var sections []SectionInfo
+	for _, sec := range peFile.Sections {
+		var entropy float64
+		if sec.Characteristics&pe.IMAGE_SCN_CNT_UNINITIALIZED_DATA == 0 {
+			entropy = regionEntropy(fileBytes, uint64(sec.Offset), uint64(sec.Size))
+		}
+		sections = append(sections, SectionInfo{
+			Name:    sec.Name,
+			Type:    peSectionType(sec.Characteristics),
+			Flags:   peSectionFlags(sec.Characteristics),
+			Addr:    imageBase + uint64(sec.VirtualAddress),
+			Offset:  uint64(sec.Offset),
+			Size:    uint64(sec.Size),
+			Entropy: entropy,
+		})
+	}
+
+	// COFF symbols are normally stripped from linked images
+	var symbols []SymbolInfo
+	for _, sym := range peFile.Symbols {
+		si := SymbolInfo{
+			Name:         sym.Name,
+			Value:        uint64(sym.Value),
+			Type:         peSymbolType(sym.Type),
+			Binding:      peSymbolBinding(sym.StorageClass),
+			SectionIndex: uint16(sym.SectionNumber),
+		}
+		if sym.SectionNumber > 0 && int(sym.SectionNumber) <= len(peFile.Sections) {
+			si.Section = peFile.Sections[sym.SectionNumber-1].Name
+		}
+		symbols = append(symbols, si)
+	}
+
+	// debug/pe reports imports as "name:library" and leaves
+	// ImportedLibraries unimplemented, so derive the library list here
+	var imports []ImportInfo
+	var dynamicNeeded []string
+	seenLibs := make(map[string]bool)
+	if imported, err := peFile.ImportedSymbols(); err == nil {
+		for _, s := range imported {
+			name, lib, _ := strings.Cut(s, ":")
+			imports = append(imports, ImportInfo{Name: name, Library: lib})
+			if lib != "" && !seenLibs[strings.ToLower(lib)] {
+				seenLibs[strings.ToLower(lib)] = true
+				dynamicNeeded = append(dynamicNeeded, lib)
+			}
+		}
+	}
+
+	var securityNotes []string
+	if peDirectory(dirs, pe.IMAGE_DIRECTORY_ENTRY_SECURITY).Size > 0 {
+		securityNotes = append(securityNotes, "Authenticode signature present")
+	}
+	if peDirectory(dirs, pe.IMAGE_DIRECTORY_ENTRY_TLS).Size > 0 {
+		securityNotes = append(securityNotes, "TLS directory present (callbacks run before entry point)")
+	}
+	if peDirectory(dirs, pe.IMAGE_DIRECTORY_ENTRY_COM_DESCRIPTOR).Size > 0 {
+		securityNotes = append(securityNotes, ".NET CLR header present")
+	}
+	if peDirectory(dirs, pe.IMAGE_DIRECTORY_ENTRY_DEBUG).Size > 0 {
+		securityNotes = append(securityNotes, "Debug directory present")
+	}
+
+	img := newPEImage(peFile)
+	return &BinaryInfo{
+		Format:        FormatPE,
+		Class:         class,
+		Data:          "LittleEndian",
+		OSABI:         "Windows",
+		Type:          typ,
+		Machine:       machine,
+		EntryPoint:    entry,
+		Entropy:       shannonEntropy(fileBytes),
+		Sections:      sections,
+		Symbols:       symbols,
+		Imports:       imports,
+		Exports:       peExports(img, dirs, imageBase),
+		DynamicNeeded: dynamicNeeded,
+		SecurityNotes: securityNotes,
+		Hardening:     analyzePEHardening(peFile, img, class, dllChars, dirs),
+	}, nil
+}
+
+func peDirectory(dirs []pe.DataDirectory, idx int) pe.DataDirectory {
+	if idx < len(dirs) {
+		return dirs[idx]
+	}
+	return pe.DataDirectory{}
+}
+
+// peImage resolves RVAs, reading each section at most once since export
+// tables look up every name and forwarder separately
+type peImage struct {
+	file *pe.File
+	data map[*pe.Section][]byte
+}
+
+func newPEImage(peFile *pe.File) *peImage {
+	return &peImage{file: peFile, data: make(map[*pe.Section][]byte)}
+}
+
+// rva returns the section contents from rva to the end of its section
+func (img *peImage) rva(rva uint32) ([]byte, *pe.Section) {
+	for _, sec := range img.file.Sections {
+		size := max(sec.VirtualSize, sec.Size)
+		if rva < sec.VirtualAddress || rva >= sec.VirtualAddress+size {
+			continue
+		}
+		data, ok := img.data[sec]
+		if !ok {
+			var err error
+			if data, err = sec.Data(); err != nil {
+				data = nil
+			}
+			img.data[sec] = data
+		}
+		if data == nil {
+			return nil, nil
+		}
+		off := rva - sec.VirtualAddress
+		if off >= uint32(len(data)) {
+			return nil, sec
+		}
+		return data[off:], sec
+	}
+	return nil, nil
+}
+
+func (img *peImage) str(rva uint32) string {
+	data, _ := img.rva(rva)
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return string(data[:i])
+	}
+	return ""
+}
+
+// peExports walks IMAGE_EXPORT_DIRECTORY, which debug/pe does not decode.
+// Forwarded exports carry their "dll.function" target in Library.
+func peExports(img *peImage, dirs []pe.DataDirectory, imageBase uint64) []SymbolInfo {
+	dir := peDirectory(dirs, pe.IMAGE_DIRECTORY_ENTRY_EXPORT)
+	if dir.VirtualAddress == 0 || dir.Size == 0 {
+		return nil
+	}
+	hdr, _ := img.rva(dir.VirtualAddress)
+	if len(hdr) < 40 {
+		return nil
+	}
+
+	ordinalBase := binary.LittleEndian.Uint32(hdr[16:])
+	numFuncs := binary.LittleEndian.Uint32(hdr[20:])
+	numNames := binary.LittleEndian.Uint32(hdr[24:])
+	funcs, _ := img.rva(binary.LittleEndian.Uint32(hdr[28:]))
+	names, _ := img.rva(binary.LittleEndian.Uint32(hdr[32:]))
+	ordinals, _ := img.rva(binary.LittleEndian.Uint32(hdr[36:]))
+
+	numFuncs = min(numFuncs, maxPEExports, uint32(len(funcs)/4))
+	numNames = min(numNames, uint32(len(names)/4), uint32(len(ordinals)/2))
+
+	named := make(map[uint32]string, numNames)
+	for i := uint32(0); i < numNames; i++ {
+		idx := uint32(binary.LittleEndian.Uint16(ordinals[2*i:]))
+		named[idx] = img.str(binary.LittleEndian.Uint32(names[4*i:]))
+	}
+
+	var exports []SymbolInfo
+	for idx := uint32(0); idx < numFuncs; idx++ {
+		rva := binary.LittleEndian.Uint32(funcs[4*idx:])
+		if rva == 0 {
+			continue
+		}
+		sym := SymbolInfo{
+			Name:       named[idx],
+			Type:       "FUNC",
+			Binding:    "GLOBAL",
+			Visibility: "DEFAULT",
+			Version:    fmt.Sprintf("@%d", ordinalBase+idx),
+		}
+		if sym.Name == "" {
+			sym.Name = fmt.Sprintf("ordinal_%d", ordinalBase+idx)
+		}
+		if rva >= dir.VirtualAddress && rva < dir.VirtualAddress+dir.Size {
+			sym.Library = img.str(rva)
+		} else {
+			sym.Value = imageBase + uint64(rva)
+			if _, sec := img.rva(rva); sec != nil {
+				sym.Section = sec.Name
+			}
+		}
+		exports = append(exports, sym)
+	}
+	return exports
+}
+
+func analyzePEHardening(peFile *pe.File, img *peImage, class string, dllChars uint16, dirs []pe.DataDirectory) *HardeningInfo {
+	info := &HardeningInfo{
+		PIE: PIENone,
+		PE: &PEHardening{
+			HighEntropyVA:  dllChars&pe.IMAGE_DLLCHARACTERISTICS_HIGH_ENTROPY_VA != 0,
+			CFG:            dllChars&pe.IMAGE_DLLCHARACTERISTICS_GUARD_CF != 0,
+			NoSEH:          dllChars&pe.IMAGE_DLLCHARACTERISTICS_NO_SEH != 0,
+			ForceIntegrity: dllChars&pe.IMAGE_DLLCHARACTERISTICS_FORCE_INTEGRITY != 0,
+			AppContainer:   dllChars&pe.IMAGE_DLLCHARACTERISTICS_APPCONTAINER != 0,
+			RelocsStripped: peFile.Characteristics&pe.IMAGE_FILE_RELOCS_STRIPPED != 0,
+			Signed:         peDirectory(dirs, pe.IMAGE_DIRECTORY_ENTRY_SECURITY).Size > 0,
+		},
+		NX:       dllChars&pe.IMAGE_DLLCHARACTERISTICS_NX_COMPAT != 0,
+		Stripped: peFile.NumberOfSymbols == 0,
+	}
+
+	// ASLR needs DYNAMIC_BASE and the relocations to rebase the image
+	if dllChars&pe.IMAGE_DLLCHARACTERISTICS_DYNAMIC_BASE != 0 && !info.PE.RelocsStripped {
+		info.PIE = PIEEnabled
+		if peFile.Characteristics&pe.IMAGE_FILE_DLL != 0 {
+			info.PIE = PIEDSO
+		}
+	}
+
+	// /GS stack cookies are registered through the load config directory
+	dir := peDirectory(dirs, pe.IMAGE_DIRECTORY_ENTRY_LOAD_CONFIG)
+	if dir.VirtualAddress != 0 {
+		lc, _ := img.rva(dir.VirtualAddress)
+		if class == "PE32+" && len(lc) >= 0x60 {
+			info.Canary = binary.LittleEndian.Uint64(lc[0x58:]) != 0
+		} else if class == "PE32" && len(lc) >= 0x40 {
+			info.Canary = binary.LittleEndian.Uint32(lc[0x3c:]) != 0
+		}
+	}
+	return info
+}
+
+func peSectionType(chars uint32) string {
+	switch {
+	case chars&pe.IMAGE_SCN_CNT_CODE != 0:
+		return "IMAGE_SCN_CNT_CODE"
+	case chars&pe.IMAGE_SCN_CNT_INITIALIZED_DATA != 0:
+		return "IMAGE_SCN_CNT_INITIALIZED_DATA"
+	case chars&pe.IMAGE_SCN_CNT_UNINITIALIZED_DATA != 0:
+		return "IMAGE_SCN_CNT_UNINITIALIZED_DATA"
+	}
+	return fmt.Sprintf("0x%x", chars)
+}
+
+func peSectionFlags(chars uint32) string {
+	var flags []string
+	if chars&pe.IMAGE_SCN_MEM_READ != 0 {
+		flags = append(flags, "IMAGE_SCN_MEM_READ")
+	}
+	if chars&pe.IMAGE_SCN_MEM_WRITE != 0 {
+		flags = append(flags, "IMAGE_SCN_MEM_WRITE")
+	}
+	if chars&pe.IMAGE_SCN_MEM_EXECUTE != 0 {
+		flags = append(flags, "IMAGE_SCN_MEM_EXECUTE")
+	}
+	if chars&pe.IMAGE_SCN_MEM_DISCARDABLE != 0 {
+		flags = append(flags, "IMAGE_SCN_MEM_DISCARDABLE")
+	}
+	if chars&0x10000000 != 0 { // IMAGE_SCN_MEM_SHARED
+		flags = append(flags, "IMAGE_SCN_MEM_SHARED")
+	}
+	if len(flags) == 0 {
+		return "0x0"
+	}
+	return strings.Join(flags, "+")
+}
+
+// peSymbolType reports functions by the COFF derived type (0x20)
+func peSymbolType(typ uint16) string {
+	if typ&0xf0 == 0x20 {
+		return "FUNC"
+	}
+	return "NOTYPE"
+}
+
+func peSymbolBinding(storageClass uint8) string {
+	switch storageClass {
+	case 2: // IMAGE_SYM_CLASS_EXTERNAL
+		return "GLOBAL"
+	case 105: // IMAGE_SYM_CLASS_WEAK_EXTERNAL
+		return "WEAK"
+	}
+	return "LOCAL"
+}
@@ -0,0 +1,300 @@
+package analyzer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ashborn3/BinTraceBench/internal/syscalls"
+)
+
+const (
+	traceTimeFormat    = "2006-01-02 15:04:05.000000"
+	maxTracedProcesses = 512
+)
+
+// traceOptions makes the kernel stop and auto-attach every child, thread and
+// exec of a tracee, and mark syscall stops with bit 7 of the signal
+const traceOptions = syscall.PTRACE_O_TRACESYSGOOD |
+	syscall.PTRACE_O_TRACEFORK |
+	syscall.PTRACE_O_TRACEVFORK |
+	syscall.PTRACE_O_TRACECLONE |
+	syscall.PTRACE_O_TRACEEXEC |
+	syscall.PTRACE_O_TRACEEXIT
+
+// TracedProcess is one task of a traced process tree. Threads are tasks too;
+// their Origin is "thread" and TGID names the process they belong to.
+type TracedProcess struct {
+	PID      int         `json:"pid"`
+	PPID     int         `json:"ppid,omitempty"`
+	TGID     int         `json:"tgid,omitempty"`
+	Origin   string      `json:"origin"` // "root", "fork", "vfork", "clone" or "thread"
+	Children []int       `json:"children,omitempty"`
+	Execs    []ExecEvent `json:"execs,omitempty"`
+	ExitCode *int        `json:"exit_code,omitempty"`
+	Signal   string      `json:"signal,omitempty"` // signal that terminated the task
+	Started  string      `json:"started"`
+	Exited   string      `json:"exited,omitempty"`
+}
+
+type ExecEvent struct {
+	Path      string   `json:"path"`
+	Args      []string `json:"args,omitempty"`
+	Timestamp string   `json:"timestamp"`
+}
+
+// SyscallStop is a syscall entry or exit stop of one traced thread
+type SyscallStop struct {
+	TID   int
+	Entry bool
+	Regs  syscall.PtraceRegs
+}
+
+// tracee is the ptrace state of one live thread
+type tracee struct {
+	proc      *TracedProcess
+	inSyscall bool
+	attached  bool // the initial SIGSTOP of an auto-attached child was seen
+}
+
+type processTracer struct {
+	mu    sync.Mutex
+	live  map[int]*tracee
+	procs []*TracedProcess
+}
+
+// TraceCommand starts cmd under ptrace and follows it and every process and
+// thread it spawns until all of them have exited, calling onSyscall at each
+// syscall entry and exit stop. A positive timeout kills whatever is still
+// running when it expires.
+func TraceCommand(cmd *exec.Cmd, timeout time.Duration, onSyscall func(SyscallStop)) ([]TracedProcess, error) {
+	// ptrace requests are only accepted from the thread that attached
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Ptrace = true
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("error starting ptraced binary: %s", err.Error())
+	}
+	// Releases the process handle; the tracer reaps the process itself
+	defer cmd.Wait()
+
+	pid := cmd.Process.Pid
+	var status syscall.WaitStatus
+	if _, err := syscall.Wait4(pid, &status, 0, nil); err != nil {
+		return nil, fmt.Errorf("initial wait failed: %w", err)
+	}
+	if err := syscall.PtraceSetOptions(pid, traceOptions); err != nil {
+		syscall.Kill(pid, syscall.SIGKILL)
+		syscall.Wait4(pid, &status, 0, nil)
+		return nil, fmt.Errorf("PtraceSetOptions failed: %w", err)
+	}
+
+	t := &processTracer{live: make(map[int]*tracee)}
+	root := t.track(pid)
+	root.attached = true
+	root.proc.Origin = "root"
+	t.recordExec(root.proc)
+
+	if timeout > 0 {
+		timer := time.AfterFunc(timeout, t.killAll)
+		defer timer.Stop()
+	}
+	t.run(pid, onSyscall)
+
+	out := make([]TracedProcess, len(t.procs))
+	for i, p := range t.procs {
+		out[i] = *p
+	}
+	return out, nil
+}
+
+func (t *processTracer) run(pid int, onSyscall func(SyscallStop)) {
+	resume, signal := pid, 0
+	for {
+		if resume != 0 {
+			syscall.PtraceSyscall(resume, signal)
+		}
+		resume, signal = 0, 0
+
+		// WNOTHREAD keeps this from reaping children that other goroutines
+		// started; tracees always belong to the locked tracer thread
+		var status syscall.WaitStatus
+		tid, err := syscall.Wait4(-1, &status, syscall.WALL|syscall.WNOTHREAD, nil)
+		if errors.Is(err, syscall.EINTR) {
+			continue
+		}
+		if err != nil {
+			return // ECHILD: every tracee is gone
+		}
+
+		if status.Exited() || status.Signaled() {
+			t.reap(tid, status)
+			continue
+		}
+		if !status.Stopped() {
+			continue
+		}
+
+		st := t.lookup(tid)
+		resume = tid
+		switch sig := status.StopSignal(); {
+		case sig == syscall.SIGTRAP|0x80:
+			st.inSyscall = !st.inSyscall
+			stop := SyscallStop{TID: tid, Entry: st.inSyscall}
+			if err := ptraceGetRegs(tid, &stop.Regs); err == nil && onSyscall != nil {
+				onSyscall(stop)
+			}
+		case sig == syscall.SIGTRAP && status.TrapCause() > 0:
+			t.event(tid, st, status.TrapCause())
+		case sig == syscall.SIGSTOP && !st.attached:
+			// First stop of an auto-attached child
+			st.attached = true
+		default:
+			// Signal delivery stop: pass the signal on
+			signal = int(sig)
+		}
+	}
+}
+
+// track starts following a new task
+func (t *processTracer) track(tid int) *tracee {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	st := &tracee{proc: &TracedProcess{PID: tid, Started: time.Now().Format(traceTimeFormat)}}
+	t.live[tid] = st
+	t.procs = append(t.procs, st.proc)
+	if len(t.live) > maxTracedProcesses {
+		t.killLocked()
+	}
+	return st
+}
+
+// lookup returns the state of tid. A new child's first stop can arrive
+// before its parent's fork event, so unknown tasks are tracked here too.
+func (t *processTracer) lookup(tid int) *tracee {
+	t.mu.Lock()
+	st, ok := t.live[tid]
+	t.mu.Unlock()
+	if !ok {
+		st = t.track(tid)
+	}
+	return st
+}
+
+func (t *processTracer) event(tid int, st *tracee, cause int) {
+	msg, _ := syscall.PtraceGetEventMsg(tid)
+	switch cause {
+	case syscall.PTRACE_EVENT_FORK, syscall.PTRACE_EVENT_VFORK, syscall.PTRACE_EVENT_CLONE:
+		child := t.lookup(int(msg)).proc
+		child.PPID = tid
+		switch cause {
+		case syscall.PTRACE_EVENT_FORK:
+			child.Origin = "fork"
+		case syscall.PTRACE_EVENT_VFORK:
+			child.Origin = "vfork"
+		default:
+			child.Origin = "clone"
+			if tgid := taskTGID(child.PID); tgid != 0 && tgid != child.PID {
+				child.Origin, child.TGID = "thread", tgid
+			}
+		}
+		st.proc.Children = append(st.proc.Children, child.PID)
+	case syscall.PTRACE_EVENT_EXEC:
+		// A non-leader thread that execs takes over the leader's tid
+		if former := int(msg); former != tid {
+			t.mu.Lock()
+			if old, ok := t.live[former]; ok {
+				st.inSyscall = old.inSyscall
+				delete(t.live, former)
+			}
+			t.mu.Unlock()
+		}
+		t.recordExec(st.proc)
+	case syscall.PTRACE_EVENT_EXIT:
+		// The task is about to exit but can still be inspected
+		t.recordExit(st.proc, syscall.WaitStatus(msg))
+	}
+}
+
+func (t *processTracer) recordExec(p *TracedProcess) {
+	path, _ := os.Readlink(fmt.Sprintf("/proc/%d/exe", p.PID))
+	var args []string
+	if cmdline, err := os.ReadFile(fmt.Sprintf("/proc/%d/cmdline", p.PID)); err == nil {
+		for _, a := range bytes.Split(bytes.TrimSuffix(cmdline, []byte{0}), []byte{0}) {
+			args = append(args, string(a))
+		}
+	}
+	p.Execs = append(p.Execs, ExecEvent{Path: path, Args: args, Timestamp: time.Now().Format(traceTimeFormat)})
+}
+
+func (t *processTracer) recordExit(p *TracedProcess, status syscall.WaitStatus) {
+	if p.Exited != "" {
+		return
+	}
+	switch {
+	case status.Exited():
+		code := status.ExitStatus()
+		p.ExitCode = &code
+	case status.Signaled():
+		p.Signal = signalName(status.Signal())
+	default:
+		return
+	}
+	p.Exited = time.Now().Format(traceTimeFormat)
+}
+
+func (t *processTracer) reap(tid int, status syscall.WaitStatus) {
+	t.mu.Lock()
+	st, ok := t.live[tid]
+	delete(t.live, tid)
+	t.mu.Unlock()
+	if ok {
+		t.recordExit(st.proc, status)
+	}
+}
+
+func (t *processTracer) killAll() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.killLocked()
+}
+
+func (t *processTracer) killLocked() {
+	for tid := range t.live {
+		syscall.Kill(tid, syscall.SIGKILL)
+	}
+}
+
+// taskTGID reads the thread group id of a task from /proc
+func taskTGID(tid int) int {
+	status, err := os.ReadFile(fmt.Sprintf("/proc/%d/status", tid))
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(status), "\n") {
+		if v, ok := strings.CutPrefix(line, "Tgid:"); ok {
+			n, _ := strconv.Atoi(strings.TrimSpace(v))
+			return n
+		}
+	}
+	return 0
+}
+
+func signalName(sig syscall.Signal) string {
+	if name, ok := syscalls.SignalNames[int(sig)]; ok {
+		return name
+	}
+	return fmt.Sprintf("signal %d", int(sig))
+}
@@ -0,0 +1,143 @@
+package analyzer
+
+import (
+	"debug/elf"
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTraceCommand_FollowsChildren(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no shell available")
+	}
+	// The subshell forks, the child execs /bin/true, and the parent exits 3
+	cmd := exec.Command("sh", "-c", "/bin/true & wait; exit 3")
+	pids := map[int]bool{}
+	procs, err := TraceCommand(cmd, 10*time.Second, func(stop SyscallStop) {
+		pids[stop.TID] = true
+	})
+	if err != nil {
+		t.Skipf("ptrace unavailable: %v", err)
+	}
+
+	if len(procs) < 2 {
+		t.Fatalf("expected the shell and its child, got %+v", procs)
+	}
+	root := procs[0]
+	if root.Origin != "root" || root.ExitCode == nil || *root.ExitCode != 3 {
+		t.Errorf("unexpected root: %+v", root)
+	}
+	if len(root.Children) == 0 {
+		t.Fatalf("root has no children: %+v", root)
+	}
+
+	var child *TracedProcess
+	for i := range procs {
+		if procs[i].PID == root.Children[0] {
+			child = &procs[i]
+		}
+	}
+	if child == nil || child.PPID != root.PID || child.Origin == "thread" {
+		t.Fatalf("child not linked to root: %+v", procs)
+	}
+	if len(child.Execs) == 0 || child.Execs[len(child.Execs)-1].Path == "" {
+		t.Errorf("child exec not recorded: %+v", child)
+	}
+	if child.ExitCode == nil || *child.ExitCode != 0 {
+		t.Errorf("unexpected child exit: %+v", child)
+	}
+	if !pids[root.PID] || !pids[child.PID] {
+		t.Errorf("syscalls not attributed to both tasks: %v", pids)
+	}
+}
+
+func TestTraceBinary_DecodesArgs(t *testing.T) {
+	data, err := os.ReadFile("/bin/true")
+	if err != nil {
+		t.Skipf("no test binary: %v", err)
+	}
+	result, err := TraceBinary(data)
+	if err != nil {
+		t.Skipf("ptrace unavailable: %v", err)
+	}
+
+	// The loader opens shared libraries read-only by path and reads their ELF
+	// header
+	var opened, readELF bool
+	for _, e := range result.Syscalls {
+		args := strings.Join(e.Args, " ")
+		switch e.Name {
+		case "openat":
+			opened = opened || strings.Contains(args, `pathname="/`) && strings.Contains(args, "flags=O_RDONLY|O_CLOEXEC") &&
+				e.Return != nil && *e.Return >= 0
+		case "read":
+			readELF = readELF || strings.Contains(args, `buf="\x7fELF`)
+		}
+		if e.Arch != hostABIs[0].Name {
+			t.Errorf("%s traced as %q", e.Name, e.Arch)
+		}
+		if e.Return != nil && *e.Return < 0 && e.Errno == "" {
+			t.Errorf("no errno for %s returning %d", e.Name, *e.Return)
+		}
+	}
+	if !opened || !readELF {
+		t.Errorf("arguments not decoded (openat %v, read %v)", opened, readELF)
+	}
+	last := result.Syscalls[len(result.Syscalls)-1]
+	if last.Name != "exit_group" || last.Return != nil {
+		t.Errorf("exit_group should be the last, unfinished call: %+v", last)
+	}
+}
+
+func TestCheckTraceable(t *testing.T) {
+	// A bare ELF header for a machine no host supports
+	header := make([]byte, 64)
+	copy(header, "\x7fELF\x02\x01\x01")
+	binary.LittleEndian.PutUint16(header[16:], uint16(elf.ET_EXEC))
+	binary.LittleEndian.PutUint16(header[18:], uint16(elf.EM_MIPS))
+	binary.LittleEndian.PutUint32(header[20:], 1)
+	binary.LittleEndian.PutUint16(header[52:], 64)
+
+	dir := t.TempDir()
+	mips := filepath.Join(dir, "mips")
+	script := filepath.Join(dir, "script")
+	os.WriteFile(mips, header, 0755)
+	os.WriteFile(script, []byte("#!/bin/sh\n"), 0755)
+
+	if err := CheckTraceable(mips); err == nil || !strings.Contains(err.Error(), "EM_MIPS") {
+		t.Errorf("foreign binary accepted: %v", err)
+	}
+	if err := CheckTraceable(script); err != nil {
+		t.Errorf("script rejected: %v", err)
+	}
+	if err := CheckTraceable("/bin/true"); err != nil {
+		t.Errorf("host binary rejected: %v", err)
+	}
+}
+
+func TestSummarizeSyscalls_Legacy(t *testing.T) {
+	// Traces stored before entry and exit were paired
+	var calls []VerboseSyscallEntry
+	err := json.Unmarshal([]byte(`[
+		{"pid":1,"name":"read","number":0,"args":[],"timestamp":"t","event":"entry"},
+		{"pid":1,"name":"read","number":0,"args":[],"return":"0x4","timestamp":"t","event":"exit"},
+		{"pid":1,"name":"exit_group","number":231,"args":[],"timestamp":"t","event":"entry"}
+	]`), &calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sum := SummarizeSyscalls(calls)
+	if sum.Calls != 2 || len(sum.Syscalls) != 2 || !sum.TimingsUnavailable {
+		t.Errorf("expected entries only and no timings: %+v", sum)
+	}
+	if SummarizeSyscalls([]VerboseSyscallEntry{{Name: "read", DurationUS: 3}}).TimingsUnavailable {
+		t.Error("paired traces have timings")
+	}
+}
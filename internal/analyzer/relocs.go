@@ -0,0 +1,414 @@
+package analyzer
+
+import (
+	"debug/elf"
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// relocEntry is one decoded entry from a SHT_REL or SHT_RELA section
+type relocEntry struct {
+	Section   string
+	Offset    uint64
+	Type      uint32
+	SymIndex  uint32
+	SymName   string
+	Addend    int64
+	HasAddend bool
+}
+
+// readRelocations decodes every REL/RELA section, resolving symbol indices
+// against the symbol table each section links to
+func readRelocations(elfFile *elf.File) []relocEntry {
+	var dynSyms, syms []elf.Symbol
+	dynSyms, _ = elfFile.DynamicSymbols()
+	syms, _ = elfFile.Symbols()
+
+	var relocs []relocEntry
+	for _, sec := range elfFile.Sections {
+		if sec.Type != elf.SHT_REL && sec.Type != elf.SHT_RELA {
+			continue
+		}
+		data, err := sec.Data()
+		if err != nil {
+			continue
+		}
+
+		// Symbol index 0 is the null symbol, which debug/elf drops
+		table := dynSyms
+		if int(sec.Link) < len(elfFile.Sections) && elfFile.Sections[sec.Link].Type == elf.SHT_SYMTAB {
+			table = syms
+		}
+		symName := func(idx uint32) string {
+			if idx == 0 || int(idx) > len(table) {
+				return ""
+			}
+			return table[idx-1].Name
+		}
+
+		order := elfFile.ByteOrder
+		rela := sec.Type == elf.SHT_RELA
+		if elfFile.Class == elf.ELFCLASS64 {
+			size := 16
+			if rela {
+				size = 24
+			}
+			for off := 0; off+size <= len(data); off += size {
+				info := order.Uint64(data[off+8:])
+				r := relocEntry{
+					Section:   sec.Name,
+					Offset:    order.Uint64(data[off:]),
+					Type:      elf.R_TYPE64(info),
+					SymIndex:  elf.R_SYM64(info),
+					HasAddend: rela,
+				}
+				if rela {
+					r.Addend = int64(order.Uint64(data[off+16:]))
+				}
+				r.SymName = symName(r.SymIndex)
+				relocs = append(relocs, r)
+			}
+		} else {
+			size := 8
+			if rela {
+				size = 12
+			}
+			for off := 0; off+size <= len(data); off += size {
+				info := order.Uint32(data[off+4:])
+				r := relocEntry{
+					Section:   sec.Name,
+					Offset:    uint64(order.Uint32(data[off:])),
+					Type:      elf.R_TYPE32(info),
+					SymIndex:  elf.R_SYM32(info),
+					HasAddend: rela,
+				}
+				if rela {
+					r.Addend = int64(int32(order.Uint32(data[off+8:])))
+				}
+				r.SymName = symName(r.SymIndex)
+				relocs = append(relocs, r)
+			}
+		}
+	}
+	return relocs
+}
+
+// gotSlotSymbols maps GOT slot addresses to the symbol their JUMP_SLOT or
+// GLOB_DAT relocation binds
+func gotSlotSymbols(elfFile *elf.File, relocs []relocEntry) map[uint64]string {
+	slots := make(map[uint64]string)
+	for _, r := range relocs {
+		if r.SymName == "" {
+			continue
+		}
+		switch relocTypeName(elfFile.Machine, r.Type) {
+		case "JUMP_SLOT", "GLOB_DAT":
+			slots[r.Offset] = r.SymName
+		}
+	}
+	return slots
+}
+
+// relocTypeName returns the architecture-neutral suffix of a dynamic
+// relocation type, e.g. R_X86_64_JUMP_SLOT -> JUMP_SLOT
+func relocTypeName(machine elf.Machine, typ uint32) string {
+	switch machine {
+	case elf.EM_X86_64:
+		switch elf.R_X86_64(typ) {
+		case elf.R_X86_64_JMP_SLOT:
+			return "JUMP_SLOT"
+		case elf.R_X86_64_GLOB_DAT:
+			return "GLOB_DAT"
+		case elf.R_X86_64_RELATIVE:
+			return "RELATIVE"
+		case elf.R_X86_64_IRELATIVE:
+			return "IRELATIVE"
+		case elf.R_X86_64_COPY:
+			return "COPY"
+		}
+	case elf.EM_386:
+		switch elf.R_386(typ) {
+		case elf.R_386_JMP_SLOT:
+			return "JUMP_SLOT"
+		case elf.R_386_GLOB_DAT:
+			return "GLOB_DAT"
+		case elf.R_386_RELATIVE:
+			return "RELATIVE"
+		case elf.R_386_IRELATIVE:
+			return "IRELATIVE"
+		case elf.R_386_COPY:
+			return "COPY"
+		}
+	case elf.EM_AARCH64:
+		switch elf.R_AARCH64(typ) {
+		case elf.R_AARCH64_JUMP_SLOT:
+			return "JUMP_SLOT"
+		case elf.R_AARCH64_GLOB_DAT:
+			return "GLOB_DAT"
+		case elf.R_AARCH64_RELATIVE:
+			return "RELATIVE"
+		case elf.R_AARCH64_IRELATIVE:
+			return "IRELATIVE"
+		case elf.R_AARCH64_COPY:
+			return "COPY"
+		}
+	}
+	return ""
+}
+
+// relativeAddends maps the target of every RELATIVE relocation to its
+// addend, which is where RELA-linked PIE images keep pointer values
+func relativeAddends(elfFile *elf.File) map[uint64]uint64 {
+	addends := make(map[uint64]uint64)
+	for _, r := range readRelocations(elfFile) {
+		if r.HasAddend && relocTypeName(elfFile.Machine, r.Type) == "RELATIVE" {
+			addends[r.Offset] = uint64(r.Addend)
+		}
+	}
+	return addends
+}
+
+// pltSymbols maps each PLT stub address to the imported symbol it jumps to,
+// by decoding the stub's indirect jump and looking up the GOT slot it loads
+func pltSymbols(elfFile *elf.File) map[uint64]string {
+	stubs := make(map[uint64]string)
+	slots := gotSlotSymbols(elfFile, readRelocations(elfFile))
+	if len(slots) == 0 {
+		return stubs
+	}
+
+	var gotPlt uint64
+	if sec := elfFile.Section(".got.plt"); sec != nil {
+		gotPlt = sec.Addr
+	} else if sec := elfFile.Section(".got"); sec != nil {
+		gotPlt = sec.Addr
+	}
+
+	for _, sec := range elfFile.Sections {
+		if !isPLTRegion(sec.Name) || sec.Type == elf.SHT_NOBITS {
+			continue
+		}
+		data, err := sec.Data()
+		if err != nil {
+			continue
+		}
+		entSize := sec.Entsize
+		if entSize == 0 {
+			entSize = 16
+		}
+
+		switch elfFile.Machine {
+		case elf.EM_X86_64, elf.EM_386:
+			for off := 0; off+6 <= len(data); off++ {
+				var slot uint64
+				switch {
+				// jmp *disp32(%rip), optionally bnd-prefixed
+				case elfFile.Machine == elf.EM_X86_64 && data[off] == 0xff && data[off+1] == 0x25:
+					disp := int32(binary.LittleEndian.Uint32(data[off+2:]))
+					slot = uint64(int64(sec.Addr) + int64(off) + 6 + int64(disp))
+				// jmp *abs32
+				case elfFile.Machine == elf.EM_386 && data[off] == 0xff && data[off+1] == 0x25:
+					slot = uint64(binary.LittleEndian.Uint32(data[off+2:]))
+				// jmp *disp32(%ebx) in PIC i386 PLTs, %ebx holds .got.plt
+				case elfFile.Machine == elf.EM_386 && data[off] == 0xff && data[off+1] == 0xa3:
+					slot = gotPlt + uint64(binary.LittleEndian.Uint32(data[off+2:]))
+				default:
+					continue
+				}
+				if name, ok := slots[slot]; ok {
+					stub := sec.Addr + (uint64(off)/entSize)*entSize
+					if _, seen := stubs[stub]; !seen {
+						stubs[stub] = name
+					}
+				}
+			}
+		case elf.EM_AARCH64:
+			// adrp x16, page; ldr x17, [x16, #off]; add x16, x16, #off; br x17
+			for off := 0; off+8 <= len(data); off += 4 {
+				adrp := binary.LittleEndian.Uint32(data[off:])
+				ldr := binary.LittleEndian.Uint32(data[off+4:])
+				if adrp&0x9f00001f != 0x90000010 || ldr&0xffc003ff != 0xf9400211 {
+					continue
+				}
+				pc := sec.Addr + uint64(off)
+				immlo := uint64(adrp>>29) & 0x3
+				immhi := uint64(adrp>>5) & 0x7ffff
+				imm := int64((immhi<<2|immlo)<<43) >> 31 // sign-extend 21 bits, scale by 4K
+				page := uint64(int64(pc&^0xfff) + imm)
+				slot := page + uint64((ldr>>10)&0xfff)*8
+				if name, ok := slots[slot]; ok {
+					stubs[pc] = name
+				}
+			}
+		}
+	}
+	return stubs
+}
+
+type RelocationInfo struct {
+	Total           int            `json:"total"`
+	ByType          map[string]int `json:"by_type"`
+	BySection       map[string]int `json:"by_section"`
+	GOT             []GOTEntry     `json:"got,omitempty"`
+	PLT             []PLTEntry     `json:"plt,omitempty"`
+	TextRel         bool           `json:"textrel"`
+	TextRelocations int            `json:"text_relocations,omitempty"`
+	IFuncs          []IFuncEntry   `json:"ifuncs,omitempty"`
+	CopyRelocations []string       `json:"copy_relocations,omitempty"`
+}
+
+// GOTEntry is a GOT slot filled by a JUMP_SLOT or GLOB_DAT relocation
+type GOTEntry struct {
+	Address uint64 `json:"address"`
+	Symbol  string `json:"symbol"`
+	Type    string `json:"type"`
+	Library string `json:"library,omitempty"`
+}
+
+type PLTEntry struct {
+	Address uint64 `json:"address"`
+	Symbol  string `json:"symbol"`
+	Library string `json:"library,omitempty"`
+}
+
+// IFuncEntry is an IRELATIVE slot and the resolver the loader calls to fill it
+type IFuncEntry struct {
+	Slot     uint64 `json:"slot"`
+	Resolver uint64 `json:"resolver,omitempty"`
+}
+
+// analyzeRelocations summarises every relocation section, maps GOT slots and
+// PLT stubs to the imports they resolve, and flags TEXTREL, IRELATIVE and
+// COPY relocations
+func analyzeRelocations(elfFile *elf.File, fileBytes []byte, imports []ImportInfo) *RelocationInfo {
+	relocs := readRelocations(elfFile)
+	if len(relocs) == 0 {
+		return nil
+	}
+
+	libraries := make(map[string]string)
+	for _, imp := range imports {
+		if imp.Library != "" {
+			libraries[imp.Name] = imp.Library
+		}
+	}
+
+	// Dynamic relocations live in SHF_ALLOC sections; the rest belong to
+	// relocatable objects and legitimately patch code
+	dynamic := make(map[string]bool)
+	for _, sec := range elfFile.Sections {
+		if (sec.Type == elf.SHT_REL || sec.Type == elf.SHT_RELA) && sec.Flags&elf.SHF_ALLOC != 0 {
+			dynamic[sec.Name] = true
+		}
+	}
+
+	info := &RelocationInfo{
+		Total:     len(relocs),
+		ByType:    make(map[string]int),
+		BySection: make(map[string]int),
+	}
+	copies := make(map[string]bool)
+	for _, r := range relocs {
+		info.ByType[relocTypeString(elfFile.Machine, r.Type)]++
+		info.BySection[r.Section]++
+		if !dynamic[r.Section] {
+			continue
+		}
+
+		switch relocTypeName(elfFile.Machine, r.Type) {
+		case "JUMP_SLOT", "GLOB_DAT":
+			if r.SymName != "" {
+				info.GOT = append(info.GOT, GOTEntry{
+					Address: r.Offset,
+					Symbol:  r.SymName,
+					Type:    relocTypeName(elfFile.Machine, r.Type),
+					Library: libraries[r.SymName],
+				})
+			}
+		case "IRELATIVE":
+			entry := IFuncEntry{Slot: r.Offset}
+			if r.HasAddend {
+				entry.Resolver = uint64(r.Addend)
+			} else {
+				entry.Resolver = loadedWord(elfFile, fileBytes, r.Offset)
+			}
+			info.IFuncs = append(info.IFuncs, entry)
+		case "COPY":
+			if r.SymName != "" && !copies[r.SymName] {
+				copies[r.SymName] = true
+				info.CopyRelocations = append(info.CopyRelocations, r.SymName)
+			}
+		}
+
+		if inReadOnlySegment(elfFile, r.Offset) {
+			info.TextRelocations++
+		}
+	}
+	sort.Slice(info.GOT, func(i, j int) bool { return info.GOT[i].Address < info.GOT[j].Address })
+	sort.Strings(info.CopyRelocations)
+
+	for addr, name := range pltSymbols(elfFile) {
+		info.PLT = append(info.PLT, PLTEntry{Address: addr, Symbol: name, Library: libraries[name]})
+	}
+	sort.Slice(info.PLT, func(i, j int) bool { return info.PLT[i].Address < info.PLT[j].Address })
+
+	vals, err := elfFile.DynValue(elf.DT_TEXTREL)
+	hasTextRel := err == nil && len(vals) > 0
+	info.TextRel = hasTextRel || dynValue(elfFile, elf.DT_FLAGS)&uint64(elf.DF_TEXTREL) != 0 || info.TextRelocations > 0
+
+	return info
+}
+
+// relocTypeString returns the full relocation type name, e.g. R_X86_64_JMP_SLOT
+func relocTypeString(machine elf.Machine, typ uint32) string {
+	switch machine {
+	case elf.EM_X86_64:
+		return elf.R_X86_64(typ).String()
+	case elf.EM_386:
+		return elf.R_386(typ).String()
+	case elf.EM_AARCH64:
+		return elf.R_AARCH64(typ).String()
+	case elf.EM_ARM:
+		return elf.R_ARM(typ).String()
+	case elf.EM_PPC64:
+		return elf.R_PPC64(typ).String()
+	case elf.EM_RISCV:
+		return elf.R_RISCV(typ).String()
+	case elf.EM_MIPS:
+		return elf.R_MIPS(typ).String()
+	}
+	return fmt.Sprintf("R_%d", typ)
+}
+
+// inReadOnlySegment reports whether addr is mapped by a non-writable PT_LOAD
+func inReadOnlySegment(elfFile *elf.File, addr uint64) bool {
+	for _, ph := range elfFile.Progs {
+		if ph.Type == elf.PT_LOAD && addr >= ph.Vaddr && addr < ph.Vaddr+ph.Memsz {
+			return ph.Flags&elf.PF_W == 0
+		}
+	}
+	return false
+}
+
+// loadedWord reads the pointer-sized value the file image holds at addr
+func loadedWord(elfFile *elf.File, fileBytes []byte, addr uint64) uint64 {
+	size := uint64(4)
+	if elfFile.Class == elf.ELFCLASS64 {
+		size = 8
+	}
+	for _, ph := range elfFile.Progs {
+		if ph.Type != elf.PT_LOAD || addr < ph.Vaddr || addr+size > ph.Vaddr+ph.Filesz {
+			continue
+		}
+		b := fileRegion(fileBytes, ph.Off+(addr-ph.Vaddr), size)
+		if uint64(len(b)) < size {
+			return 0
+		}
+		if size == 8 {
+			return elfFile.ByteOrder.Uint64(b)
+		}
+		return uint64(elfFile.ByteOrder.Uint32(b))
+	}
+	return 0
+}
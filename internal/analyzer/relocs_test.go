@@ -0,0 +1,97 @@
+package analyzer
+
+import (
+	"bytes"
+	"debug/elf"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestAnalyzeBinary_Relocations(t *testing.T) {
+	data, err := os.ReadFile("/bin/ls")
+	if err != nil {
+		t.Fatalf("failed to read test binary: %v", err)
+	}
+
+	info, err := AnalyzeBinary(data)
+	if err != nil {
+		t.Fatalf("AnalyzeBinary failed: %v", err)
+	}
+	rel := info.Relocations
+	if rel == nil || rel.Total == 0 {
+		t.Fatal("expected relocations for a dynamically linked binary")
+	}
+	if len(rel.GOT) == 0 || len(rel.PLT) == 0 {
+		t.Fatalf("expected GOT and PLT entries, got %d and %d", len(rel.GOT), len(rel.PLT))
+	}
+
+	resolved := false
+	for _, got := range rel.GOT {
+		if got.Symbol == "__libc_start_main" && strings.HasPrefix(got.Library, "libc.so") {
+			resolved = true
+		}
+	}
+	if !resolved {
+		t.Error("expected __libc_start_main GOT slot resolved to libc")
+	}
+
+	// Disassembly names PLT stubs after their import
+	elfFile, err := elf.NewFile(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to parse test binary: %v", err)
+	}
+	st := newSymbolTable(elfFile)
+	if name := st.describe(rel.PLT[0].Address); name != rel.PLT[0].Symbol+"@plt" {
+		t.Errorf("expected %s@plt, got %q", rel.PLT[0].Symbol, name)
+	}
+}
+
+func TestAnalyzeBinary_TextRelAndIFunc(t *testing.T) {
+	if runtime.GOARCH != "amd64" {
+		t.Skip("fixture uses x86-64 inline assembly")
+	}
+	gcc, err := exec.LookPath("gcc")
+	if err != nil {
+		t.Skip("gcc not available")
+	}
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "ifn.c")
+	code := `static int impl(void) { return 1; }
+static void *resolve(void) { return impl; }
+int fn(void) __attribute__((ifunc("resolve"), visibility("hidden")));
+int counter;
+long get(void) { long v; __asm__("movabs $counter, %0" : "=r"(v)); return v + fn(); }
+`
+	if err := os.WriteFile(src, []byte(code), 0644); err != nil {
+		t.Fatalf("failed to write source: %v", err)
+	}
+	lib := filepath.Join(dir, "ifn.so")
+	if out, err := exec.Command(gcc, "-shared", "-fPIC", "-Wl,-z,notext", "-o", lib, src).CombinedOutput(); err != nil {
+		t.Skipf("gcc failed: %v: %s", err, out)
+	}
+
+	data, err := os.ReadFile(lib)
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	info, err := AnalyzeBinary(data)
+	if err != nil {
+		t.Fatalf("AnalyzeBinary failed: %v", err)
+	}
+
+	rel := info.Relocations
+	if rel == nil {
+		t.Fatal("expected relocations")
+	}
+	if !rel.TextRel || rel.TextRelocations == 0 {
+		t.Errorf("expected TEXTREL to be flagged: %+v", rel)
+	}
+	if len(rel.IFuncs) != 1 || rel.IFuncs[0].Resolver == 0 {
+		t.Errorf("expected one IRELATIVE ifunc with a resolver, got %+v", rel.IFuncs)
+	}
+}
@@ -0,0 +1,202 @@
+package analyzer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Context-triggered piecewise hashing compatible with ssdeep/spamsum
+const (
+	ssdeepRollingWindow = 7
+	ssdeepMinBlockSize  = 3
+	ssdeepSpamSumLength = 64
+	ssdeepHashPrime     = 0x01000193
+	ssdeepHashInit      = 0x28021967
+	ssdeepB64           = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+)
+
+type ssdeepRoller struct {
+	window     [ssdeepRollingWindow]byte
+	h1, h2, h3 uint32
+	n          int
+}
+
+func (r *ssdeepRoller) roll(c byte) uint32 {
+	r.h2 -= r.h1
+	r.h2 += ssdeepRollingWindow * uint32(c)
+	r.h1 += uint32(c)
+	r.h1 -= uint32(r.window[r.n%ssdeepRollingWindow])
+	r.window[r.n%ssdeepRollingWindow] = c
+	r.n++
+	r.h3 <<= 5
+	r.h3 ^= uint32(c)
+	return r.h1 + r.h2 + r.h3
+}
+
+// ComputeSSDeep returns the "blocksize:hash:hash" piecewise digest of data
+func ComputeSSDeep(data []byte) string {
+	blockSize := ssdeepMinBlockSize
+	for blockSize*ssdeepSpamSumLength < len(data) {
+		blockSize *= 2
+	}
+
+	for {
+		sig1, sig2 := ssdeepPass(data, blockSize)
+		// Too few trigger points at this block size; retry with a finer one
+		if blockSize > ssdeepMinBlockSize && len(sig1) < ssdeepSpamSumLength/2 {
+			blockSize /= 2
+			continue
+		}
+		return fmt.Sprintf("%d:%s:%s", blockSize, sig1, sig2)
+	}
+}
+
+func ssdeepPass(data []byte, blockSize int) (string, string) {
+	var roller ssdeepRoller
+	var sig1, sig2 strings.Builder
+	h1, h2 := uint32(ssdeepHashInit), uint32(ssdeepHashInit)
+	bs1, bs2 := uint32(blockSize), uint32(blockSize*2)
+
+	for _, c := range data {
+		h1 = h1*ssdeepHashPrime ^ uint32(c)
+		h2 = h2*ssdeepHashPrime ^ uint32(c)
+		rh := roller.roll(c)
+
+		if rh%bs1 == bs1-1 && sig1.Len() < ssdeepSpamSumLength-1 {
+			sig1.WriteByte(ssdeepB64[h1%64])
+			h1 = ssdeepHashInit
+		}
+		if rh%bs2 == bs2-1 && sig2.Len() < ssdeepSpamSumLength/2-1 {
+			sig2.WriteByte(ssdeepB64[h2%64])
+			h2 = ssdeepHashInit
+		}
+	}
+
+	// The trailing piece after the last trigger point still contributes
+	if roller.h1+roller.h2+roller.h3 != 0 {
+		sig1.WriteByte(ssdeepB64[h1%64])
+		sig2.WriteByte(ssdeepB64[h2%64])
+	}
+	return sig1.String(), sig2.String()
+}
+
+func parseSSDeep(s string) (int, string, string, error) {
+	parts := strings.SplitN(s, ":", 3)
+	if len(parts) != 3 {
+		return 0, "", "", fmt.Errorf("invalid ssdeep digest")
+	}
+	blockSize, err := strconv.Atoi(parts[0])
+	if err != nil || blockSize < ssdeepMinBlockSize {
+		return 0, "", "", fmt.Errorf("invalid ssdeep block size")
+	}
+	return blockSize, parts[1], parts[2], nil
+}
+
+// SSDeepCompare scores two piecewise digests from 0 (unrelated) to 100 (identical)
+func SSDeepCompare(a, b string) (int, error) {
+	bsA, a1, a2, err := parseSSDeep(a)
+	if err != nil {
+		return 0, err
+	}
+	bsB, b1, b2, err := parseSSDeep(b)
+	if err != nil {
+		return 0, err
+	}
+	if bsA != bsB && bsA != bsB*2 && bsB != bsA*2 {
+		return 0, nil
+	}
+
+	a1, a2 = collapseRuns(a1), collapseRuns(a2)
+	b1, b2 = collapseRuns(b1), collapseRuns(b2)
+	if bsA == bsB && a1 == b1 && a2 == b2 {
+		return 100, nil
+	}
+
+	switch {
+	case bsA == bsB:
+		return max(ssdeepScore(a1, b1, bsA), ssdeepScore(a2, b2, bsA*2)), nil
+	case bsA == bsB*2:
+		return ssdeepScore(a1, b2, bsA), nil
+	default:
+		return ssdeepScore(a2, b1, bsB), nil
+	}
+}
+
+// collapseRuns truncates runs of more than three identical characters, which
+// carry little information and would otherwise dominate the edit distance
+func collapseRuns(s string) string {
+	var out strings.Builder
+	for i := 0; i < len(s); i++ {
+		if i >= 3 && s[i] == s[i-1] && s[i] == s[i-2] && s[i] == s[i-3] {
+			continue
+		}
+		out.WriteByte(s[i])
+	}
+	return out.String()
+}
+
+func ssdeepScore(s1, s2 string, blockSize int) int {
+	if len(s1) > ssdeepSpamSumLength || len(s2) > ssdeepSpamSumLength {
+		return 0
+	}
+	if !hasCommonSubstring(s1, s2, ssdeepRollingWindow) {
+		return 0
+	}
+
+	score := editDistance(s1, s2)
+	score = score * ssdeepSpamSumLength / (len(s1) + len(s2))
+	score = 100 * score / ssdeepSpamSumLength
+	if score >= 100 {
+		return 0
+	}
+	score = 100 - score
+
+	// Small block sizes can match by chance, so cap the score by how much
+	// data the signatures actually cover
+	if blockSize < (99+ssdeepRollingWindow)/ssdeepRollingWindow*ssdeepMinBlockSize {
+		limit := blockSize / ssdeepMinBlockSize * min(len(s1), len(s2))
+		if score > limit {
+			score = limit
+		}
+	}
+	return score
+}
+
+func hasCommonSubstring(s1, s2 string, n int) bool {
+	if len(s1) < n || len(s2) < n {
+		return false
+	}
+	seen := make(map[string]bool, len(s1)-n+1)
+	for i := 0; i+n <= len(s1); i++ {
+		seen[s1[i:i+n]] = true
+	}
+	for i := 0; i+n <= len(s2); i++ {
+		if seen[s2[i:i+n]] {
+			return true
+		}
+	}
+	return false
+}
+
+// editDistance is the Levenshtein distance with substitutions costing two,
+// matching the weighting ssdeep uses
+func editDistance(s1, s2 string) int {
+	prev := make([]int, len(s2)+1)
+	cur := make([]int, len(s2)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(s1); i++ {
+		cur[0] = i
+		for j := 1; j <= len(s2); j++ {
+			sub := prev[j-1]
+			if s1[i-1] != s2[j-1] {
+				sub += 2
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, sub)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(s2)]
+}
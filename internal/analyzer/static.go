@@ -4,47 +4,74 @@ import (
 	"bytes"
 	"debug/elf"
 	"fmt"
+	"strings"
 )
 
 type BinaryInfo struct {
-	Format        string        `json:"format"`
-	Class         string        `json:"class"`
-	Data          string        `json:"data"`
-	Version       uint8         `json:"version"`
-	OSABI         string        `json:"osabi"`
-	ABIVersion    uint8         `json:"abi_version"`
-	Type          string        `json:"type"`
-	Machine       string        `json:"machine"`
-	EntryPoint    uint64        `json:"entry_point"`
-	Sections      []SectionInfo `json:"sections"`
-	Segments      []SegmentInfo `json:"segments"`
-	Symbols       []string      `json:"symbols,omitempty"`
-	DynamicNeeded []string      `json:"dynamic_needed,omitempty"`
-	SecurityNotes []string      `json:"security_notes,omitempty"`
+	Format        string             `json:"format"`
+	Class         string             `json:"class"`
+	Data          string             `json:"data"`
+	Version       uint8              `json:"version"`
+	OSABI         string             `json:"osabi"`
+	ABIVersion    uint8              `json:"abi_version"`
+	Type          string             `json:"type"`
+	Machine       string             `json:"machine"`
+	EntryPoint    uint64             `json:"entry_point"`
+	Entropy       float64            `json:"entropy"`
+	Sections      []SectionInfo      `json:"sections"`
+	Segments      []SegmentInfo      `json:"segments"`
+	Symbols       []SymbolInfo       `json:"symbols,omitempty"`
+	Imports       []ImportInfo       `json:"imports,omitempty"`
+	Exports       []SymbolInfo       `json:"exports,omitempty"`
+	DynamicNeeded []string           `json:"dynamic_needed,omitempty"`
+	SecurityNotes []string           `json:"security_notes,omitempty"`
+	Hardening     *HardeningInfo     `json:"hardening,omitempty"`
+	Relocations   *RelocationInfo    `json:"relocations,omitempty"`
+	Packing       *PackingInfo       `json:"packing,omitempty"`
+	Fingerprints  *Fingerprints      `json:"fingerprints,omitempty"`
+	Strings       *StringsResult     `json:"strings,omitempty"`
+	Debug         *DebugInfo         `json:"debug,omitempty"`
+	Go            *GoInfo            `json:"go,omitempty"`
+	Architectures []string           `json:"architectures,omitempty"`
+	Core          *CoreInfo          `json:"core,omitempty"`
+	Slices        []*BinaryInfo      `json:"slices,omitempty"`   // remaining slices of a fat Mach-O
+	Embedded      []EmbeddedArtifact `json:"embedded,omitempty"` // payloads carved out of the file
 }
 
 type SectionInfo struct {
-	Name      string `json:"name"`
-	Type      string `json:"type"`
-	Flags     string `json:"flags"`
-	Addr      uint64 `json:"addr"`
-	Offset    uint64 `json:"offset"`
-	Size      uint64 `json:"size"`
-	Entsize   uint64 `json:"entsize"`
-	Addralign uint64 `json:"addralign"`
+	Name      string  `json:"name"`
+	Type      string  `json:"type"`
+	Flags     string  `json:"flags"`
+	Addr      uint64  `json:"addr"`
+	Offset    uint64  `json:"offset"`
+	Size      uint64  `json:"size"`
+	Entsize   uint64  `json:"entsize"`
+	Addralign uint64  `json:"addralign"`
+	Entropy   float64 `json:"entropy"`
+	SHA256    string  `json:"sha256,omitempty"`
 }
 
 type SegmentInfo struct {
-	Type   string `json:"type"`
-	Flags  string `json:"flags"`
-	Vaddr  uint64 `json:"vaddr"`
-	Paddr  uint64 `json:"paddr"`
-	Filesz uint64 `json:"filesz"`
-	Memsz  uint64 `json:"memsz"`
-	Align  uint64 `json:"align"`
+	Name    string  `json:"name,omitempty"`
+	Type    string  `json:"type"`
+	Flags   string  `json:"flags"`
+	Vaddr   uint64  `json:"vaddr"`
+	Paddr   uint64  `json:"paddr"`
+	Filesz  uint64  `json:"filesz"`
+	Memsz   uint64  `json:"memsz"`
+	Align   uint64  `json:"align"`
+	Entropy float64 `json:"entropy"`
 }
 
-func AnalyzeBinary(fileBytes []byte) (*BinaryInfo, error) {
+type elfParser struct{}
+
+func (elfParser) Format() string { return FormatELF }
+
+func (elfParser) Match(fileBytes []byte) bool {
+	return bytes.HasPrefix(fileBytes, []byte(elf.ELFMAG))
+}
+
+func (elfParser) Parse(fileBytes []byte) (*BinaryInfo, error) {
 	fileReader := bytes.NewReader(fileBytes)
 	elfFile, err := elf.NewFile(fileReader)
 	if err != nil {
@@ -77,6 +104,10 @@ func AnalyzeBinary(fileBytes []byte) (*BinaryInfo, error) {
 	// Sections
 	var sections []SectionInfo
 	for _, sec := range elfFile.Sections {
+		var entropy float64
+		if sec.Type != elf.SHT_NOBITS {
+			entropy = regionEntropy(fileBytes, sec.Offset, sec.Size)
+		}
 		sections = append(sections, SectionInfo{
 			Name:      sec.Name,
 			Type:      sec.Type.String(),
@@ -86,6 +117,7 @@ func AnalyzeBinary(fileBytes []byte) (*BinaryInfo, error) {
 			Size:      sec.Size,
 			Entsize:   sec.Entsize,
 			Addralign: sec.Addralign,
+			Entropy:   entropy,
 		})
 	}
 
@@ -93,23 +125,19 @@ func AnalyzeBinary(fileBytes []byte) (*BinaryInfo, error) {
 	var segments []SegmentInfo
 	for _, ph := range elfFile.Progs {
 		segments = append(segments, SegmentInfo{
-			Type:   ph.Type.String(),
-			Flags:  ph.Flags.String(),
-			Vaddr:  ph.Vaddr,
-			Paddr:  ph.Paddr,
-			Filesz: ph.Filesz,
-			Memsz:  ph.Memsz,
-			Align:  ph.Align,
+			Type:    ph.Type.String(),
+			Flags:   ph.Flags.String(),
+			Vaddr:   ph.Vaddr,
+			Paddr:   ph.Paddr,
+			Filesz:  ph.Filesz,
+			Memsz:   ph.Memsz,
+			Align:   ph.Align,
+			Entropy: regionEntropy(fileBytes, ph.Off, ph.Filesz),
 		})
 	}
 
-	// Symbols
-	var symbols []string
-	if syms, err := elfFile.Symbols(); err == nil {
-		for _, sym := range syms {
-			symbols = append(symbols, sym.Name)
-		}
-	}
+	// Symbols (.symtab and .dynsym) with imports and exports
+	symbols, imports, exports := collectSymbols(elfFile)
 
 	// Dynamic needed (shared libraries)
 	var dynamicNeeded []string
@@ -132,8 +160,21 @@ func AnalyzeBinary(fileBytes []byte) (*BinaryInfo, error) {
 		}
 	}
 
+	relocations := analyzeRelocations(elfFile, fileBytes, imports)
+	if relocations != nil {
+		if relocations.TextRel {
+			securityNotes = append(securityNotes, "TEXTREL present (relocations patch read-only code)")
+		}
+		if len(relocations.IFuncs) > 0 {
+			securityNotes = append(securityNotes, fmt.Sprintf("IRELATIVE relocations present (%d ifunc resolvers)", len(relocations.IFuncs)))
+		}
+		if len(relocations.CopyRelocations) > 0 {
+			securityNotes = append(securityNotes, "COPY relocations present: "+strings.Join(relocations.CopyRelocations, ", "))
+		}
+	}
+
 	return &BinaryInfo{
-		Format:        "ELF",
+		Format:        FormatELF,
 		Class:         class,
 		Data:          data,
 		Version:       uint8(elfFile.Version),
@@ -142,10 +183,20 @@ func AnalyzeBinary(fileBytes []byte) (*BinaryInfo, error) {
 		Type:          typ,
 		Machine:       machine,
 		EntryPoint:    elfFile.Entry,
+		Entropy:       shannonEntropy(fileBytes),
 		Sections:      sections,
 		Segments:      segments,
 		Symbols:       symbols,
+		Imports:       imports,
+		Exports:       exports,
 		DynamicNeeded: dynamicNeeded,
 		SecurityNotes: securityNotes,
+		Hardening:     analyzeHardening(elfFile),
+		Relocations:   relocations,
+		Packing:       detectPacking(elfFile, fileBytes, sections, segments),
+		Fingerprints:  computeFingerprints(elfFile, fileBytes, sections, imports, dynamicNeeded),
+		Debug:         analyzeDebugInfo(elfFile),
+		Go:            analyzeGoBinary(elfFile, fileBytes),
+		Core:          analyzeCore(elfFile),
 	}, nil
 }
@@ -3,6 +3,8 @@ package analyzer
 import (
 	"encoding/json"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"testing"
 )
 
@@ -26,3 +28,90 @@ func TestAnalyzeBinary_ELF(t *testing.T) {
 		t.Logf("Analyzed ELF binary:\n%s", string(b))
 	}
 }
+
+func TestAnalyzeBinary_Hardening(t *testing.T) {
+	data, err := os.ReadFile("/bin/ls")
+	if err != nil {
+		t.Fatalf("failed to read test binary: %v", err)
+	}
+
+	info, err := AnalyzeBinary(data)
+	if err != nil {
+		t.Fatalf("AnalyzeBinary failed: %v", err)
+	}
+
+	if info.Hardening == nil {
+		t.Fatal("expected hardening info")
+	}
+	if !info.Hardening.NX {
+		t.Errorf("expected NX to be enabled for /bin/ls")
+	}
+	if info.Hardening.RELRO == RELRONone {
+		t.Errorf("expected RELRO for /bin/ls, got %q", info.Hardening.RELRO)
+	}
+	t.Logf("Hardening: %+v", *info.Hardening)
+}
+
+func TestAnalyzeBinary_StaticWithoutCanary(t *testing.T) {
+	gcc, err := exec.LookPath("gcc")
+	if err != nil {
+		t.Skip("gcc not available")
+	}
+	dir := t.TempDir()
+	src := filepath.Join(dir, "main.c")
+	bin := filepath.Join(dir, "main")
+	os.WriteFile(src, []byte("int main(void) { char buf[64]; return buf[3]; }\n"), 0644)
+	if out, err := exec.Command(gcc, "-static", "-fno-stack-protector", "-U_FORTIFY_SOURCE", "-o", bin, src).CombinedOutput(); err != nil {
+		t.Skipf("static link failed: %v: %s", err, out)
+	}
+	data, err := os.ReadFile(bin)
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+
+	// The bundled libc defines __stack_chk_fail and the _chk functions
+	info, err := AnalyzeBinary(data)
+	if err != nil {
+		t.Fatalf("AnalyzeBinary failed: %v", err)
+	}
+	if info.Hardening.Canary || info.Hardening.Fortify {
+		t.Errorf("static binary without protections reported canary=%v fortify=%v %v",
+			info.Hardening.Canary, info.Hardening.Fortify, info.Hardening.FortifiedFunctions)
+	}
+}
+
+func TestAnalyzeBinary_Symbols(t *testing.T) {
+	data, err := os.ReadFile("/bin/ls")
+	if err != nil {
+		t.Fatalf("failed to read test binary: %v", err)
+	}
+
+	info, err := AnalyzeBinary(data)
+	if err != nil {
+		t.Fatalf("AnalyzeBinary failed: %v", err)
+	}
+
+	if len(info.Imports) == 0 {
+		t.Fatal("expected imports from .dynsym")
+	}
+	for _, imp := range info.Imports {
+		if imp.Name == "" {
+			t.Errorf("import with empty name: %+v", imp)
+		}
+	}
+	for _, sym := range info.Exports {
+		if !sym.Dynamic || sym.Section == "SHN_UNDEF" {
+			t.Errorf("export should be a defined dynamic symbol: %+v", sym)
+		}
+	}
+}
+
+func TestSymbolInfo_UnmarshalLegacy(t *testing.T) {
+	var info BinaryInfo
+	if err := json.Unmarshal([]byte(`{"symbols":["main","_start"]}`), &info); err != nil {
+		t.Fatalf("failed to unmarshal legacy symbols: %v", err)
+	}
+	if len(info.Symbols) != 2 || info.Symbols[0].Name != "main" {
+		t.Errorf("unexpected symbols: %+v", info.Symbols)
+	}
+}
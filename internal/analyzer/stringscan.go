@@ -0,0 +1,256 @@
+package analyzer
+
+import (
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	DefaultMinStringLength = 4
+	// MinStringLength is the shortest run reported; shorter ones are mostly
+	// noise and would make every file yield millions of strings
+	MinStringLength = 3
+	// MaxStoredStrings caps how many strings are persisted per analysis
+	MaxStoredStrings = 5000
+	// Longer strings are truncated so a single blob cannot dominate the result
+	maxStringValueLength = 1024
+
+	StringEncodingASCII   = "ascii"
+	StringEncodingUTF16LE = "utf-16le"
+
+	StringCategoryURL    = "url"
+	StringCategoryIP     = "ip"
+	StringCategoryPath   = "path"
+	StringCategoryShell  = "shell"
+	StringCategoryCrypto = "crypto"
+	StringCategoryBase64 = "base64"
+)
+
+type ExtractedString struct {
+	Value    string `json:"value"`
+	Length   int    `json:"length"`
+	Offset   uint64 `json:"offset"`
+	Section  string `json:"section,omitempty"`
+	Encoding string `json:"encoding"`
+	Category string `json:"category,omitempty"`
+}
+
+type StringsResult struct {
+	MinLength  int               `json:"min_length"`
+	Total      int               `json:"total"`
+	Truncated  bool              `json:"truncated"`
+	Categories map[string]int    `json:"categories,omitempty"`
+	Strings    []ExtractedString `json:"strings,omitempty"`
+}
+
+var (
+	urlPattern    = regexp.MustCompile(`(?i)\b(?:https?|ftp|wss?|tcp|udp)://[^\s"'<>]+`)
+	ipPattern     = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)
+	pathPattern   = regexp.MustCompile(`^(?:~|\.{1,2})?/(?:[\w.\-@%+]+/)*[\w.\-@%+]*$|^[A-Za-z]:\\`)
+	base64Pattern = regexp.MustCompile(`^[A-Za-z0-9+/]{16,}={0,2}$`)
+	shellPattern  = regexp.MustCompile(`(?i)(?:^|[\s;|&])(?:/bin/(?:ba)?sh|sh -c|bash -c|chmod [0-7+]|wget |curl |rm -rf|nc -|ncat |busybox |crontab |iptables |nohup |mkfifo |base64 -d)`)
+)
+
+// Well-known constants embedded as text by crypto implementations
+var cryptoMarkers = []string{
+	"expand 32-byte k",
+	"expand 16-byte k",
+	"ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/",
+	"-----BEGIN ",
+	"637c777bf26b6fc5", // AES S-box
+	"67452301efcdab89", // MD5/SHA-1 initial state
+	"6a09e667",         // SHA-256 initial state
+	"428a2f98",         // SHA-256 round constants
+}
+
+// ExtractStrings finds printable ASCII and UTF-16LE runs of at least minLength
+// characters and attributes each one to the section containing its offset
+func ExtractStrings(fileBytes []byte, sections []SectionInfo, minLength int) []ExtractedString {
+	return ScanStrings(fileBytes, sections, minLength, 0).Strings
+}
+
+// ScanStrings is ExtractStrings capped at limit strings, keeping classified
+// strings in preference to unclassified ones. Every string is counted and
+// classified, but at most limit of each kind and encoding are held while
+// scanning.
+func ScanStrings(fileBytes []byte, sections []SectionInfo, minLength, limit int) *StringsResult {
+	if minLength <= 0 {
+		minLength = DefaultMinStringLength
+	}
+	minLength = max(minLength, MinStringLength)
+
+	c := &stringCollector{
+		limit: limit,
+		result: &StringsResult{
+			MinLength:  minLength,
+			Categories: make(map[string]int),
+		},
+	}
+	scanASCII(fileBytes, minLength, c.add)
+	c.encoding++
+	scanUTF16LE(fileBytes, minLength, c.add)
+
+	result := c.result
+	byOffset := func(list []ExtractedString) {
+		sort.Slice(list, func(i, j int) bool {
+			return list[i].Offset < list[j].Offset
+		})
+	}
+	byOffset(c.classified)
+	byOffset(c.plain)
+	kept := c.classified
+	if limit > 0 {
+		result.Truncated = result.Total > limit
+		kept = kept[:min(len(kept), limit)]
+		kept = append(kept, c.plain[:min(len(c.plain), limit-len(kept))]...)
+	} else {
+		kept = append(kept, c.plain...)
+	}
+	byOffset(kept)
+	for i := range kept {
+		kept[i].Section = sectionForOffset(sections, kept[i].Offset)
+	}
+	result.Strings = kept
+	return result
+}
+
+// stringCollector counts every string and keeps the first limit classified
+// and unclassified ones of each encoding; scanners report in offset order,
+// so those are the ones a cap by offset keeps
+type stringCollector struct {
+	limit      int
+	encoding   int // index of the encoding being scanned
+	held       [2][2]int
+	classified []ExtractedString
+	plain      []ExtractedString
+	result     *StringsResult
+}
+
+func (c *stringCollector) add(s ExtractedString) {
+	c.result.Total++
+	s.Category = classifyString(s.Value)
+	kind := 0
+	if s.Category != "" {
+		c.result.Categories[s.Category]++
+		kind = 1
+	}
+	if c.limit > 0 && c.held[c.encoding][kind] >= c.limit {
+		return
+	}
+	c.held[c.encoding][kind]++
+	if kind == 1 {
+		c.classified = append(c.classified, s)
+	} else {
+		c.plain = append(c.plain, s)
+	}
+}
+
+func isPrintable(b byte) bool {
+	return (b >= 0x20 && b <= 0x7e) || b == '\t'
+}
+
+func scanASCII(data []byte, minLength int, emit func(ExtractedString)) {
+	start := -1
+	for i := 0; i <= len(data); i++ {
+		if i < len(data) && isPrintable(data[i]) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 && i-start >= minLength {
+			emit(newExtractedString(data[start:i], uint64(start), StringEncodingASCII))
+		}
+		start = -1
+	}
+}
+
+func scanUTF16LE(data []byte, minLength int, emit func(ExtractedString)) {
+	for i := 0; i+1 < len(data); {
+		j := i
+		var run []byte
+		for j+1 < len(data) && isPrintable(data[j]) && data[j+1] == 0 {
+			run = append(run, data[j])
+			j += 2
+		}
+		if len(run) >= minLength {
+			emit(newExtractedString(run, uint64(i), StringEncodingUTF16LE))
+			i = j
+			continue
+		}
+		i++
+	}
+}
+
+func newExtractedString(raw []byte, offset uint64, encoding string) ExtractedString {
+	value := string(raw)
+	length := len(value)
+	if length > maxStringValueLength {
+		value = value[:maxStringValueLength]
+	}
+	return ExtractedString{
+		Value:    value,
+		Length:   length,
+		Offset:   offset,
+		Encoding: encoding,
+	}
+}
+
+func sectionForOffset(sections []SectionInfo, offset uint64) string {
+	for _, sec := range sections {
+		if sec.Size == 0 || sec.Type == "SHT_NOBITS" {
+			continue
+		}
+		if offset >= sec.Offset && offset < sec.Offset+sec.Size {
+			return sec.Name
+		}
+	}
+	return ""
+}
+
+func classifyString(s string) string {
+	trimmed := strings.TrimSpace(s)
+
+	if urlPattern.MatchString(trimmed) {
+		return StringCategoryURL
+	}
+	for _, candidate := range ipPattern.FindAllString(trimmed, -1) {
+		if ip := net.ParseIP(candidate); ip != nil && !strings.HasPrefix(candidate, "0.") {
+			return StringCategoryIP
+		}
+	}
+	for _, marker := range cryptoMarkers {
+		if strings.Contains(trimmed, marker) {
+			return StringCategoryCrypto
+		}
+	}
+	if shellPattern.MatchString(trimmed) {
+		return StringCategoryShell
+	}
+	if len(trimmed) > 1 && pathPattern.MatchString(trimmed) {
+		return StringCategoryPath
+	}
+	if len(trimmed)%4 == 0 && base64Pattern.MatchString(trimmed) && hasMixedCharClasses(trimmed) {
+		return StringCategoryBase64
+	}
+	return ""
+}
+
+// hasMixedCharClasses filters out identifiers like "AAAAAAAAAAAAAAAA" that
+// happen to match the base64 alphabet
+func hasMixedCharClasses(s string) bool {
+	var upper, lower, digit bool
+	for _, r := range s {
+		switch {
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= '0' && r <= '9':
+			digit = true
+		}
+	}
+	return upper && lower && digit
+}
@@ -0,0 +1,88 @@
+package analyzer
+
+import (
+	"os"
+	"testing"
+)
+
+func TestExtractStrings(t *testing.T) {
+	data := []byte("\x00\x01http://example.com/payload\x00\x02ab\x00/etc/passwd\x00\x01\x01")
+	// UTF-16LE "cmd.exe"
+	data = append(data, []byte{'c', 0, 'm', 0, 'd', 0, '.', 0, 'e', 0, 'x', 0, 'e', 0, 0, 0}...)
+
+	found := ExtractStrings(data, nil, 4)
+	if len(found) != 3 {
+		t.Fatalf("expected 3 strings, got %d: %+v", len(found), found)
+	}
+	if found[0].Category != StringCategoryURL || found[0].Offset != 2 {
+		t.Errorf("unexpected first string: %+v", found[0])
+	}
+	if found[1].Value != "/etc/passwd" || found[1].Category != StringCategoryPath {
+		t.Errorf("unexpected second string: %+v", found[1])
+	}
+	if found[2].Value != "cmd.exe" || found[2].Encoding != StringEncodingUTF16LE {
+		t.Errorf("unexpected third string: %+v", found[2])
+	}
+}
+
+func TestClassifyString(t *testing.T) {
+	cases := map[string]string{
+		"connect to 10.0.0.1:4444":         StringCategoryIP,
+		"/bin/sh -c 'id'":                  StringCategoryShell,
+		"expand 32-byte k":                 StringCategoryCrypto,
+		"aGVsbG8gd29ybGQgZnJvbSBiYXNlNjQ0": StringCategoryBase64,
+		"GLIBC_2.2.5":                      "",
+		"usage: %s [OPTION]... [FILE]...":  "",
+	}
+	for in, want := range cases {
+		if got := classifyString(in); got != want {
+			t.Errorf("classifyString(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestScanStrings_Cap(t *testing.T) {
+	data, err := os.ReadFile("/bin/ls")
+	if err != nil {
+		t.Fatalf("failed to read test binary: %v", err)
+	}
+	info, err := AnalyzeBinary(data)
+	if err != nil {
+		t.Fatalf("AnalyzeBinary failed: %v", err)
+	}
+
+	found := ExtractStrings(data, info.Sections, DefaultMinStringLength)
+	classified := 0
+	for _, s := range found {
+		if s.Category != "" {
+			classified++
+		}
+	}
+
+	result := ScanStrings(data, info.Sections, DefaultMinStringLength, 50)
+	if result.Total != len(found) || len(result.Strings) > 50 {
+		t.Fatalf("unexpected cap: total=%d stored=%d", result.Total, len(result.Strings))
+	}
+	if len(found) > 50 && !result.Truncated {
+		t.Error("expected truncated result")
+	}
+	kept := 0
+	for i, s := range result.Strings {
+		if s.Category != "" {
+			kept++
+		}
+		if i > 0 && s.Offset < result.Strings[i-1].Offset {
+			t.Fatalf("strings out of order at %d", i)
+		}
+	}
+	if kept != min(classified, 50) {
+		t.Errorf("kept %d classified strings, want %d", kept, min(classified, 50))
+	}
+}
+
+func TestScanStrings_MinLength(t *testing.T) {
+	result := ScanStrings([]byte("ab\x00abc\x00"), nil, 1, 0)
+	if result.MinLength != MinStringLength || result.Total != 1 || result.Strings[0].Value != "abc" {
+		t.Errorf("expected runs shorter than %d to be skipped: %+v", MinStringLength, result)
+	}
+}
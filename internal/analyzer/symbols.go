@@ -0,0 +1,111 @@
+package analyzer
+
+import (
+	"debug/elf"
+	"encoding/json"
+)
+
+type SymbolInfo struct {
+	Name         string `json:"name"`
+	Demangled    string `json:"demangled,omitempty"`
+	Value        uint64 `json:"value"`
+	Size         uint64 `json:"size"`
+	Type         string `json:"type"`
+	Binding      string `json:"binding"`
+	Visibility   string `json:"visibility"`
+	SectionIndex uint16 `json:"section_index"`
+	Section      string `json:"section,omitempty"`
+	Version      string `json:"version,omitempty"`
+	Library      string `json:"library,omitempty"`
+	Dynamic      bool   `json:"dynamic"` // true if read from .dynsym, false for .symtab
+}
+
+// UnmarshalJSON also accepts the bare symbol names stored by older releases
+func (s *SymbolInfo) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*s = SymbolInfo{Name: name}
+		return nil
+	}
+	type symbolInfo SymbolInfo
+	return json.Unmarshal(data, (*symbolInfo)(s))
+}
+
+type ImportInfo struct {
+	Name      string `json:"name"`
+	Demangled string `json:"demangled,omitempty"`
+	Version   string `json:"version,omitempty"`
+	Library   string `json:"library,omitempty"`
+}
+
+// collectSymbols returns the .symtab and .dynsym entries together with the
+// imported and exported dynamic symbols
+func collectSymbols(elfFile *elf.File) (symbols []SymbolInfo, imports []ImportInfo, exports []SymbolInfo) {
+	if syms, err := elfFile.Symbols(); err == nil {
+		for _, sym := range syms {
+			symbols = append(symbols, newSymbolInfo(elfFile, sym, false))
+		}
+	}
+
+	if dynSyms, err := elfFile.DynamicSymbols(); err == nil {
+		for _, sym := range dynSyms {
+			info := newSymbolInfo(elfFile, sym, true)
+			symbols = append(symbols, info)
+			if isExported(sym) {
+				exports = append(exports, info)
+			}
+		}
+	}
+
+	if imported, err := elfFile.ImportedSymbols(); err == nil {
+		for _, imp := range imported {
+			imports = append(imports, ImportInfo{
+				Name:    imp.Name,
+				Version: imp.Version,
+				Library: imp.Library,
+			})
+		}
+	}
+
+	return symbols, imports, exports
+}
+
+func newSymbolInfo(elfFile *elf.File, sym elf.Symbol, dynamic bool) SymbolInfo {
+	return SymbolInfo{
+		Name:         sym.Name,
+		Value:        sym.Value,
+		Size:         sym.Size,
+		Type:         elf.ST_TYPE(sym.Info).String(),
+		Binding:      elf.ST_BIND(sym.Info).String(),
+		Visibility:   elf.ST_VISIBILITY(sym.Other).String(),
+		SectionIndex: uint16(sym.Section),
+		Section:      symbolSectionName(elfFile, sym.Section),
+		Version:      sym.Version,
+		Library:      sym.Library,
+		Dynamic:      dynamic,
+	}
+}
+
+func symbolSectionName(elfFile *elf.File, idx elf.SectionIndex) string {
+	if idx == elf.SHN_UNDEF || idx >= elf.SHN_LORESERVE {
+		return idx.String()
+	}
+	if int(idx) < len(elfFile.Sections) {
+		return elfFile.Sections[idx].Name
+	}
+	return ""
+}
+
+// isExported reports whether a dynamic symbol is defined here and visible to
+// other modules
+func isExported(sym elf.Symbol) bool {
+	if sym.Section == elf.SHN_UNDEF || sym.Name == "" {
+		return false
+	}
+	bind := elf.ST_BIND(sym.Info)
+	if bind != elf.STB_GLOBAL && bind != elf.STB_WEAK {
+		return false
+	}
+	vis := elf.ST_VISIBILITY(sym.Other)
+	return vis == elf.STV_DEFAULT || vis == elf.STV_PROTECTED
+}
@@ -0,0 +1,86 @@
+package analyzer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"syscall"
+	"unsafe"
+
+	"github.com/ashborn3/BinTraceBench/internal/syscalls"
+)
+
+// hostABIs are the syscall ABIs of the binaries this host can run; x86-64
+// kernels also run i386 binaries
+var hostABIs = []*syscalls.ABI{syscalls.X8664, syscalls.I386}
+
+const (
+	// i386CodeSegment is the user code segment selector of 32-bit tasks
+	i386CodeSegment = 0x23
+	// i386RegsSize is the size of the i386 user_regs_struct
+	i386RegsSize = 17 * 4
+)
+
+// i386Regs is the i386 user_regs_struct
+type i386Regs struct {
+	Ebx, Ecx, Edx, Esi, Edi, Ebp, Eax uint32
+	Ds, Es, Fs, Gs, OrigEax, Eip, Cs  uint32
+	Eflags, Esp, Ss                   uint32
+}
+
+// ptraceGetRegs reads the registers of a stopped thread. Some kernels hand a
+// 64-bit tracer the i386 register set of a 32-bit tracee, so the set is read
+// with PTRACE_GETREGSET, which reports its size, and widened when needed.
+func ptraceGetRegs(tid int, regs *syscall.PtraceRegs) error {
+	buf := make([]byte, unsafe.Sizeof(*regs))
+	iov := syscall.Iovec{Base: &buf[0]}
+	iov.SetLen(len(buf))
+	_, _, errno := syscall.Syscall6(syscall.SYS_PTRACE, syscall.PTRACE_GETREGSET, uintptr(tid),
+		ntPrstatus, uintptr(unsafe.Pointer(&iov)), 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	if iov.Len != i386RegsSize {
+		return binary.Read(bytes.NewReader(buf), binary.LittleEndian, regs)
+	}
+
+	var r i386Regs
+	if err := binary.Read(bytes.NewReader(buf), binary.LittleEndian, &r); err != nil {
+		return err
+	}
+	*regs = syscall.PtraceRegs{
+		Rbx: uint64(r.Ebx), Rcx: uint64(r.Ecx), Rdx: uint64(r.Edx), Rsi: uint64(r.Esi),
+		Rdi: uint64(r.Edi), Rbp: uint64(r.Ebp), Rax: uint64(r.Eax), Orig_rax: uint64(r.OrigEax),
+		Rip: uint64(r.Eip), Cs: uint64(r.Cs), Eflags: uint64(r.Eflags), Rsp: uint64(r.Esp),
+		Ss: uint64(r.Ss), Ds: uint64(r.Ds), Es: uint64(r.Es), Fs: uint64(r.Fs), Gs: uint64(r.Gs),
+	}
+	return nil
+}
+
+// syscallABI tells which ABI the syscall a thread stopped at uses. 32-bit
+// tasks always use the i386 one, and so does int 0x80 from 64-bit code; both
+// syscall instructions are two bytes long and Rip points past them.
+func syscallABI(tid int, regs *syscall.PtraceRegs) *syscalls.ABI {
+	if regs.Cs == i386CodeSegment {
+		return syscalls.I386
+	}
+	insn := make([]byte, 2)
+	if n, _ := syscall.PtracePeekText(tid, uintptr(regs.Rip-2), insn); n == 2 && insn[0] == 0xcd && insn[1] == 0x80 {
+		return syscalls.I386
+	}
+	return syscalls.X8664
+}
+
+func syscallNumber(regs *syscall.PtraceRegs) uint64 {
+	return regs.Orig_rax
+}
+
+func syscallArgs(abi *syscalls.ABI, regs *syscall.PtraceRegs) [6]uint64 {
+	if abi == syscalls.I386 {
+		return [6]uint64{regs.Rbx, regs.Rcx, regs.Rdx, regs.Rsi, regs.Rdi, regs.Rbp}
+	}
+	return [6]uint64{regs.Rdi, regs.Rsi, regs.Rdx, regs.R10, regs.R8, regs.R9}
+}
+
+func syscallReturn(regs *syscall.PtraceRegs) uint64 {
+	return regs.Rax
+}
@@ -0,0 +1,87 @@
+package analyzer
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"testing"
+)
+
+// The getpidThenExit programs call getpid with int 0x80, then exit with the
+// native syscall instruction of their class
+var (
+	getpidThenExit64 = []byte{
+		0xb8, 0x14, 0x00, 0x00, 0x00, // mov eax, 20 (i386 getpid)
+		0xcd, 0x80, // int 0x80
+		0xb8, 0xe7, 0x00, 0x00, 0x00, // mov eax, 231 (exit_group)
+		0x31, 0xff, // xor edi, edi
+		0x0f, 0x05, // syscall
+	}
+	getpidThenExit32 = []byte{
+		0xb8, 0x14, 0x00, 0x00, 0x00, // mov eax, 20 (getpid)
+		0xcd, 0x80, // int 0x80
+		0xb8, 0xfc, 0x00, 0x00, 0x00, // mov eax, 252 (exit_group)
+		0x31, 0xdb, // xor ebx, ebx
+		0xcd, 0x80, // int 0x80
+	}
+)
+
+// tinyELF builds a static executable made of one segment running code
+func tinyELF(class elf.Class, code []byte) []byte {
+	var buf bytes.Buffer
+	ident := [elf.EI_NIDENT]byte{0x7f, 'E', 'L', 'F', byte(class), byte(elf.ELFDATA2LSB), byte(elf.EV_CURRENT)}
+	if class == elf.ELFCLASS64 {
+		const base, hdrs = 0x400000, 64 + 56
+		binary.Write(&buf, binary.LittleEndian, elf.Header64{
+			Ident: ident, Type: uint16(elf.ET_EXEC), Machine: uint16(elf.EM_X86_64), Version: 1,
+			Entry: base + hdrs, Phoff: 64, Ehsize: 64, Phentsize: 56, Phnum: 1,
+		})
+		binary.Write(&buf, binary.LittleEndian, elf.Prog64{
+			Type: uint32(elf.PT_LOAD), Flags: uint32(elf.PF_R | elf.PF_X), Vaddr: base, Paddr: base,
+			Filesz: hdrs + uint64(len(code)), Memsz: hdrs + uint64(len(code)), Align: 0x1000,
+		})
+	} else {
+		const base, hdrs = 0x8048000, 52 + 32
+		binary.Write(&buf, binary.LittleEndian, elf.Header32{
+			Ident: ident, Type: uint16(elf.ET_EXEC), Machine: uint16(elf.EM_386), Version: 1,
+			Entry: base + hdrs, Phoff: 52, Ehsize: 52, Phentsize: 32, Phnum: 1,
+		})
+		binary.Write(&buf, binary.LittleEndian, elf.Prog32{
+			Type: uint32(elf.PT_LOAD), Flags: uint32(elf.PF_R | elf.PF_X), Vaddr: base, Paddr: base,
+			Filesz: hdrs + uint32(len(code)), Memsz: hdrs + uint32(len(code)), Align: 0x1000,
+		})
+	}
+	buf.Write(code)
+	return buf.Bytes()
+}
+
+func TestTraceBinary_I386Syscalls(t *testing.T) {
+	for name, data := range map[string][]byte{
+		// syscallABI spots the instruction, the registers stay 64-bit
+		"int 0x80 from 64-bit code": tinyELF(elf.ELFCLASS64, getpidThenExit64),
+		// ptraceGetRegs widens the i386 register set
+		"32-bit binary": tinyELF(elf.ELFCLASS32, getpidThenExit32),
+	} {
+		result, err := TraceBinary(data)
+		if err != nil {
+			t.Skipf("ptrace unavailable: %v", err)
+		}
+		if len(result.Processes) == 0 {
+			t.Fatalf("%s: no process traced", name)
+		}
+
+		var getpid *VerboseSyscallEntry
+		for i, e := range result.Syscalls {
+			if e.Number == 20 && e.Arch == "i386" {
+				getpid = &result.Syscalls[i]
+			}
+		}
+		if getpid == nil {
+			t.Errorf("%s: no i386 getpid in %+v", name, result.Syscalls)
+			continue
+		}
+		if getpid.Name != "getpid" || getpid.Return == nil || *getpid.Return != int64(result.Processes[0].PID) {
+			t.Errorf("%s: unexpected getpid record: %+v", name, *getpid)
+		}
+	}
+}
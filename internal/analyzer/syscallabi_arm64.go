@@ -0,0 +1,36 @@
+package analyzer
+
+import (
+	"syscall"
+
+	"github.com/ashborn3/BinTraceBench/internal/syscalls"
+)
+
+// hostABIs are the syscall ABIs of the binaries this host can run
+var hostABIs = []*syscalls.ABI{syscalls.ARM64}
+
+// ptraceGetRegs reads the registers of a stopped thread
+func ptraceGetRegs(tid int, regs *syscall.PtraceRegs) error {
+	return syscall.PtraceGetRegs(tid, regs)
+}
+
+// syscallABI tells which ABI the syscall a thread stopped at uses. AArch32
+// tasks are not supported, so it is always the arm64 one.
+func syscallABI(tid int, regs *syscall.PtraceRegs) *syscalls.ABI {
+	return syscalls.ARM64
+}
+
+// syscallNumber reads x8. Unlike orig_rax on x86-64 it is not preserved, but
+// the recorder only reads it at entry stops.
+func syscallNumber(regs *syscall.PtraceRegs) uint64 {
+	return regs.Regs[8]
+}
+
+func syscallArgs(abi *syscalls.ABI, regs *syscall.PtraceRegs) [6]uint64 {
+	return [6]uint64{regs.Regs[0], regs.Regs[1], regs.Regs[2], regs.Regs[3], regs.Regs[4], regs.Regs[5]}
+}
+
+// syscallReturn reads x0, which holds the first argument until the call returns
+func syscallReturn(regs *syscall.PtraceRegs) uint64 {
+	return regs.Regs[0]
+}
@@ -0,0 +1,229 @@
+package analyzer
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// TLSH parameters for the standard 128-bucket, 1-byte checksum variant
+const (
+	tlshWindow      = 5
+	tlshBuckets     = 128
+	tlshCodeSize    = tlshBuckets / 4
+	tlshMinDataLen  = 50
+	tlshVersionTag  = "T1"
+	tlshDigestBytes = 3 + tlshCodeSize
+)
+
+// tlshPearson is the Pearson permutation table from the TLSH reference implementation
+var tlshPearson = [256]byte{
+	1, 87, 49, 12, 176, 178, 102, 166, 121, 193, 6, 84, 249, 230, 44, 163,
+	14, 197, 213, 181, 161, 85, 218, 80, 64, 239, 24, 226, 236, 142, 38, 200,
+	110, 177, 104, 103, 141, 253, 255, 50, 77, 101, 81, 18, 45, 96, 31, 222,
+	25, 107, 190, 70, 86, 237, 240, 34, 72, 242, 20, 214, 244, 227, 149, 235,
+	97, 234, 57, 22, 60, 250, 82, 175, 208, 5, 127, 199, 111, 62, 135, 248,
+	174, 169, 211, 58, 66, 154, 106, 195, 245, 171, 17, 187, 182, 179, 0, 243,
+	132, 56, 148, 75, 128, 133, 158, 100, 130, 126, 91, 13, 153, 246, 216, 219,
+	119, 68, 223, 78, 83, 88, 201, 99, 122, 11, 92, 32, 136, 114, 52, 10,
+	138, 30, 48, 183, 156, 35, 61, 26, 143, 74, 251, 94, 129, 162, 63, 152,
+	170, 7, 115, 167, 241, 206, 3, 150, 55, 59, 151, 220, 90, 53, 23, 131,
+	125, 173, 15, 238, 79, 95, 89, 16, 105, 137, 225, 224, 217, 160, 37, 123,
+	118, 73, 2, 157, 46, 116, 9, 145, 134, 228, 207, 212, 202, 215, 69, 229,
+	27, 188, 67, 124, 168, 252, 42, 4, 29, 108, 21, 247, 19, 205, 39, 203,
+	233, 40, 186, 147, 198, 192, 155, 33, 164, 191, 98, 204, 165, 180, 117, 76,
+	140, 36, 210, 172, 41, 54, 159, 8, 185, 232, 113, 196, 231, 47, 146, 120,
+	51, 65, 28, 144, 254, 221, 93, 189, 194, 139, 112, 43, 71, 109, 184, 209,
+}
+
+func tlshMapping(salt, i, j, k byte) byte {
+	h := tlshPearson[salt]
+	h = tlshPearson[h^i]
+	h = tlshPearson[h^j]
+	return tlshPearson[h^k]
+}
+
+// ComputeTLSH returns the "T1"-prefixed TLSH digest of data, or an empty string
+// when the input is too short or too uniform to produce a meaningful digest
+func ComputeTLSH(data []byte) string {
+	if len(data) < tlshMinDataLen {
+		return ""
+	}
+
+	var buckets [256]uint32
+	var checksum byte
+	var window [tlshWindow]byte
+	for n, c := range data {
+		j := n % tlshWindow
+		window[j] = c
+		if n < tlshWindow-1 {
+			continue
+		}
+		j1 := window[(j+4)%tlshWindow]
+		j2 := window[(j+3)%tlshWindow]
+		j3 := window[(j+2)%tlshWindow]
+		j4 := window[(j+1)%tlshWindow]
+
+		checksum = tlshMapping(0, c, j1, checksum)
+		buckets[tlshMapping(2, c, j1, j2)]++
+		buckets[tlshMapping(3, c, j1, j3)]++
+		buckets[tlshMapping(5, c, j2, j3)]++
+		buckets[tlshMapping(7, c, j2, j4)]++
+		buckets[tlshMapping(11, c, j1, j4)]++
+		buckets[tlshMapping(13, c, j3, j4)]++
+	}
+
+	sorted := make([]uint32, tlshBuckets)
+	copy(sorted, buckets[:tlshBuckets])
+	sort.Slice(sorted, func(a, b int) bool { return sorted[a] < sorted[b] })
+	q1, q2, q3 := sorted[tlshBuckets/4-1], sorted[tlshBuckets/2-1], sorted[tlshBuckets*3/4-1]
+	if q3 == 0 {
+		return ""
+	}
+
+	nonZero := 0
+	for _, b := range buckets[:tlshBuckets] {
+		if b > 0 {
+			nonZero++
+		}
+	}
+	if nonZero <= tlshBuckets/2 {
+		return ""
+	}
+
+	var code [tlshCodeSize]byte
+	for i := 0; i < tlshCodeSize; i++ {
+		var h byte
+		for j := 0; j < 4; j++ {
+			k := buckets[4*i+j]
+			switch {
+			case k > q3:
+				h |= 3 << (j * 2)
+			case k > q2:
+				h |= 2 << (j * 2)
+			case k > q1:
+				h |= 1 << (j * 2)
+			}
+		}
+		// The digest stores the body most-significant bucket first
+		code[tlshCodeSize-1-i] = h
+	}
+
+	q1Ratio := byte(uint64(q1)*100/uint64(q3)) % 16
+	q2Ratio := byte(uint64(q2)*100/uint64(q3)) % 16
+
+	digest := make([]byte, 0, tlshDigestBytes)
+	digest = append(digest, swapNibbles(checksum), swapNibbles(tlshLengthCode(len(data))), q1Ratio<<4|q2Ratio)
+	digest = append(digest, code[:]...)
+	return tlshVersionTag + strings.ToUpper(hex.EncodeToString(digest))
+}
+
+// tlshLengthCode buckets the input length on a logarithmic scale
+func tlshLengthCode(n int) byte {
+	l := math.Log(float64(n))
+	var v float64
+	switch {
+	case n <= 656:
+		v = math.Floor(l / math.Log(1.5))
+	case n <= 3199:
+		v = math.Floor(l/math.Log(1.3) - 8.72777)
+	default:
+		v = math.Floor(l/math.Log(1.1) - 62.5472)
+	}
+	return byte(int(v) & 0xff)
+}
+
+func swapNibbles(b byte) byte {
+	return b<<4 | b>>4
+}
+
+type tlshDigest struct {
+	checksum byte
+	lvalue   byte
+	q1Ratio  byte
+	q2Ratio  byte
+	code     [tlshCodeSize]byte
+}
+
+func parseTLSH(s string) (*tlshDigest, error) {
+	s = strings.TrimPrefix(strings.ToUpper(s), tlshVersionTag)
+	raw, err := hex.DecodeString(s)
+	if err != nil || len(raw) != tlshDigestBytes {
+		return nil, fmt.Errorf("invalid TLSH digest")
+	}
+	d := &tlshDigest{
+		checksum: swapNibbles(raw[0]),
+		lvalue:   swapNibbles(raw[1]),
+		q1Ratio:  raw[2] >> 4,
+		q2Ratio:  raw[2] & 0x0f,
+	}
+	copy(d.code[:], raw[3:])
+	return d, nil
+}
+
+// TLSHDistance returns the TLSH distance between two digests, including the
+// length component; 0 means near-identical and values above ~200 are unrelated
+func TLSHDistance(a, b string) (int, error) {
+	da, err := parseTLSH(a)
+	if err != nil {
+		return 0, err
+	}
+	db, err := parseTLSH(b)
+	if err != nil {
+		return 0, err
+	}
+
+	diff := 0
+	if ld := modDiff(int(da.lvalue), int(db.lvalue), 256); ld <= 1 {
+		diff += ld
+	} else {
+		diff += ld * 12
+	}
+	for _, qd := range []int{
+		modDiff(int(da.q1Ratio), int(db.q1Ratio), 16),
+		modDiff(int(da.q2Ratio), int(db.q2Ratio), 16),
+	} {
+		if qd <= 1 {
+			diff += qd
+		} else {
+			diff += (qd - 1) * 12
+		}
+	}
+	if da.checksum != db.checksum {
+		diff++
+	}
+
+	for i := range da.code {
+		x, y := da.code[i], db.code[i]
+		for j := 0; j < 4; j++ {
+			d := int(x>>(j*2)&3) - int(y>>(j*2)&3)
+			if d < 0 {
+				d = -d
+			}
+			// Opposite quartiles are penalised more heavily than neighbours
+			if d == 3 {
+				d = 6
+			}
+			diff += d
+		}
+	}
+	return diff, nil
+}
+
+// modDiff is the distance between x and y on a ring of size r
+func modDiff(x, y, r int) int {
+	var dl, dr int
+	if y > x {
+		dl = y - x
+		dr = x + r - y
+	} else {
+		dl = x - y
+		dr = y + r - x
+	}
+	if dl < dr {
+		return dl
+	}
+	return dr
+}
@@ -2,24 +2,45 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/ashborn3/BinTraceBench/internal/analyzer"
 	"github.com/ashborn3/BinTraceBench/internal/auth"
 	"github.com/ashborn3/BinTraceBench/internal/database"
+	"github.com/ashborn3/BinTraceBench/internal/rules"
 	"github.com/ashborn3/BinTraceBench/internal/sandbox"
+	"github.com/ashborn3/BinTraceBench/internal/syscalls"
 	"github.com/ashborn3/BinTraceBench/internal/validation"
+	"github.com/ashborn3/BinTraceBench/internal/yara"
 	"github.com/ashborn3/BinTraceBench/pkg/logging"
 )
 
 type AnalyzeResponse struct {
-	ID      int                            `json:"id,omitempty"`
-	Static  *analyzer.BinaryInfo           `json:"static"`
-	Dynamic []analyzer.VerboseSyscallEntry `json:"dynamic,omitempty"`
-	Cached  bool                           `json:"cached,omitempty"`
+	ID        int                            `json:"id,omitempty"`
+	Static    *analyzer.BinaryInfo           `json:"static"`
+	Dynamic   []analyzer.VerboseSyscallEntry `json:"dynamic,omitempty"`
+	Processes []analyzer.TracedProcess       `json:"process_tree,omitempty"`
+	Summary   *syscalls.Summary              `json:"syscall_summary,omitempty"`
+	TLSH      string                         `json:"tlsh,omitempty"`
+	SSDeep    string                         `json:"ssdeep,omitempty"`
+	Rules     []rules.Match                  `json:"rule_matches,omitempty"`
+	Yara      []yara.Match                   `json:"yara_matches,omitempty"`
+	Cached    bool                           `json:"cached,omitempty"`
 }
 
-func AnalyzeHandler(db database.Database) http.HandlerFunc {
+// analyzeOptions are the per-request switches of POST /analyze
+type analyzeOptions struct {
+	dynamic bool
+	strings bool
+	yara    bool
+	minLen  int
+	// companion is the executable a core dump is symbolized against
+	companion []byte
+}
+
+func AnalyzeHandler(db database.Database, ruleEngine *rules.Engine, yaraScanner *yara.Scanner) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		user := auth.GetUserFromContext(r.Context())
 		if user == nil {
@@ -27,7 +48,20 @@ func AnalyzeHandler(db database.Database) http.HandlerFunc {
 			return
 		}
 
-		isDyna := r.URL.Query().Get("dynamic") == "true"
+		opts := analyzeOptions{
+			dynamic: r.URL.Query().Get("dynamic") == "true",
+			strings: r.URL.Query().Get("strings") == "true",
+			yara:    r.URL.Query().Get("yara") == "true",
+			minLen:  analyzer.DefaultMinStringLength,
+		}
+		if v := r.URL.Query().Get("min_len"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < analyzer.MinStringLength {
+				http.Error(w, fmt.Sprintf("Invalid min_len (must be at least %d)", analyzer.MinStringLength), http.StatusBadRequest)
+				return
+			}
+			opts.minLen = n
+		}
 
 		header, data, err := validation.ValidateFileUpload(r)
 		if err != nil {
@@ -36,77 +70,206 @@ func AnalyzeHandler(db database.Database) http.HandlerFunc {
 			return
 		}
 
-		// Validate binary before analysis
-		if err := sandbox.ValidateBinary(data); err != nil {
-			http.Error(w, "Binary validation failed: "+err.Error(), http.StatusBadRequest)
+		opts.companion, err = validation.OptionalFormFile(r, "executable")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-
-		fileHash := auth.GenerateFileHash(data)
-		filename := header.Filename
-
-		if cached, err := db.GetAnalysisResultByHash(user.ID, fileHash); err == nil && cached != nil {
-			logging.Info("Returning cached analysis", "user", user.Username, "file", filename)
-			response := AnalyzeResponse{
-				ID:      cached.ID,
-				Static:  cached.StaticData,
-				Dynamic: cached.DynamicData,
-				Cached:  true,
+		if opts.companion != nil {
+			if err := sandbox.ValidateBinary(opts.companion); err != nil {
+				http.Error(w, "Companion executable must be an ELF binary: "+err.Error(), http.StatusBadRequest)
+				return
 			}
+		}
 
-			if isDyna && len(cached.DynamicData) == 0 {
-				dynaResult, err := analyzer.TraceBinary(data)
-				if err != nil {
-					http.Error(w, "Dynamic analysis failed: "+err.Error(), http.StatusInternalServerError)
-					return
-				}
-				response.Dynamic = dynaResult
-				response.Cached = false
-
-				cached.DynamicData = dynaResult
-				db.SaveAnalysisResult(cached) // This will update if ID exists
+		if format := validation.DetectArchive(data); format != "" {
+			if opts.dynamic || opts.companion != nil {
+				http.Error(w, "Dynamic analysis and companion executables are not supported for archive uploads", http.StatusBadRequest)
+				return
 			}
+			analyzeArchive(w, db, ruleEngine, yaraScanner, user, header.Filename, format, data, opts)
+			return
+		}
 
-			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(response)
+		// Validate binary before analysis; only ELF files can be traced
+		if err := sandbox.ValidateStaticBinary(data); err != nil {
+			http.Error(w, "Binary validation failed: "+err.Error(), http.StatusBadRequest)
 			return
 		}
+		if opts.dynamic {
+			if err := sandbox.ValidateBinary(data); err != nil {
+				http.Error(w, "Dynamic analysis requires an ELF binary: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
 
-		result, err := analyzer.AnalyzeBinary(data)
+		res, err := analyzeSample(db, ruleEngine, yaraScanner, user, header.Filename, data, opts)
 		if err != nil {
-			http.Error(w, "Failed to analyze binary: "+err.Error(), http.StatusInternalServerError)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		var dynaResult []analyzer.VerboseSyscallEntry
-		if isDyna {
-			dynaResult, err = analyzer.TraceBinary(data)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(res)
+	}
+}
+
+// analyzeSample runs the analysis pipeline on one validated file, reusing and
+// topping up the user's cached result for the same hash when there is one
+func analyzeSample(db database.Database, ruleEngine *rules.Engine, yaraScanner *yara.Scanner,
+	user *database.User, filename string, data []byte, opts analyzeOptions) (*AnalyzeResponse, error) {
+	fileHash := auth.GenerateFileHash(data)
+
+	// Keep the sample so views such as disassembly can be computed later
+	if err := db.SaveSample(fileHash, data); err != nil {
+		logging.Warn("Failed to store sample", "error", err, "file", filename)
+	}
+
+	if cached, err := db.GetAnalysisResultByHash(user.ID, fileHash); err == nil && cached != nil {
+		logging.Info("Returning cached analysis", "user", user.Username, "file", filename)
+		response := &AnalyzeResponse{
+			ID:        cached.ID,
+			Static:    cached.StaticData,
+			Dynamic:   cached.DynamicData,
+			Processes: cached.ProcessTree,
+			Summary:   cached.SyscallSummary,
+			TLSH:      cached.TLSH,
+			SSDeep:    cached.SSDeep,
+			Rules:     cached.RuleMatches,
+			Yara:      cached.YaraMatches,
+			Cached:    true,
+		}
+
+		// Results stored before fuzzy hashing existed get their digests now, so
+		// they show up in similarity searches
+		updated := false
+		if cached.TLSH == "" && cached.SSDeep == "" {
+			fuzzy := analyzer.ComputeFuzzyHashes(data)
+			cached.TLSH, cached.SSDeep = fuzzy.TLSH, fuzzy.SSDeep
+			response.TLSH, response.SSDeep = fuzzy.TLSH, fuzzy.SSDeep
+			updated = true
+		}
+
+		if opts.dynamic && len(cached.DynamicData) == 0 {
+			trace, err := analyzer.TraceBinary(data)
 			if err != nil {
-				http.Error(w, "Dynamic analysis failed: "+err.Error(), http.StatusInternalServerError)
-				return
+				return nil, fmt.Errorf("Dynamic analysis failed: %v", err)
 			}
+			response.Dynamic, response.Processes, response.Summary = trace.Syscalls, trace.Processes, trace.Summary
+			cached.DynamicData, cached.ProcessTree, cached.SyscallSummary = trace.Syscalls, trace.Processes, trace.Summary
+			updated = true
 		}
 
-		analysisResult := &database.AnalysisResult{
-			UserID:      user.ID,
-			Filename:    filename,
-			FileHash:    fileHash,
-			StaticData:  result,
-			DynamicData: dynaResult,
+		if opts.strings && cached.StaticData != nil &&
+			(cached.StaticData.Strings == nil || cached.StaticData.Strings.MinLength != opts.minLen) {
+			cached.StaticData.Strings = extractStrings(data, cached.StaticData, opts.minLen)
+			updated = true
 		}
 
-		if err := db.SaveAnalysisResult(analysisResult); err != nil {
-			// Log error but don't fail the request
-			// The analysis was successful, saving is a bonus
+		// Rescan every time: the rule set may have been reloaded since
+		if opts.yara {
+			yaraMatches := yaraScanner.Scan(data)
+			response.Yara = yaraMatches
+			if !sameYaraRules(cached.YaraMatches, yaraMatches) {
+				cached.YaraMatches = yaraMatches
+				updated = true
+			}
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		res := AnalyzeResponse{
-			ID:      analysisResult.ID,
-			Static:  result,
-			Dynamic: dynaResult,
-			Cached:  false,
+		if opts.companion != nil && cached.StaticData != nil && cached.StaticData.Core != nil &&
+			cached.StaticData.Core.CompanionSHA256 != auth.GenerateFileHash(opts.companion) {
+			symbolizeCore(cached.StaticData, opts.companion)
+			updated = true
 		}
-		json.NewEncoder(w).Encode(res)
+
+		// Like YARA, re-evaluate every time since the rules may have been reloaded
+		ruleMatches := evaluateRules(ruleEngine, data, cached.StaticData, cached.DynamicData)
+		response.Rules = ruleMatches
+		if !sameRuleMatches(cached.RuleMatches, ruleMatches) {
+			cached.RuleMatches = ruleMatches
+			updated = true
+		}
+
+		if updated {
+			response.Cached = false
+			if err := db.UpdateAnalysisResult(cached); err != nil {
+				logging.Warn("Failed to update cached analysis", "error", err, "id", cached.ID)
+			}
+		}
+		return response, nil
+	}
+
+	result, err := analyzer.AnalyzeBinary(data)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to analyze binary: %v", err)
+	}
+
+	if opts.strings {
+		result.Strings = extractStrings(data, result, opts.minLen)
+	}
+	if opts.companion != nil && result.Core != nil {
+		symbolizeCore(result, opts.companion)
+	}
+
+	var dynaResult []analyzer.VerboseSyscallEntry
+	var procs []analyzer.TracedProcess
+	var summary *syscalls.Summary
+	if opts.dynamic {
+		trace, err := analyzer.TraceBinary(data)
+		if err != nil {
+			return nil, fmt.Errorf("Dynamic analysis failed: %v", err)
+		}
+		dynaResult, procs, summary = trace.Syscalls, trace.Processes, trace.Summary
+	}
+
+	fuzzy := analyzer.ComputeFuzzyHashes(data)
+	ruleMatches := evaluateRules(ruleEngine, data, result, dynaResult)
+	var yaraMatches []yara.Match
+	if opts.yara {
+		yaraMatches = yaraScanner.Scan(data)
+	}
+	analysisResult := &database.AnalysisResult{
+		UserID:         user.ID,
+		Filename:       filename,
+		FileHash:       fileHash,
+		TLSH:           fuzzy.TLSH,
+		SSDeep:         fuzzy.SSDeep,
+		StaticData:     result,
+		DynamicData:    dynaResult,
+		ProcessTree:    procs,
+		RuleMatches:    ruleMatches,
+		SyscallSummary: summary,
+		YaraMatches:    yaraMatches,
+	}
+
+	if err := db.SaveAnalysisResult(analysisResult); err != nil {
+		// Log error but don't fail the request
+		// The analysis was successful, saving is a bonus
+	}
+
+	return &AnalyzeResponse{
+		ID:        analysisResult.ID,
+		Static:    result,
+		Dynamic:   dynaResult,
+		Processes: procs,
+		Summary:   summary,
+		TLSH:      fuzzy.TLSH,
+		SSDeep:    fuzzy.SSDeep,
+		Rules:     ruleMatches,
+		Yara:      yaraMatches,
+		Cached:    false,
+	}, nil
+}
+
+func extractStrings(data []byte, info *analyzer.BinaryInfo, minLen int) *analyzer.StringsResult {
+	return analyzer.ScanStrings(data, info.Sections, minLen, analyzer.MaxStoredStrings)
+}
+
+// symbolizeCore names core dump stack frames after the companion executable's
+// symbols; a mismatched companion is reported in the result, not as a failure
+func symbolizeCore(info *analyzer.BinaryInfo, exe []byte) {
+	info.Core.SymbolizeError = ""
+	if err := analyzer.SymbolizeCore(info, exe); err != nil {
+		info.Core.SymbolizeError = err.Error()
 	}
 }
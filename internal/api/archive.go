@@ -0,0 +1,131 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ashborn3/BinTraceBench/internal/database"
+	"github.com/ashborn3/BinTraceBench/internal/rules"
+	"github.com/ashborn3/BinTraceBench/internal/sandbox"
+	"github.com/ashborn3/BinTraceBench/internal/validation"
+	"github.com/ashborn3/BinTraceBench/internal/yara"
+	"github.com/ashborn3/BinTraceBench/pkg/logging"
+)
+
+type ArchiveMemberResult struct {
+	Name    string           `json:"name"`
+	Size    int              `json:"size"`
+	Skipped string           `json:"skipped,omitempty"` // why the member was not analyzed
+	Error   string           `json:"error,omitempty"`
+	Result  *AnalyzeResponse `json:"result,omitempty"`
+}
+
+type ArchiveSummary struct {
+	Members       int            `json:"members"`
+	Analyzed      int            `json:"analyzed"`
+	Skipped       int            `json:"skipped"`
+	Failed        int            `json:"failed"`
+	Cached        int            `json:"cached"`
+	Machines      map[string]int `json:"machines,omitempty"`
+	Types         map[string]int `json:"types,omitempty"`
+	LikelyPacked  int            `json:"likely_packed"`
+	WithEmbedded  int            `json:"with_embedded"`
+	WithRuleMatch int            `json:"with_rule_matches"`
+	WithYaraMatch int            `json:"with_yara_matches"`
+	Rules         map[string]int `json:"rules,omitempty"`      // members matching each detection rule
+	YaraRules     map[string]int `json:"yara_rules,omitempty"` // members matching each YARA rule
+}
+
+type ArchiveAnalyzeResponse struct {
+	Archive string                `json:"archive"`
+	Format  string                `json:"format"`
+	Members []ArchiveMemberResult `json:"members"`
+	Summary ArchiveSummary        `json:"summary"`
+}
+
+// analyzeArchive unpacks an uploaded archive and analyzes every member the
+// static analyzer understands. Each member is stored as its own analysis
+// result, named "<archive>:<member>".
+func analyzeArchive(w http.ResponseWriter, db database.Database, ruleEngine *rules.Engine, yaraScanner *yara.Scanner,
+	user *database.User, filename, format string, data []byte, opts analyzeOptions) {
+	members, err := validation.UnpackArchive(format, data)
+	if err != nil {
+		logging.Warn("Archive unpacking failed", "error", err, "file", filename, "user", user.Username)
+		http.Error(w, "Archive validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response := ArchiveAnalyzeResponse{
+		Archive: filename,
+		Format:  format,
+		Members: make([]ArchiveMemberResult, 0, len(members)),
+	}
+	summary := &response.Summary
+	summary.Members = len(members)
+
+	for _, m := range members {
+		entry := ArchiveMemberResult{Name: m.Name, Size: len(m.Data)}
+		if err := sandbox.ValidateStaticBinary(m.Data); err != nil {
+			entry.Skipped = err.Error()
+			summary.Skipped++
+			response.Members = append(response.Members, entry)
+			continue
+		}
+
+		res, err := analyzeSample(db, ruleEngine, yaraScanner, user, filename+":"+m.Name, m.Data, opts)
+		if err != nil {
+			entry.Error = err.Error()
+			summary.Failed++
+			response.Members = append(response.Members, entry)
+			continue
+		}
+		entry.Result = res
+		summary.add(res)
+		response.Members = append(response.Members, entry)
+	}
+
+	logging.Info("Archive analyzed", "file", filename, "format", format, "members", summary.Members,
+		"analyzed", summary.Analyzed, "user", user.Username)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (s *ArchiveSummary) add(res *AnalyzeResponse) {
+	s.Analyzed++
+	if res.Cached {
+		s.Cached++
+	}
+	if info := res.Static; info != nil {
+		countKey(&s.Machines, info.Machine)
+		countKey(&s.Types, info.Type)
+		if info.Packing != nil && info.Packing.LikelyPacked {
+			s.LikelyPacked++
+		}
+		if len(info.Embedded) > 0 {
+			s.WithEmbedded++
+		}
+	}
+	if len(res.Rules) > 0 {
+		s.WithRuleMatch++
+		for _, m := range res.Rules {
+			countKey(&s.Rules, m.RuleID)
+		}
+	}
+	if len(res.Yara) > 0 {
+		s.WithYaraMatch++
+		for _, m := range res.Yara {
+			countKey(&s.YaraRules, m.Rule)
+		}
+	}
+}
+
+func countKey(counts *map[string]int, key string) {
+	if key == "" {
+		return
+	}
+	if *counts == nil {
+		*counts = map[string]int{}
+	}
+	(*counts)[key]++
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ashborn3/BinTraceBench/internal/analyzer"
+	"github.com/ashborn3/BinTraceBench/internal/database"
+)
+
+func CallGraphHandler(db database.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		result, ok := getOwnedAnalysisResult(w, r, db)
+		if !ok {
+			return
+		}
+
+		format := r.URL.Query().Get("format")
+		if format != "" && format != "json" && format != "dot" {
+			http.Error(w, "Invalid format (must be 'json' or 'dot')", http.StatusBadRequest)
+			return
+		}
+
+		data, ok := getSample(w, db, result)
+		if !ok {
+			return
+		}
+
+		graph, err := analyzer.BuildCallGraph(data)
+		if err != nil {
+			http.Error(w, "Call graph recovery failed: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if format == "dot" {
+			w.Header().Set("Content-Type", "text/vnd.graphviz")
+			w.Write([]byte(graph.DOT()))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(graph)
+	}
+}
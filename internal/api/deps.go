@@ -0,0 +1,66 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+
+	"github.com/ashborn3/BinTraceBench/internal/analyzer"
+	"github.com/ashborn3/BinTraceBench/internal/config"
+	"github.com/ashborn3/BinTraceBench/internal/database"
+)
+
+func DependenciesHandler(db database.Database, cfg config.AnalysisConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		result, ok := getOwnedAnalysisResult(w, r, db)
+		if !ok {
+			return
+		}
+
+		opts := analyzer.ResolverOptions{
+			Sysroot:     cfg.Sysroot,
+			LibraryDirs: cfg.LibraryDirs,
+			OriginDir:   r.URL.Query().Get("origin"),
+		}
+		if opts.OriginDir != "" && !path.IsAbs(opts.OriginDir) {
+			http.Error(w, "Invalid origin (must be an absolute path)", http.StatusBadRequest)
+			return
+		}
+
+		// Named sysroots are the user's uploads or shared ones in SysrootsDir
+		name := r.URL.Query().Get("sysroot")
+		if name != "" {
+			if !validSysrootName(name) {
+				http.Error(w, "Invalid sysroot name", http.StatusBadRequest)
+				return
+			}
+			dir, found := findSysroot(cfg, result.UserID, name)
+			if !found {
+				http.Error(w, "Sysroot not found", http.StatusNotFound)
+				return
+			}
+			opts.Sysroot = dir
+		}
+		if opts.Sysroot == "" {
+			http.Error(w, "No sysroot configured, upload one with POST /sysroots and pass ?sysroot=name", http.StatusBadRequest)
+			return
+		}
+
+		data, ok := getSample(w, db, result)
+		if !ok {
+			return
+		}
+
+		tree, err := analyzer.ResolveDependencies(data, opts)
+		if err != nil {
+			http.Error(w, "Dependency resolution failed: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Report the sysroot by name rather than its location on the server
+		tree.Sysroot = name
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tree)
+	}
+}
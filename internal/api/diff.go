@@ -0,0 +1,41 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ashborn3/BinTraceBench/internal/analyzer"
+	"github.com/ashborn3/BinTraceBench/internal/database"
+)
+
+type DiffResponse struct {
+	A    int                  `json:"a"`
+	B    int                  `json:"b"`
+	Diff *analyzer.BinaryDiff `json:"diff"`
+}
+
+func DiffHandler(db database.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("a") == "" || r.URL.Query().Get("b") == "" {
+			http.Error(w, "Both ?a= and ?b= result IDs are required", http.StatusBadRequest)
+			return
+		}
+
+		a, ok := loadOwnedAnalysisResult(w, r, db, r.URL.Query().Get("a"))
+		if !ok {
+			return
+		}
+		b, ok := loadOwnedAnalysisResult(w, r, db, r.URL.Query().Get("b"))
+		if !ok {
+			return
+		}
+
+		diff := analyzer.DiffBinaries(a.StaticData, b.StaticData)
+		if len(a.DynamicData) > 0 && len(b.DynamicData) > 0 {
+			diff.Syscalls = analyzer.DiffSyscalls(a.DynamicData, b.DynamicData)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(DiffResponse{A: a.ID, B: b.ID, Diff: diff})
+	}
+}
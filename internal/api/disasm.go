@@ -0,0 +1,43 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/ashborn3/BinTraceBench/internal/analyzer"
+	"github.com/ashborn3/BinTraceBench/internal/database"
+)
+
+func DisassembleHandler(db database.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		result, ok := getOwnedAnalysisResult(w, r, db)
+		if !ok {
+			return
+		}
+
+		count := analyzer.DefaultDisasmCount
+		if v := r.URL.Query().Get("count"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				http.Error(w, "Invalid count", http.StatusBadRequest)
+				return
+			}
+			count = n
+		}
+
+		data, ok := getSample(w, db, result)
+		if !ok {
+			return
+		}
+
+		dis, err := analyzer.Disassemble(data, r.URL.Query().Get("symbol"), count)
+		if err != nil {
+			http.Error(w, "Disassembly failed: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(dis)
+	}
+}
@@ -4,7 +4,11 @@ import (
 	"net/http"
 
 	"github.com/ashborn3/BinTraceBench/internal/auth"
+	"github.com/ashborn3/BinTraceBench/internal/config"
 	"github.com/ashborn3/BinTraceBench/internal/database"
+	"github.com/ashborn3/BinTraceBench/internal/rules"
+	"github.com/ashborn3/BinTraceBench/internal/yara"
+	"github.com/ashborn3/BinTraceBench/pkg/logging"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -18,12 +22,28 @@ API Documentation:
   POST /auth/login    - Login user
   GET  /auth/me       - Get current user info
   POST /auth/logout   - Logout user
-  POST /analyze       - Analyze binary (with optional ?dynamic=true)
-  GET  /analyze       - List all analysis results
-  GET  /analyze/{id}  - Get specific analysis result
+  POST /analyze       - Analyze binary or ar/tar(.gz)/zip archive (with optional ?dynamic=true, ?strings=true&min_len=N, ?yara=true)
+  POST /analyze       - ELF core dump; an optional 'executable' form file symbolizes its stack frames
+  GET  /analyze       - List all analysis results (?import_hash=&text_hash=&section_hash=)
+  GET  /analyze/diff  - Compare two analysis results (?a={id}&b={id})
+  GET  /analyze/{id}  - Get specific analysis result (?demangle=true)
+  GET  /analyze/{id}/strings - Get extracted strings (?offset=&limit=&category=&section=)
+  GET  /analyze/{id}/disasm  - Disassemble entry point or symbol (?symbol=main&count=200)
+  GET  /analyze/{id}/callgraph - Recovered functions and call graph (?format=json|dot)
+  GET  /analyze/{id}/deps      - Resolve shared library dependencies (?sysroot=name&origin=/usr/bin)
+  GET  /analyze/{id}/similar   - Previous results ranked by TLSH/ssdeep similarity (?min_score=&offset=&limit=)
+  GET  /analyze/{id}/syscalls/summary - Per-syscall counts, errors and time of a dynamic trace
+  POST /sysroots      - Upload a tar(.gz)/zip root filesystem for dependency resolution (?name=)
+  GET  /sysroots      - List uploaded and shared sysroots
+  DELETE /sysroots/{name} - Delete an uploaded sysroot
+  GET  /rules         - List loaded detection rules
+  POST /admin/rules/reload - Reload detection rules from disk (admin only)
+  GET  /yara          - List loaded YARA rules and skipped unsupported ones
+  POST /admin/yara/reload  - Reload YARA rules from disk (admin only)
   POST /bench         - Benchmark binary (with optional ?trace=true)
   GET  /bench         - List all benchmark results
   GET  /bench/{id}    - Get specific benchmark result
+  GET  /bench/{id}/syscalls/summary - Per-syscall counts, errors and time of a traced benchmark
   GET  /proc/{pid}    - Inspect process
   GET  /proc/{pid}/files - Get process open files
   GET  /proc/{pid}/net   - Get process network connections
@@ -32,10 +52,24 @@ All endpoints except /auth/register and /auth/login require authentication
 Use Authorization: Bearer <token> header for authenticated requests
 `
 
-func RegisterRoutes(router chi.Router, db database.Database) {
+func RegisterRoutes(router chi.Router, db database.Database, cfg *config.Config) {
 	authMiddleware := auth.NewMiddleware(db)
 	authHandler := auth.NewHandler(db)
 
+	ruleEngine := rules.NewEngine(cfg.Analysis.RulesDir)
+	if count, err := ruleEngine.Reload(); err != nil {
+		logging.Warn("Failed to load detection rules, starting with none", "dir", cfg.Analysis.RulesDir, "error", err)
+	} else {
+		logging.Info("Detection rules loaded", "dir", cfg.Analysis.RulesDir, "count", count)
+	}
+
+	yaraScanner := yara.NewScanner(cfg.Analysis.YaraDir)
+	if count, err := yaraScanner.Reload(); err != nil {
+		logging.Warn("Failed to load YARA rules, starting with none", "dir", cfg.Analysis.YaraDir, "error", err)
+	} else {
+		logging.Info("YARA rules loaded", "dir", cfg.Analysis.YaraDir, "count", count, "skipped", len(yaraScanner.Skipped()))
+	}
+
 	// Public routes
 	router.Get("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(api_docs))
@@ -59,15 +93,34 @@ func RegisterRoutes(router chi.Router, db database.Database) {
 		r.Post("/auth/logout", authHandler.Logout())
 
 		// Binary analysis routes
-		r.Post("/analyze", AnalyzeHandler(db))
+		r.Post("/analyze", AnalyzeHandler(db, ruleEngine, yaraScanner))
 		r.Get("/analyze", GetAnalysisResultsHandler(db))
+		r.Get("/analyze/diff", DiffHandler(db))
 		r.Get("/analyze/{id}", GetAnalysisResultHandler(db))
 		r.Delete("/analyze/{id}", DeleteAnalysisResultHandler(db))
+		r.Get("/analyze/{id}/strings", GetAnalysisStringsHandler(db))
+		r.Get("/analyze/{id}/disasm", DisassembleHandler(db))
+		r.Get("/analyze/{id}/callgraph", CallGraphHandler(db))
+		r.Get("/analyze/{id}/deps", DependenciesHandler(db, cfg.Analysis))
+		r.Get("/analyze/{id}/similar", SimilarResultsHandler(db))
+		r.Get("/analyze/{id}/syscalls/summary", AnalysisSyscallSummaryHandler(db))
+
+		// Sysroots for dependency resolution
+		r.Post("/sysroots", UploadSysrootHandler(cfg.Analysis))
+		r.Get("/sysroots", ListSysrootsHandler(cfg.Analysis))
+		r.Delete("/sysroots/{name}", DeleteSysrootHandler(cfg.Analysis))
+
+		// Detection rules; reloading is restricted to admins
+		r.Get("/rules", ListRulesHandler(ruleEngine))
+		r.With(authMiddleware.RequireRole("admin")).Post("/admin/rules/reload", ReloadRulesHandler(ruleEngine))
+		r.Get("/yara", ListYaraRulesHandler(yaraScanner))
+		r.With(authMiddleware.RequireRole("admin")).Post("/admin/yara/reload", ReloadYaraRulesHandler(yaraScanner))
 
 		// Benchmark routes
 		r.Post("/bench", BenchmarkHandler(db))
 		r.Get("/bench", GetBenchmarkResultsHandler(db))
 		r.Get("/bench/{id}", GetBenchmarkResultHandler(db))
+		r.Get("/bench/{id}/syscalls/summary", BenchSyscallSummaryHandler(db))
 		r.Delete("/bench/{id}", DeleteBenchmarkResultHandler(db))
 
 		// Process inspection routes - these can be public but are now protected
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+
+	"github.com/ashborn3/BinTraceBench/internal/analyzer"
+	"github.com/ashborn3/BinTraceBench/internal/auth"
+	"github.com/ashborn3/BinTraceBench/internal/rules"
+	"github.com/ashborn3/BinTraceBench/pkg/logging"
+)
+
+type RulesResponse struct {
+	Dir   string        `json:"dir"`
+	Count int           `json:"count"`
+	Rules []*rules.Rule `json:"rules"`
+}
+
+func ListRulesHandler(engine *rules.Engine) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		loaded := engine.Rules()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(RulesResponse{
+			Dir:   engine.Dir(),
+			Count: len(loaded),
+			Rules: loaded,
+		})
+	}
+}
+
+func ReloadRulesHandler(engine *rules.Engine) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := auth.GetUserFromContext(r.Context())
+
+		count, err := engine.Reload()
+		if err != nil {
+			logging.Warn("Rule reload failed", "error", err, "user", user.Username)
+			http.Error(w, "Rule reload failed, previous rules kept: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		logging.Info("Rules reloaded", "count", count, "user", user.Username)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"message": "Rules reloaded", "count": count})
+	}
+}
+
+// evaluateRules runs the loaded detections over one analysis
+func evaluateRules(engine *rules.Engine, data []byte, info *analyzer.BinaryInfo, dynamic []analyzer.VerboseSyscallEntry) []rules.Match {
+	in := &rules.Input{Static: info, Data: data, Syscalls: dynamic}
+	if info != nil && info.Strings != nil {
+		in.Strings = info.Strings.Strings
+	}
+	return engine.Evaluate(in)
+}
+
+// sameRuleMatches reports whether two evaluations produced the same matches,
+// so that unchanged cached results are not rewritten
+func sameRuleMatches(a, b []rules.Match) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].RuleID != b[i].RuleID || a[i].Severity != b[i].Severity ||
+			a[i].Description != b[i].Description || !slices.Equal(a[i].Tags, b[i].Tags) ||
+			!slices.Equal(a[i].Evidence, b[i].Evidence) {
+			return false
+		}
+	}
+	return true
+}
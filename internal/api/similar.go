@@ -0,0 +1,131 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ashborn3/BinTraceBench/internal/analyzer"
+	"github.com/ashborn3/BinTraceBench/internal/database"
+	"github.com/ashborn3/BinTraceBench/pkg/logging"
+)
+
+const (
+	defaultSimilarPageSize = 20
+	maxSimilarPageSize     = 200
+)
+
+type SimilarResult struct {
+	ID       int       `json:"id"`
+	Filename string    `json:"filename"`
+	FileHash string    `json:"file_hash"`
+	Created  time.Time `json:"created"`
+	analyzer.Similarity
+}
+
+type SimilarResponse struct {
+	ID      int             `json:"id"`
+	TLSH    string          `json:"tlsh,omitempty"`
+	SSDeep  string          `json:"ssdeep,omitempty"`
+	Total   int             `json:"total"`
+	Results []SimilarResult `json:"results"`
+}
+
+func SimilarResultsHandler(db database.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		result, ok := getOwnedAnalysisResult(w, r, db)
+		if !ok {
+			return
+		}
+
+		offset, limit, err := parsePagination(r, defaultSimilarPageSize, maxSimilarPageSize)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		minScore := 1
+		if v := r.URL.Query().Get("min_score"); v != "" {
+			if minScore, err = strconv.Atoi(v); err != nil || minScore < 0 || minScore > 100 {
+				http.Error(w, "Invalid min_score (must be 0-100)", http.StatusBadRequest)
+				return
+			}
+		}
+
+		// Results stored before fuzzy hashing was added are digested from the sample
+		target := analyzer.FuzzyHashes{TLSH: result.TLSH, SSDeep: result.SSDeep}
+		if target.TLSH == "" && target.SSDeep == "" {
+			data, ok := getSample(w, db, result)
+			if !ok {
+				return
+			}
+			target = analyzer.ComputeFuzzyHashes(data)
+			backfillDigests(db, result.FileHash, target)
+		}
+
+		candidates, err := db.GetAnalysisDigestsByUser(result.UserID)
+		if err != nil {
+			http.Error(w, "Failed to get analysis results: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// Candidates arrive newest first; keep one entry per distinct sample and
+		// skip byte-identical copies, which the SHA-256 cache already covers
+		seen := map[string]bool{result.FileHash: true}
+		matches := []SimilarResult{}
+		for _, c := range candidates {
+			if seen[c.FileHash] {
+				continue
+			}
+			seen[c.FileHash] = true
+
+			digests := analyzer.FuzzyHashes{TLSH: c.TLSH, SSDeep: c.SSDeep}
+			if digests.TLSH == "" && digests.SSDeep == "" {
+				data, err := db.GetSample(c.FileHash)
+				if err != nil || data == nil {
+					continue
+				}
+				digests = analyzer.ComputeFuzzyHashes(data)
+				backfillDigests(db, c.FileHash, digests)
+			}
+			sim := analyzer.CompareFuzzyHashes(target, digests)
+			if sim.Score < minScore {
+				continue
+			}
+			matches = append(matches, SimilarResult{
+				ID:         c.ID,
+				Filename:   c.Filename,
+				FileHash:   c.FileHash,
+				Created:    c.Created,
+				Similarity: sim,
+			})
+		}
+		sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+
+		page := []SimilarResult{}
+		if offset < len(matches) {
+			page = matches[offset:min(offset+limit, len(matches))]
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(SimilarResponse{
+			ID:      result.ID,
+			TLSH:    target.TLSH,
+			SSDeep:  target.SSDeep,
+			Total:   len(matches),
+			Results: page,
+		})
+	}
+}
+
+// backfillDigests stores digests computed for results that predate fuzzy
+// hashing, so that each old sample is only digested once
+func backfillDigests(db database.Database, fileHash string, digests analyzer.FuzzyHashes) {
+	if digests.TLSH == "" && digests.SSDeep == "" {
+		return
+	}
+	if err := db.BackfillAnalysisDigests(fileHash, digests.TLSH, digests.SSDeep); err != nil {
+		logging.Warn("Failed to store fuzzy digests", "error", err, "hash", fileHash)
+	}
+}
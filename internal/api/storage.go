@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
 
@@ -24,6 +25,28 @@ func GetAnalysisResultsHandler(db database.Database) http.HandlerFunc {
 			return
 		}
 
+		importHash := r.URL.Query().Get("import_hash")
+		textHash := r.URL.Query().Get("text_hash")
+		sectionHash := r.URL.Query().Get("section_hash")
+		if importHash != "" || textHash != "" || sectionHash != "" {
+			filtered := []*database.AnalysisResult{}
+			for _, result := range results {
+				if result.StaticData.MatchesFingerprint(importHash, textHash, sectionHash) {
+					filtered = append(filtered, result)
+				}
+			}
+			results = filtered
+		}
+
+		// Keep the list small: stored strings are served by /analyze/{id}/strings
+		for _, result := range results {
+			if result.StaticData != nil && result.StaticData.Strings != nil {
+				counts := *result.StaticData.Strings
+				counts.Strings = nil
+				result.StaticData.Strings = &counts
+			}
+		}
+
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(results)
 	}
@@ -60,6 +83,10 @@ func GetAnalysisResultHandler(db database.Database) http.HandlerFunc {
 			return
 		}
 
+		if r.URL.Query().Get("demangle") == "true" {
+			result.StaticData.DemangleSymbols()
+		}
+
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(result)
 	}
@@ -202,3 +229,77 @@ func DeleteBenchmarkResultHandler(db database.Database) http.HandlerFunc {
 		json.NewEncoder(w).Encode(map[string]string{"message": "Benchmark result deleted successfully"})
 	}
 }
+
+// getOwnedAnalysisResult loads the analysis result named by the {id} URL
+// parameter, writing the error response itself when the result is missing or
+// belongs to another user
+func getOwnedAnalysisResult(w http.ResponseWriter, r *http.Request, db database.Database) (*database.AnalysisResult, bool) {
+	return loadOwnedAnalysisResult(w, r, db, chi.URLParam(r, "id"))
+}
+
+// loadOwnedAnalysisResult is getOwnedAnalysisResult for an ID taken from
+// anywhere in the request, such as a query parameter
+func loadOwnedAnalysisResult(w http.ResponseWriter, r *http.Request, db database.Database, idStr string) (*database.AnalysisResult, bool) {
+	user := auth.GetUserFromContext(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	result, err := db.GetAnalysisResult(id)
+	if err != nil {
+		http.Error(w, "Failed to get analysis result: "+err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if result == nil {
+		http.Error(w, "Analysis result not found", http.StatusNotFound)
+		return nil, false
+	}
+
+	if result.UserID != user.ID {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return nil, false
+	}
+
+	return result, true
+}
+
+// parsePagination reads offset/limit query parameters, clamping limit to max
+func parsePagination(r *http.Request, defaultLimit, maxLimit int) (offset, limit int, err error) {
+	limit = defaultLimit
+	if v := r.URL.Query().Get("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("invalid offset")
+		}
+	}
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 {
+			return 0, 0, fmt.Errorf("invalid limit")
+		}
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+	return offset, limit, nil
+}
+
+// getSample loads the stored bytes behind an analysis result
+func getSample(w http.ResponseWriter, db database.Database, result *database.AnalysisResult) ([]byte, bool) {
+	data, err := db.GetSample(result.FileHash)
+	if err != nil {
+		http.Error(w, "Failed to get sample: "+err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if data == nil {
+		http.Error(w, "Sample not stored for this result, re-upload it via POST /analyze", http.StatusNotFound)
+		return nil, false
+	}
+	return data, true
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ashborn3/BinTraceBench/internal/analyzer"
+	"github.com/ashborn3/BinTraceBench/internal/database"
+)
+
+const (
+	defaultStringsPageSize = 100
+	maxStringsPageSize     = 1000
+)
+
+type StringsPageResponse struct {
+	ID        int                        `json:"id"`
+	Total     int                        `json:"total"`
+	Stored    int                        `json:"stored"`
+	Truncated bool                       `json:"truncated"`
+	Offset    int                        `json:"offset"`
+	Limit     int                        `json:"limit"`
+	Strings   []analyzer.ExtractedString `json:"strings"`
+}
+
+func GetAnalysisStringsHandler(db database.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		result, ok := getOwnedAnalysisResult(w, r, db)
+		if !ok {
+			return
+		}
+
+		if result.StaticData == nil || result.StaticData.Strings == nil {
+			http.Error(w, "No strings stored for this result, re-submit with ?strings=true", http.StatusNotFound)
+			return
+		}
+
+		offset, limit, err := parsePagination(r, defaultStringsPageSize, maxStringsPageSize)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		category := r.URL.Query().Get("category")
+		section := r.URL.Query().Get("section")
+		stored := result.StaticData.Strings
+
+		var filtered []analyzer.ExtractedString
+		for _, s := range stored.Strings {
+			if category != "" && s.Category != category {
+				continue
+			}
+			if section != "" && s.Section != section {
+				continue
+			}
+			filtered = append(filtered, s)
+		}
+
+		page := []analyzer.ExtractedString{}
+		if offset < len(filtered) {
+			end := offset + limit
+			if end > len(filtered) {
+				end = len(filtered)
+			}
+			page = filtered[offset:end]
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(StringsPageResponse{
+			ID:        result.ID,
+			Total:     len(filtered),
+			Stored:    len(stored.Strings),
+			Truncated: stored.Truncated,
+			Offset:    offset,
+			Limit:     limit,
+			Strings:   page,
+		})
+	}
+}
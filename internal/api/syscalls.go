@@ -0,0 +1,86 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/ashborn3/BinTraceBench/internal/analyzer"
+	"github.com/ashborn3/BinTraceBench/internal/auth"
+	"github.com/ashborn3/BinTraceBench/internal/database"
+	"github.com/ashborn3/BinTraceBench/internal/syscalls"
+	"github.com/go-chi/chi/v5"
+)
+
+type SyscallSummaryResponse struct {
+	ID      int               `json:"id"`
+	Summary *syscalls.Summary `json:"summary"`
+}
+
+// AnalysisSyscallSummaryHandler serves the per-syscall totals of a dynamic
+// analysis. Traces stored before summaries existed are summarized on the fly;
+// those from before calls were timed report timings_unavailable.
+func AnalysisSyscallSummaryHandler(db database.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		result, ok := getOwnedAnalysisResult(w, r, db)
+		if !ok {
+			return
+		}
+
+		if len(result.DynamicData) == 0 {
+			http.Error(w, "No syscall trace stored for this result, re-submit with ?dynamic=true", http.StatusNotFound)
+			return
+		}
+		summary := result.SyscallSummary
+		if summary == nil {
+			summary = analyzer.SummarizeSyscalls(result.DynamicData)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(SyscallSummaryResponse{ID: result.ID, Summary: summary})
+	}
+}
+
+// BenchSyscallSummaryHandler serves the per-syscall totals of a traced
+// benchmark, summarizing old traces on the fly as for analyses
+func BenchSyscallSummaryHandler(db database.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := auth.GetUserFromContext(r.Context())
+		if user == nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, "Invalid ID", http.StatusBadRequest)
+			return
+		}
+
+		result, err := db.GetBenchmarkResult(id)
+		if err != nil {
+			http.Error(w, "Failed to get benchmark result: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if result == nil {
+			http.Error(w, "Benchmark result not found", http.StatusNotFound)
+			return
+		}
+		if result.UserID != user.ID {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		if result.Result == nil || len(result.Result.Syscalls) == 0 {
+			http.Error(w, "No syscall trace stored for this benchmark, re-run with ?trace=true", http.StatusNotFound)
+			return
+		}
+		summary := result.Result.Summary
+		if summary == nil {
+			summary = syscalls.Summarize(result.Result.Syscalls)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(SyscallSummaryResponse{ID: result.ID, Summary: summary})
+	}
+}
@@ -0,0 +1,194 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/ashborn3/BinTraceBench/internal/auth"
+	"github.com/ashborn3/BinTraceBench/internal/config"
+	"github.com/ashborn3/BinTraceBench/internal/validation"
+	"github.com/ashborn3/BinTraceBench/pkg/logging"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	maxSysrootsPerUser = 8
+	// userSysrootsDir holds the uploaded sysroots below SysrootsDir, one
+	// subdirectory per user ID
+	userSysrootsDir = "users"
+)
+
+var sysrootNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+type SysrootInfo struct {
+	Name   string `json:"name"`
+	Shared bool   `json:"shared"` // prepared on the server rather than uploaded
+}
+
+type SysrootUploadResponse struct {
+	Name    string `json:"name"`
+	Members int    `json:"members"`
+}
+
+func validSysrootName(name string) bool {
+	return sysrootNamePattern.MatchString(name) && name != userSysrootsDir
+}
+
+func userSysrootDir(cfg config.AnalysisConfig, userID int) string {
+	return filepath.Join(cfg.SysrootsDir, userSysrootsDir, strconv.Itoa(userID))
+}
+
+// findSysroot returns the directory of a named sysroot, preferring the
+// user's own upload over a shared one of the same name
+func findSysroot(cfg config.AnalysisConfig, userID int, name string) (string, bool) {
+	for _, dir := range []string{
+		filepath.Join(userSysrootDir(cfg, userID), name),
+		filepath.Join(cfg.SysrootsDir, name),
+	} {
+		if fi, err := os.Stat(dir); err == nil && fi.IsDir() {
+			return dir, true
+		}
+	}
+	return "", false
+}
+
+// listSysroots returns the subdirectories of dir that are valid sysroot names
+func listSysroots(dir string) []string {
+	entries, _ := os.ReadDir(dir)
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() && validSysrootName(e.Name()) {
+			names = append(names, e.Name())
+		}
+	}
+	return names
+}
+
+// UploadSysrootHandler extracts a tar, tar.gz or zip root filesystem into a
+// sysroot of the user's, replacing an earlier one of the same name
+func UploadSysrootHandler(cfg config.AnalysisConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := auth.GetUserFromContext(r.Context())
+		if user == nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		name := r.URL.Query().Get("name")
+		if !validSysrootName(name) {
+			http.Error(w, "Invalid sysroot name (letters, digits, '.', '_' and '-', at most 64)", http.StatusBadRequest)
+			return
+		}
+
+		_, data, err := validation.ValidateFileUpload(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		format := validation.DetectArchive(data)
+		if format == "" || format == validation.ArchiveAr {
+			http.Error(w, "Sysroots must be uploaded as a tar, tar.gz or zip archive", http.StatusBadRequest)
+			return
+		}
+
+		userDir := userSysrootDir(cfg, user.ID)
+		if err := os.MkdirAll(userDir, 0755); err != nil {
+			logging.Error("Failed to create sysroot directory", "error", err, "dir", userDir)
+			http.Error(w, "Failed to store sysroot", http.StatusInternalServerError)
+			return
+		}
+		final := filepath.Join(userDir, name)
+		if _, err := os.Stat(final); os.IsNotExist(err) && len(listSysroots(userDir)) >= maxSysrootsPerUser {
+			http.Error(w, "Too many sysroots, delete one first", http.StatusBadRequest)
+			return
+		}
+
+		// Extract next to the final directory and swap it in once complete
+		tmp, err := os.MkdirTemp(userDir, ".upload-")
+		if err != nil {
+			logging.Error("Failed to create sysroot directory", "error", err, "dir", userDir)
+			http.Error(w, "Failed to store sysroot", http.StatusInternalServerError)
+			return
+		}
+		defer os.RemoveAll(tmp)
+
+		members, err := validation.ExtractArchive(format, data, tmp)
+		if err != nil {
+			http.Error(w, "Failed to extract sysroot: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := os.RemoveAll(final); err != nil {
+			logging.Error("Failed to replace sysroot", "error", err, "dir", final)
+			http.Error(w, "Failed to store sysroot", http.StatusInternalServerError)
+			return
+		}
+		if err := os.Rename(tmp, final); err != nil {
+			logging.Error("Failed to store sysroot", "error", err, "dir", final)
+			http.Error(w, "Failed to store sysroot", http.StatusInternalServerError)
+			return
+		}
+
+		logging.Info("Sysroot uploaded", "user", user.Username, "name", name, "members", members)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(SysrootUploadResponse{Name: name, Members: members})
+	}
+}
+
+// ListSysrootsHandler lists the user's uploaded sysroots and the shared ones
+func ListSysrootsHandler(cfg config.AnalysisConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := auth.GetUserFromContext(r.Context())
+		if user == nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		sysroots := []SysrootInfo{}
+		for _, name := range listSysroots(userSysrootDir(cfg, user.ID)) {
+			sysroots = append(sysroots, SysrootInfo{Name: name})
+		}
+		for _, name := range listSysroots(cfg.SysrootsDir) {
+			sysroots = append(sysroots, SysrootInfo{Name: name, Shared: true})
+		}
+		sort.SliceStable(sysroots, func(i, j int) bool { return sysroots[i].Name < sysroots[j].Name })
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(sysroots)
+	}
+}
+
+// DeleteSysrootHandler removes one of the user's uploaded sysroots
+func DeleteSysrootHandler(cfg config.AnalysisConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := auth.GetUserFromContext(r.Context())
+		if user == nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		name := chi.URLParam(r, "name")
+		if !validSysrootName(name) {
+			http.Error(w, "Invalid sysroot name", http.StatusBadRequest)
+			return
+		}
+		dir := filepath.Join(userSysrootDir(cfg, user.ID), name)
+		if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+			http.Error(w, "Sysroot not found", http.StatusNotFound)
+			return
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			logging.Error("Failed to delete sysroot", "error", err, "dir", dir)
+			http.Error(w, "Failed to delete sysroot", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"message": "Sysroot deleted successfully"})
+	}
+}
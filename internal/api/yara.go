@@ -0,0 +1,62 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ashborn3/BinTraceBench/internal/auth"
+	"github.com/ashborn3/BinTraceBench/internal/yara"
+	"github.com/ashborn3/BinTraceBench/pkg/logging"
+)
+
+type YaraRulesResponse struct {
+	Dir     string             `json:"dir"`
+	Count   int                `json:"count"`
+	Rules   []*yara.Rule       `json:"rules"`
+	Skipped []yara.SkippedRule `json:"skipped,omitempty"`
+}
+
+func ListYaraRulesHandler(scanner *yara.Scanner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		loaded := scanner.Rules()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(YaraRulesResponse{
+			Dir:     scanner.Dir(),
+			Count:   len(loaded),
+			Rules:   loaded,
+			Skipped: scanner.Skipped(),
+		})
+	}
+}
+
+func ReloadYaraRulesHandler(scanner *yara.Scanner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := auth.GetUserFromContext(r.Context())
+
+		count, err := scanner.Reload()
+		if err != nil {
+			logging.Warn("YARA reload failed", "error", err, "user", user.Username)
+			http.Error(w, "YARA reload failed, previous rules kept: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		skipped := scanner.Skipped()
+		logging.Info("YARA rules reloaded", "count", count, "skipped", len(skipped), "user", user.Username)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"message": "YARA rules reloaded", "count": count, "skipped": skipped})
+	}
+}
+
+// sameYaraRules reports whether two scans matched the same rules, ignoring
+// string details, so that unchanged cached results are not rewritten
+func sameYaraRules(a, b []yara.Match) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Rule != b[i].Rule || a[i].Namespace != b[i].Namespace {
+			return false
+		}
+	}
+	return true
+}
@@ -8,6 +8,10 @@ import (
 	"github.com/ashborn3/BinTraceBench/pkg/logging"
 )
 
+// orphanedSampleAge keeps samples whose analysis is still running, or was
+// saved moments ago, out of the sweep
+const orphanedSampleAge = time.Hour
+
 type Service struct {
 	db       database.Database
 	interval time.Duration
@@ -43,6 +47,7 @@ func (s *Service) run() {
 			return
 		case <-ticker.C:
 			s.cleanupExpiredSessions()
+			s.cleanupOrphanedSamples()
 		}
 	}
 }
@@ -55,3 +60,12 @@ func (s *Service) cleanupExpiredSessions() {
 		logging.Debug("Cleaned up expired sessions")
 	}
 }
+
+func (s *Service) cleanupOrphanedSamples() {
+	n, err := s.db.DeleteOrphanedSamples(orphanedSampleAge)
+	if err != nil {
+		logging.Error("Failed to cleanup orphaned samples", "error", err)
+	} else {
+		logging.Debug("Cleaned up orphaned samples", "count", n)
+	}
+}
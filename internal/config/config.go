@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 )
 
@@ -10,6 +11,7 @@ type Config struct {
 	Server   ServerConfig   `json:"server"`
 	Database DatabaseConfig `json:"database"`
 	Auth     AuthConfig     `json:"auth"`
+	Analysis AnalysisConfig `json:"analysis"`
 }
 
 type ServerConfig struct {
@@ -40,6 +42,14 @@ type AuthConfig struct {
 	SessionExpiry int `json:"session_expiry"` // in hours
 }
 
+type AnalysisConfig struct {
+	Sysroot     string   `json:"sysroot"`      // default root for dependency resolution, none when empty
+	SysrootsDir string   `json:"sysroots_dir"` // named sysroots, prepared on the server or uploaded per user
+	LibraryDirs []string `json:"library_dirs"` // extra search dirs inside the sysroot
+	RulesDir    string   `json:"rules_dir"`    // YAML detection rules, reloadable at runtime
+	YaraDir     string   `json:"yara_dir"`     // YARA rule files scanned on request
+}
+
 func Load() *Config {
 	return &Config{
 		Server: ServerConfig{
@@ -63,6 +73,13 @@ func Load() *Config {
 		Auth: AuthConfig{
 			SessionExpiry: getEnvAsInt("SESSION_EXPIRY_HOURS", 24),
 		},
+		Analysis: AnalysisConfig{
+			Sysroot:     getEnv("ANALYSIS_SYSROOT", ""),
+			SysrootsDir: getEnv("ANALYSIS_SYSROOTS_DIR", "./data/sysroots"),
+			LibraryDirs: getEnvAsList("ANALYSIS_LIBRARY_DIRS", nil),
+			RulesDir:    getEnv("ANALYSIS_RULES_DIR", "./data/rules"),
+			YaraDir:     getEnv("ANALYSIS_YARA_DIR", "./data/yara"),
+		},
 	}
 }
 
@@ -89,6 +106,10 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	if c.Analysis.Sysroot != "" && !filepath.IsAbs(c.Analysis.Sysroot) {
+		return fmt.Errorf("analysis sysroot must be an absolute path: %s", c.Analysis.Sysroot)
+	}
+
 	return nil
 }
 
@@ -107,3 +128,11 @@ func getEnvAsInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+// getEnvAsList splits a colon-separated variable such as a search path
+func getEnvAsList(key string, defaultValue []string) []string {
+	if value := os.Getenv(key); value != "" {
+		return filepath.SplitList(value)
+	}
+	return defaultValue
+}
@@ -1,10 +1,15 @@
 package database
 
 import (
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/ashborn3/BinTraceBench/internal/analyzer"
+	"github.com/ashborn3/BinTraceBench/internal/rules"
 	"github.com/ashborn3/BinTraceBench/internal/sandbox"
+	"github.com/ashborn3/BinTraceBench/internal/syscalls"
+	"github.com/ashborn3/BinTraceBench/internal/yara"
 )
 
 type User struct {
@@ -25,13 +30,29 @@ type Session struct {
 }
 
 type AnalysisResult struct {
-	ID          int                            `json:"id" db:"id"`
-	UserID      int                            `json:"user_id" db:"user_id"`
-	Filename    string                         `json:"filename" db:"filename"`
-	FileHash    string                         `json:"file_hash" db:"file_hash"`
-	StaticData  *analyzer.BinaryInfo           `json:"static_data" db:"static_data"`
-	DynamicData []analyzer.VerboseSyscallEntry `json:"dynamic_data" db:"dynamic_data"`
-	Created     time.Time                      `json:"created" db:"created"`
+	ID             int                            `json:"id" db:"id"`
+	UserID         int                            `json:"user_id" db:"user_id"`
+	Filename       string                         `json:"filename" db:"filename"`
+	FileHash       string                         `json:"file_hash" db:"file_hash"`
+	TLSH           string                         `json:"tlsh,omitempty" db:"tlsh"`
+	SSDeep         string                         `json:"ssdeep,omitempty" db:"ssdeep"`
+	StaticData     *analyzer.BinaryInfo           `json:"static_data" db:"static_data"`
+	DynamicData    []analyzer.VerboseSyscallEntry `json:"dynamic_data" db:"dynamic_data"`
+	RuleMatches    []rules.Match                  `json:"rule_matches,omitempty" db:"rule_matches"`
+	YaraMatches    []yara.Match                   `json:"yara_matches,omitempty" db:"yara_matches"`
+	ProcessTree    []analyzer.TracedProcess       `json:"process_tree,omitempty" db:"process_tree"`
+	SyscallSummary *syscalls.Summary              `json:"syscall_summary,omitempty" db:"syscall_summary"`
+	Created        time.Time                      `json:"created" db:"created"`
+}
+
+// AnalysisDigest is the part of an AnalysisResult similarity search reads
+type AnalysisDigest struct {
+	ID       int       `json:"id" db:"id"`
+	Filename string    `json:"filename" db:"filename"`
+	FileHash string    `json:"file_hash" db:"file_hash"`
+	TLSH     string    `json:"tlsh,omitempty" db:"tlsh"`
+	SSDeep   string    `json:"ssdeep,omitempty" db:"ssdeep"`
+	Created  time.Time `json:"created" db:"created"`
 }
 
 type BenchmarkResult struct {
@@ -69,8 +90,11 @@ type Database interface {
 
 	// Analysis results
 	SaveAnalysisResult(result *AnalysisResult) error
+	UpdateAnalysisResult(result *AnalysisResult) error
 	GetAnalysisResult(id int) (*AnalysisResult, error)
 	GetAnalysisResultsByUser(userID int) ([]*AnalysisResult, error)
+	GetAnalysisDigestsByUser(userID int) ([]*AnalysisDigest, error)
+	BackfillAnalysisDigests(fileHash, tlsh, ssdeep string) error
 	GetAnalysisResultByHash(userID int, fileHash string) (*AnalysisResult, error)
 	DeleteAnalysisResult(id int) error
 
@@ -80,4 +104,37 @@ type Database interface {
 	GetBenchmarkResultsByUser(userID int) ([]*BenchmarkResult, error)
 	GetBenchmarkResultByHash(userID int, fileHash string) (*BenchmarkResult, error)
 	DeleteBenchmarkResult(id int) error
+
+	// Sample storage (raw uploads keyed by SHA-256, shared across users)
+	SaveSample(fileHash string, data []byte) error
+	GetSample(fileHash string) ([]byte, error)
+	DeleteOrphanedSamples(olderThan time.Duration) (int64, error)
+}
+
+// analysisColumns holds the JSON-encoded columns of an AnalysisResult
+type analysisColumns struct {
+	staticData, dynamicData, ruleMatches, yaraMatches, processTree, syscallSummary string
+}
+
+func encodeAnalysisResult(result *AnalysisResult) (*analysisColumns, error) {
+	cols := &analysisColumns{}
+	for _, field := range []struct {
+		dst   *string
+		value interface{}
+		label string
+	}{
+		{&cols.staticData, result.StaticData, "static data"},
+		{&cols.dynamicData, result.DynamicData, "dynamic data"},
+		{&cols.ruleMatches, result.RuleMatches, "rule matches"},
+		{&cols.yaraMatches, result.YaraMatches, "YARA matches"},
+		{&cols.processTree, result.ProcessTree, "process tree"},
+		{&cols.syscallSummary, result.SyscallSummary, "syscall summary"},
+	} {
+		data, err := json.Marshal(field.value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal %s: %w", field.label, err)
+		}
+		*field.dst = string(data)
+	}
+	return cols, nil
 }
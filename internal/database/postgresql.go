@@ -79,8 +79,14 @@ func (p *PostgreSQLDB) CreateTables() error {
 			user_id INTEGER NOT NULL,
 			filename VARCHAR(255) NOT NULL,
 			file_hash VARCHAR(255) NOT NULL,
+			tlsh VARCHAR(80),
+			ssdeep VARCHAR(255),
 			static_data JSONB,
 			dynamic_data JSONB,
+			rule_matches JSONB,
+			yara_matches JSONB,
+			process_tree JSONB,
+			syscall_summary JSONB,
 			created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
 		)`,
@@ -94,6 +100,19 @@ func (p *PostgreSQLDB) CreateTables() error {
 			created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
 		)`,
+		`CREATE TABLE IF NOT EXISTS samples (
+			file_hash VARCHAR(255) PRIMARY KEY,
+			size BIGINT NOT NULL,
+			data BYTEA NOT NULL,
+			created TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		// Columns added after the initial schema
+		`ALTER TABLE analysis_results ADD COLUMN IF NOT EXISTS tlsh VARCHAR(80)`,
+		`ALTER TABLE analysis_results ADD COLUMN IF NOT EXISTS ssdeep VARCHAR(255)`,
+		`ALTER TABLE analysis_results ADD COLUMN IF NOT EXISTS rule_matches JSONB`,
+		`ALTER TABLE analysis_results ADD COLUMN IF NOT EXISTS yara_matches JSONB`,
+		`ALTER TABLE analysis_results ADD COLUMN IF NOT EXISTS process_tree JSONB`,
+		`ALTER TABLE analysis_results ADD COLUMN IF NOT EXISTS syscall_summary JSONB`,
 		`CREATE INDEX IF NOT EXISTS idx_users_username ON users(username)`,
 		`CREATE INDEX IF NOT EXISTS idx_sessions_token ON sessions(token)`,
 		`CREATE INDEX IF NOT EXISTS idx_sessions_expires ON sessions(expires)`,
@@ -111,6 +130,7 @@ func (p *PostgreSQLDB) CreateTables() error {
 
 func (p *PostgreSQLDB) DropTables() error {
 	queries := []string{
+		"DROP TABLE IF EXISTS samples CASCADE",
 		"DROP TABLE IF EXISTS benchmark_results CASCADE",
 		"DROP TABLE IF EXISTS analysis_results CASCADE",
 		"DROP TABLE IF EXISTS sessions CASCADE",
@@ -224,30 +244,39 @@ func (p *PostgreSQLDB) DeleteExpiredSessions() error {
 
 // Analysis results
 func (p *PostgreSQLDB) SaveAnalysisResult(result *AnalysisResult) error {
-	staticData, err := json.Marshal(result.StaticData)
+	cols, err := encodeAnalysisResult(result)
 	if err != nil {
-		return fmt.Errorf("failed to marshal static data: %w", err)
+		return err
 	}
 
-	dynamicData, err := json.Marshal(result.DynamicData)
+	query := `INSERT INTO analysis_results (user_id, filename, file_hash, tlsh, ssdeep, static_data, dynamic_data, rule_matches, yara_matches, process_tree, syscall_summary) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id, created`
+	err = p.db.QueryRow(query, result.UserID, result.Filename, result.FileHash, result.TLSH, result.SSDeep, cols.staticData, cols.dynamicData, cols.ruleMatches, cols.yaraMatches, cols.processTree, cols.syscallSummary).Scan(&result.ID, &result.Created)
 	if err != nil {
-		return fmt.Errorf("failed to marshal dynamic data: %w", err)
+		return fmt.Errorf("failed to save analysis result: %w", err)
 	}
+	return nil
+}
 
-	query := `INSERT INTO analysis_results (user_id, filename, file_hash, static_data, dynamic_data) VALUES ($1, $2, $3, $4, $5) RETURNING id, created`
-	err = p.db.QueryRow(query, result.UserID, result.Filename, result.FileHash, string(staticData), string(dynamicData)).Scan(&result.ID, &result.Created)
+func (p *PostgreSQLDB) UpdateAnalysisResult(result *AnalysisResult) error {
+	cols, err := encodeAnalysisResult(result)
 	if err != nil {
-		return fmt.Errorf("failed to save analysis result: %w", err)
+		return err
+	}
+
+	query := `UPDATE analysis_results SET tlsh = $1, ssdeep = $2, static_data = $3, dynamic_data = $4, rule_matches = $5, yara_matches = $6, process_tree = $7, syscall_summary = $8 WHERE id = $9`
+	_, err = p.db.Exec(query, result.TLSH, result.SSDeep, cols.staticData, cols.dynamicData, cols.ruleMatches, cols.yaraMatches, cols.processTree, cols.syscallSummary, result.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update analysis result: %w", err)
 	}
 	return nil
 }
 
 func (p *PostgreSQLDB) GetAnalysisResult(id int) (*AnalysisResult, error) {
 	result := &AnalysisResult{}
-	var staticDataStr, dynamicDataStr string
+	var staticDataStr, dynamicDataStr, ruleMatchesStr, yaraMatchesStr, processTreeStr, syscallSummaryStr string
 
-	query := `SELECT id, user_id, filename, file_hash, static_data, dynamic_data, created FROM analysis_results WHERE id = $1`
-	err := p.db.QueryRow(query, id).Scan(&result.ID, &result.UserID, &result.Filename, &result.FileHash, &staticDataStr, &dynamicDataStr, &result.Created)
+	query := `SELECT id, user_id, filename, file_hash, COALESCE(tlsh, ''), COALESCE(ssdeep, ''), static_data, dynamic_data, COALESCE(rule_matches::text, ''), COALESCE(yara_matches::text, ''), COALESCE(process_tree::text, ''), COALESCE(syscall_summary::text, ''), created FROM analysis_results WHERE id = $1`
+	err := p.db.QueryRow(query, id).Scan(&result.ID, &result.UserID, &result.Filename, &result.FileHash, &result.TLSH, &result.SSDeep, &staticDataStr, &dynamicDataStr, &ruleMatchesStr, &yaraMatchesStr, &processTreeStr, &syscallSummaryStr, &result.Created)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -267,11 +296,35 @@ func (p *PostgreSQLDB) GetAnalysisResult(id int) (*AnalysisResult, error) {
 		}
 	}
 
+	if ruleMatchesStr != "" {
+		if err := json.Unmarshal([]byte(ruleMatchesStr), &result.RuleMatches); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal rule matches: %w", err)
+		}
+	}
+
+	if yaraMatchesStr != "" {
+		if err := json.Unmarshal([]byte(yaraMatchesStr), &result.YaraMatches); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal YARA matches: %w", err)
+		}
+	}
+
+	if processTreeStr != "" {
+		if err := json.Unmarshal([]byte(processTreeStr), &result.ProcessTree); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal process tree: %w", err)
+		}
+	}
+
+	if syscallSummaryStr != "" {
+		if err := json.Unmarshal([]byte(syscallSummaryStr), &result.SyscallSummary); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal syscall summary: %w", err)
+		}
+	}
+
 	return result, nil
 }
 
 func (p *PostgreSQLDB) GetAnalysisResultsByUser(userID int) ([]*AnalysisResult, error) {
-	query := `SELECT id, user_id, filename, file_hash, static_data, dynamic_data, created FROM analysis_results WHERE user_id = $1 ORDER BY created DESC`
+	query := `SELECT id, user_id, filename, file_hash, COALESCE(tlsh, ''), COALESCE(ssdeep, ''), static_data, dynamic_data, COALESCE(rule_matches::text, ''), COALESCE(yara_matches::text, ''), COALESCE(process_tree::text, ''), COALESCE(syscall_summary::text, ''), created FROM analysis_results WHERE user_id = $1 ORDER BY created DESC`
 	rows, err := p.db.Query(query, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get analysis results: %w", err)
@@ -281,9 +334,9 @@ func (p *PostgreSQLDB) GetAnalysisResultsByUser(userID int) ([]*AnalysisResult,
 	var results []*AnalysisResult
 	for rows.Next() {
 		result := &AnalysisResult{}
-		var staticDataStr, dynamicDataStr string
+		var staticDataStr, dynamicDataStr, ruleMatchesStr, yaraMatchesStr, processTreeStr, syscallSummaryStr string
 
-		err := rows.Scan(&result.ID, &result.UserID, &result.Filename, &result.FileHash, &staticDataStr, &dynamicDataStr, &result.Created)
+		err := rows.Scan(&result.ID, &result.UserID, &result.Filename, &result.FileHash, &result.TLSH, &result.SSDeep, &staticDataStr, &dynamicDataStr, &ruleMatchesStr, &yaraMatchesStr, &processTreeStr, &syscallSummaryStr, &result.Created)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan analysis result: %w", err)
 		}
@@ -300,18 +353,73 @@ func (p *PostgreSQLDB) GetAnalysisResultsByUser(userID int) ([]*AnalysisResult,
 			}
 		}
 
+		if ruleMatchesStr != "" {
+			if err := json.Unmarshal([]byte(ruleMatchesStr), &result.RuleMatches); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal rule matches: %w", err)
+			}
+		}
+
+		if yaraMatchesStr != "" {
+			if err := json.Unmarshal([]byte(yaraMatchesStr), &result.YaraMatches); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal YARA matches: %w", err)
+			}
+		}
+
+		if processTreeStr != "" {
+			if err := json.Unmarshal([]byte(processTreeStr), &result.ProcessTree); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal process tree: %w", err)
+			}
+		}
+
+		if syscallSummaryStr != "" {
+			if err := json.Unmarshal([]byte(syscallSummaryStr), &result.SyscallSummary); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal syscall summary: %w", err)
+			}
+		}
+
 		results = append(results, result)
 	}
 
 	return results, nil
 }
 
+// GetAnalysisDigestsByUser lists a user's results, newest first, without
+// decoding their analysis data
+func (p *PostgreSQLDB) GetAnalysisDigestsByUser(userID int) ([]*AnalysisDigest, error) {
+	query := `SELECT id, filename, file_hash, COALESCE(tlsh, ''), COALESCE(ssdeep, ''), created FROM analysis_results WHERE user_id = $1 ORDER BY created DESC`
+	rows, err := p.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get analysis digests: %w", err)
+	}
+	defer rows.Close()
+
+	var digests []*AnalysisDigest
+	for rows.Next() {
+		d := &AnalysisDigest{}
+		if err := rows.Scan(&d.ID, &d.Filename, &d.FileHash, &d.TLSH, &d.SSDeep, &d.Created); err != nil {
+			return nil, fmt.Errorf("failed to scan analysis digest: %w", err)
+		}
+		digests = append(digests, d)
+	}
+	return digests, nil
+}
+
+// BackfillAnalysisDigests stores the fuzzy digests of a sample on every
+// result of it stored before fuzzy hashing existed
+func (p *PostgreSQLDB) BackfillAnalysisDigests(fileHash, tlsh, ssdeep string) error {
+	query := `UPDATE analysis_results SET tlsh = $1, ssdeep = $2 WHERE file_hash = $3 AND COALESCE(tlsh, '') = '' AND COALESCE(ssdeep, '') = ''`
+	if _, err := p.db.Exec(query, tlsh, ssdeep, fileHash); err != nil {
+		return fmt.Errorf("failed to backfill analysis digests: %w", err)
+	}
+	return nil
+}
+
 func (p *PostgreSQLDB) GetAnalysisResultByHash(userID int, fileHash string) (*AnalysisResult, error) {
 	result := &AnalysisResult{}
-	var staticDataStr, dynamicDataStr string
+	var staticDataStr, dynamicDataStr, ruleMatchesStr, yaraMatchesStr, processTreeStr, syscallSummaryStr string
 
-	query := `SELECT id, user_id, filename, file_hash, static_data, dynamic_data, created FROM analysis_results WHERE user_id = $1 AND file_hash = $2 ORDER BY created DESC LIMIT 1`
-	err := p.db.QueryRow(query, userID, fileHash).Scan(&result.ID, &result.UserID, &result.Filename, &result.FileHash, &staticDataStr, &dynamicDataStr, &result.Created)
+	query := `SELECT id, user_id, filename, file_hash, COALESCE(tlsh, ''), COALESCE(ssdeep, ''), static_data, dynamic_data, COALESCE(rule_matches::text, ''), COALESCE(yara_matches::text, ''), COALESCE(process_tree::text, ''), COALESCE(syscall_summary::text, ''), created FROM analysis_results WHERE user_id = $1 AND file_hash = $2 ORDER BY created DESC LIMIT 1`
+	err := p.db.QueryRow(query, userID, fileHash).Scan(&result.ID, &result.UserID, &result.Filename, &result.FileHash, &result.TLSH, &result.SSDeep, &staticDataStr, &dynamicDataStr, &ruleMatchesStr, &yaraMatchesStr, &processTreeStr, &syscallSummaryStr, &result.Created)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -331,16 +439,54 @@ func (p *PostgreSQLDB) GetAnalysisResultByHash(userID int, fileHash string) (*An
 		}
 	}
 
+	if ruleMatchesStr != "" {
+		if err := json.Unmarshal([]byte(ruleMatchesStr), &result.RuleMatches); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal rule matches: %w", err)
+		}
+	}
+
+	if yaraMatchesStr != "" {
+		if err := json.Unmarshal([]byte(yaraMatchesStr), &result.YaraMatches); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal YARA matches: %w", err)
+		}
+	}
+
+	if processTreeStr != "" {
+		if err := json.Unmarshal([]byte(processTreeStr), &result.ProcessTree); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal process tree: %w", err)
+		}
+	}
+
+	if syscallSummaryStr != "" {
+		if err := json.Unmarshal([]byte(syscallSummaryStr), &result.SyscallSummary); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal syscall summary: %w", err)
+		}
+	}
+
 	return result, nil
 }
 
+// DeleteAnalysisResult also drops the stored sample once no result refers to it
 func (p *PostgreSQLDB) DeleteAnalysisResult(id int) error {
-	query := `DELETE FROM analysis_results WHERE id = $1`
-	_, err := p.db.Exec(query, id)
+	tx, err := p.db.Begin()
 	if err != nil {
 		return fmt.Errorf("failed to delete analysis result: %w", err)
 	}
-	return nil
+	defer tx.Rollback()
+
+	var fileHash string
+	err = tx.QueryRow(`DELETE FROM analysis_results WHERE id = $1 RETURNING file_hash`, id).Scan(&fileHash)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete analysis result: %w", err)
+	}
+	query := `DELETE FROM samples WHERE file_hash = $1 AND NOT EXISTS (SELECT 1 FROM analysis_results WHERE file_hash = $1)`
+	if _, err := tx.Exec(query, fileHash); err != nil {
+		return fmt.Errorf("failed to delete sample: %w", err)
+	}
+	return tx.Commit()
 }
 
 // Benchmark results
@@ -434,3 +580,38 @@ func (p *PostgreSQLDB) DeleteBenchmarkResult(id int) error {
 	}
 	return nil
 }
+
+// Sample storage
+func (p *PostgreSQLDB) SaveSample(fileHash string, data []byte) error {
+	query := `INSERT INTO samples (file_hash, size, data) VALUES ($1, $2, $3) ON CONFLICT (file_hash) DO NOTHING`
+	_, err := p.db.Exec(query, fileHash, len(data), data)
+	if err != nil {
+		return fmt.Errorf("failed to save sample: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgreSQLDB) GetSample(fileHash string) ([]byte, error) {
+	var data []byte
+	query := `SELECT data FROM samples WHERE file_hash = $1`
+	err := p.db.QueryRow(query, fileHash).Scan(&data)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get sample: %w", err)
+	}
+	return data, nil
+}
+
+// DeleteOrphanedSamples removes samples older than olderThan that no analysis
+// result refers to, such as uploads whose analysis failed or whose owner was
+// deleted
+func (p *PostgreSQLDB) DeleteOrphanedSamples(olderThan time.Duration) (int64, error) {
+	query := `DELETE FROM samples WHERE created < $1 AND file_hash NOT IN (SELECT file_hash FROM analysis_results)`
+	res, err := p.db.Exec(query, time.Now().Add(-olderThan))
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete orphaned samples: %w", err)
+	}
+	return res.RowsAffected()
+}
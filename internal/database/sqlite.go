@@ -66,8 +66,14 @@ func (s *SQLiteDB) CreateTables() error {
 			user_id INTEGER NOT NULL,
 			filename TEXT NOT NULL,
 			file_hash TEXT NOT NULL,
+			tlsh TEXT,
+			ssdeep TEXT,
 			static_data TEXT,
 			dynamic_data TEXT,
+			rule_matches TEXT,
+			yara_matches TEXT,
+			process_tree TEXT,
+			syscall_summary TEXT,
 			created DATETIME DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
 		)`,
@@ -81,6 +87,12 @@ func (s *SQLiteDB) CreateTables() error {
 			created DATETIME DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
 		)`,
+		`CREATE TABLE IF NOT EXISTS samples (
+			file_hash TEXT PRIMARY KEY,
+			size INTEGER NOT NULL,
+			data BLOB NOT NULL,
+			created DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
 		`CREATE INDEX IF NOT EXISTS idx_users_username ON users(username)`,
 		`CREATE INDEX IF NOT EXISTS idx_sessions_token ON sessions(token)`,
 		`CREATE INDEX IF NOT EXISTS idx_sessions_expires ON sessions(expires)`,
@@ -93,11 +105,55 @@ func (s *SQLiteDB) CreateTables() error {
 			return fmt.Errorf("failed to create table: %w", err)
 		}
 	}
+
+	// Columns added after the initial schema; SQLite has no ADD COLUMN IF NOT EXISTS
+	columns := []struct{ table, column, decl string }{
+		{"analysis_results", "tlsh", "TEXT"},
+		{"analysis_results", "ssdeep", "TEXT"},
+		{"analysis_results", "rule_matches", "TEXT"},
+		{"analysis_results", "yara_matches", "TEXT"},
+		{"analysis_results", "process_tree", "TEXT"},
+		{"analysis_results", "syscall_summary", "TEXT"},
+	}
+	for _, c := range columns {
+		if err := s.addColumnIfMissing(c.table, c.column, c.decl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SQLiteDB) addColumnIfMissing(table, column, decl string) error {
+	rows, err := s.db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return fmt.Errorf("failed to inspect table %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid, notNull, pk int
+			name, typ        string
+			dflt             sql.NullString
+		)
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &dflt, &pk); err != nil {
+			return fmt.Errorf("failed to inspect table %s: %w", table, err)
+		}
+		if name == column {
+			return nil
+		}
+	}
+	rows.Close()
+
+	if _, err := s.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, decl)); err != nil {
+		return fmt.Errorf("failed to add column %s.%s: %w", table, column, err)
+	}
 	return nil
 }
 
 func (s *SQLiteDB) DropTables() error {
 	queries := []string{
+		"DROP TABLE IF EXISTS samples",
 		"DROP TABLE IF EXISTS benchmark_results",
 		"DROP TABLE IF EXISTS analysis_results",
 		"DROP TABLE IF EXISTS sessions",
@@ -221,18 +277,13 @@ func (s *SQLiteDB) DeleteExpiredSessions() error {
 
 // Analysis results
 func (s *SQLiteDB) SaveAnalysisResult(result *AnalysisResult) error {
-	staticData, err := json.Marshal(result.StaticData)
+	cols, err := encodeAnalysisResult(result)
 	if err != nil {
-		return fmt.Errorf("failed to marshal static data: %w", err)
+		return err
 	}
 
-	dynamicData, err := json.Marshal(result.DynamicData)
-	if err != nil {
-		return fmt.Errorf("failed to marshal dynamic data: %w", err)
-	}
-
-	query := `INSERT INTO analysis_results (user_id, filename, file_hash, static_data, dynamic_data) VALUES (?, ?, ?, ?, ?)`
-	dbResult, err := s.db.Exec(query, result.UserID, result.Filename, result.FileHash, string(staticData), string(dynamicData))
+	query := `INSERT INTO analysis_results (user_id, filename, file_hash, tlsh, ssdeep, static_data, dynamic_data, rule_matches, yara_matches, process_tree, syscall_summary) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	dbResult, err := s.db.Exec(query, result.UserID, result.Filename, result.FileHash, result.TLSH, result.SSDeep, cols.staticData, cols.dynamicData, cols.ruleMatches, cols.yaraMatches, cols.processTree, cols.syscallSummary)
 	if err != nil {
 		return fmt.Errorf("failed to save analysis result: %w", err)
 	}
@@ -245,12 +296,26 @@ func (s *SQLiteDB) SaveAnalysisResult(result *AnalysisResult) error {
 	return nil
 }
 
+func (s *SQLiteDB) UpdateAnalysisResult(result *AnalysisResult) error {
+	cols, err := encodeAnalysisResult(result)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE analysis_results SET tlsh = ?, ssdeep = ?, static_data = ?, dynamic_data = ?, rule_matches = ?, yara_matches = ?, process_tree = ?, syscall_summary = ? WHERE id = ?`
+	_, err = s.db.Exec(query, result.TLSH, result.SSDeep, cols.staticData, cols.dynamicData, cols.ruleMatches, cols.yaraMatches, cols.processTree, cols.syscallSummary, result.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update analysis result: %w", err)
+	}
+	return nil
+}
+
 func (s *SQLiteDB) GetAnalysisResult(id int) (*AnalysisResult, error) {
 	result := &AnalysisResult{}
-	var staticDataStr, dynamicDataStr string
+	var staticDataStr, dynamicDataStr, ruleMatchesStr, yaraMatchesStr, processTreeStr, syscallSummaryStr string
 
-	query := `SELECT id, user_id, filename, file_hash, static_data, dynamic_data, created FROM analysis_results WHERE id = ?`
-	err := s.db.QueryRow(query, id).Scan(&result.ID, &result.UserID, &result.Filename, &result.FileHash, &staticDataStr, &dynamicDataStr, &result.Created)
+	query := `SELECT id, user_id, filename, file_hash, COALESCE(tlsh, ''), COALESCE(ssdeep, ''), static_data, dynamic_data, COALESCE(rule_matches, ''), COALESCE(yara_matches, ''), COALESCE(process_tree, ''), COALESCE(syscall_summary, ''), created FROM analysis_results WHERE id = ?`
+	err := s.db.QueryRow(query, id).Scan(&result.ID, &result.UserID, &result.Filename, &result.FileHash, &result.TLSH, &result.SSDeep, &staticDataStr, &dynamicDataStr, &ruleMatchesStr, &yaraMatchesStr, &processTreeStr, &syscallSummaryStr, &result.Created)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -270,11 +335,35 @@ func (s *SQLiteDB) GetAnalysisResult(id int) (*AnalysisResult, error) {
 		}
 	}
 
+	if ruleMatchesStr != "" {
+		if err := json.Unmarshal([]byte(ruleMatchesStr), &result.RuleMatches); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal rule matches: %w", err)
+		}
+	}
+
+	if yaraMatchesStr != "" {
+		if err := json.Unmarshal([]byte(yaraMatchesStr), &result.YaraMatches); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal YARA matches: %w", err)
+		}
+	}
+
+	if processTreeStr != "" {
+		if err := json.Unmarshal([]byte(processTreeStr), &result.ProcessTree); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal process tree: %w", err)
+		}
+	}
+
+	if syscallSummaryStr != "" {
+		if err := json.Unmarshal([]byte(syscallSummaryStr), &result.SyscallSummary); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal syscall summary: %w", err)
+		}
+	}
+
 	return result, nil
 }
 
 func (s *SQLiteDB) GetAnalysisResultsByUser(userID int) ([]*AnalysisResult, error) {
-	query := `SELECT id, user_id, filename, file_hash, static_data, dynamic_data, created FROM analysis_results WHERE user_id = ? ORDER BY created DESC`
+	query := `SELECT id, user_id, filename, file_hash, COALESCE(tlsh, ''), COALESCE(ssdeep, ''), static_data, dynamic_data, COALESCE(rule_matches, ''), COALESCE(yara_matches, ''), COALESCE(process_tree, ''), COALESCE(syscall_summary, ''), created FROM analysis_results WHERE user_id = ? ORDER BY created DESC`
 	rows, err := s.db.Query(query, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get analysis results: %w", err)
@@ -284,9 +373,9 @@ func (s *SQLiteDB) GetAnalysisResultsByUser(userID int) ([]*AnalysisResult, erro
 	var results []*AnalysisResult
 	for rows.Next() {
 		result := &AnalysisResult{}
-		var staticDataStr, dynamicDataStr string
+		var staticDataStr, dynamicDataStr, ruleMatchesStr, yaraMatchesStr, processTreeStr, syscallSummaryStr string
 
-		err := rows.Scan(&result.ID, &result.UserID, &result.Filename, &result.FileHash, &staticDataStr, &dynamicDataStr, &result.Created)
+		err := rows.Scan(&result.ID, &result.UserID, &result.Filename, &result.FileHash, &result.TLSH, &result.SSDeep, &staticDataStr, &dynamicDataStr, &ruleMatchesStr, &yaraMatchesStr, &processTreeStr, &syscallSummaryStr, &result.Created)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan analysis result: %w", err)
 		}
@@ -303,18 +392,73 @@ func (s *SQLiteDB) GetAnalysisResultsByUser(userID int) ([]*AnalysisResult, erro
 			}
 		}
 
+		if ruleMatchesStr != "" {
+			if err := json.Unmarshal([]byte(ruleMatchesStr), &result.RuleMatches); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal rule matches: %w", err)
+			}
+		}
+
+		if yaraMatchesStr != "" {
+			if err := json.Unmarshal([]byte(yaraMatchesStr), &result.YaraMatches); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal YARA matches: %w", err)
+			}
+		}
+
+		if processTreeStr != "" {
+			if err := json.Unmarshal([]byte(processTreeStr), &result.ProcessTree); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal process tree: %w", err)
+			}
+		}
+
+		if syscallSummaryStr != "" {
+			if err := json.Unmarshal([]byte(syscallSummaryStr), &result.SyscallSummary); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal syscall summary: %w", err)
+			}
+		}
+
 		results = append(results, result)
 	}
 
 	return results, nil
 }
 
+// GetAnalysisDigestsByUser lists a user's results, newest first, without
+// decoding their analysis data
+func (s *SQLiteDB) GetAnalysisDigestsByUser(userID int) ([]*AnalysisDigest, error) {
+	query := `SELECT id, filename, file_hash, COALESCE(tlsh, ''), COALESCE(ssdeep, ''), created FROM analysis_results WHERE user_id = ? ORDER BY created DESC`
+	rows, err := s.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get analysis digests: %w", err)
+	}
+	defer rows.Close()
+
+	var digests []*AnalysisDigest
+	for rows.Next() {
+		d := &AnalysisDigest{}
+		if err := rows.Scan(&d.ID, &d.Filename, &d.FileHash, &d.TLSH, &d.SSDeep, &d.Created); err != nil {
+			return nil, fmt.Errorf("failed to scan analysis digest: %w", err)
+		}
+		digests = append(digests, d)
+	}
+	return digests, nil
+}
+
+// BackfillAnalysisDigests stores the fuzzy digests of a sample on every
+// result of it stored before fuzzy hashing existed
+func (s *SQLiteDB) BackfillAnalysisDigests(fileHash, tlsh, ssdeep string) error {
+	query := `UPDATE analysis_results SET tlsh = ?, ssdeep = ? WHERE file_hash = ? AND COALESCE(tlsh, '') = '' AND COALESCE(ssdeep, '') = ''`
+	if _, err := s.db.Exec(query, tlsh, ssdeep, fileHash); err != nil {
+		return fmt.Errorf("failed to backfill analysis digests: %w", err)
+	}
+	return nil
+}
+
 func (s *SQLiteDB) GetAnalysisResultByHash(userID int, fileHash string) (*AnalysisResult, error) {
 	result := &AnalysisResult{}
-	var staticDataStr, dynamicDataStr string
+	var staticDataStr, dynamicDataStr, ruleMatchesStr, yaraMatchesStr, processTreeStr, syscallSummaryStr string
 
-	query := `SELECT id, user_id, filename, file_hash, static_data, dynamic_data, created FROM analysis_results WHERE user_id = ? AND file_hash = ? ORDER BY created DESC LIMIT 1`
-	err := s.db.QueryRow(query, userID, fileHash).Scan(&result.ID, &result.UserID, &result.Filename, &result.FileHash, &staticDataStr, &dynamicDataStr, &result.Created)
+	query := `SELECT id, user_id, filename, file_hash, COALESCE(tlsh, ''), COALESCE(ssdeep, ''), static_data, dynamic_data, COALESCE(rule_matches, ''), COALESCE(yara_matches, ''), COALESCE(process_tree, ''), COALESCE(syscall_summary, ''), created FROM analysis_results WHERE user_id = ? AND file_hash = ? ORDER BY created DESC LIMIT 1`
+	err := s.db.QueryRow(query, userID, fileHash).Scan(&result.ID, &result.UserID, &result.Filename, &result.FileHash, &result.TLSH, &result.SSDeep, &staticDataStr, &dynamicDataStr, &ruleMatchesStr, &yaraMatchesStr, &processTreeStr, &syscallSummaryStr, &result.Created)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -334,16 +478,57 @@ func (s *SQLiteDB) GetAnalysisResultByHash(userID int, fileHash string) (*Analys
 		}
 	}
 
+	if ruleMatchesStr != "" {
+		if err := json.Unmarshal([]byte(ruleMatchesStr), &result.RuleMatches); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal rule matches: %w", err)
+		}
+	}
+
+	if yaraMatchesStr != "" {
+		if err := json.Unmarshal([]byte(yaraMatchesStr), &result.YaraMatches); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal YARA matches: %w", err)
+		}
+	}
+
+	if processTreeStr != "" {
+		if err := json.Unmarshal([]byte(processTreeStr), &result.ProcessTree); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal process tree: %w", err)
+		}
+	}
+
+	if syscallSummaryStr != "" {
+		if err := json.Unmarshal([]byte(syscallSummaryStr), &result.SyscallSummary); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal syscall summary: %w", err)
+		}
+	}
+
 	return result, nil
 }
 
+// DeleteAnalysisResult also drops the stored sample once no result refers to it
 func (s *SQLiteDB) DeleteAnalysisResult(id int) error {
-	query := `DELETE FROM analysis_results WHERE id = ?`
-	_, err := s.db.Exec(query, id)
+	tx, err := s.db.Begin()
 	if err != nil {
 		return fmt.Errorf("failed to delete analysis result: %w", err)
 	}
-	return nil
+	defer tx.Rollback()
+
+	var fileHash string
+	err = tx.QueryRow(`SELECT file_hash FROM analysis_results WHERE id = ?`, id).Scan(&fileHash)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete analysis result: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM analysis_results WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete analysis result: %w", err)
+	}
+	query := `DELETE FROM samples WHERE file_hash = ? AND NOT EXISTS (SELECT 1 FROM analysis_results WHERE file_hash = ?)`
+	if _, err := tx.Exec(query, fileHash, fileHash); err != nil {
+		return fmt.Errorf("failed to delete sample: %w", err)
+	}
+	return tx.Commit()
 }
 
 // Benchmark results
@@ -443,3 +628,38 @@ func (s *SQLiteDB) DeleteBenchmarkResult(id int) error {
 	}
 	return nil
 }
+
+// Sample storage
+func (s *SQLiteDB) SaveSample(fileHash string, data []byte) error {
+	query := `INSERT OR IGNORE INTO samples (file_hash, size, data) VALUES (?, ?, ?)`
+	_, err := s.db.Exec(query, fileHash, len(data), data)
+	if err != nil {
+		return fmt.Errorf("failed to save sample: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteDB) GetSample(fileHash string) ([]byte, error) {
+	var data []byte
+	query := `SELECT data FROM samples WHERE file_hash = ?`
+	err := s.db.QueryRow(query, fileHash).Scan(&data)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get sample: %w", err)
+	}
+	return data, nil
+}
+
+// DeleteOrphanedSamples removes samples older than olderThan that no analysis
+// result refers to, such as uploads whose analysis failed or whose owner was
+// deleted
+func (s *SQLiteDB) DeleteOrphanedSamples(olderThan time.Duration) (int64, error) {
+	query := `DELETE FROM samples WHERE created < ? AND file_hash NOT IN (SELECT file_hash FROM analysis_results)`
+	res, err := s.db.Exec(query, time.Now().UTC().Add(-olderThan))
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete orphaned samples: %w", err)
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,439 @@
+package rules
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ashborn3/BinTraceBench/internal/analyzer"
+)
+
+// maxEvidence caps how many hits a single test reports
+const maxEvidence = 5
+
+type matcher func(ctx *evalContext) (bool, []string)
+
+// evalContext caches the derived views of an Input shared by all rules
+type evalContext struct {
+	in *Input
+
+	fields       any
+	fieldsLoaded bool
+
+	strings       []analyzer.ExtractedString
+	stringsLoaded bool
+
+	syscalls []analyzer.VerboseSyscallEntry
+}
+
+func newEvalContext(in *Input) *evalContext {
+	return &evalContext{in: in, syscalls: in.Syscalls}
+}
+
+// staticFields is the static result as generic JSON, so that rules can address
+// any field by its JSON name without a hand-written accessor per field
+func (ctx *evalContext) staticFields() any {
+	if !ctx.fieldsLoaded {
+		ctx.fieldsLoaded = true
+		if ctx.in.Static != nil {
+			if raw, err := json.Marshal(ctx.in.Static); err == nil {
+				json.Unmarshal(raw, &ctx.fields)
+			}
+		}
+	}
+	return ctx.fields
+}
+
+func (ctx *evalContext) extractedStrings() []analyzer.ExtractedString {
+	if !ctx.stringsLoaded {
+		ctx.stringsLoaded = true
+		switch {
+		case ctx.in.Strings != nil:
+			ctx.strings = ctx.in.Strings
+		case ctx.in.Data != nil:
+			var sections []analyzer.SectionInfo
+			if ctx.in.Static != nil {
+				sections = ctx.in.Static.Sections
+			}
+			ctx.strings = analyzer.ExtractStrings(ctx.in.Data, sections, analyzer.DefaultMinStringLength)
+		}
+	}
+	return ctx.strings
+}
+
+func compileCondition(c *Condition) (matcher, error) {
+	kinds := 0
+	for _, set := range []bool{
+		c.All != nil, c.Any != nil, c.Not != nil, c.Sequence != nil,
+		c.Field != "", c.String != "", c.StringRegex != "", c.Bytes != "", c.Syscall != "",
+	} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return nil, fmt.Errorf("each condition needs exactly one of all, any, not, sequence, field, string, string_regex, bytes or syscall")
+	}
+
+	switch {
+	case c.All != nil:
+		return compileAll(c.All)
+	case c.Any != nil:
+		return compileAny(c.Any)
+	case c.Not != nil:
+		inner, err := compileCondition(c.Not)
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx *evalContext) (bool, []string) {
+			ok, _ := inner(ctx)
+			return !ok, nil
+		}, nil
+	case c.Sequence != nil:
+		return compileSequence(c.Sequence)
+	case c.Field != "":
+		return compileField(c)
+	case c.String != "" || c.StringRegex != "":
+		return compileString(c)
+	case c.Bytes != "":
+		return compileBytes(c)
+	default:
+		test, err := compileSyscallTest(c)
+		if err != nil {
+			return nil, err
+		}
+		return countSyscalls(test, c.MinCount), nil
+	}
+}
+
+func compileAll(conds []Condition) (matcher, error) {
+	subs, err := compileList(conds)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx *evalContext) (bool, []string) {
+		var evidence []string
+		for _, m := range subs {
+			ok, ev := m(ctx)
+			if !ok {
+				return false, nil
+			}
+			evidence = append(evidence, ev...)
+		}
+		return true, evidence
+	}, nil
+}
+
+func compileAny(conds []Condition) (matcher, error) {
+	subs, err := compileList(conds)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx *evalContext) (bool, []string) {
+		matched := false
+		var evidence []string
+		for _, m := range subs {
+			if ok, ev := m(ctx); ok {
+				matched = true
+				evidence = append(evidence, ev...)
+			}
+		}
+		return matched, evidence
+	}, nil
+}
+
+func compileList(conds []Condition) ([]matcher, error) {
+	if len(conds) == 0 {
+		return nil, fmt.Errorf("empty condition list")
+	}
+	subs := make([]matcher, 0, len(conds))
+	for i := range conds {
+		m, err := compileCondition(&conds[i])
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, m)
+	}
+	return subs, nil
+}
+
+func compileField(c *Condition) (matcher, error) {
+	var re *regexp.Regexp
+	if c.Matches != "" {
+		var err error
+		if re, err = regexp.Compile(c.Matches); err != nil {
+			return nil, fmt.Errorf("field %s: invalid regex: %w", c.Field, err)
+		}
+	}
+	path := strings.Split(c.Field, ".")
+
+	test := func(v any) bool {
+		s := fmt.Sprint(v)
+		switch {
+		case c.Equals != nil:
+			return s == fmt.Sprint(c.Equals)
+		case c.Contains != "":
+			return strings.Contains(s, c.Contains)
+		case re != nil:
+			return re.MatchString(s)
+		case c.Min != nil || c.Max != nil:
+			n, ok := v.(float64)
+			if !ok {
+				return false
+			}
+			return (c.Min == nil || n >= *c.Min) && (c.Max == nil || n <= *c.Max)
+		default:
+			return truthy(v)
+		}
+	}
+
+	return func(ctx *evalContext) (bool, []string) {
+		for _, v := range lookupField(ctx.staticFields(), path) {
+			if test(v) {
+				return true, []string{fmt.Sprintf("field %s = %v", c.Field, v)}
+			}
+		}
+		return false, nil
+	}, nil
+}
+
+// lookupField walks a dotted path through decoded JSON; arrays fan out so that
+// "sections.name" yields the name of every section
+func lookupField(v any, path []string) []any {
+	if len(path) == 0 {
+		if arr, ok := v.([]any); ok {
+			return arr
+		}
+		return []any{v}
+	}
+	switch node := v.(type) {
+	case map[string]any:
+		child, ok := node[path[0]]
+		if !ok {
+			return nil
+		}
+		return lookupField(child, path[1:])
+	case []any:
+		var out []any
+		for _, elem := range node {
+			out = append(out, lookupField(elem, path)...)
+		}
+		return out
+	}
+	return nil
+}
+
+func truthy(v any) bool {
+	switch x := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return x
+	case float64:
+		return x != 0
+	case string:
+		return x != ""
+	case []any:
+		return len(x) > 0
+	case map[string]any:
+		return len(x) > 0
+	}
+	return true
+}
+
+func compileString(c *Condition) (matcher, error) {
+	var re *regexp.Regexp
+	if c.StringRegex != "" {
+		var err error
+		if re, err = regexp.Compile(c.StringRegex); err != nil {
+			return nil, fmt.Errorf("string_regex: %w", err)
+		}
+	}
+	need := max(c.MinCount, 1)
+
+	return func(ctx *evalContext) (bool, []string) {
+		count := 0
+		var evidence []string
+		for _, s := range ctx.extractedStrings() {
+			if c.Category != "" && s.Category != c.Category {
+				continue
+			}
+			if re != nil && !re.MatchString(s.Value) {
+				continue
+			}
+			if re == nil && !strings.Contains(s.Value, c.String) {
+				continue
+			}
+			count++
+			if len(evidence) < maxEvidence {
+				evidence = append(evidence, fmt.Sprintf("string %s at 0x%x", strconv.Quote(s.Value), s.Offset))
+			}
+		}
+		return count >= need, evidence
+	}, nil
+}
+
+func compileBytes(c *Condition) (matcher, error) {
+	pat, err := parseBytePattern(c.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	need := max(c.MinCount, 1)
+
+	return func(ctx *evalContext) (bool, []string) {
+		offsets := pat.findAll(ctx.in.Data, need)
+		if len(offsets) < need {
+			return false, nil
+		}
+		var evidence []string
+		for _, off := range offsets[:min(len(offsets), maxEvidence)] {
+			evidence = append(evidence, fmt.Sprintf("bytes %s at 0x%x", c.Bytes, off))
+		}
+		return true, evidence
+	}, nil
+}
+
+// bytePattern is a fixed-length hex pattern where mask[i] == false means any byte
+type bytePattern struct {
+	value []byte
+	mask  []bool
+}
+
+func parseBytePattern(s string) (*bytePattern, error) {
+	compact := strings.Join(strings.Fields(s), "")
+	if len(compact) == 0 || len(compact)%2 != 0 {
+		return nil, fmt.Errorf("bytes %q: expected pairs of hex digits or ??", s)
+	}
+	p := &bytePattern{}
+	for i := 0; i < len(compact); i += 2 {
+		pair := compact[i : i+2]
+		if pair == "??" {
+			p.value = append(p.value, 0)
+			p.mask = append(p.mask, false)
+			continue
+		}
+		b, err := hex.DecodeString(pair)
+		if err != nil {
+			return nil, fmt.Errorf("bytes %q: invalid hex %q", s, pair)
+		}
+		p.value = append(p.value, b[0])
+		p.mask = append(p.mask, true)
+	}
+	if !p.mask[0] {
+		return nil, fmt.Errorf("bytes %q: pattern must start with a concrete byte", s)
+	}
+	return p, nil
+}
+
+// findAll returns match offsets, stopping once limit matches beyond the
+// evidence cap have been seen
+func (p *bytePattern) findAll(data []byte, limit int) []int {
+	var offsets []int
+	stop := max(limit, maxEvidence)
+	for start := 0; start+len(p.value) <= len(data); {
+		i := bytes.IndexByte(data[start:], p.value[0])
+		if i < 0 {
+			break
+		}
+		off := start + i
+		if off+len(p.value) > len(data) {
+			break
+		}
+		if p.matchAt(data, off) {
+			offsets = append(offsets, off)
+			if len(offsets) >= stop {
+				break
+			}
+		}
+		start = off + 1
+	}
+	return offsets
+}
+
+func (p *bytePattern) matchAt(data []byte, off int) bool {
+	for j, b := range p.value {
+		if p.mask[j] && data[off+j] != b {
+			return false
+		}
+	}
+	return true
+}
+
+type syscallTest func(e analyzer.VerboseSyscallEntry) bool
+
+func compileSyscallTest(c *Condition) (syscallTest, error) {
+	if c.Syscall == "" {
+		return nil, fmt.Errorf("sequence steps must be syscall tests")
+	}
+	name, err := regexp.Compile("^(?:" + c.Syscall + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("syscall: %w", err)
+	}
+	var args *regexp.Regexp
+	if c.Args != "" {
+		if args, err = regexp.Compile(c.Args); err != nil {
+			return nil, fmt.Errorf("args: %w", err)
+		}
+	}
+	return func(e analyzer.VerboseSyscallEntry) bool {
+		if !name.MatchString(e.Name) {
+			return false
+		}
+		return args == nil || args.MatchString(strings.Join(e.Args, " "))
+	}, nil
+}
+
+func countSyscalls(test syscallTest, minCount int) matcher {
+	need := max(minCount, 1)
+	return func(ctx *evalContext) (bool, []string) {
+		count := 0
+		var evidence []string
+		for i, e := range ctx.syscalls {
+			if !test(e) {
+				continue
+			}
+			count++
+			if len(evidence) < maxEvidence {
+				evidence = append(evidence, describeSyscall(i, e))
+			}
+		}
+		return count >= need, evidence
+	}
+}
+
+func compileSequence(conds []Condition) (matcher, error) {
+	if len(conds) == 0 {
+		return nil, fmt.Errorf("empty sequence")
+	}
+	steps := make([]syscallTest, 0, len(conds))
+	for i := range conds {
+		test, err := compileSyscallTest(&conds[i])
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, test)
+	}
+
+	return func(ctx *evalContext) (bool, []string) {
+		var hits []string
+		step := 0
+		for i, e := range ctx.syscalls {
+			if steps[step](e) {
+				hits = append(hits, describeSyscall(i, e))
+				step++
+				if step == len(steps) {
+					return true, []string{"sequence " + strings.Join(hits, " -> ")}
+				}
+			}
+		}
+		return false, nil
+	}, nil
+}
+
+func describeSyscall(index int, e analyzer.VerboseSyscallEntry) string {
+	return fmt.Sprintf("syscall #%d %s(%s) pid %d", index, e.Name, strings.Join(e.Args, ", "), e.PID)
+}
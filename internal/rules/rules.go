@@ -0,0 +1,246 @@
+// Package rules evaluates declarative YAML detections against static analysis
+// results, extracted strings, raw bytes and syscall traces.
+//
+// A rule file holds one or more YAML documents, each describing one rule:
+//
+//	id: anti-debug-proc-status
+//	description: Checks for a tracer via ptrace and /proc/self/status
+//	severity: high
+//	condition:
+//	  all:
+//	    - syscall: ptrace
+//	    - syscall: "open(at)?"
+//	      args: /proc/self/status
+//
+// Conditions combine with all/any/not/sequence and test one of field (a dotted
+// path into BinaryInfo), string, string_regex, bytes or syscall.
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ashborn3/BinTraceBench/internal/analyzer"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	SeverityInfo   = "info"
+	SeverityLow    = "low"
+	SeverityMedium = "medium"
+	SeverityHigh   = "high"
+)
+
+type Rule struct {
+	ID          string    `yaml:"id" json:"id"`
+	Description string    `yaml:"description" json:"description,omitempty"`
+	Severity    string    `yaml:"severity" json:"severity"`
+	Tags        []string  `yaml:"tags" json:"tags,omitempty"`
+	Condition   Condition `yaml:"condition" json:"-"`
+	File        string    `yaml:"-" json:"file"`
+
+	match matcher
+}
+
+// Condition is one node of a rule's condition tree. Exactly one of the
+// combinators (All, Any, Not, Sequence) or tests (Field, String, StringRegex,
+// Bytes, Syscall) must be set; the remaining fields qualify the test.
+type Condition struct {
+	All      []Condition `yaml:"all"`
+	Any      []Condition `yaml:"any"`
+	Not      *Condition  `yaml:"not"`
+	Sequence []Condition `yaml:"sequence"` // syscall tests that must occur in this order
+
+	Field    string   `yaml:"field"`
+	Equals   any      `yaml:"equals"`
+	Contains string   `yaml:"contains"`
+	Matches  string   `yaml:"matches"`
+	Min      *float64 `yaml:"min"`
+	Max      *float64 `yaml:"max"`
+
+	String      string `yaml:"string"`
+	StringRegex string `yaml:"string_regex"`
+	Category    string `yaml:"category"`
+
+	Bytes string `yaml:"bytes"` // hex with ?? wildcards, e.g. "0f 05 ?? c3"
+
+	Syscall string `yaml:"syscall"` // anchored regex over the syscall name
+	Args    string `yaml:"args"`    // regex over the decoded arguments
+
+	MinCount int `yaml:"min_count"` // occurrences required for string, bytes and syscall tests
+}
+
+// Match is a rule that fired, with the evidence that satisfied its condition
+type Match struct {
+	RuleID      string   `json:"rule_id"`
+	Description string   `json:"description,omitempty"`
+	Severity    string   `json:"severity"`
+	Tags        []string `json:"tags,omitempty"`
+	Evidence    []string `json:"evidence"`
+}
+
+// Input is everything a rule may inspect. Data and Strings are optional; when
+// Strings is nil they are extracted from Data on first use.
+type Input struct {
+	Static   *analyzer.BinaryInfo
+	Data     []byte
+	Strings  []analyzer.ExtractedString
+	Syscalls []analyzer.VerboseSyscallEntry
+}
+
+// Engine holds the rules loaded from a directory and can reload them while
+// evaluations are in flight
+type Engine struct {
+	dir   string
+	mu    sync.RWMutex
+	rules []*Rule
+}
+
+func NewEngine(dir string) *Engine {
+	return &Engine{dir: dir}
+}
+
+// Dir returns the directory rules are loaded from
+func (e *Engine) Dir() string {
+	return e.dir
+}
+
+// Reload parses every *.yml/*.yaml file in the rules directory. The new set
+// replaces the old one only if all files parse, so a bad edit cannot silently
+// drop detections. A missing directory yields an empty rule set.
+func (e *Engine) Reload() (int, error) {
+	loaded, err := LoadDir(e.dir)
+	if err != nil {
+		return 0, err
+	}
+	e.mu.Lock()
+	e.rules = loaded
+	e.mu.Unlock()
+	return len(loaded), nil
+}
+
+// Rules returns the currently loaded rules sorted by ID
+func (e *Engine) Rules() []*Rule {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return append([]*Rule(nil), e.rules...)
+}
+
+// Evaluate runs every loaded rule against in
+func (e *Engine) Evaluate(in *Input) []Match {
+	return Evaluate(e.Rules(), in)
+}
+
+// Evaluate runs rules against in and returns the matches in rule order
+func Evaluate(rules []*Rule, in *Input) []Match {
+	if len(rules) == 0 || in == nil {
+		return nil
+	}
+	ctx := newEvalContext(in)
+	var matches []Match
+	for _, r := range rules {
+		ok, evidence := r.match(ctx)
+		if !ok {
+			continue
+		}
+		matches = append(matches, Match{
+			RuleID:      r.ID,
+			Description: r.Description,
+			Severity:    r.Severity,
+			Tags:        r.Tags,
+			Evidence:    evidence,
+		})
+	}
+	return matches
+}
+
+// LoadDir parses all rule files in dir
+func LoadDir(dir string) ([]*Rule, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read rules directory: %w", err)
+	}
+
+	var all []*Rule
+	seen := map[string]string{}
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".yml" && ext != ".yaml") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open rule file: %w", err)
+		}
+		parsed, err := Parse(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		for _, r := range parsed {
+			if prev, dup := seen[r.ID]; dup {
+				return nil, fmt.Errorf("%s: rule %q already defined in %s", entry.Name(), r.ID, prev)
+			}
+			seen[r.ID] = entry.Name()
+			r.File = entry.Name()
+			all = append(all, r)
+		}
+	}
+
+	sort.Slice(all, func(i, j int) bool { return all[i].ID < all[j].ID })
+	return all, nil
+}
+
+// Parse reads and compiles every rule document in r
+func Parse(r io.Reader) ([]*Rule, error) {
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+
+	var out []*Rule
+	for {
+		rule := &Rule{}
+		if err := dec.Decode(rule); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("invalid rule YAML: %w", err)
+		}
+		if err := rule.compile(); err != nil {
+			return nil, err
+		}
+		out = append(out, rule)
+	}
+	return out, nil
+}
+
+func (r *Rule) compile() error {
+	if r.ID == "" {
+		return fmt.Errorf("rule without id")
+	}
+	switch r.Severity {
+	case "":
+		r.Severity = SeverityMedium
+	case SeverityInfo, SeverityLow, SeverityMedium, SeverityHigh:
+	default:
+		return fmt.Errorf("rule %s: unknown severity %q", r.ID, r.Severity)
+	}
+	m, err := compileCondition(&r.Condition)
+	if err != nil {
+		return fmt.Errorf("rule %s: %w", r.ID, err)
+	}
+	r.match = m
+	return nil
+}
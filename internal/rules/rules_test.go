@@ -0,0 +1,125 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ashborn3/BinTraceBench/internal/analyzer"
+)
+
+const antiDebugRule = `
+id: anti-debug-proc-status
+description: Detects a tracer via ptrace and /proc/self/status
+severity: high
+condition:
+  all:
+    - syscall: ptrace
+    - sequence:
+        - syscall: "open|openat"
+          args: /proc/self/status
+        - syscall: read
+---
+id: packed-upx
+condition:
+  any:
+    - field: sections.name
+      matches: "^UPX[0-9]$"
+    - bytes: "55 50 58 21"
+---
+id: no-nx
+severity: low
+condition:
+  field: hardening.nx
+  equals: false
+`
+
+func TestParseAndEvaluate(t *testing.T) {
+	rules, err := Parse(strings.NewReader(antiDebugRule))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(rules) != 3 || rules[1].Severity != SeverityMedium {
+		t.Fatalf("unexpected rules: %+v", rules)
+	}
+
+	in := &Input{
+		Static: &analyzer.BinaryInfo{
+			Sections:  []analyzer.SectionInfo{{Name: ".text"}, {Name: "UPX1"}},
+			Hardening: &analyzer.HardeningInfo{NX: true},
+		},
+		Data: []byte("\x7fELF....UPX!...."),
+		Syscalls: []analyzer.VerboseSyscallEntry{
+			{Name: "ptrace", Errno: "EPERM"},
+			{Name: "openat", Args: []string{"dirfd=AT_FDCWD", `pathname="/proc/self/status"`, "flags=O_RDONLY"}},
+			{Name: "read"},
+		},
+	}
+
+	matches := Evaluate(rules, in)
+	if len(matches) != 2 {
+		t.Fatalf("expected 2 matches, got %+v", matches)
+	}
+	if matches[0].RuleID != "anti-debug-proc-status" || len(matches[0].Evidence) != 2 {
+		t.Errorf("unexpected anti-debug match: %+v", matches[0])
+	}
+	if !strings.Contains(matches[0].Evidence[1], "openat") {
+		t.Errorf("expected the sequence as evidence: %v", matches[0].Evidence)
+	}
+	if matches[1].RuleID != "packed-upx" || len(matches[1].Evidence) != 2 {
+		t.Errorf("unexpected UPX match: %+v", matches[1])
+	}
+
+	// Reversed order breaks the sequence
+	in.Syscalls[1], in.Syscalls[2] = in.Syscalls[2], in.Syscalls[1]
+	for _, m := range Evaluate(rules, in) {
+		if m.RuleID == "anti-debug-proc-status" {
+			t.Errorf("sequence should not match out of order: %+v", m)
+		}
+	}
+}
+
+func TestParseRejectsInvalidRules(t *testing.T) {
+	cases := map[string]string{
+		"missing id":      "condition: {syscall: ptrace}",
+		"two tests":       "id: x\ncondition: {syscall: ptrace, string: foo}",
+		"bad regex":       "id: x\ncondition: {string_regex: '('}",
+		"bad bytes":       "id: x\ncondition: {bytes: '0f 0'}",
+		"leading wild":    "id: x\ncondition: {bytes: '?? 0f'}",
+		"unknown field":   "id: x\ncondition: {syscal: ptrace}",
+		"bad severity":    "id: x\nseverity: urgent\ncondition: {syscall: ptrace}",
+		"sequence static": "id: x\ncondition: {sequence: [{string: foo}]}",
+	}
+	for name, doc := range cases {
+		if _, err := Parse(strings.NewReader(doc)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestEngineReloadKeepsRulesOnError(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "base.yml"), []byte(antiDebugRule), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	engine := NewEngine(dir)
+	if n, err := engine.Reload(); err != nil || n != 3 {
+		t.Fatalf("Reload = %d, %v", n, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "broken.yaml"), []byte("id: [oops"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := engine.Reload(); err == nil {
+		t.Fatal("expected reload to fail on a broken file")
+	}
+	if len(engine.Rules()) != 3 {
+		t.Errorf("expected previous rules to be kept, got %d", len(engine.Rules()))
+	}
+
+	if n, err := NewEngine(filepath.Join(dir, "missing")).Reload(); err != nil || n != 0 {
+		t.Errorf("missing directory should load no rules: %d, %v", n, err)
+	}
+}
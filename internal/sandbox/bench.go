@@ -2,24 +2,27 @@ package sandbox
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
-	"strconv"
 	"strings"
 	"time"
 
+	"github.com/ashborn3/BinTraceBench/internal/analyzer"
 	"github.com/ashborn3/BinTraceBench/internal/syscalls"
 )
 
 type BenchResult struct {
-	CGroup       string                  `json:"c_group"`
-	InvocationID string                  `json:"invocation_id"` // Question: what's the use for this?
-	ExitCode     int                     `json:"exit_code"`
-	RuntimeMS    int64                   `json:"runtime_ms"`
-	Success      bool                    `json:"success"`
-	ErrorMessage string                  `json:"error_message,omitempty"`
-	Syscalls     []syscalls.SyscallEntry `json:"syscalls,omitempty"`
+	CGroup       string                   `json:"c_group"`
+	InvocationID string                   `json:"invocation_id"` // Question: what's the use for this?
+	ExitCode     int                      `json:"exit_code"`
+	RuntimeMS    int64                    `json:"runtime_ms"`
+	Success      bool                     `json:"success"`
+	ErrorMessage string                   `json:"error_message,omitempty"`
+	Syscalls     []syscalls.SyscallEntry  `json:"syscalls,omitempty"`
+	Summary      *syscalls.Summary        `json:"syscall_summary,omitempty"`
+	Processes    []analyzer.TracedProcess `json:"processes,omitempty"`
 }
 
 func RunBenchmark(filebytes []byte) (*BenchResult, error) {
@@ -85,34 +88,7 @@ func RunBenchmarkWithTrace(filebytes []byte) (*BenchResult, error) {
 	start := time.Now()
 	err = cmd.Run()
 	elapsed := time.Since(start)
-	lines := strings.Split(stdout.String(), "\n")
-	var logs []syscalls.SyscallEntry
-	var cgroupName, invocationID string
-
-	if len(lines) > 0 {
-		firstLine := strings.TrimSpace(lines[0])
-		if strings.HasPrefix(firstLine, "Running as unit:") {
-			// "Running as unit: run-r03369332ebd9417c89fbefe2f3853162.scope; invocation ID: 84495dcd32c040348d67aeeffa22b498"
-			parts := strings.Split(firstLine, ";")
-			if len(parts) >= 2 {
-				unitPart := strings.TrimPrefix(strings.TrimSpace(parts[0]), "Running as unit:")
-				cgroupName = strings.TrimSpace(unitPart)
-				invIDPart := strings.TrimPrefix(strings.TrimSpace(parts[1]), "invocation ID:")
-				invocationID = strings.TrimSpace(invIDPart)
-			}
-		}
-	}
-
-	for _, line := range lines[1:] {
-		if strings.TrimSpace(line) != "" {
-			regVals := strings.Split(line, " ")
-			idx, _ := strconv.Atoi(regVals[0])
-			logs = append(logs, syscalls.SyscallEntry{
-				Name: syscalls.SyscallNames[uint64(idx)],
-				Args: regVals[1:],
-			})
-		}
-	}
+	cgroupName, invocationID, logs, procs := parseTracerOutput(stdout.String())
 
 	exitCode := 0
 	if exitErr, ok := err.(*exec.ExitError); ok {
@@ -126,5 +102,36 @@ func RunBenchmarkWithTrace(filebytes []byte) (*BenchResult, error) {
 		RuntimeMS:    elapsed.Milliseconds(),
 		Success:      exitCode == 0,
 		Syscalls:     logs,
+		Summary:      syscalls.Summarize(logs),
+		Processes:    procs,
 	}, nil
 }
+
+// parseTracerOutput reads what bintracer prints under systemd-run: an optional
+// "Running as unit" banner, one "syscall <json>" line per syscall and a
+// final "processes <json>" line with the process tree
+func parseTracerOutput(out string) (cgroupName, invocationID string, logs []syscalls.SyscallEntry, procs []analyzer.TracedProcess) {
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "Running as unit:"):
+			// "Running as unit: run-r03369332ebd9417c89fbefe2f3853162.scope; invocation ID: 84495dcd32c040348d67aeeffa22b498"
+			parts := strings.Split(line, ";")
+			if len(parts) >= 2 {
+				unitPart := strings.TrimPrefix(strings.TrimSpace(parts[0]), "Running as unit:")
+				cgroupName = strings.TrimSpace(unitPart)
+				invIDPart := strings.TrimPrefix(strings.TrimSpace(parts[1]), "invocation ID:")
+				invocationID = strings.TrimSpace(invIDPart)
+			}
+		case strings.HasPrefix(line, "processes "):
+			json.Unmarshal([]byte(strings.TrimPrefix(line, "processes ")), &procs)
+		case strings.HasPrefix(line, "syscall "):
+			var entry syscalls.SyscallEntry
+			if json.Unmarshal([]byte(strings.TrimPrefix(line, "syscall ")), &entry) == nil {
+				logs = append(logs, entry)
+			}
+		}
+	}
+	return cgroupName, invocationID, logs, procs
+}
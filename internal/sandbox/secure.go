@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"os/exec"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/ashborn3/BinTraceBench/internal/syscalls"
@@ -114,40 +113,9 @@ func RunBenchmarkWithTraceSecure(filebytes []byte, config *Config) (*BenchResult
 	err = cmd.Run()
 	elapsed := time.Since(start)
 
-	lines := strings.Split(stdout.String(), "\n")
-	var logs []syscalls.SyscallEntry
-	var cgroupName, invocationID string
+	cgroupName, invocationID, logs, procs := parseTracerOutput(stdout.String())
 	var errorMsg string
 
-	if len(lines) > 0 {
-		firstLine := strings.TrimSpace(lines[0])
-		if strings.HasPrefix(firstLine, "Running as unit:") {
-			parts := strings.Split(firstLine, ";")
-			if len(parts) >= 2 {
-				unitPart := strings.TrimPrefix(strings.TrimSpace(parts[0]), "Running as unit:")
-				cgroupName = strings.TrimSpace(unitPart)
-				invIDPart := strings.TrimPrefix(strings.TrimSpace(parts[1]), "invocation ID:")
-				invocationID = strings.TrimSpace(invIDPart)
-			}
-		}
-	}
-
-	// Parse syscall logs
-	for _, line := range lines[1:] {
-		if strings.TrimSpace(line) != "" {
-			regVals := strings.Split(line, " ")
-			if len(regVals) > 0 {
-				idx, parseErr := strconv.Atoi(regVals[0])
-				if parseErr == nil {
-					logs = append(logs, syscalls.SyscallEntry{
-						Name: syscalls.SyscallNames[uint64(idx)],
-						Args: regVals[1:],
-					})
-				}
-			}
-		}
-	}
-
 	exitCode := 0
 	if err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
@@ -168,6 +136,8 @@ func RunBenchmarkWithTraceSecure(filebytes []byte, config *Config) (*BenchResult
 		RuntimeMS:    elapsed.Milliseconds(),
 		Success:      exitCode == 0,
 		Syscalls:     logs,
+		Summary:      syscalls.Summarize(logs),
+		Processes:    procs,
 	}
 
 	if errorMsg != "" {
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+
+	"github.com/ashborn3/BinTraceBench/internal/analyzer"
 )
 
 const (
@@ -17,17 +19,8 @@ func ValidateBinary(data []byte) error {
 }
 
 func ValidateBinaryWithConfig(data []byte, config *Config) error {
-	if len(data) == 0 {
-		return fmt.Errorf("empty file")
-	}
-
-	if int64(len(data)) > config.MaxFileSize {
-		return fmt.Errorf("file too large: %d bytes (max %d)", len(data), config.MaxFileSize)
-	}
-
-	// Check if it's an ELF file
-	if len(data) < 4 {
-		return fmt.Errorf("file too small to be a valid binary")
+	if err := validateSize(data, config); err != nil {
+		return err
 	}
 
 	// ELF magic number check
@@ -45,6 +38,36 @@ func ValidateBinaryWithConfig(data []byte, config *Config) error {
 	return nil
 }
 
+// ValidateStaticBinary accepts any format the static analyzer understands
+// (ELF, PE or Mach-O). Only ValidateBinary clears a file for execution.
+func ValidateStaticBinary(data []byte) error {
+	if err := validateSize(data, DefaultConfig()); err != nil {
+		return err
+	}
+
+	if _, err := analyzer.DetectFormat(data); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func validateSize(data []byte, config *Config) error {
+	if len(data) == 0 {
+		return fmt.Errorf("empty file")
+	}
+
+	if int64(len(data)) > config.MaxFileSize {
+		return fmt.Errorf("file too large: %d bytes (max %d)", len(data), config.MaxFileSize)
+	}
+
+	if len(data) < 4 {
+		return fmt.Errorf("file too small to be a valid binary")
+	}
+
+	return nil
+}
+
 func CreateSecureTempFile(data []byte, prefix string) (string, func(), error) {
 	tempDir, err := os.MkdirTemp("", "bintracebench-sandbox-*")
 	if err != nil {
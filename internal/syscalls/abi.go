@@ -0,0 +1,161 @@
+package syscalls
+
+import (
+	"debug/elf"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ABI is a Linux syscall calling convention: its syscall numbers and the
+// sizes and layouts of the structures syscall arguments point to
+type ABI struct {
+	Name  string
+	Names map[uint64]string
+
+	longSize  int // size of a C long and of pointers
+	openFlags []flagName
+	stat      statLayout // struct stat
+	stat64    statLayout // struct stat64, for the *64 calls of 32-bit ABIs
+	// signatures overrides Signatures where a call of the same name takes
+	// other arguments
+	signatures map[string][]ArgSpec
+}
+
+// statLayout locates the fields of a struct stat that get decoded
+type statLayout struct {
+	size                          int
+	ino, mode, uid, gid, fileSize statField
+}
+
+type statField struct{ off, size int }
+
+var (
+	X8664 = &ABI{
+		Name:      "x86_64",
+		Names:     SyscallNames,
+		longSize:  8,
+		openFlags: openFlags,
+		stat: statLayout{size: 144, ino: statField{8, 8}, mode: statField{24, 4},
+			uid: statField{28, 4}, gid: statField{32, 4}, fileSize: statField{48, 8}},
+	}
+	I386 = &ABI{
+		Name:      "i386",
+		Names:     SyscallNamesI386,
+		longSize:  4,
+		openFlags: openFlags,
+		stat: statLayout{size: 64, ino: statField{4, 4}, mode: statField{8, 2},
+			uid: statField{12, 2}, gid: statField{14, 2}, fileSize: statField{20, 4}},
+		stat64: statLayout{size: 96, ino: statField{88, 8}, mode: statField{16, 4},
+			uid: statField{24, 4}, gid: statField{28, 4}, fileSize: statField{44, 8}},
+		signatures: map[string][]ArgSpec{
+			// old_mmap, which takes its arguments in memory like socketcall
+			"mmap": {arg("args", ArgHex)},
+		},
+	}
+	ARM64 = &ABI{
+		Name:      "arm64",
+		Names:     SyscallNamesARM64,
+		longSize:  8,
+		openFlags: openFlagsARM64,
+		stat: statLayout{size: 128, ino: statField{8, 8}, mode: statField{16, 4},
+			uid: statField{24, 4}, gid: statField{28, 4}, fileSize: statField{48, 8}},
+	}
+)
+
+// ABIForMachine returns the native syscall ABI of an ELF machine type, or nil
+// when it is not supported
+func ABIForMachine(machine elf.Machine) *ABI {
+	switch machine {
+	case elf.EM_X86_64:
+		return X8664
+	case elf.EM_386:
+		return I386
+	case elf.EM_AARCH64:
+		return ARM64
+	}
+	return nil
+}
+
+// SyscallName names syscall number nr of the ABI
+func (a *ABI) SyscallName(nr uint64) string {
+	if name, ok := a.Names[nr]; ok {
+		return name
+	}
+	return fmt.Sprintf("syscall_%d", nr)
+}
+
+// signature returns the arguments syscall name takes on the ABI
+func (a *ABI) signature(name string) ([]ArgSpec, bool) {
+	if specs, ok := a.signatures[name]; ok {
+		return specs, true
+	}
+	specs, ok := Signatures[name]
+	return specs, ok
+}
+
+// Return interprets a return value register. On 32-bit ABIs only the low
+// half counts; it is negative only when it holds an errno, so addresses
+// above 2GB returned by mmap2 stay positive.
+func (a *ABI) Return(raw uint64) int64 {
+	if a.longSize == 4 {
+		v := uint32(raw)
+		if v > ^uint32(maxErrno) {
+			return int64(int32(v))
+		}
+		return int64(v)
+	}
+	return int64(raw)
+}
+
+// word reads a C long or pointer from tracee memory
+func (a *ABI) word(data []byte) uint64 {
+	if a.longSize == 4 {
+		return uint64(binary.LittleEndian.Uint32(data))
+	}
+	return binary.LittleEndian.Uint64(data)
+}
+
+// signed sign-extends an int argument of the ABI
+func (a *ABI) signed(raw uint64) int64 {
+	if a.longSize == 4 {
+		return int64(int32(raw))
+	}
+	return int64(raw)
+}
+
+// socketcall unpacks the i386 socket syscall multiplexer: the first argument
+// picks the call and the second points to an array holding its arguments
+func (a *ABI) socketcall(regs [6]uint64, mem Memory) (string, [6]uint64, bool) {
+	var inner [6]uint64
+	name, ok := socketcalls[regs[0]]
+	if !ok {
+		return "", inner, false
+	}
+	specs, _ := a.signature(name)
+	n := len(specs)
+	data := mem(regs[1], n*a.longSize)
+	if len(data) < n*a.longSize {
+		return "", inner, false
+	}
+	for i := 0; i < n; i++ {
+		inner[i] = a.word(data[i*a.longSize:])
+	}
+	return name, inner, true
+}
+
+// socketcallValue names a socketcall call number, e.g. SYS_CONNECT
+func socketcallValue(call uint64) string {
+	if name, ok := socketcalls[call]; ok {
+		return "SYS_" + strings.ToUpper(name)
+	}
+	return strconv.FormatUint(call, 10)
+}
+
+var socketcalls = map[uint64]string{
+	1: "socket", 2: "bind", 3: "connect", 4: "listen", 5: "accept", 6: "getsockname",
+	7: "getpeername", 8: "socketpair", 9: "send", 10: "recv", 11: "sendto", 12: "recvfrom",
+	13: "shutdown", 14: "setsockopt", 15: "getsockopt", 16: "sendmsg", 17: "recvmsg",
+	18: "accept4", 19: "recvmmsg", 20: "sendmmsg",
+}
@@ -0,0 +1,558 @@
+package syscalls
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const (
+	maxStringLen      = 256
+	maxStringArrayLen = 16
+	maxBufferPreview  = 32
+	maxSockaddrLen    = 110 // sizeof(struct sockaddr_un)
+)
+
+// Memory reads up to n bytes of the traced process at addr. It returns the
+// readable prefix, which is empty when addr is not mapped.
+type Memory func(addr uint64, n int) []byte
+
+// Arg is one decoded syscall argument
+type Arg struct {
+	Name  string  `json:"name"`
+	Type  ArgType `json:"type"`
+	Raw   uint64  `json:"raw"`
+	Value string  `json:"value"`
+}
+
+// String renders the argument strace-style as name=value
+func (a Arg) String() string {
+	return a.Name + "=" + a.Value
+}
+
+// DecodeEntry decodes the arguments of a syscall at entry. Output arguments
+// are only shown as addresses since the kernel has not filled them in yet.
+func (a *ABI) DecodeEntry(name string, regs [6]uint64, mem Memory) []Arg {
+	if name == "socketcall" {
+		if inner, innerRegs, ok := a.socketcall(regs, mem); ok {
+			call := a.decodeArg(Signatures["socketcall"][0], regs, regs[0], -1, mem)
+			return append([]Arg{call}, a.DecodeEntry(inner, innerRegs, mem)...)
+		}
+	}
+	specs, ok := a.signature(name)
+	if !ok {
+		args := make([]Arg, len(regs))
+		for i, raw := range regs {
+			args[i] = Arg{Name: fmt.Sprintf("arg%d", i), Type: ArgHex, Raw: raw, Value: hexValue(raw)}
+		}
+		return args
+	}
+	args := make([]Arg, len(specs))
+	for i, spec := range specs {
+		if spec.Out {
+			args[i] = Arg{Name: spec.Name, Type: ArgHex, Raw: regs[i], Value: hexValue(regs[i])}
+			continue
+		}
+		args[i] = a.decodeArg(spec, regs, regs[i], -1, mem)
+	}
+	return args
+}
+
+// DecodeExit decodes the output arguments of a completed syscall in place,
+// given the arguments DecodeEntry produced and the registers it was given.
+// Outputs of failed calls stay addresses.
+func (a *ABI) DecodeExit(name string, regs [6]uint64, ret int64, mem Memory, args []Arg) {
+	if ret < 0 {
+		return
+	}
+	if name == "socketcall" {
+		inner, innerRegs, ok := a.socketcall(regs, mem)
+		if !ok || len(args) == 0 {
+			return
+		}
+		name, regs, args = inner, innerRegs, args[1:]
+	}
+	specs, _ := a.signature(name)
+	for i, spec := range specs {
+		if spec.Out && i < len(args) {
+			args[i] = a.decodeArg(spec, regs, regs[i], ret, mem)
+		}
+	}
+}
+
+// Strings renders decoded arguments strace-style
+func Strings(args []Arg) []string {
+	out := make([]string, len(args))
+	for i, a := range args {
+		out[i] = a.String()
+	}
+	return out
+}
+
+// decodeArg renders one argument. ret is the syscall's return value at exit
+// and negative at entry.
+func (abi *ABI) decodeArg(spec ArgSpec, regs [6]uint64, raw uint64, ret int64, mem Memory) Arg {
+	a := Arg{Name: spec.Name, Type: spec.Type, Raw: raw}
+	switch spec.Type {
+	case ArgInt:
+		a.Value = strconv.FormatInt(abi.signed(raw), 10)
+	case ArgUint:
+		a.Value = strconv.FormatUint(raw, 10)
+	case ArgFD:
+		a.Value = strconv.Itoa(int(int32(raw)))
+	case ArgDirFD:
+		a.Value = dirfdValue(raw)
+	case ArgPath, ArgString:
+		a.Value = pointerValue(raw, func() string { return strconv.Quote(readString(mem, raw)) })
+	case ArgStringArray:
+		a.Value = pointerValue(raw, func() string { return stringArrayValue(mem, raw, abi) })
+	case ArgBuffer:
+		n := regs[spec.Len]
+		if ret >= 0 && uint64(ret) < n {
+			n = uint64(ret)
+		}
+		a.Value = pointerValue(raw, func() string { return bufferValue(mem, raw, n) })
+	case ArgSockaddr:
+		n := uint64(maxSockaddrLen)
+		if spec.Len >= 0 && regs[spec.Len] < n {
+			n = regs[spec.Len]
+		}
+		a.Value = pointerValue(raw, func() string { return SockaddrString(mem(raw, int(n))) })
+	case ArgStat:
+		a.Value = pointerValue(raw, func() string { return statValue(mem(raw, abi.stat.size), abi.stat) })
+	case ArgStat64:
+		a.Value = pointerValue(raw, func() string { return statValue(mem(raw, abi.stat64.size), abi.stat64) })
+	case ArgTimespec:
+		a.Value = pointerValue(raw, func() string { return timespecValue(mem(raw, 2*abi.longSize), abi.longSize) })
+	case ArgTimespec64:
+		a.Value = pointerValue(raw, func() string { return timespecValue(mem(raw, 16), 8) })
+	case ArgFDPair:
+		a.Value = pointerValue(raw, func() string { return fdPairValue(mem(raw, 8)) })
+	case ArgOpenFlags:
+		a.Value = abi.OpenFlags(raw)
+	case ArgMode:
+		a.Value = fmt.Sprintf("%#o", raw)
+	case ArgAccessMode:
+		a.Value = flagsValue(raw, accessModes, "F_OK")
+	case ArgAtFlags:
+		a.Value = flagsValue(raw, atFlags, "0")
+	case ArgProt:
+		a.Value = ProtFlags(raw)
+	case ArgMmapFlags:
+		a.Value = mmapFlagsValue(raw)
+	case ArgSocketDomain:
+		a.Value = enumValue(raw, socketDomains)
+	case ArgSocketType:
+		a.Value = socketTypeValue(raw)
+	case ArgSignal:
+		a.Value = enumValue(raw, SignalNames)
+	case ArgSigmaskHow:
+		a.Value = enumValue(raw, sigmaskHows)
+	case ArgWhence:
+		a.Value = enumValue(raw, whences)
+	case ArgCloneFlags:
+		a.Value = cloneFlagsValue(raw)
+	case ArgFcntlCmd:
+		a.Value = enumValue(raw, fcntlCmds)
+	case ArgPtraceRequest:
+		a.Value = enumValue(raw, ptraceRequests)
+	case ArgPrctlOption:
+		a.Value = enumValue(raw, prctlOptions)
+	case ArgArchPrctlCode:
+		a.Value = enumValue(raw, archPrctlCodes)
+	case ArgFutexOp:
+		a.Value = futexOpValue(raw)
+	case ArgWaitOptions:
+		a.Value = flagsValue(raw, waitOptions, "0")
+	case ArgClockID:
+		a.Value = enumValue(raw, clockIDs)
+	case ArgMemfdFlags:
+		a.Value = flagsValue(raw, memfdFlags, "0")
+	case ArgGetrandomFlags:
+		a.Value = flagsValue(raw, getrandomFlags, "0")
+	case ArgSocketcall:
+		a.Value = socketcallValue(raw)
+	default:
+		a.Value = hexValue(raw)
+	}
+	return a
+}
+
+func hexValue(raw uint64) string {
+	return fmt.Sprintf("%#x", raw)
+}
+
+// pointerValue shows NULL for null pointers and decodes the pointee otherwise
+func pointerValue(raw uint64, decode func() string) string {
+	if raw == 0 {
+		return "NULL"
+	}
+	return decode()
+}
+
+func dirfdValue(raw uint64) string {
+	fd := int32(raw)
+	if fd == atFDCWD {
+		return "AT_FDCWD"
+	}
+	return strconv.Itoa(int(fd))
+}
+
+func readString(mem Memory, addr uint64) string {
+	var data []byte
+	for len(data) < maxStringLen {
+		chunk := mem(addr+uint64(len(data)), 64)
+		if len(chunk) == 0 {
+			break
+		}
+		if i := strings.IndexByte(string(chunk), 0); i >= 0 {
+			return string(append(data, chunk[:i]...))
+		}
+		data = append(data, chunk...)
+	}
+	if len(data) > maxStringLen {
+		data = data[:maxStringLen]
+	}
+	return string(data)
+}
+
+func stringArrayValue(mem Memory, addr uint64, abi *ABI) string {
+	var items []string
+	for i := 0; i < maxStringArrayLen; i++ {
+		ptr := mem(addr+uint64(i*abi.longSize), abi.longSize)
+		if len(ptr) < abi.longSize {
+			break
+		}
+		p := abi.word(ptr)
+		if p == 0 {
+			return "[" + strings.Join(items, ", ") + "]"
+		}
+		items = append(items, strconv.Quote(readString(mem, p)))
+	}
+	return "[" + strings.Join(items, ", ") + ", ...]"
+}
+
+// bufferValue quotes the first bytes of a buffer of n bytes
+func bufferValue(mem Memory, addr, n uint64) string {
+	data := mem(addr, int(min(n, maxBufferPreview)))
+	s := strconv.Quote(string(data))
+	if n > uint64(len(data)) {
+		s += "..."
+	}
+	return s
+}
+
+// SockaddrString renders a socket address as host:port, [host]:port for IPv6
+// or the socket path for AF_UNIX
+func SockaddrString(data []byte) string {
+	if len(data) < 2 {
+		return "{}"
+	}
+	family := binary.LittleEndian.Uint16(data)
+	switch family {
+	case afInet:
+		if len(data) >= 8 {
+			port := binary.BigEndian.Uint16(data[2:])
+			return net.JoinHostPort(net.IP(data[4:8]).String(), strconv.Itoa(int(port)))
+		}
+	case afInet6:
+		if len(data) >= 24 {
+			port := binary.BigEndian.Uint16(data[2:])
+			return net.JoinHostPort(net.IP(data[8:24]).String(), strconv.Itoa(int(port)))
+		}
+	case afUnix:
+		path := data[2:]
+		if len(path) > 0 && path[0] == 0 {
+			// Abstract socket names are not NUL-terminated
+			return strconv.Quote("@" + strings.TrimRight(string(path[1:]), "\x00"))
+		}
+		if i := strings.IndexByte(string(path), 0); i >= 0 {
+			path = path[:i]
+		}
+		return strconv.Quote(string(path))
+	}
+	return "{" + enumValue(uint64(family), socketDomains) + "}"
+}
+
+// statValue shows the interesting fields of a struct stat
+func statValue(data []byte, layout statLayout) string {
+	if layout.size == 0 || len(data) < layout.size {
+		return "{...}"
+	}
+	field := func(f statField) uint64 {
+		switch f.size {
+		case 2:
+			return uint64(binary.LittleEndian.Uint16(data[f.off:]))
+		case 4:
+			return uint64(binary.LittleEndian.Uint32(data[f.off:]))
+		}
+		return binary.LittleEndian.Uint64(data[f.off:])
+	}
+	return fmt.Sprintf("{st_mode=%s, st_size=%d, st_ino=%d, st_uid=%d, st_gid=%d}",
+		fileModeValue(uint32(field(layout.mode))), int64(field(layout.fileSize)), field(layout.ino),
+		field(layout.uid), field(layout.gid))
+}
+
+func fileModeValue(mode uint32) string {
+	kind, ok := fileTypes[mode&sIFMT]
+	if !ok {
+		kind = fmt.Sprintf("%#o", mode&sIFMT)
+	}
+	return fmt.Sprintf("%s|%#o", kind, mode&^sIFMT)
+}
+
+// timespecValue decodes a struct timespec whose fields are size bytes each
+func timespecValue(data []byte, size int) string {
+	if len(data) < 2*size {
+		return "{...}"
+	}
+	le := binary.LittleEndian
+	if size == 4 {
+		return fmt.Sprintf("{tv_sec=%d, tv_nsec=%d}", int32(le.Uint32(data)), int32(le.Uint32(data[4:])))
+	}
+	return fmt.Sprintf("{tv_sec=%d, tv_nsec=%d}", int64(le.Uint64(data)), int64(le.Uint64(data[8:])))
+}
+
+func fdPairValue(data []byte) string {
+	if len(data) < 8 {
+		return "[...]"
+	}
+	le := binary.LittleEndian
+	return fmt.Sprintf("[%d, %d]", int32(le.Uint32(data)), int32(le.Uint32(data[4:])))
+}
+
+type flagName struct {
+	mask uint64
+	name string
+}
+
+// flagsValue joins the names of the set flags with |, in table order so that
+// multi-bit flags listed first claim their bits. Unknown bits are appended in hex.
+func flagsValue(v uint64, names []flagName, zero string) string {
+	if v == 0 {
+		return zero
+	}
+	var parts []string
+	for _, f := range names {
+		if f.mask != 0 && v&f.mask == f.mask {
+			parts = append(parts, f.name)
+			v &^= f.mask
+		}
+	}
+	if v != 0 {
+		parts = append(parts, hexValue(v))
+	}
+	return strings.Join(parts, "|")
+}
+
+func enumValue[K int | uint64](v uint64, names map[K]string) string {
+	if name, ok := names[K(v)]; ok {
+		return name
+	}
+	return strconv.FormatInt(int64(v), 10)
+}
+
+// OpenFlags renders open(2) flags, e.g. O_RDONLY|O_CLOEXEC
+func (a *ABI) OpenFlags(v uint64) string {
+	access := []string{"O_RDONLY", "O_WRONLY", "O_RDWR", "O_ACCMODE"}[v&3]
+	if rest := v &^ 3; rest != 0 {
+		return access + "|" + flagsValue(rest, a.openFlags, "")
+	}
+	return access
+}
+
+// ProtFlags renders mmap/mprotect protection bits, e.g. PROT_READ|PROT_EXEC
+func ProtFlags(v uint64) string {
+	return flagsValue(v, protFlags, "PROT_NONE")
+}
+
+func mmapFlagsValue(v uint64) string {
+	kind, ok := mmapTypes[v&0xf]
+	if !ok {
+		return flagsValue(v, mmapFlags, "0")
+	}
+	if rest := v &^ 0xf; rest != 0 {
+		return kind + "|" + flagsValue(rest, mmapFlags, "")
+	}
+	return kind
+}
+
+// socketTypeValue decodes a socket type; accept4 passes only the flags
+func socketTypeValue(v uint64) string {
+	if v&0xf == 0 {
+		return flagsValue(v, socketTypeFlags, "0")
+	}
+	kind := enumValue(v&0xf, socketTypes)
+	if rest := v &^ 0xf; rest != 0 {
+		return kind + "|" + flagsValue(rest, socketTypeFlags, "")
+	}
+	return kind
+}
+
+// cloneFlagsValue decodes clone flags; the low byte is the exit signal
+func cloneFlagsValue(v uint64) string {
+	flags := flagsValue(v&^0xff, cloneFlags, "")
+	if sig := v & 0xff; sig != 0 {
+		if flags != "" {
+			flags += "|"
+		}
+		flags += enumValue(sig, SignalNames)
+	}
+	if flags == "" {
+		return "0"
+	}
+	return flags
+}
+
+func futexOpValue(v uint64) string {
+	op := enumValue(v&^(futexPrivate|futexClockRealtime), futexOps)
+	if v&futexPrivate != 0 {
+		op += "|FUTEX_PRIVATE_FLAG"
+	}
+	if v&futexClockRealtime != 0 {
+		op += "|FUTEX_CLOCK_REALTIME"
+	}
+	return op
+}
+
+const (
+	atFDCWD            = -100
+	afUnix             = 1
+	afInet             = 2
+	afInet6            = 10
+	sIFMT              = 0170000
+	futexPrivate       = 128
+	futexClockRealtime = 256
+)
+
+// SignalNames maps Linux signal numbers to their names
+var SignalNames = map[int]string{
+	1: "SIGHUP", 2: "SIGINT", 3: "SIGQUIT", 4: "SIGILL", 5: "SIGTRAP", 6: "SIGABRT",
+	7: "SIGBUS", 8: "SIGFPE", 9: "SIGKILL", 10: "SIGUSR1", 11: "SIGSEGV", 12: "SIGUSR2",
+	13: "SIGPIPE", 14: "SIGALRM", 15: "SIGTERM", 16: "SIGSTKFLT", 17: "SIGCHLD",
+	18: "SIGCONT", 19: "SIGSTOP", 20: "SIGTSTP", 21: "SIGTTIN", 22: "SIGTTOU",
+	23: "SIGURG", 24: "SIGXCPU", 25: "SIGXFSZ", 26: "SIGVTALRM", 27: "SIGPROF",
+	28: "SIGWINCH", 29: "SIGIO", 30: "SIGPWR", 31: "SIGSYS",
+}
+
+var openFlags = []flagName{
+	{020200000, "O_TMPFILE"}, {04010000, "O_SYNC"},
+	{0100, "O_CREAT"}, {0200, "O_EXCL"}, {0400, "O_NOCTTY"}, {01000, "O_TRUNC"},
+	{02000, "O_APPEND"}, {04000, "O_NONBLOCK"}, {010000, "O_DSYNC"}, {020000, "O_ASYNC"},
+	{040000, "O_DIRECT"}, {0100000, "O_LARGEFILE"}, {0200000, "O_DIRECTORY"},
+	{0400000, "O_NOFOLLOW"}, {01000000, "O_NOATIME"}, {02000000, "O_CLOEXEC"},
+	{010000000, "O_PATH"},
+}
+
+// openFlagsARM64 differs from x86 in where O_DIRECTORY, O_NOFOLLOW, O_DIRECT
+// and O_LARGEFILE live
+var openFlagsARM64 = []flagName{
+	{020040000, "O_TMPFILE"}, {04010000, "O_SYNC"},
+	{0100, "O_CREAT"}, {0200, "O_EXCL"}, {0400, "O_NOCTTY"}, {01000, "O_TRUNC"},
+	{02000, "O_APPEND"}, {04000, "O_NONBLOCK"}, {010000, "O_DSYNC"}, {020000, "O_ASYNC"},
+	{040000, "O_DIRECTORY"}, {0100000, "O_NOFOLLOW"}, {0200000, "O_DIRECT"},
+	{0400000, "O_LARGEFILE"}, {01000000, "O_NOATIME"}, {02000000, "O_CLOEXEC"},
+	{010000000, "O_PATH"},
+}
+
+var accessModes = []flagName{{4, "R_OK"}, {2, "W_OK"}, {1, "X_OK"}}
+
+var atFlags = []flagName{
+	{0x100, "AT_SYMLINK_NOFOLLOW"}, {0x200, "AT_REMOVEDIR"}, {0x400, "AT_SYMLINK_FOLLOW"},
+	{0x800, "AT_NO_AUTOMOUNT"}, {0x1000, "AT_EMPTY_PATH"},
+}
+
+var protFlags = []flagName{
+	{1, "PROT_READ"}, {2, "PROT_WRITE"}, {4, "PROT_EXEC"},
+	{0x01000000, "PROT_GROWSDOWN"}, {0x02000000, "PROT_GROWSUP"},
+}
+
+var mmapTypes = map[uint64]string{1: "MAP_SHARED", 2: "MAP_PRIVATE", 3: "MAP_SHARED_VALIDATE"}
+
+var mmapFlags = []flagName{
+	{0x10, "MAP_FIXED"}, {0x20, "MAP_ANONYMOUS"}, {0x40, "MAP_32BIT"}, {0x100, "MAP_GROWSDOWN"},
+	{0x800, "MAP_DENYWRITE"}, {0x1000, "MAP_EXECUTABLE"}, {0x2000, "MAP_LOCKED"},
+	{0x4000, "MAP_NORESERVE"}, {0x8000, "MAP_POPULATE"}, {0x10000, "MAP_NONBLOCK"},
+	{0x20000, "MAP_STACK"}, {0x40000, "MAP_HUGETLB"}, {0x80000, "MAP_SYNC"},
+	{0x100000, "MAP_FIXED_NOREPLACE"},
+}
+
+var socketDomains = map[uint64]string{
+	0: "AF_UNSPEC", 1: "AF_UNIX", 2: "AF_INET", 10: "AF_INET6", 16: "AF_NETLINK",
+	17: "AF_PACKET", 31: "AF_BLUETOOTH", 38: "AF_ALG", 40: "AF_VSOCK",
+}
+
+var socketTypes = map[uint64]string{
+	1: "SOCK_STREAM", 2: "SOCK_DGRAM", 3: "SOCK_RAW", 4: "SOCK_RDM", 5: "SOCK_SEQPACKET",
+	10: "SOCK_PACKET",
+}
+
+var socketTypeFlags = []flagName{{04000, "SOCK_NONBLOCK"}, {02000000, "SOCK_CLOEXEC"}}
+
+var fileTypes = map[uint32]string{
+	0140000: "S_IFSOCK", 0120000: "S_IFLNK", 0100000: "S_IFREG", 060000: "S_IFBLK",
+	040000: "S_IFDIR", 020000: "S_IFCHR", 010000: "S_IFIFO",
+}
+
+var sigmaskHows = map[uint64]string{0: "SIG_BLOCK", 1: "SIG_UNBLOCK", 2: "SIG_SETMASK"}
+
+var whences = map[uint64]string{0: "SEEK_SET", 1: "SEEK_CUR", 2: "SEEK_END", 3: "SEEK_DATA", 4: "SEEK_HOLE"}
+
+var cloneFlags = []flagName{
+	{0x100, "CLONE_VM"}, {0x200, "CLONE_FS"}, {0x400, "CLONE_FILES"}, {0x800, "CLONE_SIGHAND"},
+	{0x1000, "CLONE_PIDFD"}, {0x2000, "CLONE_PTRACE"}, {0x4000, "CLONE_VFORK"},
+	{0x8000, "CLONE_PARENT"}, {0x10000, "CLONE_THREAD"}, {0x20000, "CLONE_NEWNS"},
+	{0x40000, "CLONE_SYSVSEM"}, {0x80000, "CLONE_SETTLS"}, {0x100000, "CLONE_PARENT_SETTID"},
+	{0x200000, "CLONE_CHILD_CLEARTID"}, {0x400000, "CLONE_DETACHED"}, {0x800000, "CLONE_UNTRACED"},
+	{0x1000000, "CLONE_CHILD_SETTID"}, {0x2000000, "CLONE_NEWCGROUP"}, {0x4000000, "CLONE_NEWUTS"},
+	{0x8000000, "CLONE_NEWIPC"}, {0x10000000, "CLONE_NEWUSER"}, {0x20000000, "CLONE_NEWPID"},
+	{0x40000000, "CLONE_NEWNET"}, {0x80000000, "CLONE_IO"},
+}
+
+var fcntlCmds = map[uint64]string{
+	0: "F_DUPFD", 1: "F_GETFD", 2: "F_SETFD", 3: "F_GETFL", 4: "F_SETFL", 5: "F_GETLK",
+	6: "F_SETLK", 7: "F_SETLKW", 8: "F_SETOWN", 9: "F_GETOWN", 1030: "F_DUPFD_CLOEXEC",
+	1031: "F_SETPIPE_SZ", 1032: "F_GETPIPE_SZ", 1033: "F_ADD_SEALS", 1034: "F_GET_SEALS",
+}
+
+var ptraceRequests = map[uint64]string{
+	0: "PTRACE_TRACEME", 1: "PTRACE_PEEKTEXT", 2: "PTRACE_PEEKDATA", 3: "PTRACE_PEEKUSER",
+	4: "PTRACE_POKETEXT", 5: "PTRACE_POKEDATA", 6: "PTRACE_POKEUSER", 7: "PTRACE_CONT",
+	8: "PTRACE_KILL", 9: "PTRACE_SINGLESTEP", 12: "PTRACE_GETREGS", 13: "PTRACE_SETREGS",
+	16: "PTRACE_ATTACH", 17: "PTRACE_DETACH", 24: "PTRACE_SYSCALL", 0x4200: "PTRACE_SETOPTIONS",
+	0x4201: "PTRACE_GETEVENTMSG", 0x4202: "PTRACE_GETSIGINFO", 0x4206: "PTRACE_SEIZE",
+	0x4207: "PTRACE_INTERRUPT",
+}
+
+var prctlOptions = map[uint64]string{
+	1: "PR_SET_PDEATHSIG", 2: "PR_GET_PDEATHSIG", 3: "PR_GET_DUMPABLE", 4: "PR_SET_DUMPABLE",
+	15: "PR_SET_NAME", 16: "PR_GET_NAME", 21: "PR_GET_SECCOMP", 22: "PR_SET_SECCOMP",
+	38: "PR_SET_NO_NEW_PRIVS", 39: "PR_GET_NO_NEW_PRIVS", 0x59616d61: "PR_SET_PTRACER",
+}
+
+var archPrctlCodes = map[uint64]string{
+	0x1001: "ARCH_SET_GS", 0x1002: "ARCH_SET_FS", 0x1003: "ARCH_GET_FS", 0x1004: "ARCH_GET_GS",
+}
+
+var futexOps = map[uint64]string{
+	0: "FUTEX_WAIT", 1: "FUTEX_WAKE", 2: "FUTEX_FD", 3: "FUTEX_REQUEUE", 4: "FUTEX_CMP_REQUEUE",
+	5: "FUTEX_WAKE_OP", 6: "FUTEX_LOCK_PI", 7: "FUTEX_UNLOCK_PI", 8: "FUTEX_TRYLOCK_PI",
+	9: "FUTEX_WAIT_BITSET", 10: "FUTEX_WAKE_BITSET",
+}
+
+var waitOptions = []flagName{
+	{1, "WNOHANG"}, {2, "WUNTRACED"}, {8, "WCONTINUED"},
+	{0x20000000, "__WNOTHREAD"}, {0x40000000, "__WALL"}, {0x80000000, "__WCLONE"},
+}
+
+var clockIDs = map[uint64]string{
+	0: "CLOCK_REALTIME", 1: "CLOCK_MONOTONIC", 2: "CLOCK_PROCESS_CPUTIME_ID",
+	3: "CLOCK_THREAD_CPUTIME_ID", 4: "CLOCK_MONOTONIC_RAW", 5: "CLOCK_REALTIME_COARSE",
+	6: "CLOCK_MONOTONIC_COARSE", 7: "CLOCK_BOOTTIME",
+}
+
+var memfdFlags = []flagName{{1, "MFD_CLOEXEC"}, {2, "MFD_ALLOW_SEALING"}, {4, "MFD_HUGETLB"}}
+
+var getrandomFlags = []flagName{{1, "GRND_NONBLOCK"}, {2, "GRND_RANDOM"}, {4, "GRND_INSECURE"}}
@@ -0,0 +1,299 @@
+package syscalls
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// fakeMemory maps a few regions of a pretend tracee
+type fakeMemory map[uint64][]byte
+
+func (m fakeMemory) read(addr uint64, n int) []byte {
+	for base, data := range m {
+		if addr >= base && addr < base+uint64(len(data)) {
+			data = data[addr-base:]
+			return data[:min(n, len(data))]
+		}
+	}
+	return nil
+}
+
+func argValues(args []Arg) map[string]string {
+	out := map[string]string{}
+	for _, a := range args {
+		out[a.Name] = a.Value
+	}
+	return out
+}
+
+func TestDecodeEntry(t *testing.T) {
+	inet := make([]byte, 16)
+	binary.LittleEndian.PutUint16(inet, afInet)
+	binary.BigEndian.PutUint16(inet[2:], 443)
+	copy(inet[4:], []byte{93, 184, 216, 34})
+
+	argv := make([]byte, 24)
+	binary.LittleEndian.PutUint64(argv, 0x3000)
+	binary.LittleEndian.PutUint64(argv[8:], 0x3010)
+
+	mem := fakeMemory{
+		0x1000: []byte("/etc/passwd\x00"),
+		0x2000: inet,
+		0x3000: []byte("/bin/sh\x00\x00\x00\x00\x00\x00\x00\x00\x00-c\x00"),
+		0x4000: argv,
+		0x5000: []byte("hello, world\n"),
+	}
+
+	tests := []struct {
+		name string
+		regs [6]uint64
+		want map[string]string
+	}{
+		{"openat", [6]uint64{0xffffff9c, 0x1000, 0x80000 | 0x40 | 1, 0644},
+			map[string]string{"dirfd": "AT_FDCWD", "pathname": `"/etc/passwd"`, "flags": "O_WRONLY|O_CREAT|O_CLOEXEC", "mode": "0644"}},
+		{"mmap", [6]uint64{0, 4096, 5, 0x22, 0xffffffff, 0},
+			map[string]string{"addr": "0x0", "prot": "PROT_READ|PROT_EXEC", "flags": "MAP_PRIVATE|MAP_ANONYMOUS", "fd": "-1"}},
+		{"connect", [6]uint64{3, 0x2000, 16},
+			map[string]string{"sockfd": "3", "addr": "93.184.216.34:443"}},
+		{"execve", [6]uint64{0x3000, 0x4000, 0},
+			map[string]string{"pathname": `"/bin/sh"`, "argv": `["/bin/sh", "-c"]`}},
+		{"write", [6]uint64{1, 0x5000, 13},
+			map[string]string{"buf": `"hello, world\n"`, "count": "13"}},
+		{"kill", [6]uint64{1234, 9},
+			map[string]string{"pid": "1234", "sig": "SIGKILL"}},
+		{"socket", [6]uint64{10, 1 | 02000000, 0},
+			map[string]string{"domain": "AF_INET6", "type": "SOCK_STREAM|SOCK_CLOEXEC"}},
+		{"clone", [6]uint64{0x01200000 | 17},
+			map[string]string{"flags": "CLONE_CHILD_CLEARTID|CLONE_CHILD_SETTID|SIGCHLD"}},
+		{"ptrace", [6]uint64{0},
+			map[string]string{"request": "PTRACE_TRACEME"}},
+	}
+	for _, tt := range tests {
+		got := argValues(X8664.DecodeEntry(tt.name, tt.regs, mem.read))
+		for k, want := range tt.want {
+			if got[k] != want {
+				t.Errorf("%s: %s = %q, want %q", tt.name, k, got[k], want)
+			}
+		}
+	}
+}
+
+func TestDecodeEntry_Unknown(t *testing.T) {
+	args := X8664.DecodeEntry("syscall_999", [6]uint64{1, 2, 3, 4, 5, 0xff}, fakeMemory{}.read)
+	if len(args) != 6 || args[5].String() != "arg5=0xff" {
+		t.Errorf("unexpected args: %v", Strings(args))
+	}
+}
+
+func TestDecodeExit(t *testing.T) {
+	stat := make([]byte, X8664.stat.size)
+	binary.LittleEndian.PutUint32(stat[24:], 0100644)
+	binary.LittleEndian.PutUint64(stat[48:], 1234)
+	mem := fakeMemory{
+		0x1000: []byte(strings.Repeat("A", 100)),
+		0x2000: stat,
+	}
+
+	// read returned fewer bytes than requested; the preview stops there
+	regs := [6]uint64{3, 0x1000, 4096}
+	args := X8664.DecodeEntry("read", regs, mem.read)
+	if args[1].Value != "0x1000" {
+		t.Errorf("output buffer decoded at entry: %v", Strings(args))
+	}
+	X8664.DecodeExit("read", regs, 40, mem.read, args)
+	if args[1].Value != `"`+strings.Repeat("A", maxBufferPreview)+`"...` {
+		t.Errorf("unexpected read buffer: %v", Strings(args))
+	}
+
+	regs = [6]uint64{3, 0x2000}
+	args = X8664.DecodeEntry("fstat", regs, mem.read)
+	X8664.DecodeExit("fstat", regs, 0, mem.read, args)
+	if !strings.HasPrefix(args[1].Value, "{st_mode=S_IFREG|0644, st_size=1234,") {
+		t.Errorf("unexpected stat: %v", Strings(args))
+	}
+
+	// Outputs of failed calls are left alone
+	regs = [6]uint64{3, 0x1000, 4096}
+	args = X8664.DecodeEntry("read", regs, mem.read)
+	X8664.DecodeExit("read", regs, -9, mem.read, args)
+	if args[1].Value != "0x1000" {
+		t.Errorf("failed call decoded outputs: %v", Strings(args))
+	}
+}
+
+func TestDecodeEntry_I386(t *testing.T) {
+	inet := make([]byte, 16)
+	binary.LittleEndian.PutUint16(inet, afInet)
+	binary.BigEndian.PutUint16(inet[2:], 80)
+	copy(inet[4:], []byte{127, 0, 0, 1})
+
+	// socketcall(SYS_CONNECT, {3, 0x2000, 16}) with 32-bit words
+	call := make([]byte, 12)
+	binary.LittleEndian.PutUint32(call, 3)
+	binary.LittleEndian.PutUint32(call[4:], 0x2000)
+	binary.LittleEndian.PutUint32(call[8:], 16)
+
+	argv := make([]byte, 12)
+	binary.LittleEndian.PutUint32(argv, 0x3000)
+	binary.LittleEndian.PutUint32(argv[4:], 0x3008)
+
+	mem := fakeMemory{
+		0x1000: call,
+		0x2000: inet,
+		0x3000: []byte("/bin/sh\x00-c\x00"),
+		0x4000: argv,
+	}
+
+	tests := []struct {
+		name string
+		regs [6]uint64
+		want map[string]string
+	}{
+		{"socketcall", [6]uint64{3, 0x1000},
+			map[string]string{"call": "SYS_CONNECT", "sockfd": "3", "addr": "127.0.0.1:80", "addrlen": "16"}},
+		{"execve", [6]uint64{0x3000, 0x4000, 0},
+			map[string]string{"argv": `["/bin/sh", "-c"]`}},
+		{"mmap2", [6]uint64{0, 8192, 3, 0x22, 0xffffffff, 0},
+			map[string]string{"prot": "PROT_READ|PROT_WRITE", "fd": "-1"}},
+		{"mmap", [6]uint64{0x4000},
+			map[string]string{"args": "0x4000"}},
+		{"kill", [6]uint64{0xffffffff, 15},
+			map[string]string{"pid": "-1", "sig": "SIGTERM"}},
+	}
+	for _, tt := range tests {
+		got := argValues(I386.DecodeEntry(tt.name, tt.regs, mem.read))
+		for k, want := range tt.want {
+			if got[k] != want {
+				t.Errorf("%s: %s = %q, want %q", tt.name, k, got[k], want)
+			}
+		}
+	}
+}
+
+func TestDecodeExit_I386(t *testing.T) {
+	stat := make([]byte, I386.stat64.size)
+	binary.LittleEndian.PutUint32(stat[16:], 040755)
+	binary.LittleEndian.PutUint64(stat[44:], 4096)
+	binary.LittleEndian.PutUint64(stat[88:], 42)
+
+	// socketcall(SYS_RECV, {3, 0x3000, 64, 0})
+	call := make([]byte, 16)
+	binary.LittleEndian.PutUint32(call, 3)
+	binary.LittleEndian.PutUint32(call[4:], 0x3000)
+	binary.LittleEndian.PutUint32(call[8:], 64)
+
+	mem := fakeMemory{0x1000: stat, 0x2000: call, 0x3000: []byte("pong")}
+
+	regs := [6]uint64{3, 0x1000}
+	args := I386.DecodeEntry("fstat64", regs, mem.read)
+	I386.DecodeExit("fstat64", regs, 0, mem.read, args)
+	if args[1].Value != "{st_mode=S_IFDIR|0755, st_size=4096, st_ino=42, st_uid=0, st_gid=0}" {
+		t.Errorf("unexpected stat64: %v", Strings(args))
+	}
+
+	regs = [6]uint64{10, 0x2000}
+	args = I386.DecodeEntry("socketcall", regs, mem.read)
+	I386.DecodeExit("socketcall", regs, 4, mem.read, args)
+	if got := argValues(args); got["call"] != "SYS_RECV" || got["buf"] != `"pong"` {
+		t.Errorf("unexpected recv: %v", Strings(args))
+	}
+}
+
+func TestDecodeEntry_ARM64(t *testing.T) {
+	mem := fakeMemory{0x1000: []byte("/tmp\x00")}
+	// O_DIRECTORY is 040000 on arm64, where x86 has O_DIRECT
+	args := ARM64.DecodeEntry("openat", [6]uint64{0xffffff9c, 0x1000, 040000 | 02000000}, mem.read)
+	if got := argValues(args)["flags"]; got != "O_RDONLY|O_DIRECTORY|O_CLOEXEC" {
+		t.Errorf("flags = %q", got)
+	}
+	if got := X8664.OpenFlags(040000); got != "O_RDONLY|O_DIRECT" {
+		t.Errorf("x86-64 flags = %q", got)
+	}
+}
+
+func TestABI_SyscallName(t *testing.T) {
+	tests := []struct {
+		abi  *ABI
+		nr   uint64
+		want string
+	}{
+		{X8664, 257, "openat"},
+		{I386, 5, "open"},
+		{I386, 102, "socketcall"},
+		{I386, 192, "mmap2"},
+		{ARM64, 56, "openat"},
+		{ARM64, 221, "execve"},
+		{X8664, 9999, "syscall_9999"},
+	}
+	for _, tt := range tests {
+		if got := tt.abi.SyscallName(tt.nr); got != tt.want {
+			t.Errorf("%s: SyscallName(%d) = %q, want %q", tt.abi.Name, tt.nr, got, tt.want)
+		}
+	}
+}
+
+func TestABI_Return(t *testing.T) {
+	tests := []struct {
+		abi  *ABI
+		raw  uint64
+		want int64
+	}{
+		{X8664, 3, 3},
+		{X8664, 0xfffffffffffffffe, -2},
+		{I386, 0xfffffffe, -2},
+		{I386, 0xf7f00000, 0xf7f00000}, // an mmap2 address above 2GB
+		{I386, 0xffffffff_00000003, 3},
+	}
+	for _, tt := range tests {
+		if got := tt.abi.Return(tt.raw); got != tt.want {
+			t.Errorf("%s: Return(%#x) = %d, want %d", tt.abi.Name, tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestErrnoName(t *testing.T) {
+	for ret, want := range map[int64]string{
+		0: "", 3: "", -2: "ENOENT", -13: "EACCES", -512: "ERESTARTSYS", -4000: "E4000",
+		-4096: "", -0x7f0000000000: "",
+	} {
+		if got := ErrnoName(ret); got != want {
+			t.Errorf("ErrnoName(%d) = %q, want %q", ret, got, want)
+		}
+	}
+}
+
+func TestSockaddrString(t *testing.T) {
+	inet6 := make([]byte, 28)
+	binary.LittleEndian.PutUint16(inet6, afInet6)
+	binary.BigEndian.PutUint16(inet6[2:], 53)
+	inet6[23] = 1
+
+	unix := append([]byte{afUnix, 0}, "/run/docker.sock\x00"...)
+	abstract := append([]byte{afUnix, 0, 0}, "hidden"...)
+
+	for data, want := range map[string]string{
+		string(inet6):    "[::1]:53",
+		string(unix):     `"/run/docker.sock"`,
+		string(abstract): `"@hidden"`,
+		"\x10\x00":       "{AF_NETLINK}",
+	} {
+		if got := SockaddrString([]byte(data)); got != want {
+			t.Errorf("SockaddrString = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestOpenFlags(t *testing.T) {
+	for flags, want := range map[uint64]string{
+		0:                   "O_RDONLY",
+		02:                  "O_RDWR",
+		04010000 | 01:       "O_WRONLY|O_SYNC",
+		020200000 | 02:      "O_RDWR|O_TMPFILE",
+		0200000 | 040000000: "O_RDONLY|O_DIRECTORY|0x800000",
+	} {
+		if got := X8664.OpenFlags(flags); got != want {
+			t.Errorf("OpenFlags(%#o) = %q, want %q", flags, got, want)
+		}
+	}
+}
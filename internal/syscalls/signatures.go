@@ -0,0 +1,217 @@
+package syscalls
+
+// ArgType says how a raw syscall argument register is interpreted
+type ArgType string
+
+const (
+	ArgInt            ArgType = "int"
+	ArgUint           ArgType = "uint"
+	ArgHex            ArgType = "hex" // addresses and opaque pointers
+	ArgFD             ArgType = "fd"
+	ArgDirFD          ArgType = "dirfd" // fd or AT_FDCWD
+	ArgPath           ArgType = "path"
+	ArgString         ArgType = "string"
+	ArgStringArray    ArgType = "string_array"
+	ArgBuffer         ArgType = "buffer" // length in another argument or the return value
+	ArgOpenFlags      ArgType = "open_flags"
+	ArgMode           ArgType = "mode"
+	ArgAccessMode     ArgType = "access_mode"
+	ArgAtFlags        ArgType = "at_flags"
+	ArgProt           ArgType = "prot"
+	ArgMmapFlags      ArgType = "mmap_flags"
+	ArgSockaddr       ArgType = "sockaddr"
+	ArgSocketDomain   ArgType = "socket_domain"
+	ArgSocketType     ArgType = "socket_type"
+	ArgStat           ArgType = "stat"
+	ArgStat64         ArgType = "stat64"
+	ArgSignal         ArgType = "signal"
+	ArgSigmaskHow     ArgType = "sigmask_how"
+	ArgWhence         ArgType = "whence"
+	ArgCloneFlags     ArgType = "clone_flags"
+	ArgFcntlCmd       ArgType = "fcntl_cmd"
+	ArgPtraceRequest  ArgType = "ptrace_request"
+	ArgPrctlOption    ArgType = "prctl_option"
+	ArgArchPrctlCode  ArgType = "arch_prctl_code"
+	ArgFutexOp        ArgType = "futex_op"
+	ArgWaitOptions    ArgType = "wait_options"
+	ArgClockID        ArgType = "clock_id"
+	ArgTimespec       ArgType = "timespec"
+	ArgTimespec64     ArgType = "timespec64" // 64-bit time even on 32-bit ABIs
+	ArgSocketcall     ArgType = "socketcall"
+	ArgFDPair         ArgType = "fd_pair"
+	ArgMemfdFlags     ArgType = "memfd_flags"
+	ArgGetrandomFlags ArgType = "getrandom_flags"
+)
+
+// ArgSpec describes one syscall argument
+type ArgSpec struct {
+	Name string
+	Type ArgType
+	// Out marks pointers the kernel fills in; they are decoded at syscall exit
+	Out bool
+	// Len is the index of the argument holding a buffer's length. Output
+	// buffers are cut to the return value instead when it is smaller.
+	Len int
+}
+
+func arg(name string, t ArgType) ArgSpec { return ArgSpec{Name: name, Type: t} }
+
+func out(name string, t ArgType) ArgSpec { return ArgSpec{Name: name, Type: t, Out: true} }
+
+func inBuf(name string, length int) ArgSpec {
+	return ArgSpec{Name: name, Type: ArgBuffer, Len: length}
+}
+
+func outBuf(name string, length int) ArgSpec {
+	return ArgSpec{Name: name, Type: ArgBuffer, Len: length, Out: true}
+}
+
+func sockaddr(name string, length int) ArgSpec {
+	return ArgSpec{Name: name, Type: ArgSockaddr, Len: length}
+}
+
+func outSockaddr(name string) ArgSpec {
+	return ArgSpec{Name: name, Type: ArgSockaddr, Out: true, Len: -1}
+}
+
+// Signatures gives the arguments of the syscalls the tracer decodes, by name.
+// Syscalls missing here have their six argument registers shown as hex.
+var Signatures = map[string][]ArgSpec{
+	"read":         {arg("fd", ArgFD), outBuf("buf", 2), arg("count", ArgUint)},
+	"write":        {arg("fd", ArgFD), inBuf("buf", 2), arg("count", ArgUint)},
+	"pread64":      {arg("fd", ArgFD), outBuf("buf", 2), arg("count", ArgUint), arg("offset", ArgInt)},
+	"pwrite64":     {arg("fd", ArgFD), inBuf("buf", 2), arg("count", ArgUint), arg("offset", ArgInt)},
+	"readv":        {arg("fd", ArgFD), arg("iov", ArgHex), arg("iovcnt", ArgInt)},
+	"writev":       {arg("fd", ArgFD), arg("iov", ArgHex), arg("iovcnt", ArgInt)},
+	"open":         {arg("pathname", ArgPath), arg("flags", ArgOpenFlags), arg("mode", ArgMode)},
+	"openat":       {arg("dirfd", ArgDirFD), arg("pathname", ArgPath), arg("flags", ArgOpenFlags), arg("mode", ArgMode)},
+	"creat":        {arg("pathname", ArgPath), arg("mode", ArgMode)},
+	"close":        {arg("fd", ArgFD)},
+	"close_range":  {arg("first", ArgFD), arg("last", ArgUint), arg("flags", ArgHex)},
+	"stat":         {arg("pathname", ArgPath), out("statbuf", ArgStat)},
+	"lstat":        {arg("pathname", ArgPath), out("statbuf", ArgStat)},
+	"fstat":        {arg("fd", ArgFD), out("statbuf", ArgStat)},
+	"newfstatat":   {arg("dirfd", ArgDirFD), arg("pathname", ArgPath), out("statbuf", ArgStat), arg("flags", ArgAtFlags)},
+	"statx":        {arg("dirfd", ArgDirFD), arg("pathname", ArgPath), arg("flags", ArgAtFlags), arg("mask", ArgHex), arg("statxbuf", ArgHex)},
+	"lseek":        {arg("fd", ArgFD), arg("offset", ArgInt), arg("whence", ArgWhence)},
+	"mmap":         {arg("addr", ArgHex), arg("length", ArgUint), arg("prot", ArgProt), arg("flags", ArgMmapFlags), arg("fd", ArgFD), arg("offset", ArgHex)},
+	"mprotect":     {arg("addr", ArgHex), arg("len", ArgUint), arg("prot", ArgProt)},
+	"munmap":       {arg("addr", ArgHex), arg("length", ArgUint)},
+	"mremap":       {arg("old_address", ArgHex), arg("old_size", ArgUint), arg("new_size", ArgUint), arg("flags", ArgHex), arg("new_address", ArgHex)},
+	"brk":          {arg("addr", ArgHex)},
+	"access":       {arg("pathname", ArgPath), arg("mode", ArgAccessMode)},
+	"faccessat":    {arg("dirfd", ArgDirFD), arg("pathname", ArgPath), arg("mode", ArgAccessMode)},
+	"faccessat2":   {arg("dirfd", ArgDirFD), arg("pathname", ArgPath), arg("mode", ArgAccessMode), arg("flags", ArgAtFlags)},
+	"pipe":         {out("pipefd", ArgFDPair)},
+	"pipe2":        {out("pipefd", ArgFDPair), arg("flags", ArgOpenFlags)},
+	"dup":          {arg("oldfd", ArgFD)},
+	"dup2":         {arg("oldfd", ArgFD), arg("newfd", ArgFD)},
+	"dup3":         {arg("oldfd", ArgFD), arg("newfd", ArgFD), arg("flags", ArgOpenFlags)},
+	"fcntl":        {arg("fd", ArgFD), arg("cmd", ArgFcntlCmd), arg("arg", ArgHex)},
+	"ioctl":        {arg("fd", ArgFD), arg("request", ArgHex), arg("argp", ArgHex)},
+	"flock":        {arg("fd", ArgFD), arg("operation", ArgInt)},
+	"fsync":        {arg("fd", ArgFD)},
+	"truncate":     {arg("path", ArgPath), arg("length", ArgInt)},
+	"ftruncate":    {arg("fd", ArgFD), arg("length", ArgInt)},
+	"getdents64":   {arg("fd", ArgFD), arg("dirp", ArgHex), arg("count", ArgUint)},
+	"getcwd":       {out("buf", ArgString), arg("size", ArgUint)},
+	"chdir":        {arg("path", ArgPath)},
+	"fchdir":       {arg("fd", ArgFD)},
+	"rename":       {arg("oldpath", ArgPath), arg("newpath", ArgPath)},
+	"renameat":     {arg("olddirfd", ArgDirFD), arg("oldpath", ArgPath), arg("newdirfd", ArgDirFD), arg("newpath", ArgPath)},
+	"renameat2":    {arg("olddirfd", ArgDirFD), arg("oldpath", ArgPath), arg("newdirfd", ArgDirFD), arg("newpath", ArgPath), arg("flags", ArgHex)},
+	"mkdir":        {arg("pathname", ArgPath), arg("mode", ArgMode)},
+	"mkdirat":      {arg("dirfd", ArgDirFD), arg("pathname", ArgPath), arg("mode", ArgMode)},
+	"rmdir":        {arg("pathname", ArgPath)},
+	"link":         {arg("oldpath", ArgPath), arg("newpath", ArgPath)},
+	"unlink":       {arg("pathname", ArgPath)},
+	"unlinkat":     {arg("dirfd", ArgDirFD), arg("pathname", ArgPath), arg("flags", ArgAtFlags)},
+	"symlink":      {arg("target", ArgPath), arg("linkpath", ArgPath)},
+	"readlink":     {arg("pathname", ArgPath), outBuf("buf", 2), arg("bufsiz", ArgUint)},
+	"readlinkat":   {arg("dirfd", ArgDirFD), arg("pathname", ArgPath), outBuf("buf", 3), arg("bufsiz", ArgUint)},
+	"chmod":        {arg("pathname", ArgPath), arg("mode", ArgMode)},
+	"fchmod":       {arg("fd", ArgFD), arg("mode", ArgMode)},
+	"fchmodat":     {arg("dirfd", ArgDirFD), arg("pathname", ArgPath), arg("mode", ArgMode)},
+	"chown":        {arg("pathname", ArgPath), arg("owner", ArgInt), arg("group", ArgInt)},
+	"umask":        {arg("mask", ArgMode)},
+	"memfd_create": {arg("name", ArgString), arg("flags", ArgMemfdFlags)},
+
+	"socket":      {arg("domain", ArgSocketDomain), arg("type", ArgSocketType), arg("protocol", ArgInt)},
+	"socketpair":  {arg("domain", ArgSocketDomain), arg("type", ArgSocketType), arg("protocol", ArgInt), out("sv", ArgFDPair)},
+	"connect":     {arg("sockfd", ArgFD), sockaddr("addr", 2), arg("addrlen", ArgUint)},
+	"bind":        {arg("sockfd", ArgFD), sockaddr("addr", 2), arg("addrlen", ArgUint)},
+	"listen":      {arg("sockfd", ArgFD), arg("backlog", ArgInt)},
+	"accept":      {arg("sockfd", ArgFD), outSockaddr("addr"), arg("addrlen", ArgHex)},
+	"accept4":     {arg("sockfd", ArgFD), outSockaddr("addr"), arg("addrlen", ArgHex), arg("flags", ArgSocketType)},
+	"getsockname": {arg("sockfd", ArgFD), outSockaddr("addr"), arg("addrlen", ArgHex)},
+	"getpeername": {arg("sockfd", ArgFD), outSockaddr("addr"), arg("addrlen", ArgHex)},
+	"sendto":      {arg("sockfd", ArgFD), inBuf("buf", 2), arg("len", ArgUint), arg("flags", ArgHex), sockaddr("dest_addr", 5), arg("addrlen", ArgUint)},
+	"recvfrom":    {arg("sockfd", ArgFD), outBuf("buf", 2), arg("len", ArgUint), arg("flags", ArgHex), outSockaddr("src_addr"), arg("addrlen", ArgHex)},
+	"sendmsg":     {arg("sockfd", ArgFD), arg("msg", ArgHex), arg("flags", ArgHex)},
+	"recvmsg":     {arg("sockfd", ArgFD), arg("msg", ArgHex), arg("flags", ArgHex)},
+	"shutdown":    {arg("sockfd", ArgFD), arg("how", ArgInt)},
+	"setsockopt":  {arg("sockfd", ArgFD), arg("level", ArgInt), arg("optname", ArgInt), arg("optval", ArgHex), arg("optlen", ArgUint)},
+	"getsockopt":  {arg("sockfd", ArgFD), arg("level", ArgInt), arg("optname", ArgInt), arg("optval", ArgHex), arg("optlen", ArgHex)},
+
+	"clone":           {arg("flags", ArgCloneFlags), arg("stack", ArgHex), arg("parent_tid", ArgHex), arg("child_tid", ArgHex), arg("tls", ArgHex)},
+	"clone3":          {arg("cl_args", ArgHex), arg("size", ArgUint)},
+	"execve":          {arg("pathname", ArgPath), arg("argv", ArgStringArray), arg("envp", ArgHex)},
+	"execveat":        {arg("dirfd", ArgDirFD), arg("pathname", ArgPath), arg("argv", ArgStringArray), arg("envp", ArgHex), arg("flags", ArgAtFlags)},
+	"exit":            {arg("status", ArgInt)},
+	"exit_group":      {arg("status", ArgInt)},
+	"wait4":           {arg("pid", ArgInt), arg("wstatus", ArgHex), arg("options", ArgWaitOptions), arg("rusage", ArgHex)},
+	"kill":            {arg("pid", ArgInt), arg("sig", ArgSignal)},
+	"tkill":           {arg("tid", ArgInt), arg("sig", ArgSignal)},
+	"tgkill":          {arg("tgid", ArgInt), arg("tid", ArgInt), arg("sig", ArgSignal)},
+	"setpgid":         {arg("pid", ArgInt), arg("pgid", ArgInt)},
+	"setuid":          {arg("uid", ArgInt)},
+	"setgid":          {arg("gid", ArgInt)},
+	"ptrace":          {arg("request", ArgPtraceRequest), arg("pid", ArgInt), arg("addr", ArgHex), arg("data", ArgHex)},
+	"prctl":           {arg("option", ArgPrctlOption), arg("arg2", ArgHex), arg("arg3", ArgHex), arg("arg4", ArgHex), arg("arg5", ArgHex)},
+	"arch_prctl":      {arg("code", ArgArchPrctlCode), arg("addr", ArgHex)},
+	"set_tid_address": {arg("tidptr", ArgHex)},
+	"set_robust_list": {arg("head", ArgHex), arg("len", ArgUint)},
+	"rseq":            {arg("rseq", ArgHex), arg("rseq_len", ArgUint), arg("flags", ArgInt), arg("sig", ArgHex)},
+	"prlimit64":       {arg("pid", ArgInt), arg("resource", ArgInt), arg("new_limit", ArgHex), arg("old_limit", ArgHex)},
+	"futex":           {arg("uaddr", ArgHex), arg("futex_op", ArgFutexOp), arg("val", ArgUint), arg("timeout", ArgHex), arg("uaddr2", ArgHex), arg("val3", ArgUint)},
+
+	"rt_sigaction":   {arg("signum", ArgSignal), arg("act", ArgHex), arg("oldact", ArgHex), arg("sigsetsize", ArgUint)},
+	"rt_sigprocmask": {arg("how", ArgSigmaskHow), arg("set", ArgHex), arg("oldset", ArgHex), arg("sigsetsize", ArgUint)},
+	"rt_sigreturn":   {},
+	"alarm":          {arg("seconds", ArgUint)},
+	"pause":          {},
+
+	"nanosleep":       {arg("req", ArgTimespec), arg("rem", ArgHex)},
+	"clock_gettime":   {arg("clockid", ArgClockID), out("tp", ArgTimespec)},
+	"clock_nanosleep": {arg("clockid", ArgClockID), arg("flags", ArgInt), arg("request", ArgTimespec), arg("remain", ArgHex)},
+	"getrandom":       {outBuf("buf", 1), arg("buflen", ArgUint), arg("flags", ArgGetrandomFlags)},
+	"uname":           {arg("buf", ArgHex)},
+	"sysinfo":         {arg("info", ArgHex)},
+
+	// i386 variants
+	"mmap2":                  {arg("addr", ArgHex), arg("length", ArgUint), arg("prot", ArgProt), arg("flags", ArgMmapFlags), arg("fd", ArgFD), arg("pgoffset", ArgHex)},
+	"_llseek":                {arg("fd", ArgFD), arg("offset_high", ArgUint), arg("offset_low", ArgUint), arg("result", ArgHex), arg("whence", ArgWhence)},
+	"stat64":                 {arg("pathname", ArgPath), out("statbuf", ArgStat64)},
+	"lstat64":                {arg("pathname", ArgPath), out("statbuf", ArgStat64)},
+	"fstat64":                {arg("fd", ArgFD), out("statbuf", ArgStat64)},
+	"fstatat64":              {arg("dirfd", ArgDirFD), arg("pathname", ArgPath), out("statbuf", ArgStat64), arg("flags", ArgAtFlags)},
+	"fcntl64":                {arg("fd", ArgFD), arg("cmd", ArgFcntlCmd), arg("arg", ArgHex)},
+	"waitpid":                {arg("pid", ArgInt), arg("wstatus", ArgHex), arg("options", ArgWaitOptions)},
+	"clock_gettime64":        {arg("clockid", ArgClockID), out("tp", ArgTimespec64)},
+	"clock_nanosleep_time64": {arg("clockid", ArgClockID), arg("flags", ArgInt), arg("request", ArgTimespec64), arg("remain", ArgHex)},
+	"socketcall":             {arg("call", ArgSocketcall), arg("args", ArgHex)},
+	"send":                   {arg("sockfd", ArgFD), inBuf("buf", 2), arg("len", ArgUint), arg("flags", ArgHex)},
+	"recv":                   {arg("sockfd", ArgFD), outBuf("buf", 2), arg("len", ArgUint), arg("flags", ArgHex)},
+
+	"getpid":      {},
+	"getppid":     {},
+	"gettid":      {},
+	"getuid":      {},
+	"geteuid":     {},
+	"getgid":      {},
+	"getegid":     {},
+	"setsid":      {},
+	"fork":        {},
+	"vfork":       {},
+	"sched_yield": {},
+}
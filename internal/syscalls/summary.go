@@ -0,0 +1,89 @@
+package syscalls
+
+import "sort"
+
+// Summary aggregates a trace per syscall, like strace -c
+type Summary struct {
+	Calls    int          `json:"calls"`
+	Errors   int          `json:"errors"`
+	TotalUS  int64        `json:"total_us"`
+	Syscalls []SummaryRow `json:"syscalls"`
+	// TimingsUnavailable is set for traces recorded without durations or
+	// return values, whose rows only count calls
+	TimingsUnavailable bool `json:"timings_unavailable,omitempty"`
+}
+
+type SummaryRow struct {
+	Name    string  `json:"name"`
+	Calls   int     `json:"calls"`
+	Errors  int     `json:"errors"`
+	TotalUS int64   `json:"total_us"`
+	AvgUS   float64 `json:"avg_us"`
+	Percent float64 `json:"percent"` // share of the time spent in all syscalls
+}
+
+// Summarizer builds a Summary one call at a time
+type Summarizer struct {
+	rows map[string]*SummaryRow
+}
+
+func NewSummarizer() *Summarizer {
+	return &Summarizer{rows: make(map[string]*SummaryRow)}
+}
+
+// Add counts one call; failed calls count as errors
+func (s *Summarizer) Add(name string, durationUS int64, failed bool) {
+	row, ok := s.rows[name]
+	if !ok {
+		row = &SummaryRow{Name: name}
+		s.rows[name] = row
+	}
+	row.Calls++
+	row.TotalUS += durationUS
+	if failed {
+		row.Errors++
+	}
+}
+
+// Summary returns the rows ordered by time spent, then by call count
+func (s *Summarizer) Summary() *Summary {
+	sum := &Summary{Syscalls: make([]SummaryRow, 0, len(s.rows))}
+	for _, row := range s.rows {
+		sum.Calls += row.Calls
+		sum.Errors += row.Errors
+		sum.TotalUS += row.TotalUS
+		sum.Syscalls = append(sum.Syscalls, *row)
+	}
+	for i := range sum.Syscalls {
+		row := &sum.Syscalls[i]
+		row.AvgUS = float64(row.TotalUS) / float64(row.Calls)
+		if sum.TotalUS > 0 {
+			row.Percent = 100 * float64(row.TotalUS) / float64(sum.TotalUS)
+		}
+	}
+	sort.Slice(sum.Syscalls, func(i, j int) bool {
+		a, b := sum.Syscalls[i], sum.Syscalls[j]
+		if a.TotalUS != b.TotalUS {
+			return a.TotalUS > b.TotalUS
+		}
+		if a.Calls != b.Calls {
+			return a.Calls > b.Calls
+		}
+		return a.Name < b.Name
+	})
+	return sum
+}
+
+// Summarize aggregates the syscalls of a traced benchmark. Traces stored
+// before calls were paired with their return have no timings at all.
+func Summarize(entries []SyscallEntry) *Summary {
+	s := NewSummarizer()
+	timed := false
+	for _, e := range entries {
+		s.Add(e.Name, e.DurationUS, e.Errno != "")
+		timed = timed || e.Return != nil || e.DurationUS > 0
+	}
+	sum := s.Summary()
+	sum.TimingsUnavailable = len(entries) > 0 && !timed
+	return sum
+}
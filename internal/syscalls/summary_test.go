@@ -0,0 +1,45 @@
+package syscalls
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	sum := Summarize([]SyscallEntry{
+		{Name: "read", DurationUS: 10},
+		{Name: "openat", DurationUS: 50, Errno: "ENOENT"},
+		{Name: "read", DurationUS: 30},
+		{Name: "openat", DurationUS: 10},
+		{Name: "exit_group"},
+	})
+
+	if sum.Calls != 5 || sum.Errors != 1 || sum.TotalUS != 100 {
+		t.Errorf("unexpected totals: %+v", sum)
+	}
+	if len(sum.Syscalls) != 3 {
+		t.Fatalf("expected 3 rows, got %+v", sum.Syscalls)
+	}
+	open, read, exit := sum.Syscalls[0], sum.Syscalls[1], sum.Syscalls[2]
+	if open.Name != "openat" || open.Calls != 2 || open.Errors != 1 || open.TotalUS != 60 ||
+		open.AvgUS != 30 || math.Abs(open.Percent-60) > 1e-9 {
+		t.Errorf("unexpected openat row: %+v", open)
+	}
+	if read.Name != "read" || read.Errors != 0 || read.AvgUS != 20 || math.Abs(read.Percent-40) > 1e-9 {
+		t.Errorf("unexpected read row: %+v", read)
+	}
+	if exit.Name != "exit_group" || exit.Calls != 1 || exit.Percent != 0 {
+		t.Errorf("unexpected exit_group row: %+v", exit)
+	}
+}
+
+func TestSummarize_Legacy(t *testing.T) {
+	// Benchmarks traced before calls were paired only recorded entries
+	sum := Summarize([]SyscallEntry{{Name: "read"}, {Name: "exit_group"}})
+	if sum.Calls != 2 || !sum.TimingsUnavailable {
+		t.Errorf("expected calls without timings: %+v", sum)
+	}
+	if Summarize([]SyscallEntry{{Name: "read", DurationUS: 1}}).TimingsUnavailable {
+		t.Error("timed traces have timings")
+	}
+}
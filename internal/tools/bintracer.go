@@ -2,13 +2,21 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
 	"os/exec"
-	"syscall"
+
+	"github.com/ashborn3/BinTraceBench/internal/analyzer"
 )
 
+// Output, once the trace is over: one line per syscall of any traced task in
+// order of entry, holding its decoded arguments, return value and duration
+//
+//	syscall {"pid":<tid>,"name":"openat","args":["dirfd=AT_FDCWD",...],"retval":3,...}
+//
+// followed by a final "processes <json>" line holding the process tree
 func main() {
 	if len(os.Args) < 2 {
 		log.Fatal("Usage: bintracer <binary> [args...]")
@@ -16,52 +24,27 @@ func main() {
 	binary := os.Args[1]
 	args := os.Args[2:]
 
-	cmd := exec.Command(binary, args...)
-	cmd.SysProcAttr = &syscall.SysProcAttr{
-		Ptrace: true,
-	}
-
-	err := cmd.Start()
-	if err != nil {
-		log.Fatalf("Failed to start: %v", err)
-	}
-
-	pid := cmd.Process.Pid
-	var status syscall.WaitStatus
-
-	// Wait for initial PTRACE_TRACEME stop
-	_, err = syscall.Wait4(pid, &status, 0, nil)
-	if err != nil {
-		log.Fatalf("Initial wait failed: %v", err)
+	if err := analyzer.CheckTraceable(binary); err != nil {
+		log.Fatalf("Trace failed: %v", err)
 	}
 
-	err = syscall.PtraceSetOptions(pid, syscall.PTRACE_O_TRACESYSGOOD)
+	recorder := analyzer.NewSyscallRecorder()
+	procs, err := analyzer.TraceCommand(exec.Command(binary, args...), 0, recorder.Stop)
 	if err != nil {
-		log.Fatalf("PtraceSetOptions failed: %v", err)
+		log.Fatalf("Trace failed: %v", err)
 	}
 
-	for {
-		err = syscall.PtraceSyscall(pid, 0)
+	for _, call := range recorder.Records() {
+		line, err := json.Marshal(call)
 		if err != nil {
-			break
+			log.Fatalf("Failed to encode syscall: %v", err)
 		}
-
-		_, err = syscall.Wait4(pid, &status, 0, nil)
-		if err != nil || status.Exited() || status.Signaled() {
-			break
-		}
-
-		// Get syscall number
-		var regs syscall.PtraceRegs
-		syscall.PtraceGetRegs(pid, &regs)
-
-		fmt.Printf("%d %#x %#x %#x %#x %#x %#x\n",
-			regs.Orig_rax,
-			regs.Rdi, regs.Rsi, regs.Rdx, regs.R10, regs.R8, regs.R9)
-
-		syscall.PtraceSyscall(pid, 0)
-		syscall.Wait4(pid, &status, 0, nil)
+		fmt.Printf("syscall %s\n", line)
 	}
 
-	cmd.Wait()
+	tree, err := json.Marshal(procs)
+	if err != nil {
+		log.Fatalf("Failed to encode process tree: %v", err)
+	}
+	fmt.Printf("processes %s\n", tree)
 }
@@ -0,0 +1,345 @@
+package validation
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	ArchiveAr    = "ar"
+	ArchiveTar   = "tar"
+	ArchiveTarGz = "tar.gz"
+	ArchiveZip   = "zip"
+)
+
+const (
+	MaxArchiveEntries    = 4096
+	MaxArchiveMemberSize = MaxUploadSize
+	MaxArchiveTotalSize  = 512 << 20 // 512MB unpacked
+)
+
+var (
+	arMagic   = []byte("!<arch>\n")
+	gzipMagic = []byte{0x1f, 0x8b}
+	zipMagic  = []byte("PK\x03\x04")
+)
+
+// ArchiveMember is one regular file unpacked from an uploaded archive
+type ArchiveMember struct {
+	Name string
+	Data []byte
+	Link string // symbolic link target, only kept by ExtractArchive
+}
+
+// DetectArchive returns the archive format of an upload, or "" for a single
+// file. Gzip data only counts as an archive when it wraps a tar.
+func DetectArchive(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, arMagic):
+		return ArchiveAr
+	case bytes.HasPrefix(data, zipMagic):
+		return ArchiveZip
+	case isTar(data):
+		return ArchiveTar
+	case bytes.HasPrefix(data, gzipMagic):
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return ""
+		}
+		head := make([]byte, 512)
+		if n, _ := io.ReadFull(zr, head); isTar(head[:n]) {
+			return ArchiveTarGz
+		}
+	}
+	return ""
+}
+
+func isTar(data []byte) bool {
+	return len(data) >= 512 && bytes.HasPrefix(data[257:], []byte("ustar"))
+}
+
+// UnpackArchive extracts the regular files of an ar, tar, tar.gz or zip
+// archive in memory. Uploads exceeding MaxArchiveEntries members,
+// MaxArchiveMemberSize per member or MaxArchiveTotalSize in total are rejected
+// rather than partially unpacked.
+func UnpackArchive(format string, data []byte) ([]ArchiveMember, error) {
+	u := &unpacker{}
+	if err := u.unpack(format, data); err != nil {
+		return nil, err
+	}
+	return u.members, nil
+}
+
+// ExtractArchive writes the regular files and symbolic links of a tar,
+// tar.gz or zip archive below the empty directory dir, with the limits of
+// UnpackArchive. Hard links become symbolic links to the absolute in-archive
+// path. Link targets are kept verbatim, so they must only be followed within
+// dir; no file or directory is ever created through a link.
+func ExtractArchive(format string, data []byte, dir string) (int, error) {
+	if format == ArchiveAr {
+		return 0, fmt.Errorf("ar archives cannot be extracted")
+	}
+	u := &unpacker{links: true}
+	if err := u.unpack(format, data); err != nil {
+		return 0, err
+	}
+
+	// Links come last so that none exists while files and directories are
+	// created
+	sort.SliceStable(u.members, func(i, j int) bool {
+		return u.members[i].Link == "" && u.members[j].Link != ""
+	})
+	for _, m := range u.members {
+		if m.Name == "" {
+			continue
+		}
+		parent, err := mkdirInside(dir, path.Dir(m.Name))
+		if err != nil {
+			return 0, fmt.Errorf("cannot extract %s: %v", m.Name, err)
+		}
+		target := filepath.Join(parent, path.Base(m.Name))
+		if m.Link != "" {
+			err = os.Symlink(m.Link, target)
+		} else {
+			err = writeNew(target, m.Data)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("cannot extract %s: %v", m.Name, err)
+		}
+	}
+	return len(u.members), nil
+}
+
+// mkdirInside creates the directories of rel below root, refusing to pass
+// through anything that is not a directory, such as an extracted link
+func mkdirInside(root, rel string) (string, error) {
+	dir := root
+	for _, part := range strings.Split(rel, "/") {
+		if part == "" || part == "." {
+			continue
+		}
+		dir = filepath.Join(dir, part)
+		fi, err := os.Lstat(dir)
+		if os.IsNotExist(err) {
+			if err := os.Mkdir(dir, 0755); err != nil {
+				return "", err
+			}
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if !fi.IsDir() {
+			return "", fmt.Errorf("%s is not a directory", part)
+		}
+	}
+	return dir, nil
+}
+
+// writeNew writes a regular file, replacing an earlier member of the same
+// name but never a link or directory
+func writeNew(name string, data []byte) error {
+	if fi, err := os.Lstat(name); err == nil {
+		if !fi.Mode().IsRegular() {
+			return fmt.Errorf("%s already exists", filepath.Base(name))
+		}
+		if err := os.Remove(name); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+type unpacker struct {
+	members []ArchiveMember
+	total   int64
+	links   bool // keep symbolic and hard links
+}
+
+func (u *unpacker) unpack(format string, data []byte) error {
+	var err error
+	switch format {
+	case ArchiveAr:
+		err = u.unpackAr(data)
+	case ArchiveTar:
+		err = u.unpackTar(bytes.NewReader(data))
+	case ArchiveTarGz:
+		var zr *gzip.Reader
+		if zr, err = gzip.NewReader(bytes.NewReader(data)); err == nil {
+			err = u.unpackTar(zr)
+		}
+	case ArchiveZip:
+		err = u.unpackZip(data)
+	default:
+		err = fmt.Errorf("unsupported archive format %q", format)
+	}
+	return err
+}
+
+// add reads one member from r, enforcing the limits
+func (u *unpacker) add(name string, r io.Reader) error {
+	if len(u.members) >= MaxArchiveEntries {
+		return fmt.Errorf("archive has more than %d members", MaxArchiveEntries)
+	}
+	data, err := io.ReadAll(io.LimitReader(r, MaxArchiveMemberSize+1))
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", name, err)
+	}
+	if int64(len(data)) > MaxArchiveMemberSize {
+		return fmt.Errorf("member %s too large (max %d bytes)", name, MaxArchiveMemberSize)
+	}
+	u.total += int64(len(data))
+	if u.total > MaxArchiveTotalSize {
+		return fmt.Errorf("archive unpacks to more than %d bytes", MaxArchiveTotalSize)
+	}
+	u.members = append(u.members, ArchiveMember{Name: cleanMemberName(name), Data: data})
+	return nil
+}
+
+// addLink records a link member, which counts against the entry limit only
+func (u *unpacker) addLink(name, target string) error {
+	if len(u.members) >= MaxArchiveEntries {
+		return fmt.Errorf("archive has more than %d members", MaxArchiveEntries)
+	}
+	if target == "" {
+		return fmt.Errorf("link %s has no target", name)
+	}
+	u.members = append(u.members, ArchiveMember{Name: cleanMemberName(name), Link: target})
+	return nil
+}
+
+func cleanMemberName(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+func (u *unpacker) unpackTar(r io.Reader) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid tar archive: %v", err)
+		}
+		switch {
+		case hdr.Typeflag == tar.TypeReg:
+			err = u.add(hdr.Name, tr)
+		case hdr.Typeflag == tar.TypeSymlink && u.links:
+			err = u.addLink(hdr.Name, hdr.Linkname)
+		case hdr.Typeflag == tar.TypeLink && u.links:
+			err = u.addLink(hdr.Name, "/"+cleanMemberName(hdr.Linkname))
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (u *unpacker) unpackZip(data []byte) error {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("invalid zip archive: %v", err)
+	}
+	for _, f := range zr.File {
+		link := f.Mode()&os.ModeSymlink != 0 && u.links
+		if !f.Mode().IsRegular() && !link {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %v", f.Name, err)
+		}
+		if link {
+			// The target is the content of the entry
+			var target []byte
+			target, err = io.ReadAll(io.LimitReader(rc, 4096))
+			if err == nil {
+				err = u.addLink(f.Name, string(target))
+			}
+		} else {
+			err = u.add(f.Name, rc)
+		}
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unpackAr reads System V/GNU and BSD ar archives, the format of static
+// libraries. Symbol tables and the GNU long name table are not members.
+func (u *unpacker) unpackAr(data []byte) error {
+	var longNames []byte
+	for pos := len(arMagic); pos < len(data); {
+		if pos+60 > len(data) {
+			return fmt.Errorf("truncated ar member header at offset %d", pos)
+		}
+		hdr := data[pos : pos+60]
+		if hdr[58] != '`' || hdr[59] != '\n' {
+			return fmt.Errorf("invalid ar member header at offset %d", pos)
+		}
+		size, err := strconv.ParseInt(strings.TrimSpace(string(hdr[48:58])), 10, 64)
+		if err != nil || size < 0 || int64(pos+60)+size > int64(len(data)) {
+			return fmt.Errorf("invalid ar member size at offset %d", pos)
+		}
+		body := data[pos+60 : pos+60+int(size)]
+		pos += 60 + int(size) + int(size&1) // members are 2-byte aligned
+
+		name := strings.TrimRight(string(hdr[:16]), " ")
+		switch {
+		case name == "/" || name == "/SYM64/" || strings.HasPrefix(name, "__.SYMDEF"):
+			continue
+		case name == "//":
+			longNames = body
+			continue
+		case strings.HasPrefix(name, "#1/"):
+			// BSD: the name precedes the data
+			n, err := strconv.Atoi(name[3:])
+			if err != nil || n < 0 || n > len(body) {
+				return fmt.Errorf("invalid BSD ar name %q", name)
+			}
+			name = strings.TrimRight(string(body[:n]), "\x00")
+			body = body[n:]
+			if strings.HasPrefix(name, "__.SYMDEF") {
+				continue
+			}
+		case strings.HasPrefix(name, "/"):
+			// GNU: offset into the long name table, entries end with "/\n"
+			off, err := strconv.Atoi(name[1:])
+			if err != nil || off < 0 || off >= len(longNames) {
+				return fmt.Errorf("invalid GNU ar name %q", name)
+			}
+			name = string(longNames[off:])
+			if end := strings.Index(name, "/\n"); end >= 0 {
+				name = name[:end]
+			}
+		default:
+			name = strings.TrimSuffix(name, "/")
+		}
+		if err := u.add(name, bytes.NewReader(body)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
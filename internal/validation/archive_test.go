@@ -0,0 +1,219 @@
+package validation
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// buildAr writes a GNU-style ar archive with a symbol table and a long name table
+func buildAr(files map[string]string, order []string) []byte {
+	var buf bytes.Buffer
+	buf.Write(arMagic)
+	header := func(name string, size int) {
+		fmt.Fprintf(&buf, "%-16s%-12d%-6d%-6d%-8o%-10d`\n", name, 0, 0, 0, 0644, size)
+	}
+	body := func(data string) {
+		buf.WriteString(data)
+		if len(data)%2 == 1 {
+			buf.WriteByte('\n')
+		}
+	}
+
+	header("/", 4)
+	body("\x00\x00\x00\x00")
+
+	var longNames strings.Builder
+	offsets := map[string]int{}
+	for _, name := range order {
+		if len(name) > 15 {
+			offsets[name] = longNames.Len()
+			longNames.WriteString(name + "/\n")
+		}
+	}
+	if longNames.Len() > 0 {
+		header("//", longNames.Len())
+		body(longNames.String())
+	}
+
+	for _, name := range order {
+		if off, ok := offsets[name]; ok {
+			header(fmt.Sprintf("/%d", off), len(files[name]))
+		} else {
+			header(name+"/", len(files[name]))
+		}
+		body(files[name])
+	}
+	return buf.Bytes()
+}
+
+func memberMap(t *testing.T, format string, data []byte) map[string]string {
+	t.Helper()
+	if got := DetectArchive(data); got != format {
+		t.Fatalf("DetectArchive = %q, want %q", got, format)
+	}
+	members, err := UnpackArchive(format, data)
+	if err != nil {
+		t.Fatalf("UnpackArchive failed: %v", err)
+	}
+	out := map[string]string{}
+	for _, m := range members {
+		out[m.Name] = string(m.Data)
+	}
+	return out
+}
+
+func TestUnpackAr(t *testing.T) {
+	files := map[string]string{
+		"a.o":                          "odd",
+		"a_rather_long_object_name.o":  "\x7fELF long",
+		"another_very_long_name_123.o": "x",
+	}
+	data := buildAr(files, []string{"a.o", "a_rather_long_object_name.o", "another_very_long_name_123.o"})
+	got := memberMap(t, ArchiveAr, data)
+	if len(got) != len(files) {
+		t.Fatalf("got members %v, want %v", got, files)
+	}
+	for name, content := range files {
+		if got[name] != content {
+			t.Errorf("member %q = %q, want %q", name, got[name], content)
+		}
+	}
+
+	for _, name := range []string{"#1/-3", "/-1"} {
+		bad := append([]byte{}, arMagic...)
+		bad = fmt.Appendf(bad, "%-16s%-12d%-6d%-6d%-8o%-10d`\nxy", name, 0, 0, 0, 0644, 2)
+		if _, err := UnpackArchive(ArchiveAr, bad); err == nil {
+			t.Errorf("expected member name %q to be rejected", name)
+		}
+	}
+}
+
+func TestUnpackTarGz(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	tw.WriteHeader(&tar.Header{Name: "fw/", Typeflag: tar.TypeDir, Mode: 0755})
+	tw.WriteHeader(&tar.Header{Name: "fw/bin/busybox", Typeflag: tar.TypeReg, Mode: 0755, Size: 4})
+	tw.Write([]byte("\x7fELF"))
+	tw.WriteHeader(&tar.Header{Name: "fw/bin/sh", Typeflag: tar.TypeSymlink, Linkname: "busybox"})
+	tw.WriteHeader(&tar.Header{Name: "../../etc/passwd", Typeflag: tar.TypeReg, Mode: 0644, Size: 1})
+	tw.Write([]byte("x"))
+	tw.Close()
+	zw.Close()
+
+	got := memberMap(t, ArchiveTarGz, buf.Bytes())
+	if len(got) != 2 || got["fw/bin/busybox"] != "\x7fELF" || got["etc/passwd"] != "x" {
+		t.Errorf("unexpected members: %q", got)
+	}
+}
+
+func TestUnpackZip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	zw.Create("lib/")
+	f, _ := zw.Create("lib/libfoo.so")
+	f.Write([]byte("\x7fELF"))
+	zw.Close()
+
+	got := memberMap(t, ArchiveZip, buf.Bytes())
+	if len(got) != 1 || got["lib/libfoo.so"] != "\x7fELF" {
+		t.Errorf("unexpected members: %q", got)
+	}
+}
+
+// buildTar writes a tar of headers, with the content of regular files
+func buildTar(headers []tar.Header, contents map[string]string) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, hdr := range headers {
+		hdr.Size = int64(len(contents[hdr.Name]))
+		tw.WriteHeader(&hdr)
+		tw.Write([]byte(contents[hdr.Name]))
+	}
+	tw.Close()
+	return buf.Bytes()
+}
+
+func TestExtractArchive(t *testing.T) {
+	dir := t.TempDir()
+	data := buildTar([]tar.Header{
+		{Name: "lib/libc.so", Typeflag: tar.TypeSymlink, Linkname: "libc.so.6"},
+		{Name: "lib/libc.so.6", Typeflag: tar.TypeReg, Mode: 0755},
+		{Name: "usr/lib/libc.so.6", Typeflag: tar.TypeLink, Linkname: "./lib/libc.so.6"},
+	}, map[string]string{"lib/libc.so.6": "\x7fELF"})
+	if _, err := ExtractArchive(ArchiveTar, data, dir); err != nil {
+		t.Fatalf("ExtractArchive failed: %v", err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, "lib/libc.so.6")); string(got) != "\x7fELF" {
+		t.Errorf("unexpected content %q", got)
+	}
+	if got, _ := os.Readlink(filepath.Join(dir, "lib/libc.so")); got != "libc.so.6" {
+		t.Errorf("symbolic link target %q", got)
+	}
+	if got, _ := os.Readlink(filepath.Join(dir, "usr/lib/libc.so.6")); got != "/lib/libc.so.6" {
+		t.Errorf("hard link target %q", got)
+	}
+}
+
+func TestExtractArchive_ThroughLinks(t *testing.T) {
+	outside := t.TempDir()
+	for name, headers := range map[string][]tar.Header{
+		"file below link": {
+			{Name: "lib", Typeflag: tar.TypeSymlink, Linkname: outside},
+			{Name: "lib/evil", Typeflag: tar.TypeReg},
+		},
+		"link below link": {
+			{Name: "lib", Typeflag: tar.TypeSymlink, Linkname: outside},
+			{Name: "lib/evil", Typeflag: tar.TypeSymlink, Linkname: "/"},
+		},
+		"file over link": {
+			{Name: "evil", Typeflag: tar.TypeSymlink, Linkname: filepath.Join(outside, "evil")},
+			{Name: "evil", Typeflag: tar.TypeReg},
+		},
+	} {
+		_, err := ExtractArchive(ArchiveTar, buildTar(headers, nil), t.TempDir())
+		if err == nil {
+			t.Errorf("%s: expected the archive to be rejected", name)
+		}
+		if entries, _ := os.ReadDir(outside); len(entries) != 0 {
+			t.Fatalf("%s: wrote outside the directory: %v", name, entries)
+		}
+	}
+}
+
+func TestUnpackArchive_EntryLimit(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for i := 0; i <= MaxArchiveEntries; i++ {
+		zw.Create(fmt.Sprintf("f%d", i))
+	}
+	zw.Close()
+
+	if _, err := UnpackArchive(ArchiveZip, buf.Bytes()); err == nil {
+		t.Error("expected an archive over the entry limit to be rejected")
+	}
+}
+
+func TestDetectArchive_SingleFiles(t *testing.T) {
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte("\x7fELF not a tarball"))
+	zw.Close()
+
+	for name, data := range map[string][]byte{
+		"elf":  []byte("\x7fELF\x02\x01\x01"),
+		"gzip": gz.Bytes(),
+		"thin": []byte("!<thin>\n"),
+	} {
+		if got := DetectArchive(data); got != "" {
+			t.Errorf("%s: DetectArchive = %q, want none", name, got)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package validation
 
 import (
 	"fmt"
+	"io"
 	"mime/multipart"
 	"net/http"
 )
@@ -26,10 +27,33 @@ func ValidateFileUpload(r *http.Request) (*multipart.FileHeader, []byte, error)
 	}
 
 	data := make([]byte, header.Size)
-	_, err = file.Read(data)
+	_, err = io.ReadFull(file, data)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to read file: %v", err)
 	}
 
 	return header, data, nil
 }
+
+// OptionalFormFile reads an additional file field of an already parsed upload
+// form, returning nil data when the field is absent
+func OptionalFormFile(r *http.Request, field string) ([]byte, error) {
+	file, header, err := r.FormFile(field)
+	if err == http.ErrMissingFile {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s file: %v", field, err)
+	}
+	defer file.Close()
+
+	if header.Size > MaxUploadSize {
+		return nil, fmt.Errorf("%s file too large: %d bytes (max %d)", field, header.Size, MaxUploadSize)
+	}
+
+	data := make([]byte, header.Size)
+	if _, err := io.ReadFull(file, data); err != nil {
+		return nil, fmt.Errorf("failed to read %s file: %v", field, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,307 @@
+package yara
+
+import "encoding/binary"
+
+// value is the result of a condition expression. YARA conditions are integer
+// valued with booleans as 0/1; undefined values (reads past the end of the
+// file, missing match indexes) poison arithmetic and count as false.
+type value struct {
+	n     int64
+	undef bool
+}
+
+var undefined = value{undef: true}
+
+func boolValue(b bool) value {
+	if b {
+		return value{n: 1}
+	}
+	return value{}
+}
+
+func (v value) truth() bool {
+	return !v.undef && v.n != 0
+}
+
+type expr interface {
+	eval(ctx *scanContext) value
+}
+
+// scanContext holds the per-scan state shared by the rules of one file
+type scanContext struct {
+	data  []byte
+	hits  map[string][]stringHit
+	rules map[string]bool
+	defs  map[string]*stringDef
+}
+
+func (ctx *scanContext) stringHits(id string) []stringHit {
+	hits, ok := ctx.hits[id]
+	if !ok {
+		hits = ctx.defs[id].scan(ctx.data)
+		ctx.hits[id] = hits
+	}
+	return hits
+}
+
+type constExpr struct{ n int64 }
+
+func (e *constExpr) eval(*scanContext) value { return value{n: e.n} }
+
+type filesizeExpr struct{}
+
+func (e *filesizeExpr) eval(ctx *scanContext) value { return value{n: int64(len(ctx.data))} }
+
+type logicExpr struct {
+	or          bool
+	left, right expr
+}
+
+func (e *logicExpr) eval(ctx *scanContext) value {
+	l := e.left.eval(ctx).truth()
+	if e.or && l {
+		return boolValue(true)
+	}
+	if !e.or && !l {
+		return boolValue(false)
+	}
+	return boolValue(e.right.eval(ctx).truth())
+}
+
+type notExpr struct{ inner expr }
+
+func (e *notExpr) eval(ctx *scanContext) value {
+	v := e.inner.eval(ctx)
+	if v.undef {
+		return undefined
+	}
+	return boolValue(v.n == 0)
+}
+
+type unaryExpr struct {
+	op    string
+	inner expr
+}
+
+func (e *unaryExpr) eval(ctx *scanContext) value {
+	v := e.inner.eval(ctx)
+	if v.undef {
+		return undefined
+	}
+	if e.op == "-" {
+		return value{n: -v.n}
+	}
+	return value{n: ^v.n}
+}
+
+type binaryExpr struct {
+	op          string
+	left, right expr
+}
+
+func (e *binaryExpr) eval(ctx *scanContext) value {
+	l, r := e.left.eval(ctx), e.right.eval(ctx)
+	if l.undef || r.undef {
+		return undefined
+	}
+	a, b := l.n, r.n
+	switch e.op {
+	case "==":
+		return boolValue(a == b)
+	case "!=":
+		return boolValue(a != b)
+	case "<":
+		return boolValue(a < b)
+	case "<=":
+		return boolValue(a <= b)
+	case ">":
+		return boolValue(a > b)
+	case ">=":
+		return boolValue(a >= b)
+	case "+":
+		return value{n: a + b}
+	case "-":
+		return value{n: a - b}
+	case "*":
+		return value{n: a * b}
+	case "\\", "%":
+		if b == 0 {
+			return undefined
+		}
+		if e.op == "%" {
+			return value{n: a % b}
+		}
+		return value{n: a / b}
+	case "&":
+		return value{n: a & b}
+	case "|":
+		return value{n: a | b}
+	case "^":
+		return value{n: a ^ b}
+	case "<<", ">>":
+		if b < 0 {
+			return undefined
+		}
+		if b >= 64 {
+			return value{}
+		}
+		if e.op == "<<" {
+			return value{n: a << uint(b)}
+		}
+		return value{n: a >> uint(b)}
+	}
+	return undefined
+}
+
+// stringExpr is $a, $a at offset or $a in (lo..hi)
+type stringExpr struct {
+	id     string
+	at     expr
+	lo, hi expr
+}
+
+func (e *stringExpr) eval(ctx *scanContext) value {
+	hits := ctx.stringHits(e.id)
+	switch {
+	case e.at != nil:
+		at := e.at.eval(ctx)
+		if at.undef {
+			return undefined
+		}
+		for _, h := range hits {
+			if int64(h.offset) == at.n {
+				return boolValue(true)
+			}
+		}
+		return boolValue(false)
+	case e.lo != nil:
+		lo, hi := e.lo.eval(ctx), e.hi.eval(ctx)
+		if lo.undef || hi.undef {
+			return undefined
+		}
+		for _, h := range hits {
+			if off := int64(h.offset); off >= lo.n && off <= hi.n {
+				return boolValue(true)
+			}
+		}
+		return boolValue(false)
+	}
+	return boolValue(len(hits) > 0)
+}
+
+type countExpr struct{ id string }
+
+func (e *countExpr) eval(ctx *scanContext) value {
+	return value{n: int64(len(ctx.stringHits(e.id)))}
+}
+
+// hitExpr is @a[i] (offset) or !a[i] (length) of the i-th match, 1-based
+type hitExpr struct {
+	id     string
+	length bool
+	index  expr
+}
+
+func (e *hitExpr) eval(ctx *scanContext) value {
+	i := e.index.eval(ctx)
+	hits := ctx.stringHits(e.id)
+	if i.undef || i.n < 1 || i.n > int64(len(hits)) {
+		return undefined
+	}
+	h := hits[i.n-1]
+	if e.length {
+		return value{n: int64(h.length)}
+	}
+	return value{n: int64(h.offset)}
+}
+
+type intReader struct {
+	size      int
+	signed    bool
+	bigEndian bool
+}
+
+// readExpr is uint8/16/32(offset) and their signed and big-endian variants
+type readExpr struct {
+	reader intReader
+	offset expr
+}
+
+func (e *readExpr) eval(ctx *scanContext) value {
+	off := e.offset.eval(ctx)
+	size := int64(e.reader.size)
+	if off.undef || off.n < 0 || off.n > int64(len(ctx.data))-size {
+		return undefined
+	}
+	b := ctx.data[off.n : off.n+size]
+	order := binary.ByteOrder(binary.LittleEndian)
+	if e.reader.bigEndian {
+		order = binary.BigEndian
+	}
+	switch e.reader.size {
+	case 1:
+		if e.reader.signed {
+			return value{n: int64(int8(b[0]))}
+		}
+		return value{n: int64(b[0])}
+	case 2:
+		v := order.Uint16(b)
+		if e.reader.signed {
+			return value{n: int64(int16(v))}
+		}
+		return value{n: int64(v)}
+	default:
+		v := order.Uint32(b)
+		if e.reader.signed {
+			return value{n: int64(int32(v))}
+		}
+		return value{n: int64(v)}
+	}
+}
+
+// quantifier is the left side of an of expression: all, any, none, N or N%
+type quantifier struct {
+	keyword string
+	count   expr
+	percent bool
+}
+
+type ofExpr struct {
+	quant *quantifier
+	ids   []string
+}
+
+func (e *ofExpr) eval(ctx *scanContext) value {
+	matched := 0
+	for _, id := range e.ids {
+		if len(ctx.stringHits(id)) > 0 {
+			matched++
+		}
+	}
+	total := len(e.ids)
+	switch e.quant.keyword {
+	case "all":
+		return boolValue(matched == total)
+	case "any":
+		return boolValue(matched > 0)
+	case "none":
+		return boolValue(matched == 0)
+	}
+	n := e.quant.count.eval(ctx)
+	if n.undef {
+		return undefined
+	}
+	if e.quant.percent {
+		return boolValue(int64(matched)*100 >= n.n*int64(total))
+	}
+	if n.n == 0 {
+		return boolValue(matched == 0)
+	}
+	return boolValue(int64(matched) >= n.n)
+}
+
+type ruleRefExpr struct{ name string }
+
+func (e *ruleRefExpr) eval(ctx *scanContext) value {
+	return boolValue(ctx.rules[e.name])
+}
@@ -0,0 +1,276 @@
+package yara
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokStringID // $name, $name*, or bare $
+	tokCountID  // #name
+	tokOffsetID // @name
+	tokLengthID // !name
+	tokInt
+	tokText  // "quoted"
+	tokHex   // { 4D 5A ?? }
+	tokRegex // /pattern/flags
+	tokPunct
+)
+
+type token struct {
+	kind  tokenKind
+	text  string // identifier, punctuation, decoded text or raw hex/regex body
+	num   int64
+	flags string // regex flags
+	line  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of input"
+	case tokText:
+		return strconv.Quote(t.text)
+	case tokInt:
+		return strconv.FormatInt(t.num, 10)
+	}
+	return t.text
+}
+
+type lexer struct {
+	src  string
+	pos  int
+	line int
+	// afterAssign is set after "=" so that "{" starts a hex string and "/" a regex
+	afterAssign bool
+}
+
+func tokenize(src string) ([]token, error) {
+	lx := &lexer{src: src, line: 1}
+	var toks []token
+	for {
+		t, err := lx.next()
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lx.line, err)
+		}
+		toks = append(toks, t)
+		if t.kind == tokEOF {
+			return toks, nil
+		}
+	}
+}
+
+func (lx *lexer) skipSpaceAndComments() error {
+	for lx.pos < len(lx.src) {
+		c := lx.src[lx.pos]
+		switch {
+		case c == '\n':
+			lx.line++
+			lx.pos++
+		case c == ' ' || c == '\t' || c == '\r':
+			lx.pos++
+		case strings.HasPrefix(lx.src[lx.pos:], "//"):
+			for lx.pos < len(lx.src) && lx.src[lx.pos] != '\n' {
+				lx.pos++
+			}
+		case strings.HasPrefix(lx.src[lx.pos:], "/*"):
+			end := strings.Index(lx.src[lx.pos+2:], "*/")
+			if end < 0 {
+				return fmt.Errorf("unterminated comment")
+			}
+			lx.line += strings.Count(lx.src[lx.pos:lx.pos+2+end], "\n")
+			lx.pos += end + 4
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func (lx *lexer) next() (token, error) {
+	if err := lx.skipSpaceAndComments(); err != nil {
+		return token{}, err
+	}
+	afterAssign := lx.afterAssign
+	lx.afterAssign = false
+	if lx.pos >= len(lx.src) {
+		return token{kind: tokEOF, line: lx.line}, nil
+	}
+
+	start := lx.pos
+	c := lx.src[lx.pos]
+	switch {
+	case c == '"':
+		return lx.text()
+	case c == '{' && afterAssign:
+		end := strings.IndexByte(lx.src[lx.pos:], '}')
+		if end < 0 {
+			return token{}, fmt.Errorf("unterminated hex string")
+		}
+		body := lx.src[lx.pos+1 : lx.pos+end]
+		lx.line += strings.Count(body, "\n")
+		lx.pos += end + 1
+		return token{kind: tokHex, text: body, line: lx.line}, nil
+	case c == '/' && afterAssign:
+		return lx.regex()
+	case c == '$' || c == '#' || c == '@' || c == '!':
+		lx.pos++
+		for lx.pos < len(lx.src) && isIdentChar(lx.src[lx.pos]) {
+			lx.pos++
+		}
+		if c == '$' && lx.pos < len(lx.src) && lx.src[lx.pos] == '*' {
+			lx.pos++
+		}
+		name := lx.src[start:lx.pos]
+		if c == '!' && len(name) == 1 {
+			// Plain "!" only appears as part of "!="
+			if lx.pos < len(lx.src) && lx.src[lx.pos] == '=' {
+				lx.pos++
+				return token{kind: tokPunct, text: "!=", line: lx.line}, nil
+			}
+			return token{}, fmt.Errorf("unexpected '!'")
+		}
+		kind := map[byte]tokenKind{'$': tokStringID, '#': tokCountID, '@': tokOffsetID, '!': tokLengthID}[c]
+		return token{kind: kind, text: name, line: lx.line}, nil
+	case isDigit(c):
+		return lx.number()
+	case isIdentChar(c):
+		// Dots join module member names such as pe.sections, but ".." is a range
+		for lx.pos < len(lx.src) && (isIdentChar(lx.src[lx.pos]) ||
+			(lx.src[lx.pos] == '.' && !strings.HasPrefix(lx.src[lx.pos:], ".."))) {
+			lx.pos++
+		}
+		return token{kind: tokIdent, text: lx.src[start:lx.pos], line: lx.line}, nil
+	}
+
+	for _, p := range []string{"..", "==", "!=", "<=", ">=", "<<", ">>"} {
+		if strings.HasPrefix(lx.src[lx.pos:], p) {
+			lx.pos += len(p)
+			return token{kind: tokPunct, text: p, line: lx.line}, nil
+		}
+	}
+	if strings.ContainsRune("{}()[]:=,<>+-*\\%&|^~", rune(c)) {
+		lx.pos++
+		if c == '=' {
+			lx.afterAssign = true
+		}
+		return token{kind: tokPunct, text: string(c), line: lx.line}, nil
+	}
+	return token{}, fmt.Errorf("unexpected character %q", c)
+}
+
+func (lx *lexer) text() (token, error) {
+	var sb strings.Builder
+	lx.pos++ // opening quote
+	for lx.pos < len(lx.src) {
+		c := lx.src[lx.pos]
+		switch c {
+		case '"':
+			lx.pos++
+			return token{kind: tokText, text: sb.String(), line: lx.line}, nil
+		case '\n':
+			return token{}, fmt.Errorf("newline in string")
+		case '\\':
+			if lx.pos+1 >= len(lx.src) {
+				return token{}, fmt.Errorf("unterminated string")
+			}
+			lx.pos++
+			switch e := lx.src[lx.pos]; e {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			case 'r':
+				sb.WriteByte('\r')
+			case '"', '\\':
+				sb.WriteByte(e)
+			case 'x':
+				if lx.pos+2 >= len(lx.src) {
+					return token{}, fmt.Errorf("short \\x escape")
+				}
+				b, err := strconv.ParseUint(lx.src[lx.pos+1:lx.pos+3], 16, 8)
+				if err != nil {
+					return token{}, fmt.Errorf("invalid \\x escape")
+				}
+				sb.WriteByte(byte(b))
+				lx.pos += 2
+			default:
+				return token{}, fmt.Errorf("unknown escape \\%c", e)
+			}
+			lx.pos++
+		default:
+			sb.WriteByte(c)
+			lx.pos++
+		}
+	}
+	return token{}, fmt.Errorf("unterminated string")
+}
+
+func (lx *lexer) regex() (token, error) {
+	lx.pos++ // opening slash
+	start := lx.pos
+	for lx.pos < len(lx.src) {
+		switch lx.src[lx.pos] {
+		case '\\':
+			lx.pos += 2
+			continue
+		case '\n':
+			return token{}, fmt.Errorf("newline in regular expression")
+		case '/':
+			body := lx.src[start:lx.pos]
+			lx.pos++
+			flagStart := lx.pos
+			for lx.pos < len(lx.src) && (lx.src[lx.pos] == 'i' || lx.src[lx.pos] == 's') {
+				lx.pos++
+			}
+			return token{kind: tokRegex, text: body, flags: lx.src[flagStart:lx.pos], line: lx.line}, nil
+		}
+		lx.pos++
+	}
+	return token{}, fmt.Errorf("unterminated regular expression")
+}
+
+func (lx *lexer) number() (token, error) {
+	start := lx.pos
+	base := 10
+	if strings.HasPrefix(lx.src[lx.pos:], "0x") || strings.HasPrefix(lx.src[lx.pos:], "0X") {
+		base = 16
+		lx.pos += 2
+		start = lx.pos
+		for lx.pos < len(lx.src) && isHexDigit(lx.src[lx.pos]) {
+			lx.pos++
+		}
+	} else {
+		for lx.pos < len(lx.src) && isDigit(lx.src[lx.pos]) {
+			lx.pos++
+		}
+	}
+	n, err := strconv.ParseInt(lx.src[start:lx.pos], base, 64)
+	if err != nil {
+		return token{}, fmt.Errorf("invalid number %q", lx.src[start:lx.pos])
+	}
+	switch {
+	case strings.HasPrefix(lx.src[lx.pos:], "KB"):
+		n *= 1024
+		lx.pos += 2
+	case strings.HasPrefix(lx.src[lx.pos:], "MB"):
+		n *= 1024 * 1024
+		lx.pos += 2
+	}
+	return token{kind: tokInt, num: n, line: lx.line}, nil
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
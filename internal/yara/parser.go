@@ -0,0 +1,651 @@
+package yara
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// unsupportedError marks valid YARA that this scanner does not implement, such
+// as modules or for loops. The affected rule is skipped instead of failing the
+// whole file.
+type unsupportedError struct {
+	what string
+}
+
+func (e *unsupportedError) Error() string {
+	return "unsupported: " + e.what
+}
+
+func unsupported(format string, args ...any) error {
+	return &unsupportedError{what: fmt.Sprintf(format, args...)}
+}
+
+type parser struct {
+	toks []token
+	pos  int
+
+	// per file
+	rules map[string]*Rule
+
+	// per rule
+	strings map[string]*stringDef
+	order   []string
+}
+
+func (p *parser) peek() token { return p.toks[p.pos] }
+
+func (p *parser) peekAt(n int) token {
+	if p.pos+n >= len(p.toks) {
+		return p.toks[len(p.toks)-1]
+	}
+	return p.toks[p.pos+n]
+}
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isPunct(s string) bool {
+	t := p.peek()
+	return t.kind == tokPunct && t.text == s
+}
+
+func (p *parser) isKeyword(s string) bool {
+	t := p.peek()
+	return t.kind == tokIdent && t.text == s
+}
+
+func (p *parser) expectPunct(s string) error {
+	if t := p.next(); t.kind != tokPunct || t.text != s {
+		return fmt.Errorf("line %d: expected %q, found %s", t.line, s, t)
+	}
+	return nil
+}
+
+func (p *parser) expectKeyword(s string) error {
+	if t := p.next(); t.kind != tokIdent || t.text != s {
+		return fmt.Errorf("line %d: expected %q, found %s", t.line, s, t)
+	}
+	return nil
+}
+
+func (p *parser) expectIdent() (token, error) {
+	t := p.next()
+	if t.kind != tokIdent {
+		return t, fmt.Errorf("line %d: expected an identifier, found %s", t.line, t)
+	}
+	return t, nil
+}
+
+// Compile parses YARA source into rules. Rules using unsupported features are
+// returned in skipped; syntax errors fail the whole source.
+func Compile(src string) (rules []*Rule, skipped []SkippedRule, err error) {
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, nil, err
+	}
+	p := &parser{toks: toks, rules: map[string]*Rule{}}
+
+	for p.peek().kind != tokEOF {
+		switch {
+		case p.isKeyword("import"):
+			// Imports are accepted so that files mixing module and plain rules
+			// load; rules that use a module's fields are skipped
+			p.next()
+			if t := p.next(); t.kind != tokText {
+				return nil, nil, fmt.Errorf("line %d: expected a module name after import", t.line)
+			}
+		case p.isKeyword("include"):
+			return nil, nil, fmt.Errorf("line %d: include is not supported", p.peek().line)
+		default:
+			start := p.pos
+			rule, err := p.parseRule()
+			var unsup *unsupportedError
+			if errors.As(err, &unsup) {
+				name, end, skipErr := p.skipRule(start)
+				if skipErr != nil {
+					return nil, nil, skipErr
+				}
+				p.pos = end
+				skipped = append(skipped, SkippedRule{Rule: name, Reason: unsup.what})
+				continue
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			if _, dup := p.rules[rule.Name]; dup {
+				return nil, nil, fmt.Errorf("duplicate rule %q", rule.Name)
+			}
+			p.rules[rule.Name] = rule
+			rules = append(rules, rule)
+		}
+	}
+	return rules, skipped, nil
+}
+
+// skipRule finds the name and the end of the rule starting at token start by
+// balancing braces; hex strings are single tokens so their braces don't count
+func (p *parser) skipRule(start int) (string, int, error) {
+	name := ""
+	depth := 0
+	for i := start; i < len(p.toks); i++ {
+		t := p.toks[i]
+		if t.kind == tokIdent && t.text == "rule" && name == "" && i+1 < len(p.toks) {
+			name = p.toks[i+1].text
+		}
+		if t.kind != tokPunct {
+			continue
+		}
+		switch t.text {
+		case "{":
+			depth++
+		case "}":
+			depth--
+			if depth == 0 {
+				return name, i + 1, nil
+			}
+		}
+	}
+	return "", 0, fmt.Errorf("rule %q: missing closing brace", name)
+}
+
+func (p *parser) parseRule() (*Rule, error) {
+	rule := &Rule{Meta: map[string]any{}}
+	for {
+		switch {
+		case p.isKeyword("private"):
+			rule.Private = true
+			p.next()
+			continue
+		case p.isKeyword("global"):
+			rule.Global = true
+			p.next()
+			continue
+		}
+		break
+	}
+	if err := p.expectKeyword("rule"); err != nil {
+		return nil, err
+	}
+	name, err := p.expectIdent()
+	if err != nil {
+		return nil, err
+	}
+	rule.Name = name.text
+	if p.isPunct(":") {
+		p.next()
+		for p.peek().kind == tokIdent {
+			rule.Tags = append(rule.Tags, p.next().text)
+		}
+	}
+	if err := p.expectPunct("{"); err != nil {
+		return nil, err
+	}
+
+	p.strings = map[string]*stringDef{}
+	p.order = nil
+
+	if p.isKeyword("meta") {
+		p.next()
+		if err := p.expectPunct(":"); err != nil {
+			return nil, err
+		}
+		if err := p.parseMeta(rule); err != nil {
+			return nil, err
+		}
+	}
+	if p.isKeyword("strings") {
+		p.next()
+		if err := p.expectPunct(":"); err != nil {
+			return nil, err
+		}
+		if err := p.parseStrings(); err != nil {
+			return nil, fmt.Errorf("rule %s: %w", rule.Name, err)
+		}
+	}
+	if err := p.expectKeyword("condition"); err != nil {
+		return nil, fmt.Errorf("rule %s: %w", rule.Name, err)
+	}
+	if err := p.expectPunct(":"); err != nil {
+		return nil, err
+	}
+	cond, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("rule %s: %w", rule.Name, err)
+	}
+	if err := p.expectPunct("}"); err != nil {
+		return nil, fmt.Errorf("rule %s: %w", rule.Name, err)
+	}
+
+	rule.cond = cond
+	for _, id := range p.order {
+		rule.strings = append(rule.strings, p.strings[id])
+	}
+	return rule, nil
+}
+
+func (p *parser) parseMeta(rule *Rule) error {
+	for p.peek().kind == tokIdent && !p.isKeyword("strings") && !p.isKeyword("condition") {
+		key := p.next().text
+		if err := p.expectPunct("="); err != nil {
+			return err
+		}
+		t := p.next()
+		switch {
+		case t.kind == tokText:
+			rule.Meta[key] = t.text
+		case t.kind == tokInt:
+			rule.Meta[key] = t.num
+		case t.kind == tokPunct && t.text == "-" && p.peek().kind == tokInt:
+			rule.Meta[key] = -p.next().num
+		case t.kind == tokIdent && (t.text == "true" || t.text == "false"):
+			rule.Meta[key] = t.text == "true"
+		default:
+			return fmt.Errorf("line %d: invalid meta value %s", t.line, t)
+		}
+	}
+	return nil
+}
+
+func (p *parser) parseStrings() error {
+	anon := 0
+	for p.peek().kind == tokStringID {
+		idTok := p.next()
+		id := idTok.text
+		if strings.HasSuffix(id, "*") {
+			return fmt.Errorf("line %d: invalid string identifier %s", idTok.line, id)
+		}
+		if id == "$" {
+			anon++
+			id = fmt.Sprintf("$%d", anon)
+		}
+		if _, dup := p.strings[id]; dup {
+			return fmt.Errorf("duplicate string %s", id)
+		}
+		if err := p.expectPunct("="); err != nil {
+			return err
+		}
+		value := p.next()
+
+		var modifiers []string
+		for p.peek().kind == tokIdent && !p.isKeyword("condition") {
+			m := p.next().text
+			if p.isPunct("(") {
+				return unsupported("string modifier %q with arguments", m)
+			}
+			modifiers = append(modifiers, m)
+		}
+
+		def, err := compileString(id, value, modifiers)
+		if err != nil {
+			return err
+		}
+		p.strings[id] = def
+		p.order = append(p.order, id)
+	}
+	return nil
+}
+
+// Condition grammar, lowest precedence first:
+//
+//	or, and, not, comparison, |, ^, &, << >>, + -, * \ %, unary - ~, primary
+func (p *parser) parseOr() (expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicExpr{or: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (expr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("and") {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicExpr{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (expr, error) {
+	if p.isKeyword("not") {
+		p.next()
+		inner, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notExpr{inner: inner}, nil
+	}
+	return p.parseComparison()
+}
+
+var comparisonOps = map[string]bool{"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true}
+
+func (p *parser) parseComparison() (expr, error) {
+	left, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t.kind == tokIdent {
+			switch t.text {
+			case "contains", "icontains", "startswith", "istartswith", "endswith", "iendswith", "iequals", "matches":
+				return nil, unsupported("string operator %s", t.text)
+			}
+		}
+		if t.kind != tokPunct || !comparisonOps[t.text] {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseBinary(0)
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: t.text, left: left, right: right}
+	}
+}
+
+// binaryLevels lists arithmetic and bitwise operators from lowest to highest
+// precedence
+var binaryLevels = [][]string{
+	{"|"},
+	{"^"},
+	{"&"},
+	{"<<", ">>"},
+	{"+", "-"},
+	{"*", "\\", "%"},
+}
+
+func (p *parser) parseBinary(level int) (expr, error) {
+	if level == len(binaryLevels) {
+		return p.parseUnary()
+	}
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t.kind != tokPunct || !containsString(binaryLevels[level], t.text) {
+			return left, nil
+		}
+		// "N% of them" is a quantifier, not a modulo
+		if t.text == "%" && p.peekAt(1).kind == tokIdent && p.peekAt(1).text == "of" {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: t.text, left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (expr, error) {
+	if p.isPunct("-") || p.isPunct("~") {
+		op := p.next().text
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryExpr{op: op, inner: inner}, nil
+	}
+	return p.parsePrimary()
+}
+
+var intReaders = map[string]intReader{
+	"uint8": {1, false, false}, "uint16": {2, false, false}, "uint32": {4, false, false},
+	"uint16be": {2, false, true}, "uint32be": {4, false, true},
+	"int8": {1, true, false}, "int16": {2, true, false}, "int32": {4, true, false},
+	"int16be": {2, true, true}, "int32be": {4, true, true},
+}
+
+func (p *parser) parsePrimary() (expr, error) {
+	t := p.next()
+	switch t.kind {
+	case tokInt:
+		n := &constExpr{n: t.num}
+		return p.maybeOf(n)
+	case tokText:
+		return nil, unsupported("string literals in conditions")
+	case tokStringID:
+		return p.parseStringRef(t)
+	case tokCountID:
+		id := "$" + t.text[1:]
+		if err := p.checkString(id, t.line); err != nil {
+			return nil, err
+		}
+		if p.isKeyword("in") {
+			return nil, unsupported("#%s in range", t.text[1:])
+		}
+		return &countExpr{id: id}, nil
+	case tokOffsetID, tokLengthID:
+		id := "$" + t.text[1:]
+		if err := p.checkString(id, t.line); err != nil {
+			return nil, err
+		}
+		var index expr = &constExpr{n: 1}
+		if p.isPunct("[") {
+			p.next()
+			var err error
+			if index, err = p.parseOr(); err != nil {
+				return nil, err
+			}
+			if err := p.expectPunct("]"); err != nil {
+				return nil, err
+			}
+		}
+		return &hitExpr{id: id, length: t.kind == tokLengthID, index: index}, nil
+	case tokPunct:
+		if t.text == "(" {
+			inner, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectPunct(")"); err != nil {
+				return nil, err
+			}
+			return p.maybeOf(inner)
+		}
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return &constExpr{n: 1}, nil
+		case "false":
+			return &constExpr{n: 0}, nil
+		case "filesize":
+			return &filesizeExpr{}, nil
+		case "all", "any", "none":
+			if p.isKeyword("of") {
+				return p.parseOf(&quantifier{keyword: t.text})
+			}
+		case "for":
+			return nil, unsupported("for loops")
+		case "entrypoint":
+			return nil, unsupported("entrypoint")
+		default:
+			if reader, ok := intReaders[t.text]; ok {
+				if err := p.expectPunct("("); err != nil {
+					return nil, err
+				}
+				offset, err := p.parseOr()
+				if err != nil {
+					return nil, err
+				}
+				if err := p.expectPunct(")"); err != nil {
+					return nil, err
+				}
+				return &readExpr{reader: reader, offset: offset}, nil
+			}
+			if strings.Contains(t.text, ".") {
+				return nil, unsupported("module field %s", t.text)
+			}
+			if _, ok := p.rules[t.text]; ok {
+				return &ruleRefExpr{name: t.text}, nil
+			}
+			return nil, fmt.Errorf("line %d: undefined identifier %s", t.line, t.text)
+		}
+	}
+	return nil, fmt.Errorf("line %d: unexpected %s in condition", t.line, t)
+}
+
+// maybeOf turns a just-parsed number into the quantity of an "N of" or
+// "N% of" expression when one follows
+func (p *parser) maybeOf(n expr) (expr, error) {
+	if p.isPunct("%") && p.peekAt(1).kind == tokIdent && p.peekAt(1).text == "of" {
+		p.next()
+		return p.parseOf(&quantifier{count: n, percent: true})
+	}
+	if p.isKeyword("of") {
+		return p.parseOf(&quantifier{count: n})
+	}
+	return n, nil
+}
+
+func (p *parser) parseOf(q *quantifier) (expr, error) {
+	if err := p.expectKeyword("of"); err != nil {
+		return nil, err
+	}
+	ids, err := p.parseStringSet()
+	if err != nil {
+		return nil, err
+	}
+	of := &ofExpr{quant: q, ids: ids}
+	if p.isKeyword("in") {
+		return nil, unsupported("of ... in range")
+	}
+	if p.isKeyword("at") {
+		return nil, unsupported("of ... at offset")
+	}
+	return of, nil
+}
+
+func (p *parser) parseStringSet() ([]string, error) {
+	if p.isKeyword("them") {
+		p.next()
+		if len(p.order) == 0 {
+			return nil, fmt.Errorf("them used in a rule without strings")
+		}
+		return append([]string(nil), p.order...), nil
+	}
+	if !p.isPunct("(") {
+		t := p.peek()
+		if t.kind == tokIdent {
+			return nil, unsupported("rule sets in of expressions")
+		}
+		return nil, fmt.Errorf("line %d: expected them or a string set, found %s", t.line, t)
+	}
+	p.next()
+	var ids []string
+	for {
+		t := p.next()
+		if t.kind != tokStringID {
+			if t.kind == tokIdent {
+				return nil, unsupported("rule sets in of expressions")
+			}
+			return nil, fmt.Errorf("line %d: expected a string identifier, found %s", t.line, t)
+		}
+		if prefix, ok := strings.CutSuffix(t.text, "*"); ok {
+			found := false
+			for _, id := range p.order {
+				if strings.HasPrefix(id, prefix) {
+					ids = append(ids, id)
+					found = true
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("line %d: %s matches no strings", t.line, t.text)
+			}
+		} else {
+			if err := p.checkString(t.text, t.line); err != nil {
+				return nil, err
+			}
+			ids = append(ids, t.text)
+		}
+		if p.isPunct(",") {
+			p.next()
+			continue
+		}
+		if err := p.expectPunct(")"); err != nil {
+			return nil, err
+		}
+		return ids, nil
+	}
+}
+
+func (p *parser) parseStringRef(t token) (expr, error) {
+	if t.text == "$" || strings.HasSuffix(t.text, "*") {
+		return nil, fmt.Errorf("line %d: %s can only be used in an of expression", t.line, t.text)
+	}
+	if err := p.checkString(t.text, t.line); err != nil {
+		return nil, err
+	}
+	ref := &stringExpr{id: t.text}
+	switch {
+	case p.isKeyword("at"):
+		p.next()
+		at, err := p.parseBinary(0)
+		if err != nil {
+			return nil, err
+		}
+		ref.at = at
+	case p.isKeyword("in"):
+		p.next()
+		if err := p.expectPunct("("); err != nil {
+			return nil, err
+		}
+		lo, err := p.parseBinary(0)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectPunct(".."); err != nil {
+			return nil, err
+		}
+		hi, err := p.parseBinary(0)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectPunct(")"); err != nil {
+			return nil, err
+		}
+		ref.lo, ref.hi = lo, hi
+	}
+	return ref, nil
+}
+
+func (p *parser) checkString(id string, line int) error {
+	if _, ok := p.strings[id]; !ok {
+		return fmt.Errorf("line %d: undefined string %s", line, id)
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
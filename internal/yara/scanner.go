@@ -0,0 +1,226 @@
+// Package yara is a pure-Go scanner for a practical subset of the YARA rule
+// language, so samples can be checked against existing rule sets without
+// linking libyara.
+//
+// Supported: text strings (nocase, wide, ascii, fullword, private), hex strings
+// with wildcards, nibbles, jumps and alternatives, regular expressions, and
+// conditions built from and/or/not, comparisons and arithmetic, $a, $a at N,
+// $a in (lo..hi), #a, @a[i], !a[i], N/all/any/none of (...)/them, filesize,
+// uint8/16/32(be) and int8/16/32(be), and references to earlier rules.
+// Private and global rules behave as in YARA.
+//
+// Rules that rely on anything else, such as modules (pe.*, elf.*), for loops
+// or the xor/base64 modifiers, are skipped and reported rather than failing
+// the whole file.
+package yara
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	// maxReportedStrings caps the string matches stored for one rule
+	maxReportedStrings = 100
+	// maxMatchData caps how many bytes of each string match are reported
+	maxMatchData = 64
+)
+
+type Rule struct {
+	Name      string         `json:"name"`
+	Namespace string         `json:"namespace"`
+	Tags      []string       `json:"tags,omitempty"`
+	Meta      map[string]any `json:"meta,omitempty"`
+	Private   bool           `json:"private,omitempty"`
+	Global    bool           `json:"global,omitempty"`
+
+	strings []*stringDef
+	cond    expr
+}
+
+// SkippedRule is a rule that parsed but uses features the scanner lacks
+type SkippedRule struct {
+	Rule      string `json:"rule"`
+	Namespace string `json:"namespace"`
+	Reason    string `json:"reason"`
+}
+
+// Match is a rule whose condition held for the scanned data
+type Match struct {
+	Rule      string         `json:"rule"`
+	Namespace string         `json:"namespace"`
+	Tags      []string       `json:"tags,omitempty"`
+	Meta      map[string]any `json:"meta,omitempty"`
+	Strings   []StringMatch  `json:"strings,omitempty"`
+}
+
+// StringMatch is one occurrence of a rule string. Data holds the matched bytes
+// in hex, truncated to maxMatchData bytes.
+type StringMatch struct {
+	ID     string `json:"id"`
+	Offset int    `json:"offset"`
+	Length int    `json:"length"`
+	Data   string `json:"data"`
+}
+
+// Scanner holds the rules compiled from a directory and can reload them while
+// scans are in flight
+type Scanner struct {
+	dir     string
+	mu      sync.RWMutex
+	rules   []*Rule
+	skipped []SkippedRule
+}
+
+func NewScanner(dir string) *Scanner {
+	return &Scanner{dir: dir}
+}
+
+// Dir returns the directory rules are loaded from
+func (s *Scanner) Dir() string {
+	return s.dir
+}
+
+// Reload compiles every *.yar/*.yara file in the rules directory. The new set
+// replaces the old one only if all files compile. A missing directory yields
+// an empty rule set.
+func (s *Scanner) Reload() (int, error) {
+	rules, skipped, err := LoadDir(s.dir)
+	if err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	s.rules, s.skipped = rules, skipped
+	s.mu.Unlock()
+	return len(rules), nil
+}
+
+// Rules returns the currently loaded rules in file order
+func (s *Scanner) Rules() []*Rule {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]*Rule(nil), s.rules...)
+}
+
+// Skipped returns the rules left out of the last successful load
+func (s *Scanner) Skipped() []SkippedRule {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]SkippedRule(nil), s.skipped...)
+}
+
+// Scan runs every loaded rule against data
+func (s *Scanner) Scan(data []byte) []Match {
+	return Scan(s.Rules(), data)
+}
+
+// LoadDir compiles all rule files in dir. Each file is its own namespace.
+func LoadDir(dir string) ([]*Rule, []SkippedRule, error) {
+	if dir == "" {
+		return nil, nil, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil, nil
+		}
+		return nil, nil, fmt.Errorf("failed to read YARA directory: %w", err)
+	}
+
+	var all []*Rule
+	var allSkipped []SkippedRule
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".yar" && ext != ".yara") {
+			continue
+		}
+		src, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read YARA file: %w", err)
+		}
+		rules, skipped, err := Compile(string(src))
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		for _, r := range rules {
+			r.Namespace = entry.Name()
+		}
+		for i := range skipped {
+			skipped[i].Namespace = entry.Name()
+		}
+		all = append(all, rules...)
+		allSkipped = append(allSkipped, skipped...)
+	}
+	return all, allSkipped, nil
+}
+
+// Scan evaluates rules in order against data. Rule references and global
+// rules apply within a namespace, as they do in YARA.
+func Scan(rules []*Rule, data []byte) []Match {
+	var matches []Match
+	for start := 0; start < len(rules); {
+		end := start
+		for end < len(rules) && rules[end].Namespace == rules[start].Namespace {
+			end++
+		}
+		matches = append(matches, scanNamespace(rules[start:end], data)...)
+		start = end
+	}
+	return matches
+}
+
+func scanNamespace(rules []*Rule, data []byte) []Match {
+	ctx := &scanContext{data: data, rules: map[string]bool{}}
+	var matches []Match
+	for _, r := range rules {
+		ctx.hits = map[string][]stringHit{}
+		ctx.defs = map[string]*stringDef{}
+		for _, def := range r.strings {
+			ctx.defs[def.id] = def
+		}
+
+		ok := r.cond.eval(ctx).truth()
+		ctx.rules[r.Name] = ok
+		if r.Global && !ok {
+			return nil
+		}
+		if !ok || r.Private {
+			continue
+		}
+		matches = append(matches, Match{
+			Rule:      r.Name,
+			Namespace: r.Namespace,
+			Tags:      r.Tags,
+			Meta:      r.Meta,
+			Strings:   reportStrings(ctx, r),
+		})
+	}
+	return matches
+}
+
+func reportStrings(ctx *scanContext, r *Rule) []StringMatch {
+	var out []StringMatch
+	for _, def := range r.strings {
+		if def.private {
+			continue
+		}
+		for _, h := range ctx.stringHits(def.id) {
+			if len(out) == maxReportedStrings {
+				return out
+			}
+			data := ctx.data[h.offset : h.offset+min(h.length, maxMatchData)]
+			out = append(out, StringMatch{
+				ID:     def.id,
+				Offset: h.offset,
+				Length: h.length,
+				Data:   hex.EncodeToString(data),
+			})
+		}
+	}
+	return out
+}
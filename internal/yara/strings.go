@@ -0,0 +1,356 @@
+package yara
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxStringHits bounds how many matches a single string records, which also
+// bounds the work spent on patterns that match everywhere
+const maxStringHits = 10000
+
+// Go's regexp engine decodes UTF-8, so arbitrary bytes are fed to it as
+// Latin-1 runes instead: byte b becomes rune b with width 1. Patterns use
+// \x{hh} to name bytes and reported positions remain byte offsets.
+type latin1Reader struct {
+	data []byte
+	pos  int
+}
+
+func (r *latin1Reader) ReadRune() (rune, int, error) {
+	if r.pos >= len(r.data) {
+		return 0, 0, io.EOF
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return rune(b), 1, nil
+}
+
+type stringHit struct {
+	offset int
+	length int
+}
+
+type stringDef struct {
+	id       string
+	fullword bool
+	private  bool
+
+	re       *regexp.Regexp // unanchored
+	anchored *regexp.Regexp
+	prefix   []byte // literal bytes every match starts with, if any
+}
+
+var supportedModifiers = map[string]bool{
+	"nocase": true, "wide": true, "ascii": true, "fullword": true, "private": true,
+}
+
+func compileString(id string, tok token, modifiers []string) (*stringDef, error) {
+	def := &stringDef{id: id}
+	mods := map[string]bool{}
+	for _, m := range modifiers {
+		if !supportedModifiers[m] {
+			return nil, unsupported("string modifier %q", m)
+		}
+		mods[m] = true
+	}
+	def.fullword = mods["fullword"]
+	def.private = mods["private"]
+
+	var pattern string
+	switch tok.kind {
+	case tokText:
+		if tok.text == "" {
+			return nil, fmt.Errorf("%s: empty string", id)
+		}
+		var alts []string
+		if mods["ascii"] || !mods["wide"] {
+			alts = append(alts, bytesPattern([]byte(tok.text), false))
+		}
+		if mods["wide"] {
+			alts = append(alts, bytesPattern([]byte(tok.text), true))
+		}
+		pattern = strings.Join(alts, "|")
+		if mods["nocase"] {
+			pattern = "(?i)(?:" + pattern + ")"
+		}
+	case tokHex:
+		if len(modifiers) > 0 && !(len(modifiers) == 1 && def.private) {
+			return nil, fmt.Errorf("%s: hex strings only accept the private modifier", id)
+		}
+		p, err := hexPattern(tok.text)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", id, err)
+		}
+		pattern = p
+	case tokRegex:
+		if mods["wide"] {
+			return nil, unsupported("wide regular expressions")
+		}
+		flags := ""
+		if strings.Contains(tok.flags, "i") || mods["nocase"] {
+			flags += "i"
+		}
+		if strings.Contains(tok.flags, "s") {
+			flags += "s"
+		}
+		pattern = tok.text
+		if flags != "" {
+			pattern = "(?" + flags + ")" + pattern
+		}
+	default:
+		return nil, fmt.Errorf("%s: expected a text, hex or regex string", id)
+	}
+
+	var err error
+	if def.re, err = regexp.Compile(pattern); err != nil {
+		return nil, fmt.Errorf("%s: %w", id, err)
+	}
+	def.anchored = regexp.MustCompile("^(?:" + pattern + ")")
+	if lit, _ := def.re.LiteralPrefix(); lit != "" {
+		for _, r := range lit {
+			if r > 0xff {
+				def.prefix = nil
+				break
+			}
+			def.prefix = append(def.prefix, byte(r))
+		}
+	}
+	return def, nil
+}
+
+// bytesPattern matches data literally, optionally as UTF-16LE
+func bytesPattern(data []byte, wide bool) string {
+	var sb strings.Builder
+	for _, b := range data {
+		fmt.Fprintf(&sb, `\x{%02x}`, b)
+		if wide {
+			sb.WriteString(`\x{00}`)
+		}
+	}
+	return sb.String()
+}
+
+// hexPattern translates a YARA hex string body into a regular expression over
+// Latin-1 runes: ?? and nibble wildcards, ~ negation, [n-m] jumps and (a|b)
+// alternatives are supported
+func hexPattern(body string) (string, error) {
+	var sb strings.Builder
+	tokens := 0
+	depth := 0
+	for i := 0; i < len(body); {
+		c := body[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			sb.WriteString("(?:")
+			depth++
+			i++
+		case c == '|':
+			if depth == 0 {
+				return "", fmt.Errorf("'|' outside of an alternative")
+			}
+			sb.WriteByte('|')
+			i++
+		case c == ')':
+			if depth == 0 {
+				return "", fmt.Errorf("unbalanced ')'")
+			}
+			sb.WriteByte(')')
+			depth--
+			i++
+		case c == '[':
+			end := strings.IndexByte(body[i:], ']')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated jump")
+			}
+			rep, err := jumpRepeat(strings.TrimSpace(body[i+1 : i+end]))
+			if err != nil {
+				return "", err
+			}
+			sb.WriteString(`(?s:.)` + rep)
+			i += end + 1
+		case c == '~':
+			if i+3 > len(body) {
+				return "", fmt.Errorf("~ must be followed by a byte")
+			}
+			class, err := nibbleClass(body[i+1:i+3], true)
+			if err != nil {
+				return "", err
+			}
+			sb.WriteString(class)
+			tokens++
+			i += 3
+		default:
+			if i+2 > len(body) {
+				return "", fmt.Errorf("odd number of hex digits")
+			}
+			class, err := nibbleClass(body[i:i+2], false)
+			if err != nil {
+				return "", err
+			}
+			sb.WriteString(class)
+			tokens++
+			i += 2
+		}
+	}
+	if depth != 0 {
+		return "", fmt.Errorf("unbalanced '('")
+	}
+	if tokens == 0 {
+		return "", fmt.Errorf("empty hex string")
+	}
+	return sb.String(), nil
+}
+
+func jumpRepeat(spec string) (string, error) {
+	lo, hi, found := strings.Cut(spec, "-")
+	lo, hi = strings.TrimSpace(lo), strings.TrimSpace(hi)
+	parse := func(s string) (int, error) {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 || n > 1000 {
+			return 0, fmt.Errorf("invalid jump [%s] (bounds must be 0-1000)", spec)
+		}
+		return n, nil
+	}
+	if !found {
+		n, err := parse(lo)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("{%d}", n), nil
+	}
+	min := 0
+	if lo != "" {
+		var err error
+		if min, err = parse(lo); err != nil {
+			return "", err
+		}
+	}
+	if hi == "" {
+		return fmt.Sprintf("{%d,}", min), nil
+	}
+	max, err := parse(hi)
+	if err != nil {
+		return "", err
+	}
+	if max < min {
+		return "", fmt.Errorf("invalid jump [%s]", spec)
+	}
+	return fmt.Sprintf("{%d,%d}", min, max), nil
+}
+
+// nibbleClass turns a two-character hex token such as "4D", "4?", "?D" or "??"
+// into a single-byte pattern, optionally negated
+func nibbleClass(tok string, negate bool) (string, error) {
+	hiWild, loWild := tok[0] == '?', tok[1] == '?'
+	if (!hiWild && !isHexDigit(tok[0])) || (!loWild && !isHexDigit(tok[1])) {
+		return "", fmt.Errorf("invalid hex token %q", tok)
+	}
+	if hiWild && loWild {
+		if negate {
+			return "", fmt.Errorf("~?? matches nothing")
+		}
+		return `(?s:.)`, nil
+	}
+
+	var members []byte
+	for v := 0; v < 256; v++ {
+		h, l := byte(v>>4), byte(v&0xf)
+		if (hiWild || h == hexVal(tok[0])) && (loWild || l == hexVal(tok[1])) {
+			members = append(members, byte(v))
+		}
+	}
+	if len(members) == 1 && !negate {
+		return fmt.Sprintf(`\x{%02x}`, members[0]), nil
+	}
+	var sb strings.Builder
+	sb.WriteByte('[')
+	if negate {
+		sb.WriteByte('^')
+	}
+	for _, m := range members {
+		fmt.Fprintf(&sb, `\x{%02x}`, m)
+	}
+	sb.WriteByte(']')
+	return sb.String(), nil
+}
+
+func hexVal(c byte) byte {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0'
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
+
+// scan finds every (possibly overlapping) match start in data
+func (s *stringDef) scan(data []byte) []stringHit {
+	var hits []stringHit
+	record := func(off, length int) bool {
+		if s.fullword && !isFullword(data, off, length) {
+			return true
+		}
+		hits = append(hits, stringHit{offset: off, length: length})
+		return len(hits) < maxStringHits
+	}
+
+	if len(s.prefix) > 0 {
+		for pos := 0; pos < len(data); {
+			i := bytes.Index(data[pos:], s.prefix)
+			if i < 0 {
+				break
+			}
+			off := pos + i
+			if loc := s.anchored.FindReaderIndex(&latin1Reader{data: data[off:]}); loc != nil {
+				if !record(off, loc[1]) {
+					break
+				}
+			}
+			pos = off + 1
+		}
+		return hits
+	}
+
+	for pos := 0; pos < len(data); {
+		loc := s.re.FindReaderIndex(&latin1Reader{data: data[pos:]})
+		if loc == nil {
+			break
+		}
+		off := pos + loc[0]
+		if !record(off, loc[1]-loc[0]) {
+			break
+		}
+		pos = off + 1
+	}
+	return hits
+}
+
+// isFullword reports whether a match is delimited by non-alphanumeric bytes,
+// looking past the NUL high bytes of UTF-16LE text
+func isFullword(data []byte, off, length int) bool {
+	wide := length >= 2 && data[off+1] == 0
+	before, after := off-1, off+length
+	if wide {
+		before = off - 2
+	}
+	if before >= 0 && isAlnum(data[before]) {
+		return false
+	}
+	if after < len(data) && isAlnum(data[after]) {
+		return false
+	}
+	return true
+}
+
+func isAlnum(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,165 @@
+package yara
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const sampleRules = `
+import "pe"
+
+private rule is_elf {
+    condition:
+        uint32(0) == 0x464c457f
+}
+
+rule upx_packed : packer {
+    meta:
+        author = "test"
+        score = 70
+        active = true
+    strings:
+        $magic = "UPX!"
+        $name = { 55 50 58 ( 21 | 3? ) }
+        $wide = "packed" wide nocase
+    condition:
+        is_elf and $magic at 8 and #name >= 1 and $wide
+}
+
+rule two_of_three {
+    strings:
+        $a = "alpha" fullword
+        $b = /be+ta/
+        $c = { 67 [1-3] 6D ( 6D | 61 ) }
+    condition:
+        2 of them and @b[1] > @a[1] and filesize < 1KB
+}
+
+rule uses_pe {
+    condition:
+        pe.number_of_sections > 2
+}
+
+rule none_here {
+    strings:
+        $x = "absent"
+    condition:
+        none of ($x*) and not uint16be(0) == 0x4d5a
+}
+`
+
+func sampleData() []byte {
+	return []byte("\x7fELF\x02\x01\x01\x00UPX!....P\x00A\x00C\x00K\x00E\x00D\x00.. alpha beeeta gXXmma")
+}
+
+func TestCompileAndScan(t *testing.T) {
+	rules, skipped, err := Compile(sampleRules)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	if len(rules) != 4 {
+		t.Fatalf("expected 4 rules, got %d", len(rules))
+	}
+	if len(skipped) != 1 || skipped[0].Rule != "uses_pe" {
+		t.Fatalf("expected uses_pe to be skipped, got %+v", skipped)
+	}
+	if rules[1].Meta["score"] != int64(70) || rules[1].Meta["active"] != true {
+		t.Errorf("unexpected meta: %v", rules[1].Meta)
+	}
+
+	matches := Scan(rules, sampleData())
+	names := map[string]Match{}
+	for _, m := range matches {
+		names[m.Rule] = m
+	}
+	if _, ok := names["is_elf"]; ok {
+		t.Error("private rules must not be reported")
+	}
+	upx, ok := names["upx_packed"]
+	if !ok {
+		t.Fatalf("expected upx_packed to match, got %+v", matches)
+	}
+	if len(upx.Strings) != 3 || upx.Strings[0].Offset != 8 || upx.Strings[0].Data != "55505821" {
+		t.Errorf("unexpected string matches: %+v", upx.Strings)
+	}
+	if upx.Strings[2].Length != 12 {
+		t.Errorf("wide match should be 12 bytes: %+v", upx.Strings[2])
+	}
+	if _, ok := names["two_of_three"]; !ok {
+		t.Error("expected two_of_three to match")
+	}
+	if _, ok := names["none_here"]; !ok {
+		t.Error("expected none_here to match")
+	}
+
+	huge, _, err := Compile(`rule huge_offset { condition: uint32(0x7fffffffffffffff) == 0 }`)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	if got := Scan(huge, sampleData()); len(got) != 0 {
+		t.Errorf("a read past the end of the data should be undefined: %+v", got)
+	}
+}
+
+func TestFullwordAndGlobal(t *testing.T) {
+	rules, _, err := Compile(`
+global rule small { condition: filesize < 100 }
+rule word { strings: $a = "alpha" fullword condition: $a }
+`)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	if got := Scan(rules, []byte("alphabet")); len(got) != 1 || got[0].Rule != "small" {
+		t.Errorf("fullword should not match inside a word: %+v", got)
+	}
+	if got := Scan(rules, []byte("an alpha.")); len(got) != 2 {
+		t.Errorf("expected small and word to match, got %+v", got)
+	}
+	big := make([]byte, 200)
+	copy(big, "alpha ")
+	if got := Scan(rules, big); len(got) != 0 {
+		t.Errorf("a failing global rule should suppress the namespace: %+v", got)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	cases := map[string]string{
+		"undefined string": `rule a { condition: $x }`,
+		"undefined rule":   `rule a { condition: b }`,
+		"bad hex":          `rule a { strings: $h = { 4D 5 } condition: $h }`,
+		"huge jump":        `rule a { strings: $h = { 4D [0-5000] 5A } condition: $h }`,
+		"bad regex":        `rule a { strings: $r = /(/ condition: $r }`,
+		"unterminated":     `rule a { condition: true`,
+		"duplicate":        `rule a { condition: true } rule a { condition: true }`,
+	}
+	for name, src := range cases {
+		if _, _, err := Compile(src); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestScannerReloadKeepsRulesOnError(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "base.yar"), []byte(sampleRules), 0644); err != nil {
+		t.Fatal(err)
+	}
+	scanner := NewScanner(dir)
+	if n, err := scanner.Reload(); err != nil || n != 4 {
+		t.Fatalf("Reload = %d, %v", n, err)
+	}
+	if len(scanner.Skipped()) != 1 || scanner.Skipped()[0].Namespace != "base.yar" {
+		t.Errorf("unexpected skipped rules: %+v", scanner.Skipped())
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "broken.yara"), []byte("rule {"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := scanner.Reload(); err == nil {
+		t.Fatal("expected reload to fail on a broken file")
+	}
+	if len(scanner.Rules()) != 4 {
+		t.Errorf("expected previous rules to be kept, got %d", len(scanner.Rules()))
+	}
+}